@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	"github.com/hash-walker/giki-wallet/internal/wallet"
+)
+
+// services are the dependencies subcommands may use
+type services struct {
+	payment *payment.Service
+	wallet  *wallet.Service
+}
+
+// runCommand executes a one-off subcommand instead of starting the server
+func runCommand(ctx context.Context, svc services, args []string) error {
+	switch args[0] {
+	case "reconcile-settlement":
+		return reconcileSettlement(ctx, svc, args[1:])
+	case "verify-ledger":
+		return verifyLedger(ctx, svc, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (available: reconcile-settlement, verify-ledger)", args[0])
+	}
+}
+
+// reconcileSettlement imports a gateway settlement CSV and prints the discrepancy report
+func reconcileSettlement(ctx context.Context, svc services, args []string) error {
+	flags := flag.NewFlagSet("reconcile-settlement", flag.ContinueOnError)
+	filePath := flags.String("file", "", "settlement CSV to import (required)")
+	gatewayName := flags.String("gateway", gateway.JazzCashName, "gateway that produced the file")
+	from := flags.String("from", "", "first day covered, YYYY-MM-DD (default: from file dates)")
+	to := flags.String("to", "", "last day covered, YYYY-MM-DD (default: from file dates)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *filePath == "" {
+		flags.Usage()
+		return fmt.Errorf("-file is required")
+	}
+
+	periodStart, periodEnd, err := payment.ParseSettlementPeriod(*from, *to)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(*filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report, err := svc.payment.ImportSettlement(ctx, payment.SettlementImport{
+		Gateway:     strings.ToUpper(*gatewayName),
+		SourceName:  filepath.Base(*filePath),
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+	}, file)
+	if err != nil {
+		return err
+	}
+
+	run := report.Run
+	fmt.Printf("Settlement run %s (%s, %s)\n", run.ID, run.Gateway, run.SourceName)
+	fmt.Printf("Period:        %s to %s\n", run.PeriodStart.Format("2006-01-02 15:04"), run.PeriodEnd.Format("2006-01-02 15:04"))
+	fmt.Printf("Rows:          %d\n", run.RowCount)
+	fmt.Printf("Matched:       %d\n", run.MatchedCount)
+	fmt.Printf("Discrepancies: %d\n", run.DiscrepancyCount)
+
+	if len(report.Discrepancies) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tTXN REF\tRRN\tLOCAL STATUS\tLOCAL AMOUNT\tGATEWAY AMOUNT")
+	for _, d := range report.Discrepancies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Kind, d.TxnRefNo, d.GatewayRRN, d.LocalStatus, formatAmount(d.LocalAmount), formatAmount(d.GatewayAmount))
+	}
+	return w.Flush()
+}
+
+func formatAmount(amount *money.Money) string {
+	if amount == nil {
+		return "-"
+	}
+	return amount.String()
+}
+
+// verifyLedger walks every wallet's hash chain and prints the first broken
+// link per wallet; it fails when anything does not check out
+func verifyLedger(ctx context.Context, svc services, args []string) error {
+	flags := flag.NewFlagSet("verify-ledger", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	result, err := svc.wallet.VerifyLedger(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wallets:           %d\n", result.WalletsChecked)
+	fmt.Printf("Entries:           %d\n", result.EntriesChecked)
+	fmt.Printf("Broken chains:     %d\n", len(result.Breaks))
+	fmt.Printf("Unbalanced groups: %d\n", len(result.UnbalancedGroups))
+
+	if len(result.Breaks) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "WALLET\tNAME\tSEQ\tENTRY\tREASON")
+		for _, b := range result.Breaks {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", b.WalletID, b.WalletName, b.Seq, b.EntryID, b.Reason)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(result.UnbalancedGroups) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TRANSACTION GROUP\tSUM")
+		for _, g := range result.UnbalancedGroups {
+			fmt.Fprintf(w, "%s\t%s\n", g.TransactionGroupID, g.Total)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if !result.OK() {
+		return fmt.Errorf("ledger verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/payment/testutils"
+)
+
+// defaultsRequest changes the answers for transactions no script matches
+type defaultsRequest struct {
+	MWallet testutils.Scenario `json:"mwallet,omitempty"`
+	Inquiry testutils.Scenario `json:"inquiry,omitempty"`
+	Refund  testutils.Scenario `json:"refund,omitempty"`
+}
+
+// ipnRequest optionally overrides the result an IPN push reports
+type ipnRequest struct {
+	Result testutils.Scenario `json:"result,omitempty"`
+}
+
+// failRequest makes the next N requests of any kind answer 503
+type failRequest struct {
+	Count int `json:"count"`
+}
+
+// mountAdminRoutes registers the runtime control API:
+//
+//	GET    /sim/scripts                  list scenario scripts in match order
+//	POST   /sim/scripts                  add a script
+//	DELETE /sim/scripts                  remove every script
+//	DELETE /sim/scripts/{id}             remove one script
+//	PUT    /sim/defaults                 set answers for unscripted transactions
+//	POST   /sim/outage                   answer the next N requests with 503
+//	GET    /sim/transactions             transactions seen so far
+//	POST   /sim/transactions/{txn}/ipn   push an IPN for a transaction now
+func mountAdminRoutes(mux *http.ServeMux, mock *testutils.MockGatewayServer) {
+	mux.HandleFunc("GET /sim/scripts", func(w http.ResponseWriter, r *http.Request) {
+		common.ResponseWithJSON(w, http.StatusOK, mock.Scripts())
+	})
+
+	mux.HandleFunc("POST /sim/scripts", func(w http.ResponseWriter, r *http.Request) {
+		var script testutils.ScenarioScript
+		if err := json.NewDecoder(r.Body).Decode(&script); err != nil {
+			common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		added, err := mock.AddScript(script)
+		if err != nil {
+			common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		common.ResponseWithJSON(w, http.StatusCreated, added)
+	})
+
+	mux.HandleFunc("DELETE /sim/scripts", func(w http.ResponseWriter, r *http.Request) {
+		mock.ClearScripts()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /sim/scripts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !mock.RemoveScript(r.PathValue("id")) {
+			common.ResponseWithError(w, http.StatusNotFound, "Script not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("PUT /sim/defaults", func(w http.ResponseWriter, r *http.Request) {
+		var req defaultsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		if req.MWallet != "" {
+			mock.SetMWalletScenario(req.MWallet)
+		}
+		if req.Inquiry != "" {
+			mock.SetInquiryScenario(req.Inquiry)
+		}
+		if req.Refund != "" {
+			mock.SetRefundScenario(req.Refund)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /sim/outage", func(w http.ResponseWriter, r *http.Request) {
+		var req failRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Count < 0 {
+			common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		mock.FailNextRequests(req.Count)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /sim/transactions", func(w http.ResponseWriter, r *http.Request) {
+		common.ResponseWithJSON(w, http.StatusOK, mock.Transactions())
+	})
+
+	mux.HandleFunc("POST /sim/transactions/{txnRefNo}/ipn", func(w http.ResponseWriter, r *http.Request) {
+		var req ipnRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+
+		err := mock.PushIPN(r.PathValue("txnRefNo"), req.Result)
+		switch {
+		case errors.Is(err, testutils.ErrUnknownTransaction):
+			common.ResponseWithError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			common.ResponseWithError(w, http.StatusBadGateway, err.Error())
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Command jazzcash-sim runs the JazzCash mock gateway as a standalone sandbox
+// so the whole top-up flow can be clicked through without a real gateway.
+//
+// Point the backend at it with:
+//
+//	JAZZCASH_INTEGRITY_SALT=<-salt>
+//	JAZZCASH_WALLET_PAYMENT_URL=http://localhost:9090/ApplicationAPI/API/2.0/Purchase/DoMWalletTransaction
+//	JAZZCASH_STATUS_INQUIRY_URL=http://localhost:9090/ApplicationAPI/API/PaymentInquiry/Inquire
+//	JAZZCASH_CARD_PAYMENT_URL=http://localhost:9090/ApplicationAPI/API/CardPayment
+//	JAZZCASH_WALLET_REFUND_URL=http://localhost:9090/ApplicationAPI/API/Purchase/domwalletrefundtransaction
+//	JAZZCASH_CARD_REFUND_URL=http://localhost:9090/ApplicationAPI/API/authorize/Refund
+//
+// Scenarios are changed at runtime through the admin API under /sim.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/payment/testutils"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	salt := flag.String("salt", envOr("JAZZCASH_INTEGRITY_SALT", "sim_integrity_salt"), "integrity salt shared with the backend")
+	ipnURL := flag.String("ipn-url", "http://localhost:8080/payments/ipn/jazzcash", "where IPN pushes are posted")
+	scriptsFile := flag.String("scripts", "", "JSON file with scenario scripts to load at start")
+	flag.Parse()
+
+	mock := testutils.NewMockGateway(*salt)
+	mock.SetIPNURL(*ipnURL)
+
+	if *scriptsFile != "" {
+		if err := loadScripts(mock, *scriptsFile); err != nil {
+			log.Fatalf("loading %s: %v", *scriptsFile, err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mountAdminRoutes(mux, mock)
+	mux.Handle("/", mock.Handler())
+
+	server := &http.Server{
+		Addr:    *addr,
+		Handler: mux,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("JazzCash simulator listening on %s (IPN to %s)", *addr, *ipnURL)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+}
+
+// loadScripts adds the scripts in a JSON array file, in order
+func loadScripts(mock *testutils.MockGatewayServer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var scripts []testutils.ScenarioScript
+	if err := json.Unmarshal(data, &scripts); err != nil {
+		return err
+	}
+
+	for _, script := range scripts {
+		added, err := mock.AddScript(script)
+		if err != nil {
+			return err
+		}
+		log.Printf("loaded scenario %s: %+v", added.ID, added)
+	}
+	return nil
+}
+
+func envOr(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -2,16 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/hash-walker/giki-wallet/internal/api"
 	"github.com/hash-walker/giki-wallet/internal/auth"
 	"github.com/hash-walker/giki-wallet/internal/config"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/notification"
 	"github.com/hash-walker/giki-wallet/internal/payment"
 	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	"github.com/hash-walker/giki-wallet/internal/storage"
 	"github.com/hash-walker/giki-wallet/internal/user"
+	"github.com/hash-walker/giki-wallet/internal/wallet"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/go-chi/cors"
@@ -19,7 +28,8 @@ import (
 )
 
 func main() {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
 	// Load .env file if it exists (for local development)
 	// In Docker, environment variables come from docker-compose.yml
@@ -32,17 +42,10 @@ func main() {
 
 	cfg := config.LoadConfig()
 
-	jazzcashClient := gateway.NewJazzCashClient(
-		cfg.Jazzcash.MerchantID,
-		cfg.Jazzcash.Password,
-		cfg.Jazzcash.IntegritySalt,
-		cfg.Jazzcash.MerchantMPIN,
-		cfg.Jazzcash.CardCallbackURL,
-		cfg.Jazzcash.BaseURL,
-		cfg.Jazzcash.WalletPaymentURl,
-		cfg.Jazzcash.CardPaymentURL,
-		cfg.Jazzcash.StatusInquiryURL,
-	)
+	jazzcashCodes, err := gateway.LoadJazzCashResponseCodes(cfg.Jazzcash.ResponseCodesFile)
+	if err != nil {
+		log.Fatalf("Unable to load JazzCash response codes: %v\n", err)
+	}
 
 	pool, err := pgxpool.New(ctx, dbURL)
 	if err != nil {
@@ -55,10 +58,57 @@ func main() {
 	userHandler := user.NewHandler(userService)
 	authService := auth.NewService(pool)
 	authHandler := auth.NewHandler(authService)
-	paymentService := payment.NewService(pool, jazzcashClient, inquiryRateLimiter)
-	_ = payment.NewHandler(paymentService)
+	paymentProviders := payment.NewRegistry()
+	registerJazzCash(ctx, paymentProviders, payment.DefaultMerchantProfile, cfg.Jazzcash.JazzcashMerchantConfig, cfg.Jazzcash, jazzcashCodes)
+	for name, merchant := range cfg.Jazzcash.Profiles {
+		registerJazzCash(ctx, paymentProviders, name, merchant, cfg.Jazzcash, jazzcashCodes)
+	}
+	for userType, profile := range cfg.Jazzcash.ProfileRoutes {
+		paymentProviders.Route(userType, profile)
+	}
+	if cfg.Easypaisa.Enabled() {
+		easypaisaClient := gateway.NewEasypaisaClient(
+			cfg.Easypaisa.StoreID,
+			cfg.Easypaisa.Username,
+			cfg.Easypaisa.Password,
+			cfg.Easypaisa.HashKey,
+			cfg.Easypaisa.InitiateURL,
+			cfg.Easypaisa.InquiryURL,
+			gateway.DefaultResilienceConfig(),
+		)
+		paymentProviders.Register(payment.PaymentMethodEasypaisa, payment.NewEasypaisaProvider(easypaisaClient))
+	}
+	receipts, err := storage.NewLocalDisk(cfg.Storage.Dir)
+	if err != nil {
+		log.Fatalf("Unable to open upload storage: %v\n", err)
+	}
+	notificationService := notification.NewService(pool)
+	notificationHandler := notification.NewHandler(notificationService)
+	walletService := wallet.NewService(pool, notificationService, wallet.TransferLimits{
+		MaxPerTransaction: money.Paisa(cfg.Transfer.MaxPaisa),
+		DailyCap:          money.Paisa(cfg.Transfer.DailyCapPaisa),
+	}, []byte(cfg.Ledger.HMACKey), cfg.Statement.VerifyURL)
+	walletHandler := wallet.NewHandler(walletService)
+	// Modules that sell through payment intents register their completion handlers here
+	paymentPurposes := payment.NewPurposeRegistry()
+	paymentPurposes.Register(payment.PurposeTopUp, payment.PurposeHandlerFunc(walletService.FulfillTopUp))
+	paymentService := payment.NewService(pool, paymentProviders, paymentPurposes, walletService, inquiryRateLimiter, topUpLimits(cfg.TopUp), receipts)
+	statusBroker := payment.NewStatusBroker(pool)
+	paymentHandler := payment.NewHandler(paymentService, payment.HandlerConfig{
+		CardReturnPath: cfg.Jazzcash.CardCallbackPath(),
+		CardResultURL:  cfg.Jazzcash.CardResultURL,
+		Statuses:       statusBroker,
+	})
 
-	srv := api.NewServer(userHandler, authHandler)
+	// One-off subcommands share the wiring above but never start the server
+	if len(os.Args) > 1 {
+		if err := runCommand(ctx, services{payment: paymentService, wallet: walletService}, os.Args[1:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	srv := api.NewServer(userHandler, authHandler, paymentHandler, walletHandler, notificationHandler)
 	srv.MountRoutes()
 
 	c := cors.New(cors.Options{
@@ -82,6 +132,118 @@ func main() {
 		Handler: handler,
 	}
 
-	log.Printf("Server starting on port %s\n", port)
-	log.Fatal(server.ListenAndServe())
+	// Reconciler settles pending gateway transactions until shutdown
+	reconciler := payment.NewReconciler(paymentService, payment.DefaultReconcilerConfig())
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reconciler.Run(ctx)
+	}()
+
+	// Sweeper closes transactions after a final inquiry once they expire at the gateway
+	sweeper := payment.NewSweeper(paymentService, payment.DefaultSweeperConfig())
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		sweeper.Run(ctx)
+	}()
+
+	// Ledger auditor re-verifies every wallet's hash chain in the background
+	ledgerAuditor := wallet.NewLedgerAuditor(walletService, wallet.DefaultLedgerAuditorConfig())
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		ledgerAuditor.Run(ctx)
+	}()
+
+	// Status broker feeds /payments/{txnRefNo}/events with changes from every replica
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		statusBroker.Run(ctx)
+	}()
+
+	go func() {
+		log.Printf("Server starting on port %s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	workers.Wait()
+}
+
+// registerJazzCash registers the JazzCash wallet and card providers for one
+// merchant profile, each profile getting its own client and circuit breaker.
+// Credentials from a file are watched for rotations until ctx is cancelled.
+func registerJazzCash(
+	ctx context.Context,
+	providers *payment.Registry,
+	profile string,
+	merchant config.JazzcashMerchantConfig,
+	cfg config.JazzcashConfig,
+	codes *gateway.ResponseCodeCatalog,
+) {
+	credentials := gateway.NewCredentialStore(gateway.JazzCashCredentials{
+		Version:       "env",
+		MerchantID:    merchant.MerchantID,
+		Password:      merchant.Password,
+		IntegritySalt: merchant.IntegritySalt,
+		MerchantMPIN:  merchant.MerchantMPIN,
+	}, cfg.CredentialGrace)
+	if merchant.CredentialsFile != "" {
+		var err error
+		credentials, err = gateway.LoadCredentialStore(merchant.CredentialsFile, cfg.CredentialGrace)
+		if err != nil {
+			log.Fatalf("Unable to load JazzCash credentials for profile %s: %v\n", profile, err)
+		}
+		go credentials.Watch(ctx, merchant.CredentialsFile)
+	}
+
+	client := gateway.NewJazzCashClient(
+		credentials,
+		cfg.CardCallbackURL,
+		merchant.BaseURL,
+		merchant.WalletPaymentURl,
+		merchant.CardPaymentURL,
+		merchant.StatusInquiryURL,
+		merchant.WalletRefundURL,
+		merchant.CardRefundURL,
+		gateway.DefaultResilienceConfig(),
+		codes,
+	)
+
+	providers.RegisterProfile(profile, payment.PaymentMethodMWallet, payment.NewMWalletProvider(client))
+	providers.RegisterProfile(profile, payment.PaymentMethodCard, payment.NewCardProvider(client, cfg.CardCallbackURL))
+}
+
+// topUpLimits converts the paisa amounts in config into payment limits
+func topUpLimits(cfg config.TopUpLimitsConfig) payment.TopUpLimits {
+	userTypes := make(map[string]payment.UserTypeLimits, len(cfg.UserTypeCaps))
+	for userType, caps := range cfg.UserTypeCaps {
+		userTypes[userType] = payment.UserTypeLimits{
+			DailyCap:   money.Paisa(caps.DailyCapPaisa),
+			MonthlyCap: money.Paisa(caps.MonthlyCapPaisa),
+		}
+	}
+
+	return payment.TopUpLimits{
+		MinPerTransaction:  money.Paisa(cfg.MinPaisa),
+		MaxPerTransaction:  money.Paisa(cfg.MaxPaisa),
+		DailyCap:           money.Paisa(cfg.DailyCapPaisa),
+		MonthlyCap:         money.Paisa(cfg.MonthlyCapPaisa),
+		UserTypes:          userTypes,
+		MaxAttemptsPerHour: cfg.MaxAttemptsPerHour,
+		MaxPending:         cfg.MaxPending,
+	}
 }
@@ -6,20 +6,35 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/notification"
+	"github.com/hash-walker/giki-wallet/internal/payment"
 	"github.com/hash-walker/giki-wallet/internal/user"
+	"github.com/hash-walker/giki-wallet/internal/wallet"
 )
 
 type Server struct {
-	Router *chi.Mux
-	User   *user.Handler
-	Auth   *auth.Handler
+	Router       *chi.Mux
+	User         *user.Handler
+	Auth         *auth.Handler
+	Payment      *payment.Handler
+	Wallet       *wallet.Handler
+	Notification *notification.Handler
 }
 
-func NewServer(userHandler *user.Handler, authHandler *auth.Handler) *Server {
+func NewServer(
+	userHandler *user.Handler,
+	authHandler *auth.Handler,
+	paymentHandler *payment.Handler,
+	walletHandler *wallet.Handler,
+	notificationHandler *notification.Handler,
+) *Server {
 	return &Server{
-		Router: chi.NewRouter(),
-		User:   userHandler,
-		Auth:   authHandler,
+		Router:       chi.NewRouter(),
+		User:         userHandler,
+		Auth:         authHandler,
+		Payment:      paymentHandler,
+		Wallet:       walletHandler,
+		Notification: notificationHandler,
 	}
 }
 
@@ -38,4 +53,62 @@ func (s *Server) MountRoutes() {
 	s.Router.Post("/auth/register", s.User.Register)
 	s.Router.Post("/auth/signin", s.Auth.Login)
 
+	// JazzCash posts card results from the customer's browser, so no auth here
+	s.Router.Post(s.Payment.CardReturnPath(), s.Payment.CardReturn)
+
+	// Gateways push payment results server-to-server; the payload signature is the auth
+	s.Router.Post("/payments/ipn/{gateway}", s.Payment.Notification)
+
+	// Anyone holding a statement can check its verification code
+	s.Router.Get("/statements/verify/{code}", s.Wallet.VerifyStatement)
+
+	s.Router.Group(func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+
+		r.Post("/payments/topup", s.Payment.TopUp)
+		r.Post("/payments/bank-transfers", s.Payment.SubmitBankTransfer)
+		r.Get("/payments/intents/{intentID}", s.Payment.GetIntent)
+		r.Post("/payments/intents/{intentID}/pay", s.Payment.PayIntent)
+		r.Get("/payments/gateways", s.Payment.GatewayHealth)
+		r.Get("/payments/{txnRefNo}", s.Payment.GetPaymentStatus)
+		r.Get("/payments/{txnRefNo}/events", s.Payment.StreamPaymentStatus)
+
+		r.Get("/wallet", s.Wallet.GetWallet)
+		r.Get("/wallet/transactions", s.Wallet.ListTransactions)
+		r.Post("/wallet/transfers", s.Wallet.Transfer)
+		r.Get("/wallet/statement", s.Wallet.Statement)
+
+		r.Get("/notifications", s.Notification.ListNotifications)
+		r.Post("/notifications/{notificationID}/read", s.Notification.MarkRead)
+	})
+
+	s.Router.Route("/admin", func(r chi.Router) {
+		r.Use(auth.RequireAuth)
+		r.Use(s.Auth.RequireAdmin)
+
+		r.Get("/payments", s.Payment.SearchPayments)
+		r.Get("/payments/export", s.Payment.ExportPayments)
+		r.Get("/payments/{txnRefNo}/timeline", s.Payment.GetTransactionTimeline)
+		r.Post("/payments/{txnRefNo}/inquire", s.Payment.ReinquireTransaction)
+		r.Post("/payments/{txnRefNo}/resolve", s.Payment.ResolveTransaction)
+		r.Get("/late-successes", s.Payment.ListLateSuccesses)
+		r.Post("/payments/{txnRefNo}/refunds", s.Payment.RequestRefund)
+		r.Get("/payments/{txnRefNo}/refunds", s.Payment.ListRefunds)
+		r.Get("/refunds/unknown", s.Payment.ListUnknownRefunds)
+		r.Get("/refunds/{refundID}", s.Payment.GetRefund)
+		r.Post("/refunds/{refundID}/resolve", s.Payment.ResolveRefund)
+
+		r.Post("/settlements", s.Payment.ImportSettlement)
+		r.Get("/settlements", s.Payment.ListSettlementRuns)
+		r.Get("/settlements/{runID}", s.Payment.GetSettlementReport)
+		r.Post("/settlements/discrepancies/{discrepancyID}/resolve", s.Payment.ResolveDiscrepancy)
+
+		r.Get("/bank-transfers", s.Payment.ListBankTransfers)
+		r.Get("/bank-transfers/{transferID}/receipt", s.Payment.GetBankTransferReceipt)
+		r.Post("/bank-transfers/{transferID}/review", s.Payment.ReviewBankTransfer)
+
+		r.Get("/users/{userID}/statement", s.Wallet.UserStatement)
+		r.Get("/ledger/trial-balance", s.Wallet.TrialBalance)
+	})
+
 }
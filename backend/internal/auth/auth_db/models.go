@@ -13,6 +13,49 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type BankTransferReview string
+
+const (
+	BankTransferReviewPENDING  BankTransferReview = "PENDING"
+	BankTransferReviewAPPROVED BankTransferReview = "APPROVED"
+	BankTransferReviewREJECTED BankTransferReview = "REJECTED"
+)
+
+func (e *BankTransferReview) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = BankTransferReview(s)
+	case string:
+		*e = BankTransferReview(s)
+	default:
+		return fmt.Errorf("unsupported scan type for BankTransferReview: %T", src)
+	}
+	return nil
+}
+
+type NullBankTransferReview struct {
+	BankTransferReview BankTransferReview `json:"bank_transfer_review"`
+	Valid              bool               `json:"valid"` // Valid is true if BankTransferReview is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullBankTransferReview) Scan(value interface{}) error {
+	if value == nil {
+		ns.BankTransferReview, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.BankTransferReview.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullBankTransferReview) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.BankTransferReview), nil
+}
+
 type CurrentStatus string
 
 const (
@@ -57,12 +100,290 @@ func (ns NullCurrentStatus) Value() (driver.Value, error) {
 	return string(ns.CurrentStatus), nil
 }
 
+type GatewayEventKind string
+
+const (
+	GatewayEventKindINITIATE GatewayEventKind = "INITIATE"
+	GatewayEventKindINQUIRY  GatewayEventKind = "INQUIRY"
+	GatewayEventKindCALLBACK GatewayEventKind = "CALLBACK"
+	GatewayEventKindIPN      GatewayEventKind = "IPN"
+	GatewayEventKindREFUND   GatewayEventKind = "REFUND"
+	GatewayEventKindMANUAL   GatewayEventKind = "MANUAL"
+)
+
+func (e *GatewayEventKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = GatewayEventKind(s)
+	case string:
+		*e = GatewayEventKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for GatewayEventKind: %T", src)
+	}
+	return nil
+}
+
+type NullGatewayEventKind struct {
+	GatewayEventKind GatewayEventKind `json:"gateway_event_kind"`
+	Valid            bool             `json:"valid"` // Valid is true if GatewayEventKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullGatewayEventKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.GatewayEventKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.GatewayEventKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullGatewayEventKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.GatewayEventKind), nil
+}
+
+type PaymentIntentState string
+
+const (
+	PaymentIntentStateREQUIRESPAYMENT PaymentIntentState = "REQUIRES_PAYMENT"
+	PaymentIntentStatePROCESSING      PaymentIntentState = "PROCESSING"
+	PaymentIntentStateFAILED          PaymentIntentState = "FAILED"
+	PaymentIntentStateSUCCEEDED       PaymentIntentState = "SUCCEEDED"
+	PaymentIntentStateFULFILLED       PaymentIntentState = "FULFILLED"
+	PaymentIntentStateCANCELLED       PaymentIntentState = "CANCELLED"
+)
+
+func (e *PaymentIntentState) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PaymentIntentState(s)
+	case string:
+		*e = PaymentIntentState(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PaymentIntentState: %T", src)
+	}
+	return nil
+}
+
+type NullPaymentIntentState struct {
+	PaymentIntentState PaymentIntentState `json:"payment_intent_state"`
+	Valid              bool               `json:"valid"` // Valid is true if PaymentIntentState is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPaymentIntentState) Scan(value interface{}) error {
+	if value == nil {
+		ns.PaymentIntentState, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PaymentIntentState.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPaymentIntentState) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PaymentIntentState), nil
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPENDING RefundStatus = "PENDING"
+	RefundStatusSUCCESS RefundStatus = "SUCCESS"
+	RefundStatusFAILED  RefundStatus = "FAILED"
+	RefundStatusUNKNOWN RefundStatus = "UNKNOWN"
+)
+
+func (e *RefundStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = RefundStatus(s)
+	case string:
+		*e = RefundStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for RefundStatus: %T", src)
+	}
+	return nil
+}
+
+type NullRefundStatus struct {
+	RefundStatus RefundStatus `json:"refund_status"`
+	Valid        bool         `json:"valid"` // Valid is true if RefundStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullRefundStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.RefundStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.RefundStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullRefundStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.RefundStatus), nil
+}
+
+type SettlementDiscrepancyKind string
+
+const (
+	SettlementDiscrepancyKindPAIDNOTSUCCESS    SettlementDiscrepancyKind = "PAID_NOT_SUCCESS"
+	SettlementDiscrepancyKindSUCCESSNOTSETTLED SettlementDiscrepancyKind = "SUCCESS_NOT_SETTLED"
+	SettlementDiscrepancyKindAMOUNTMISMATCH    SettlementDiscrepancyKind = "AMOUNT_MISMATCH"
+)
+
+func (e *SettlementDiscrepancyKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = SettlementDiscrepancyKind(s)
+	case string:
+		*e = SettlementDiscrepancyKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for SettlementDiscrepancyKind: %T", src)
+	}
+	return nil
+}
+
+type NullSettlementDiscrepancyKind struct {
+	SettlementDiscrepancyKind SettlementDiscrepancyKind `json:"settlement_discrepancy_kind"`
+	Valid                     bool                      `json:"valid"` // Valid is true if SettlementDiscrepancyKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullSettlementDiscrepancyKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.SettlementDiscrepancyKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.SettlementDiscrepancyKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullSettlementDiscrepancyKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.SettlementDiscrepancyKind), nil
+}
+
+type WalletStatus string
+
+const (
+	WalletStatusACTIVE WalletStatus = "ACTIVE"
+	WalletStatusFROZEN WalletStatus = "FROZEN"
+)
+
+func (e *WalletStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WalletStatus(s)
+	case string:
+		*e = WalletStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WalletStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWalletStatus struct {
+	WalletStatus WalletStatus `json:"wallet_status"`
+	Valid        bool         `json:"valid"` // Valid is true if WalletStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWalletStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WalletStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WalletStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWalletStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WalletStatus), nil
+}
+
+type WalletType string
+
+const (
+	WalletTypePERSONAL     WalletType = "PERSONAL"
+	WalletTypeSYSREVENUE   WalletType = "SYS_REVENUE"
+	WalletTypeSYSLIABILITY WalletType = "SYS_LIABILITY"
+)
+
+func (e *WalletType) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WalletType(s)
+	case string:
+		*e = WalletType(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WalletType: %T", src)
+	}
+	return nil
+}
+
+type NullWalletType struct {
+	WalletType WalletType `json:"wallet_type"`
+	Valid      bool       `json:"valid"` // Valid is true if WalletType is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWalletType) Scan(value interface{}) error {
+	if value == nil {
+		ns.WalletType, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WalletType.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWalletType) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WalletType), nil
+}
+
 type GikiWalletAdmin struct {
 	UserID      uuid.UUID `json:"user_id"`
 	Role        string    `json:"role"`
 	Permissions []string  `json:"permissions"`
 }
 
+type GikiWalletBankTransfer struct {
+	ID                   uuid.UUID          `json:"id"`
+	GatewayTransactionID uuid.UUID          `json:"gateway_transaction_id"`
+	BankReference        string             `json:"bank_reference"`
+	ReceiptKey           string             `json:"receipt_key"`
+	ReceiptContentType   string             `json:"receipt_content_type"`
+	ReviewStatus         BankTransferReview `json:"review_status"`
+	ReviewedBy           pgtype.UUID        `json:"reviewed_by"`
+	ReviewReason         pgtype.Text        `json:"review_reason"`
+	ReviewedAt           pgtype.Timestamptz `json:"reviewed_at"`
+	CreatedAt            time.Time          `json:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+}
+
 type GikiWalletEmployeeProfile struct {
 	UserID      uuid.UUID   `json:"user_id"`
 	EmployeeID  string      `json:"employee_id"`
@@ -70,20 +391,107 @@ type GikiWalletEmployeeProfile struct {
 	Department  pgtype.Text `json:"department"`
 }
 
+type GikiWalletGatewayLateSuccess struct {
+	ID                   uuid.UUID        `json:"id"`
+	GatewayTransactionID uuid.UUID        `json:"gateway_transaction_id"`
+	Source               GatewayEventKind `json:"source"`
+	PreviousResponseCode pgtype.Text      `json:"previous_response_code"`
+	ResponseCode         pgtype.Text      `json:"response_code"`
+	GatewayRrn           pgtype.Text      `json:"gateway_rrn"`
+	FailedAt             time.Time        `json:"failed_at"`
+	CreatedAt            time.Time        `json:"created_at"`
+}
+
+type GikiWalletGatewayNotification struct {
+	ID           uuid.UUID `json:"id"`
+	Gateway      string    `json:"gateway"`
+	TxnRefNo     string    `json:"txn_ref_no"`
+	DedupKey     string    `json:"dedup_key"`
+	ResponseCode string    `json:"response_code"`
+	Payload      []byte    `json:"payload"`
+	ReceivedAt   time.Time `json:"received_at"`
+}
+
 type GikiWalletGatewayTransaction struct {
-	ID             uuid.UUID     `json:"id"`
-	UserID         uuid.UUID     `json:"user_id"`
-	IdempotencyKey uuid.UUID     `json:"idempotency_key"`
-	BillRefID      string        `json:"bill_ref_id"`
-	TxnRefNo       string        `json:"txn_ref_no"`
-	PaymentMethod  string        `json:"payment_method"`
-	GatewayRrn     pgtype.Text   `json:"gateway_rrn"`
-	Status         CurrentStatus `json:"status"`
-	Amount         int64         `json:"amount"`
-	RawResponse    []byte        `json:"raw_response"`
-	IsPolling      pgtype.Bool   `json:"is_polling"`
-	CreatedAt      time.Time     `json:"created_at"`
-	UpdatedAt      time.Time     `json:"updated_at"`
+	ID              uuid.UUID          `json:"id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	IdempotencyKey  uuid.UUID          `json:"idempotency_key"`
+	BillRefID       string             `json:"bill_ref_id"`
+	TxnRefNo        string             `json:"txn_ref_no"`
+	PaymentMethod   string             `json:"payment_method"`
+	GatewayRrn      pgtype.Text        `json:"gateway_rrn"`
+	Status          CurrentStatus      `json:"status"`
+	Amount          int64              `json:"amount"`
+	RawResponse     []byte             `json:"raw_response"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	LeaseOwner      pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt  pgtype.Timestamptz `json:"lease_expires_at"`
+	NextInquiryAt   time.Time          `json:"next_inquiry_at"`
+	InquiryAttempts int32              `json:"inquiry_attempts"`
+	ResponseCode    pgtype.Text        `json:"response_code"`
+	ExpiresAt       time.Time          `json:"expires_at"`
+	MerchantProfile string             `json:"merchant_profile"`
+	PaymentIntentID uuid.UUID          `json:"payment_intent_id"`
+}
+
+type GikiWalletGatewayTransactionEvent struct {
+	ID                   uuid.UUID        `json:"id"`
+	GatewayTransactionID uuid.UUID        `json:"gateway_transaction_id"`
+	Gateway              string           `json:"gateway"`
+	Kind                 GatewayEventKind `json:"kind"`
+	Request              []byte           `json:"request"`
+	Response             []byte           `json:"response"`
+	ResponseCode         pgtype.Text      `json:"response_code"`
+	GatewayRrn           pgtype.Text      `json:"gateway_rrn"`
+	Error                pgtype.Text      `json:"error"`
+	CreatedAt            time.Time        `json:"created_at"`
+}
+
+type GikiWalletLedger struct {
+	ID                 uuid.UUID `json:"id"`
+	Seq                int64     `json:"seq"`
+	WalletID           uuid.UUID `json:"wallet_id"`
+	Amount             int64     `json:"amount"`
+	BalanceAfter       int64     `json:"balance_after"`
+	TransactionGroupID uuid.UUID `json:"transaction_group_id"`
+	TransactionType    string    `json:"transaction_type"`
+	ReferenceID        string    `json:"reference_id"`
+	Description        string    `json:"description"`
+	RowHash            string    `json:"row_hash"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type GikiWalletNotification struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      uuid.UUID          `json:"user_id"`
+	Type        string             `json:"type"`
+	Channel     string             `json:"channel"`
+	Destination pgtype.Text        `json:"destination"`
+	Title       string             `json:"title"`
+	Body        string             `json:"body"`
+	Data        []byte             `json:"data"`
+	Status      string             `json:"status"`
+	ErrorLog    pgtype.Text        `json:"error_log"`
+	CreatedAt   time.Time          `json:"created_at"`
+	SentAt      pgtype.Timestamptz `json:"sent_at"`
+	ReadAt      pgtype.Timestamptz `json:"read_at"`
+}
+
+type GikiWalletPaymentIntent struct {
+	ID                  uuid.UUID          `json:"id"`
+	Purpose             string             `json:"purpose"`
+	PurposeReference    string             `json:"purpose_reference"`
+	Amount              int64              `json:"amount"`
+	PayerID             uuid.UUID          `json:"payer_id"`
+	State               PaymentIntentState `json:"state"`
+	Metadata            []byte             `json:"metadata"`
+	FulfillmentAttempts int32              `json:"fulfillment_attempts"`
+	NextFulfillmentAt   pgtype.Timestamptz `json:"next_fulfillment_at"`
+	LastError           pgtype.Text        `json:"last_error"`
+	FulfilledAt         pgtype.Timestamptz `json:"fulfilled_at"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
 }
 
 type GikiWalletRefreshToken struct {
@@ -99,6 +507,55 @@ type GikiWalletRefreshToken struct {
 	UpdatedAt       time.Time        `json:"updated_at"`
 }
 
+type GikiWalletRefundRequest struct {
+	ID                   uuid.UUID          `json:"id"`
+	GatewayTransactionID uuid.UUID          `json:"gateway_transaction_id"`
+	IdempotencyKey       uuid.UUID          `json:"idempotency_key"`
+	RequestedBy          uuid.UUID          `json:"requested_by"`
+	Reason               string             `json:"reason"`
+	Amount               int64              `json:"amount"`
+	Status               RefundStatus       `json:"status"`
+	Attempts             int32              `json:"attempts"`
+	ResponseCode         pgtype.Text        `json:"response_code"`
+	ResponseMessage      pgtype.Text        `json:"response_message"`
+	RawResponse          []byte             `json:"raw_response"`
+	LeaseOwner           pgtype.Text        `json:"lease_owner"`
+	LeaseExpiresAt       pgtype.Timestamptz `json:"lease_expires_at"`
+	NextAttemptAt        time.Time          `json:"next_attempt_at"`
+	HoldReleasedAt       pgtype.Timestamptz `json:"hold_released_at"`
+	ProcessedAt          pgtype.Timestamptz `json:"processed_at"`
+	CreatedAt            time.Time          `json:"created_at"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+}
+
+type GikiWalletSettlementDiscrepancy struct {
+	ID                 uuid.UUID                 `json:"id"`
+	RunID              uuid.UUID                 `json:"run_id"`
+	Kind               SettlementDiscrepancyKind `json:"kind"`
+	TxnRefNo           pgtype.Text               `json:"txn_ref_no"`
+	GatewayRrn         pgtype.Text               `json:"gateway_rrn"`
+	LocalStatus        NullCurrentStatus         `json:"local_status"`
+	LocalAmountPaisa   pgtype.Int8               `json:"local_amount_paisa"`
+	GatewayAmountPaisa pgtype.Int8               `json:"gateway_amount_paisa"`
+	ResolvedAt         pgtype.Timestamptz        `json:"resolved_at"`
+	ResolvedBy         pgtype.UUID               `json:"resolved_by"`
+	ResolutionNote     pgtype.Text               `json:"resolution_note"`
+	CreatedAt          time.Time                 `json:"created_at"`
+}
+
+type GikiWalletSettlementRun struct {
+	ID               uuid.UUID   `json:"id"`
+	Gateway          string      `json:"gateway"`
+	SourceName       string      `json:"source_name"`
+	ImportedBy       pgtype.UUID `json:"imported_by"`
+	PeriodStart      time.Time   `json:"period_start"`
+	PeriodEnd        time.Time   `json:"period_end"`
+	RowCount         int32       `json:"row_count"`
+	MatchedCount     int32       `json:"matched_count"`
+	DiscrepancyCount int32       `json:"discrepancy_count"`
+	CreatedAt        time.Time   `json:"created_at"`
+}
+
 type GikiWalletStudentProfile struct {
 	UserID        uuid.UUID   `json:"user_id"`
 	RegID         string      `json:"reg_id"`
@@ -121,3 +578,46 @@ type GikiWalletUser struct {
 	CreatedAt    time.Time   `json:"created_at"`
 	UpdatedAt    time.Time   `json:"updated_at"`
 }
+
+type GikiWalletWallet struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     pgtype.UUID  `json:"user_id"`
+	Code       pgtype.Text  `json:"code"`
+	Name       string       `json:"name"`
+	Type       WalletType   `json:"type"`
+	Status     WalletStatus `json:"status"`
+	Currency   string       `json:"currency"`
+	HeadHash   pgtype.Text  `json:"head_hash"`
+	EntryCount int64        `json:"entry_count"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+type GikiWalletWalletStatement struct {
+	ID               uuid.UUID `json:"id"`
+	VerificationCode string    `json:"verification_code"`
+	WalletID         uuid.UUID `json:"wallet_id"`
+	HolderName       string    `json:"holder_name"`
+	PeriodStart      time.Time `json:"period_start"`
+	PeriodEnd        time.Time `json:"period_end"`
+	OpeningBalance   int64     `json:"opening_balance"`
+	ClosingBalance   int64     `json:"closing_balance"`
+	TotalCredits     int64     `json:"total_credits"`
+	TotalDebits      int64     `json:"total_debits"`
+	EntryCount       int32     `json:"entry_count"`
+	Format           string    `json:"format"`
+	DocumentSha256   string    `json:"document_sha256"`
+	IssuedBy         uuid.UUID `json:"issued_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type GikiWalletWalletTransfer struct {
+	ID                 uuid.UUID `json:"id"`
+	IdempotencyKey     uuid.UUID `json:"idempotency_key"`
+	SenderID           uuid.UUID `json:"sender_id"`
+	RecipientID        uuid.UUID `json:"recipient_id"`
+	Amount             int64     `json:"amount"`
+	Note               string    `json:"note"`
+	TransactionGroupID uuid.UUID `json:"transaction_group_id"`
+	CreatedAt          time.Time `json:"created_at"`
+}
@@ -6,10 +6,13 @@ package auth
 
 import (
 	"context"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (GikiWalletRefreshToken, error)
+	GetAdminByUserID(ctx context.Context, userID uuid.UUID) (GikiWalletAdmin, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -42,3 +42,19 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 	)
 	return i, err
 }
+
+const getAdminByUserID = `-- name: GetAdminByUserID :one
+SELECT user_id, role, permissions FROM giki_wallet.admins
+WHERE user_id = $1
+`
+
+func (q *Queries) GetAdminByUserID(ctx context.Context, userID uuid.UUID) (GikiWalletAdmin, error) {
+	row := q.db.QueryRow(ctx, getAdminByUserID, userID)
+	var i GikiWalletAdmin
+	err := row.Scan(
+		&i.UserID,
+		&i.Role,
+		&i.Permissions,
+	)
+	return i, err
+}
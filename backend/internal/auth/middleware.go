@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -14,7 +15,10 @@ import (
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey    contextKey = "user_id"
+	adminRoleKey contextKey = "admin_role"
+)
 
 func RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -23,7 +27,7 @@ func RequireAuth(next http.Handler) http.Handler {
 		token, err := GetBearerToken(authHeader)
 
 		if err != nil {
-			common.ResponseWithError(w, http.StatusNonAuthoritativeInfo, err.Error())
+			common.ResponseWithError(w, http.StatusUnauthorized, err.Error())
 			return
 		}
 
@@ -31,12 +35,38 @@ func RequireAuth(next http.Handler) http.Handler {
 		tokenSecret := os.Getenv("TOKEN_SECRET")
 		userID, err := ValidateJWT(token, tokenSecret)
 		if err != nil {
+			common.ResponseWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDKey, userID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAdmin only lets through users listed in the admins table.
+// It must run after RequireAuth.
+func (h *Handler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := GetUserIDFromContext(r.Context())
+		if !ok {
+			common.ResponseWithError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		admin, err := h.service.GetAdmin(r.Context(), userID)
+		if errors.Is(err, ErrNotAdmin) {
+			common.ResponseWithError(w, http.StatusForbidden, "Admin access required")
+			return
+		} else if err != nil {
+			common.ResponseWithError(w, http.StatusInternalServerError, "Internal Server Error")
 			return
 		}
 
-		context.WithValue(r.Context(), userIDKey, userID)
+		ctx := context.WithValue(r.Context(), adminRoleKey, admin.Role)
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -47,13 +77,16 @@ func GetBearerToken(authHeader string) (string, error) {
 	}
 
 	authorizationSplit := strings.Split(authHeader, " ")
+	if len(authorizationSplit) != 2 || authorizationSplit[0] != "Bearer" {
+		return "", fmt.Errorf("malformed authorization header")
+	}
 
 	return authorizationSplit[1], nil
 }
 
 func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 
-	parsedClaims := &jwt.RegisteredClaims{}
+	parsedClaims := &CustomClaims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, parsedClaims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(tokenSecret), nil
@@ -79,3 +112,9 @@ func GetUserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
 	userID, ok := ctx.Value(userIDKey).(uuid.UUID)
 	return userID, ok
 }
+
+// GetAdminRoleFromContext returns the role set by RequireAdmin
+func GetAdminRoleFromContext(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(adminRoleKey).(string)
+	return role, ok
+}
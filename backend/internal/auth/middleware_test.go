@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const testTokenSecret = "test-token-secret"
+
+func signTestToken(t *testing.T, subject string, secret string, expiresIn time.Duration) string {
+	t.Helper()
+
+	claims := CustomClaims{
+		UserType: "student",
+		Email:    "student@giki.edu.pk",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "giki-wallet",
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   subject,
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestRequireAuthRejects(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", testTokenSecret)
+	userID := uuid.NewString()
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing header", ""},
+		{"not a bearer token", "Basic dXNlcjpwYXNz"},
+		{"bearer without token", "Bearer"},
+		{"garbage token", "Bearer not-a-jwt"},
+		{"wrong secret", "Bearer " + signTestToken(t, userID, "another-secret", time.Hour)},
+		{"expired", "Bearer " + signTestToken(t, userID, testTokenSecret, -time.Minute)},
+		{"subject is not a user id", "Bearer " + signTestToken(t, "someone", testTokenSecret, time.Hour)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			called := false
+			handler := RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/wallet", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+			}
+			if called {
+				t.Error("next handler ran for an unauthenticated request")
+			}
+		})
+	}
+}
+
+func TestRequireAuthSetsUserID(t *testing.T) {
+	t.Setenv("TOKEN_SECRET", testTokenSecret)
+	userID := uuid.New()
+
+	var got uuid.UUID
+	handler := RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetUserIDFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/wallet", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, userID.String(), testTokenSecret, time.Hour))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got != userID {
+		t.Errorf("user id in context = %s, want %s", got, userID)
+	}
+}
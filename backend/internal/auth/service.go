@@ -11,6 +11,7 @@ import (
 	"crypto/rand"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	auth "github.com/hash-walker/giki-wallet/internal/auth/auth_db"
 	"github.com/hash-walker/giki-wallet/internal/common"
 	"github.com/hash-walker/giki-wallet/internal/user/user_db"
@@ -24,6 +25,7 @@ var (
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrUserInactive    = errors.New("user inactive")
 	ErrTokenCreation   = errors.New("error creating secure token")
+	ErrNotAdmin        = errors.New("user is not an admin")
 )
 
 type Service struct {
@@ -146,6 +148,19 @@ func (s *Service) CheckUserAndPassword(ctx context.Context, email string, passwo
 
 }
 
+// GetAdmin returns the admin record of userID, or ErrNotAdmin
+func (s *Service) GetAdmin(ctx context.Context, userID uuid.UUID) (auth.GikiWalletAdmin, error) {
+	admin, err := s.authQ.GetAdminByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return auth.GikiWalletAdmin{}, ErrNotAdmin
+	} else if err != nil {
+		log.Printf("Error getting admin: %v", err)
+		return auth.GikiWalletAdmin{}, fmt.Errorf("error getting admin: %w", err)
+	}
+
+	return admin, nil
+}
+
 func MakeRefreshToken() (string, error) {
 	key := make([]byte, 32)
 	rand.Read(key)
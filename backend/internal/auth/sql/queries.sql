@@ -2,4 +2,8 @@
 
 INSERT INTO giki_wallet.refresh_tokens(token_hash, expires_at, user_id)
 VALUES ($1, $2, $3)
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetAdminByUserID :one
+SELECT * FROM giki_wallet.admins
+WHERE user_id = $1;
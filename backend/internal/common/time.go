@@ -0,0 +1,7 @@
+package common
+
+import "time"
+
+// PKT is Pakistan Standard Time, UTC+5 with no daylight saving. Gateway
+// timestamps, settlement files, statements and calendar-day limits all use it.
+var PKT = time.FixedZone("PKT", 5*60*60)
@@ -2,13 +2,23 @@ package config
 
 import (
 	"log"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
-	Jazzcash JazzcashConfig
+	Database  DatabaseConfig
+	Server    ServerConfig
+	Jazzcash  JazzcashConfig
+	Easypaisa EasypaisaConfig
+	TopUp     TopUpLimitsConfig
+	Transfer  TransferLimitsConfig
+	Ledger    LedgerConfig
+	Statement StatementConfig
+	Storage   StorageConfig
 }
 
 type DatabaseConfig struct {
@@ -19,16 +29,87 @@ type ServerConfig struct {
 	Port string
 }
 
+// minLedgerKeyLength keeps LEDGER_HMAC_KEY at 256 bits or more
+const minLedgerKeyLength = 32
+
+// LedgerConfig holds the secret that signs ledger row hashes. Changing it
+// makes every existing row fail verification.
+type LedgerConfig struct {
+	HMACKey string
+}
+
+// StatementConfig holds the public page that wallet statement verification
+// codes link to; the code is appended as the last path segment
+type StatementConfig struct {
+	VerifyURL string
+}
+
+// StorageConfig holds where uploaded files such as bank transfer receipts are kept
+type StorageConfig struct {
+	Dir string
+}
+
+// JazzcashConfig holds the default merchant profile plus any named profiles.
+// Every profile shares the card callback URL; the callback is matched back to
+// its profile through the transaction it names.
 type JazzcashConfig struct {
+	JazzcashMerchantConfig
+	CardCallbackURL   string
+	CardResultURL     string
+	ResponseCodesFile string                            // optional JSON laid over the built-in response code catalog
+	Profiles          map[string]JazzcashMerchantConfig // named merchant accounts besides the default one
+	ProfileRoutes     map[string]string                 // user type -> profile name; unrouted user types use the default
+	CredentialGrace   time.Duration                     // how long rotated-out credentials still verify incoming hashes
+}
+
+// JazzcashMerchantConfig is one JazzCash merchant account. With a
+// CredentialsFile the secrets come from that file, which is watched so they
+// can be rotated without a restart, and the secret env vars are not needed.
+type JazzcashMerchantConfig struct {
+	CredentialsFile  string
 	MerchantID       string
 	Password         string
 	IntegritySalt    string
 	MerchantMPIN     string
-	CardCallbackURL  string
 	BaseURL          string
 	WalletPaymentURl string
 	CardPaymentURL   string
 	StatusInquiryURL string
+	WalletRefundURL  string
+	CardRefundURL    string
+}
+
+// EasypaisaConfig is optional: leave EASYPAISA_STORE_ID unset to run without Easypaisa
+type EasypaisaConfig struct {
+	StoreID     string
+	Username    string
+	Password    string
+	HashKey     string
+	InitiateURL string
+	InquiryURL  string
+}
+
+// TopUpLimitsConfig holds top-up limits in paisa; 0 disables a cap.
+// Per-user-type caps of 0 fall back to the default caps.
+type TopUpLimitsConfig struct {
+	MinPaisa           int64
+	MaxPaisa           int64
+	DailyCapPaisa      int64
+	MonthlyCapPaisa    int64
+	UserTypeCaps       map[string]UserTypeCapsConfig
+	MaxAttemptsPerHour int64
+	MaxPending         int64
+}
+
+// TransferLimitsConfig holds peer-to-peer transfer limits in paisa; 0 disables a limit
+type TransferLimitsConfig struct {
+	MaxPaisa      int64
+	DailyCapPaisa int64
+}
+
+type UserTypeCapsConfig struct {
+	DailyCapPaisa   int64
+	MonthlyCapPaisa int64
 }
 
 func LoadConfig() *Config {
@@ -40,21 +121,166 @@ func LoadConfig() *Config {
 			Port: getEnvWithDefault("PORT", "8080"),
 		},
 		Jazzcash: JazzcashConfig{
-			MerchantID:       getRequiredEnv("JAZZCASH_MERCHANT_ID"),
-			Password:         getRequiredEnv("JAZZCASH_PASSWORD"),
-			IntegritySalt:    getRequiredEnv("JAZZCASH_INTEGRITY_SALT"),
-			MerchantMPIN:     getRequiredEnv("JAZZCASH_MERCHANT_MPIN"),
-			CardCallbackURL:  getRequiredEnv("JAZZCASH_RETURN_URL"),
-			BaseURL:          getRequiredEnv("JAZZCASH_BASE_URL"),
-			WalletPaymentURl: getRequiredEnv("JAZZCASH_WALLET_PAYMENT_URL"),
-			CardPaymentURL:   getRequiredEnv("JAZZCASH_CARD_PAYMENT_URL"),
-			StatusInquiryURL: getRequiredEnv("JAZZCASH_STATUS_INQUIRY_URL"),
+			JazzcashMerchantConfig: loadJazzcashMerchant("JAZZCASH_", nil),
+			CardCallbackURL:        getRequiredEnv("JAZZCASH_RETURN_URL"),
+			CardResultURL:          getEnvWithDefault("JAZZCASH_CARD_RESULT_URL", "http://localhost:5173/"),
+			ResponseCodesFile:      os.Getenv("JAZZCASH_RESPONSE_CODES_FILE"),
+			CredentialGrace:        getDurationEnvWithDefault("JAZZCASH_CREDENTIAL_GRACE", time.Hour),
+		},
+		TopUp: TopUpLimitsConfig{
+			MinPaisa:        getInt64EnvWithDefault("TOPUP_MIN_PAISA", 100_00),
+			MaxPaisa:        getInt64EnvWithDefault("TOPUP_MAX_PAISA", 50_000_00),
+			DailyCapPaisa:   getInt64EnvWithDefault("TOPUP_DAILY_CAP_PAISA", 100_000_00),
+			MonthlyCapPaisa: getInt64EnvWithDefault("TOPUP_MONTHLY_CAP_PAISA", 500_000_00),
+			UserTypeCaps: map[string]UserTypeCapsConfig{
+				"student": {
+					DailyCapPaisa:   getInt64EnvWithDefault("TOPUP_STUDENT_DAILY_CAP_PAISA", 0),
+					MonthlyCapPaisa: getInt64EnvWithDefault("TOPUP_STUDENT_MONTHLY_CAP_PAISA", 0),
+				},
+				"employee": {
+					DailyCapPaisa:   getInt64EnvWithDefault("TOPUP_EMPLOYEE_DAILY_CAP_PAISA", 0),
+					MonthlyCapPaisa: getInt64EnvWithDefault("TOPUP_EMPLOYEE_MONTHLY_CAP_PAISA", 0),
+				},
+			},
+			MaxAttemptsPerHour: getInt64EnvWithDefault("TOPUP_MAX_ATTEMPTS_PER_HOUR", 10),
+			MaxPending:         getInt64EnvWithDefault("TOPUP_MAX_PENDING", 2),
+		},
+		Transfer: TransferLimitsConfig{
+			MaxPaisa:      getInt64EnvWithDefault("TRANSFER_MAX_PAISA", 5_000_00),
+			DailyCapPaisa: getInt64EnvWithDefault("TRANSFER_DAILY_CAP_PAISA", 20_000_00),
+		},
+		Ledger: LedgerConfig{
+			HMACKey: getRequiredEnv("LEDGER_HMAC_KEY"),
+		},
+		Statement: StatementConfig{
+			VerifyURL: getEnvWithDefault("STATEMENT_VERIFY_URL", "http://localhost:8080/statements/verify"),
+		},
+		Storage: StorageConfig{
+			Dir: getEnvWithDefault("STORAGE_DIR", "uploads"),
 		},
 	}
 
+	if len(cfg.Ledger.HMACKey) < minLedgerKeyLength {
+		log.Fatalf("LEDGER_HMAC_KEY must be at least %d characters", minLedgerKeyLength)
+	}
+
+	cfg.Jazzcash.Profiles = loadJazzcashProfiles(cfg.Jazzcash.JazzcashMerchantConfig)
+	cfg.Jazzcash.ProfileRoutes = loadJazzcashProfileRoutes(cfg.Jazzcash.Profiles)
+
+	if storeID := os.Getenv("EASYPAISA_STORE_ID"); storeID != "" {
+		cfg.Easypaisa = EasypaisaConfig{
+			StoreID:     storeID,
+			Username:    getRequiredEnv("EASYPAISA_USERNAME"),
+			Password:    getRequiredEnv("EASYPAISA_PASSWORD"),
+			HashKey:     getRequiredEnv("EASYPAISA_HASH_KEY"),
+			InitiateURL: getRequiredEnv("EASYPAISA_INITIATE_URL"),
+			InquiryURL:  getRequiredEnv("EASYPAISA_INQUIRY_URL"),
+		}
+	}
+
 	return cfg
 }
 
+// CardCallbackPath returns the route JazzCash posts card results to
+func (c JazzcashConfig) CardCallbackPath() string {
+	callbackURL, err := url.Parse(c.CardCallbackURL)
+	if err != nil || callbackURL.Path == "" {
+		log.Fatalf("JAZZCASH_RETURN_URL must be an absolute URL with a path, got %q", c.CardCallbackURL)
+	}
+	return callbackURL.Path
+}
+
+// Enabled reports whether Easypaisa credentials were configured
+func (c EasypaisaConfig) Enabled() bool {
+	return c.StoreID != ""
+}
+
+// loadJazzcashMerchant reads one merchant account from env vars starting with
+// prefix. URLs not set fall back to defaults; with no defaults they are required.
+func loadJazzcashMerchant(prefix string, defaults *JazzcashMerchantConfig) JazzcashMerchantConfig {
+	merchant := JazzcashMerchantConfig{CredentialsFile: os.Getenv(prefix + "CREDENTIALS_FILE")}
+
+	secret := getRequiredEnv
+	if merchant.CredentialsFile != "" {
+		secret = os.Getenv
+	}
+	merchant.MerchantID = secret(prefix + "MERCHANT_ID")
+	merchant.Password = secret(prefix + "PASSWORD")
+	merchant.IntegritySalt = secret(prefix + "INTEGRITY_SALT")
+	merchant.MerchantMPIN = secret(prefix + "MERCHANT_MPIN")
+
+	required := defaults == nil
+	if required {
+		defaults = &JazzcashMerchantConfig{}
+	}
+	endpoint := func(key, fallback string) string {
+		if required {
+			return getRequiredEnv(prefix + key)
+		}
+		return getEnvWithDefault(prefix+key, fallback)
+	}
+	merchant.BaseURL = endpoint("BASE_URL", defaults.BaseURL)
+	merchant.WalletPaymentURl = endpoint("WALLET_PAYMENT_URL", defaults.WalletPaymentURl)
+	merchant.CardPaymentURL = endpoint("CARD_PAYMENT_URL", defaults.CardPaymentURL)
+	merchant.StatusInquiryURL = endpoint("STATUS_INQUIRY_URL", defaults.StatusInquiryURL)
+	merchant.WalletRefundURL = endpoint("WALLET_REFUND_URL", defaults.WalletRefundURL)
+	merchant.CardRefundURL = endpoint("CARD_REFUND_URL", defaults.CardRefundURL)
+
+	return merchant
+}
+
+// loadJazzcashProfiles reads the profiles named in JAZZCASH_PROFILES, e.g.
+// "student_transport,employee_transport". Each needs its own credentials in
+// JAZZCASH_<NAME>_MERCHANT_ID and friends, or JAZZCASH_<NAME>_CREDENTIALS_FILE;
+// URLs default to the default profile's.
+func loadJazzcashProfiles(defaults JazzcashMerchantConfig) map[string]JazzcashMerchantConfig {
+	profiles := make(map[string]JazzcashMerchantConfig)
+
+	for _, name := range splitList(os.Getenv("JAZZCASH_PROFILES")) {
+		if name == "default" {
+			log.Fatalf("JAZZCASH_PROFILES must not name the default profile, it is configured by JAZZCASH_MERCHANT_ID")
+		}
+		if _, ok := profiles[name]; ok {
+			log.Fatalf("JAZZCASH_PROFILES lists %q twice", name)
+		}
+
+		profiles[name] = loadJazzcashMerchant("JAZZCASH_"+strings.ToUpper(name)+"_", &defaults)
+	}
+
+	return profiles
+}
+
+// loadJazzcashProfileRoutes reads JAZZCASH_PROFILE_ROUTES, e.g.
+// "student=student_transport,employee=employee_transport"
+func loadJazzcashProfileRoutes(profiles map[string]JazzcashMerchantConfig) map[string]string {
+	routes := make(map[string]string)
+
+	for _, route := range splitList(os.Getenv("JAZZCASH_PROFILE_ROUTES")) {
+		userType, profile, ok := strings.Cut(route, "=")
+		userType, profile = strings.TrimSpace(userType), strings.TrimSpace(profile)
+		if !ok || userType == "" || profile == "" {
+			log.Fatalf("JAZZCASH_PROFILE_ROUTES entries must look like user_type=profile, got %q", route)
+		}
+		if _, known := profiles[profile]; !known && profile != "default" {
+			log.Fatalf("JAZZCASH_PROFILE_ROUTES routes %s to unknown profile %q", userType, profile)
+		}
+		routes[userType] = profile
+	}
+
+	return routes
+}
+
+// splitList splits a comma separated value, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getRequiredEnv(key string) string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -70,3 +296,27 @@ func getEnvWithDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+func getInt64EnvWithDefault(key string, defaultValue int64) int64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		log.Fatalf("Environment variable %s must be a non-negative integer, got %q", key, value)
+	}
+	return parsed
+}
+
+func getDurationEnvWithDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Fatalf("Environment variable %s must be a non-negative duration like 30m, got %q", key, value)
+	}
+	return parsed
+}
@@ -0,0 +1,369 @@
+// Package money carries amounts as integer minor units together with their
+// currency, so a rupee amount can never be mistaken for a paisa amount.
+//
+// Conversions to the decimal or minor-unit strings gateways expect happen only
+// at the gateway edge, through MinorString and Decimal.
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	ErrOverflow            = errors.New("money: amount out of range")
+	ErrCurrencyMismatch    = errors.New("money: currency mismatch")
+	ErrUnsupportedCurrency = errors.New("money: unsupported currency")
+	ErrInvalidAmount       = errors.New("money: invalid amount")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+type Currency string
+
+// PKR is the only currency the wallet handles; one rupee is 100 paisa
+const PKR Currency = "PKR"
+
+// minorPerMajor is the number of minor units in one major unit per currency
+var minorPerMajor = map[Currency]int64{
+	PKR: 100,
+}
+
+// storageCurrency is the currency of every amount column in the database
+const storageCurrency = PKR
+
+// Money is an amount in minor units (paisa for PKR) and its currency.
+// The zero value is zero PKR.
+type Money struct {
+	minor    int64
+	currency Currency
+}
+
+// moneyJSON is the wire format. A bare number is rejected so no client can
+// send rupees where paisa are expected; the one legacy body that sent rupees,
+// payment.TopUpRequest, converts them itself.
+type moneyJSON struct {
+	MinorUnits *int64   `json:"minor_units"`
+	Currency   Currency `json:"currency"`
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// New returns minor units of currency
+func New(minor int64, currency Currency) (Money, error) {
+	if _, ok := minorPerMajor[currency]; !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+	return Money{minor: minor, currency: currency}, nil
+}
+
+// Paisa returns an amount of PKR given in paisa
+func Paisa(paisa int64) Money {
+	return Money{minor: paisa, currency: PKR}
+}
+
+// Rupees returns an amount of PKR given in whole rupees
+func Rupees(rupees int64) (Money, error) {
+	paisa, ok := mul(rupees, minorPerMajor[PKR])
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %d rupees", ErrOverflow, rupees)
+	}
+	return Paisa(paisa), nil
+}
+
+// ParseMinor parses an integer count of minor units such as a gateway's "50000"
+func ParseMinor(raw string, currency Currency) (Money, error) {
+	minor, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+	return New(minor, currency)
+}
+
+// ParseDecimal parses a major-unit amount such as "1,500" or "1500.50"
+func ParseDecimal(raw string, currency Currency) (Money, error) {
+	ratio, ok := minorPerMajor[currency]
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnsupportedCurrency, currency)
+	}
+
+	cleaned := strings.ReplaceAll(strings.TrimSpace(raw), ",", "")
+	if cleaned == "" {
+		return Money{}, fmt.Errorf("%w: empty amount", ErrInvalidAmount)
+	}
+
+	major, fraction, hasFraction := strings.Cut(cleaned, ".")
+	if hasFraction && (len(fraction) == 0 || len(fraction) > 2) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+
+	whole, err := strconv.ParseInt(major, 10, 64)
+	if err != nil || whole < 0 {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+	}
+
+	var minor int64
+	if hasFraction {
+		for len(fraction) < 2 {
+			fraction += "0"
+		}
+		minor, err = strconv.ParseInt(fraction, 10, 64)
+		if err != nil || minor < 0 {
+			return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, raw)
+		}
+	}
+
+	total, ok := mul(whole, ratio)
+	if ok {
+		total, ok = add(total, minor)
+	}
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrOverflow, raw)
+	}
+	return Money{minor: total, currency: currency}, nil
+}
+
+// =============================================================================
+// ACCESSORS
+// =============================================================================
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return m.minor
+}
+
+// Currency returns the currency, PKR for the zero value
+func (m Money) Currency() Currency {
+	if m.currency == "" {
+		return PKR
+	}
+	return m.currency
+}
+
+func (m Money) IsZero() bool     { return m.minor == 0 }
+func (m Money) IsPositive() bool { return m.minor > 0 }
+func (m Money) IsNegative() bool { return m.minor < 0 }
+
+// =============================================================================
+// ARITHMETIC
+// =============================================================================
+
+// Add returns m + other
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	sum, ok := add(m.minor, other.minor)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrOverflow, m, other)
+	}
+	return Money{minor: sum, currency: m.Currency()}, nil
+}
+
+// Sub returns m - other
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if other.minor == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, other)
+	}
+	difference, ok := add(m.minor, -other.minor)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrOverflow, m, other)
+	}
+	return Money{minor: difference, currency: m.Currency()}, nil
+}
+
+// Mul returns m × n
+func (m Money) Mul(n int64) (Money, error) {
+	product, ok := mul(m.minor, n)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s × %d", ErrOverflow, m, n)
+	}
+	return Money{minor: product, currency: m.Currency()}, nil
+}
+
+// Neg returns -m
+func (m Money) Neg() (Money, error) {
+	if m.minor == math.MinInt64 {
+		return Money{}, fmt.Errorf("%w: -%s", ErrOverflow, m)
+	}
+	return Money{minor: -m.minor, currency: m.Currency()}, nil
+}
+
+// Cmp returns -1, 0 or +1 as m is less than, equal to or greater than other
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.sameCurrency(other); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.minor < other.minor:
+		return -1, nil
+	case m.minor > other.minor:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// Equal reports whether m and other are the same amount of the same currency
+func (m Money) Equal(other Money) bool {
+	return m.Currency() == other.Currency() && m.minor == other.minor
+}
+
+// =============================================================================
+// FORMATTING
+// =============================================================================
+
+// MinorString formats the minor units, e.g. "50000" for JazzCash pp_Amount
+func (m Money) MinorString() string {
+	return strconv.FormatInt(m.minor, 10)
+}
+
+// Decimal formats the amount in major units with two decimals, e.g. "500.00"
+func (m Money) Decimal() string {
+	ratio := minorPerMajor[m.Currency()]
+	sign := ""
+	minor := m.minor
+	if minor < 0 {
+		sign = "-"
+	}
+
+	// Work on the magnitude without negating, which overflows for MinInt64
+	major, fraction := minor/ratio, minor%ratio
+	if major < 0 {
+		major = -major
+	}
+	if fraction < 0 {
+		fraction = -fraction
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, uint64(major), fraction)
+}
+
+// String formats the amount for people, e.g. "PKR 500.00"
+func (m Money) String() string {
+	return string(m.Currency()) + " " + m.Decimal()
+}
+
+// =============================================================================
+// ENCODING - JSON
+// =============================================================================
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	minor := m.minor
+	return json.Marshal(moneyJSON{MinorUnits: &minor, Currency: m.Currency()})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(trimmed, "{") {
+		return fmt.Errorf(`%w: expected {"minor_units": ..., "currency": "PKR"}, got %s`, ErrInvalidAmount, trimmed)
+	}
+
+	var wire moneyJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAmount, err)
+	}
+	if wire.MinorUnits == nil {
+		return fmt.Errorf("%w: minor_units is required", ErrInvalidAmount)
+	}
+	if wire.Currency == "" {
+		return fmt.Errorf("%w: currency is required", ErrInvalidAmount)
+	}
+
+	parsed, err := New(*wire.MinorUnits, wire.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// =============================================================================
+// ENCODING - Database
+// =============================================================================
+
+// Amount columns are BIGINT paisa. pgx uses ScanInt64 and Int64Value; Scan
+// and Value serve database/sql.
+
+func (m *Money) ScanInt64(v pgtype.Int8) error {
+	if !v.Valid {
+		return fmt.Errorf("%w: NULL amount", ErrInvalidAmount)
+	}
+	*m = Money{minor: v.Int64, currency: storageCurrency}
+	return nil
+}
+
+func (m Money) Int64Value() (pgtype.Int8, error) {
+	if m.Currency() != storageCurrency {
+		return pgtype.Int8{}, fmt.Errorf("%w: cannot store %s in a %s column", ErrCurrencyMismatch, m.Currency(), storageCurrency)
+	}
+	return pgtype.Int8{Int64: m.minor, Valid: true}, nil
+}
+
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		return m.ScanInt64(pgtype.Int8{Int64: v, Valid: true})
+	case nil:
+		return m.ScanInt64(pgtype.Int8{})
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidAmount, src)
+	}
+}
+
+func (m Money) Value() (driver.Value, error) {
+	v, err := m.Int64Value()
+	if err != nil {
+		return nil, err
+	}
+	return v.Int64, nil
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+func (m Money) sameCurrency(other Money) error {
+	if m.Currency() != other.Currency() {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency(), other.Currency())
+	}
+	return nil
+}
+
+// add returns a + b and whether it fit in an int64
+func add(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// mul returns a × b and whether it fit in an int64
+func mul(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
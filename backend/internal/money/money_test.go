@@ -0,0 +1,172 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int64
+		wantErr  error
+	}{
+		{"whole rupees", "500", 50000, nil},
+		{"two decimals", "500.25", 50025, nil},
+		{"one decimal", "500.5", 50050, nil},
+		{"thousands separator", "1,500.00", 150000, nil},
+		{"too many decimals", "1.005", 0, ErrInvalidAmount},
+		{"trailing dot", "1.", 0, ErrInvalidAmount},
+		{"negative", "-5", 0, ErrInvalidAmount},
+		{"empty", "", 0, ErrInvalidAmount},
+		{"garbage", "abc", 0, ErrInvalidAmount},
+		{"overflow", "92233720368547758.08", 0, ErrOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDecimal(tt.input, PKR)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ParseDecimal(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+			if got.Minor() != tt.expected {
+				t.Errorf("ParseDecimal(%q) = %d, want %d", tt.input, got.Minor(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMinor(t *testing.T) {
+	got, err := ParseMinor("50000", PKR)
+	if err != nil || !got.Equal(Paisa(50000)) {
+		t.Errorf("ParseMinor(50000) = %v, %v, want PKR 500.00", got, err)
+	}
+
+	if _, err := ParseMinor("500.00", PKR); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("ParseMinor(500.00) error = %v, want %v", err, ErrInvalidAmount)
+	}
+	if _, err := ParseMinor("100", "USD"); !errors.Is(err, ErrUnsupportedCurrency) {
+		t.Errorf("ParseMinor(USD) error = %v, want %v", err, ErrUnsupportedCurrency)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	tests := []struct {
+		amount  Money
+		minor   string
+		decimal string
+		str     string
+	}{
+		{Paisa(50000), "50000", "500.00", "PKR 500.00"},
+		{Paisa(5), "5", "0.05", "PKR 0.05"},
+		{Paisa(-150), "-150", "-1.50", "PKR -1.50"},
+		{Money{}, "0", "0.00", "PKR 0.00"},
+		{Paisa(math.MinInt64), "-9223372036854775808", "-92233720368547758.08", "PKR -92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.amount.MinorString(); got != tt.minor {
+			t.Errorf("MinorString() = %q, want %q", got, tt.minor)
+		}
+		if got := tt.amount.Decimal(); got != tt.decimal {
+			t.Errorf("Decimal() = %q, want %q", got, tt.decimal)
+		}
+		if got := tt.amount.String(); got != tt.str {
+			t.Errorf("String() = %q, want %q", got, tt.str)
+		}
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	sum, err := Paisa(100).Add(Paisa(50))
+	if err != nil || !sum.Equal(Paisa(150)) {
+		t.Errorf("Add() = %v, %v, want PKR 1.50", sum, err)
+	}
+
+	if _, err := Paisa(math.MaxInt64).Add(Paisa(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Add() past MaxInt64 error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Paisa(math.MinInt64).Sub(Paisa(1)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub() past MinInt64 error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Paisa(0).Sub(Paisa(math.MinInt64)); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Sub(MinInt64) error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Paisa(math.MaxInt64 / 2).Mul(3); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Mul() error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Paisa(math.MinInt64).Neg(); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Neg(MinInt64) error = %v, want %v", err, ErrOverflow)
+	}
+	if _, err := Rupees(math.MaxInt64 / 10); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Rupees() error = %v, want %v", err, ErrOverflow)
+	}
+
+	other := Money{minor: 100, currency: "USD"}
+	if _, err := Paisa(100).Add(other); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if _, err := Paisa(100).Cmp(other); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp() across currencies error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+	if Paisa(100).Equal(other) {
+		t.Error("Equal() = true across currencies")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(Paisa(50000))
+	if err != nil || string(data) != `{"minor_units":50000,"currency":"PKR"}` {
+		t.Errorf("MarshalJSON() = %s, %v", data, err)
+	}
+
+	var decoded Money
+	if err := json.Unmarshal(data, &decoded); err != nil || !decoded.Equal(Paisa(50000)) {
+		t.Errorf("UnmarshalJSON() = %v, %v, want PKR 500.00", decoded, err)
+	}
+
+	rejected := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"bare number", `500`, ErrInvalidAmount},
+		{"string", `"500"`, ErrInvalidAmount},
+		{"missing minor units", `{"currency":"PKR"}`, ErrInvalidAmount},
+		{"missing currency", `{"minor_units":500}`, ErrInvalidAmount},
+		{"fractional minor units", `{"minor_units":500.5,"currency":"PKR"}`, ErrInvalidAmount},
+		{"unknown currency", `{"minor_units":500,"currency":"USD"}`, ErrUnsupportedCurrency},
+	}
+
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			if err := json.Unmarshal([]byte(tt.input), &m); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalJSON(%s) error = %v, want %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatabaseEncoding(t *testing.T) {
+	var m Money
+	if err := m.ScanInt64(pgtype.Int8{Int64: 50000, Valid: true}); err != nil || !m.Equal(Paisa(50000)) {
+		t.Errorf("ScanInt64() = %v, %v, want PKR 500.00", m, err)
+	}
+	if err := m.ScanInt64(pgtype.Int8{}); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("ScanInt64(NULL) error = %v, want %v", err, ErrInvalidAmount)
+	}
+
+	v, err := Paisa(50000).Int64Value()
+	if err != nil || !v.Valid || v.Int64 != 50000 {
+		t.Errorf("Int64Value() = %v, %v, want 50000", v, err)
+	}
+	if _, err := (Money{minor: 1, currency: "USD"}).Int64Value(); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Int64Value(USD) error = %v, want %v", err, ErrCurrencyMismatch)
+	}
+}
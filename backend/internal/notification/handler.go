@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/common"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// ListNotifications returns a page of the user's notifications and the unread count
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	list, err := h.service.ListMine(r.Context(), limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, list)
+}
+
+// MarkRead marks one of the user's notifications read
+func (h *Handler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationID, err := uuid.Parse(chi.URLParam(r, "notificationID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid notification id")
+		return
+	}
+
+	notification, err := h.service.MarkRead(r.Context(), notificationID)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, notification)
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// pageParams reads limit (default 20, max 100) and offset from the query string
+func pageParams(r *http.Request) (int32, int32) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return int32(limit), int32(offset)
+}
+
+// handleServiceError maps service errors to HTTP responses
+func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotificationNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Notification not found.")
+	case errors.Is(err, ErrUserIDNotFound):
+		common.ResponseWithError(w, http.StatusUnauthorized, "Authentication required.")
+	default:
+		log.Printf("notification error: %v", err)
+		common.ResponseWithError(w, http.StatusInternalServerError, "An unexpected error occurred. Please try again later.")
+	}
+}
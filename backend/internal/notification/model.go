@@ -0,0 +1,38 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Type says what happened; clients pick icons and deep links by it
+type Type string
+
+const (
+	TypeTransferSent     Type = "TRANSFER_SENT"
+	TypeTransferReceived Type = "TRANSFER_RECEIVED"
+)
+
+// Message is a notification to store for one user
+type Message struct {
+	Type  Type
+	Title string
+	Body  string
+	Data  map[string]string // e.g. the id of the transfer it is about
+}
+
+type Notification struct {
+	ID        uuid.UUID         `json:"id"`
+	Type      Type              `json:"type"`
+	Title     string            `json:"title"`
+	Body      string            `json:"body"`
+	Data      map[string]string `json:"data,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ReadAt    *time.Time        `json:"read_at,omitempty"`
+}
+
+type NotificationList struct {
+	Notifications []Notification `json:"notifications"`
+	Unread        int64          `json:"unread"`
+}
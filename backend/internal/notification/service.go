@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	notificationdb "github.com/hash-walker/giki-wallet/internal/notification/notification_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrNotificationNotFound Unknown id or someone else's notification (404)
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	// ErrUserIDNotFound No authenticated user in the request context (401)
+	ErrUserIDNotFound = errors.New("user id not found in context")
+
+	// ErrDatabaseQuery Database errors (500)
+	ErrDatabaseQuery = errors.New("database query failed")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+type Service struct {
+	q      *notificationdb.Queries
+	dbPool *pgxpool.Pool
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+func NewService(dbPool *pgxpool.Pool) *Service {
+	return &Service{
+		q:      notificationdb.New(dbPool),
+		dbPool: dbPool,
+	}
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS
+// =============================================================================
+
+// Notify stores an in-app notification for userID in tx, so it appears
+// exactly when the change it describes commits
+func (s *Service) Notify(ctx context.Context, tx pgx.Tx, userID uuid.UUID, msg Message) error {
+	var data []byte
+	if len(msg.Data) > 0 {
+		var err error
+		if data, err = json.Marshal(msg.Data); err != nil {
+			return fmt.Errorf("encode notification data: %w", err)
+		}
+	}
+
+	_, err := s.q.WithTx(tx).CreateInAppNotification(ctx, notificationdb.CreateInAppNotificationParams{
+		UserID: userID,
+		Type:   string(msg.Type),
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Data:   data,
+	})
+	if err != nil {
+		log.Printf("failed to store %s notification for user %s: %v", msg.Type, userID, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return nil
+}
+
+// ListMine returns a page of the authenticated user's notifications, newest
+// first, with the number still unread
+func (s *Service) ListMine(ctx context.Context, limit, offset int32) (*NotificationList, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	rows, err := s.q.ListNotifications(ctx, notificationdb.ListNotificationsParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("failed to list notifications of user %s: %v", userID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	unread, err := s.q.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		log.Printf("failed to count unread notifications of user %s: %v", userID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	list := &NotificationList{Notifications: make([]Notification, len(rows)), Unread: unread}
+	for i, row := range rows {
+		list.Notifications[i] = toNotification(row)
+	}
+	return list, nil
+}
+
+// MarkRead marks one of the authenticated user's notifications read
+func (s *Service) MarkRead(ctx context.Context, notificationID uuid.UUID) (*Notification, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	row, err := s.q.MarkNotificationRead(ctx, notificationdb.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotificationNotFound
+	} else if err != nil {
+		log.Printf("failed to mark notification %s read: %v", notificationID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	notification := toNotification(row)
+	return &notification, nil
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+func toNotification(row notificationdb.GikiWalletNotification) Notification {
+	notification := Notification{
+		ID:        row.ID,
+		Type:      Type(row.Type),
+		Title:     row.Title,
+		Body:      row.Body,
+		CreatedAt: row.CreatedAt,
+	}
+	if len(row.Data) > 0 {
+		if err := json.Unmarshal(row.Data, &notification.Data); err != nil {
+			log.Printf("failed to decode data of notification %s: %v", row.ID, err)
+		}
+	}
+	if row.ReadAt.Valid {
+		readAt := row.ReadAt.Time
+		notification.ReadAt = &readAt
+	}
+	return notification
+}
@@ -0,0 +1,22 @@
+--- notifications
+
+-- name: CreateInAppNotification :one
+INSERT INTO giki_wallet.notifications (user_id, type, channel, title, body, data, status, sent_at)
+VALUES ($1, $2, 'IN_APP', $3, $4, $5, 'SENT', NOW())
+RETURNING *;
+
+-- name: ListNotifications :many
+SELECT * FROM giki_wallet.notifications
+WHERE user_id = $1 AND channel = 'IN_APP'
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM giki_wallet.notifications
+WHERE user_id = $1 AND channel = 'IN_APP' AND read_at IS NULL;
+
+-- name: MarkNotificationRead :one
+UPDATE giki_wallet.notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2
+RETURNING *;
@@ -0,0 +1,446 @@
+package payment
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidPaymentSearch Bad filter or cursor (400)
+	ErrInvalidPaymentSearch = errors.New("invalid payment search")
+
+	// ErrResolutionReasonRequired Manual resolution needs a reason and a final status (400)
+	ErrResolutionReasonRequired = errors.New("resolution reason is required")
+	ErrInvalidResolutionStatus  = errors.New("resolution status must be SUCCESS or FAILED")
+
+	// ErrTransactionAlreadySucceeded Re-inquiry only applies to transactions that may still change (409)
+	ErrTransactionAlreadySucceeded = errors.New("transaction has already succeeded")
+
+	// ErrTransactionNotUnknown Only UNKNOWN transactions are resolved by hand (409)
+	ErrTransactionNotUnknown = errors.New("only UNKNOWN transactions can be resolved manually")
+)
+
+const (
+	defaultPaymentPageSize = 50
+	maxPaymentPageSize     = 200
+
+	// exportPageSize is how many rows the CSV export reads per query
+	exportPageSize = 500
+)
+
+// paymentExportHeader is the first line of the CSV export
+var paymentExportHeader = []string{
+	"created_at", "txn_ref_no", "bill_ref_id", "user_id", "user_name", "user_email",
+	"payment_method", "status", "amount_paisa", "amount_pkr", "gateway_rrn", "response_code", "updated_at",
+	"merchant_profile",
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Payments Console
+// =============================================================================
+
+// SearchPayments returns one page of transactions matching search, newest
+// first, with totals over every match
+func (s *Service) SearchPayments(ctx context.Context, search PaymentSearch) (*PaymentSearchResult, error) {
+	params, err := searchParams(search)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := search.Limit
+	if limit <= 0 {
+		limit = defaultPaymentPageSize
+	} else if limit > maxPaymentPageSize {
+		limit = maxPaymentPageSize
+	}
+	// One extra row tells whether there is a next page
+	params.PageSize = limit + 1
+
+	rows, err := s.q.SearchGatewayTransactions(ctx, params)
+	if err != nil {
+		log.Printf("failed to search gateway transactions: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	result := &PaymentSearchResult{Payments: []AdminPayment{}}
+	for i, row := range rows {
+		if int32(i) == limit {
+			result.NextCursor = encodePaymentCursor(rows[i-1].CreatedAt, rows[i-1].ID)
+			break
+		}
+		result.Payments = append(result.Payments, toAdminPayment(row))
+	}
+
+	totals, err := s.summarizePayments(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Totals = *totals
+
+	return result, nil
+}
+
+// ExportPaymentsCSV writes every transaction matching search as CSV, newest
+// first. Cursor and Limit are ignored; the export always starts from the top.
+func (s *Service) ExportPaymentsCSV(ctx context.Context, search PaymentSearch, w io.Writer) error {
+	search.Cursor = ""
+	params, err := searchParams(search)
+	if err != nil {
+		return err
+	}
+	params.PageSize = exportPageSize
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(paymentExportHeader); err != nil {
+		return err
+	}
+
+	for {
+		rows, err := s.q.SearchGatewayTransactions(ctx, params)
+		if err != nil {
+			log.Printf("failed to export gateway transactions: %v", err)
+			return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+
+		for _, row := range rows {
+			if err := writer.Write(paymentExportRecord(toAdminPayment(row))); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+
+		if len(rows) < exportPageSize {
+			return nil
+		}
+		last := rows[len(rows)-1]
+		params.AfterCreatedAt = pgtype.Timestamptz{Time: last.CreatedAt, Valid: true}
+		params.AfterID = pgtype.UUID{Bytes: last.ID, Valid: true}
+	}
+}
+
+// ReinquireTransaction asks the gateway about a transaction right away instead
+// of waiting for the reconciler, and applies a final answer. A success for a
+// FAILED transaction goes through the audited late-success path.
+func (s *Service) ReinquireTransaction(ctx context.Context, txnRefNo string) (*TopUpResult, error) {
+	gatewayTxn, err := s.q.GetTransactionByTxnRefNo(ctx, txnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	// FAILED is allowed so a payment approved after expiry can be found
+	status := PaymentStatus(gatewayTxn.Status)
+	if status == PaymentStatusSuccess {
+		return nil, ErrTransactionAlreadySucceeded
+	}
+	if PaymentMethod(gatewayTxn.PaymentMethod) == PaymentMethodBankTransfer {
+		return nil, ErrNotGatewayTransaction
+	}
+
+	if err := s.rateLimiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	inquiryResult, err := s.inquire(ctx, gatewayTxn)
+	s.rateLimiter.Release()
+	if err != nil {
+		log.Printf("admin re-inquiry failed for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+	}
+
+	status = gatewayStatusToPaymentStatus(inquiryResult.Status)
+	if status == PaymentStatusSuccess || status == PaymentStatusFailed {
+		gatewayTxn, err = s.finalizeAndCommit(ctx, gatewayTxn, status, inquiryOutcome(inquiryResult))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &TopUpResult{
+		ID:            gatewayTxn.ID,
+		TxnRefNo:      gatewayTxn.TxnRefNo,
+		Status:        PaymentStatus(gatewayTxn.Status),
+		Message:       inquiryResult.Message,
+		PaymentMethod: PaymentMethod(gatewayTxn.PaymentMethod),
+		Amount:        gatewayTxn.Amount,
+	}, nil
+}
+
+// ResolveTransaction settles a stuck UNKNOWN transaction as SUCCESS or FAILED
+// by hand. The admin and reason are kept in the transaction's event history.
+func (s *Service) ResolveTransaction(ctx context.Context, txnRefNo string, req ResolveTransactionRequest) (*TopUpResult, error) {
+	adminID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, ErrResolutionReasonRequired
+	}
+	if req.Status != PaymentStatusSuccess && req.Status != PaymentStatusFailed {
+		return nil, ErrInvalidResolutionStatus
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin resolution transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	resolved, err := paymentQ.ResolveUnknownTransaction(ctx, payment.ResolveUnknownTransactionParams{
+		Status:   payment.CurrentStatus(req.Status),
+		TxnRefNo: txnRefNo,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := paymentQ.GetTransactionByTxnRefNo(ctx, txnRefNo); err == nil {
+			return nil, ErrTransactionNotUnknown
+		}
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to resolve transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	if err := s.settleIntent(ctx, tx, resolved); err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(ctx, paymentQ, resolved, gatewayEvent{
+		Gateway: s.gatewayNameFor(PaymentMethod(resolved.PaymentMethod)),
+		Kind:    GatewayEventManual,
+		Exchange: gateway.Exchange{Request: map[string]string{
+			"action":          "resolve",
+			"previous_status": string(PaymentStatusUnknown),
+			"status":          string(req.Status),
+			"reason":          reason,
+			"resolved_by":     adminID.String(),
+		}},
+	})
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit resolution of %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	log.Printf("transaction %s resolved as %s by admin %s: %s", txnRefNo, req.Status, adminID, reason)
+
+	return &TopUpResult{
+		ID:            resolved.ID,
+		TxnRefNo:      resolved.TxnRefNo,
+		Status:        PaymentStatus(resolved.Status),
+		Message:       reason,
+		PaymentMethod: PaymentMethod(resolved.PaymentMethod),
+		Amount:        resolved.Amount,
+	}, nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Payments Console
+// =============================================================================
+
+// summarizePayments totals every transaction matching params, ignoring the cursor
+func (s *Service) summarizePayments(ctx context.Context, params payment.SearchGatewayTransactionsParams) (*PaymentTotals, error) {
+	rows, err := s.q.SummarizeGatewayTransactions(ctx, payment.SummarizeGatewayTransactionsParams{
+		UserID:        params.UserID,
+		Statuses:      params.Statuses,
+		PaymentMethod: params.PaymentMethod,
+		CreatedFrom:   params.CreatedFrom,
+		CreatedTo:     params.CreatedTo,
+		MinAmount:     params.MinAmount,
+		MaxAmount:     params.MaxAmount,
+		TxnRefNo:      params.TxnRefNo,
+		GatewayRrn:    params.GatewayRrn,
+	})
+	if err != nil {
+		log.Printf("failed to summarize gateway transactions: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	totals := &PaymentTotals{ByStatus: map[PaymentStatus]StatusTotal{}}
+	for _, row := range rows {
+		amount := money.Paisa(row.TotalAmount)
+		totals.ByStatus[PaymentStatus(row.Status)] = StatusTotal{Count: row.TransactionCount, Amount: amount}
+		totals.Count += row.TransactionCount
+		if totals.Amount, err = totals.Amount.Add(amount); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+		}
+	}
+	return totals, nil
+}
+
+// gatewayNameFor names the gateway behind a payment method for event records
+func (s *Service) gatewayNameFor(method PaymentMethod) string {
+	provider, err := s.providers.Provider(method)
+	if err != nil {
+		return string(method)
+	}
+	return provider.Gateway().Name()
+}
+
+// =============================================================================
+// HELPERS - Payments Console
+// =============================================================================
+
+// searchParams turns a search into query parameters; PageSize is left to the caller
+func searchParams(search PaymentSearch) (payment.SearchGatewayTransactionsParams, error) {
+	var params payment.SearchGatewayTransactionsParams
+
+	if search.UserID != nil {
+		params.UserID = pgtype.UUID{Bytes: *search.UserID, Valid: true}
+	}
+	for _, status := range search.Statuses {
+		switch status {
+		case PaymentStatusPending, PaymentStatusSuccess, PaymentStatusFailed, PaymentStatusUnknown:
+			params.Statuses = append(params.Statuses, string(status))
+		default:
+			return params, fmt.Errorf("%w: unknown status %q", ErrInvalidPaymentSearch, status)
+		}
+	}
+	if search.Method != "" {
+		params.PaymentMethod = common.StringToText(string(search.Method))
+	}
+	if !search.From.IsZero() {
+		params.CreatedFrom = pgtype.Timestamptz{Time: search.From, Valid: true}
+	}
+	if !search.To.IsZero() {
+		params.CreatedTo = pgtype.Timestamptz{Time: search.To, Valid: true}
+	}
+
+	if search.MinAmount != nil {
+		minAmount, err := search.MinAmount.Int64Value()
+		if err != nil {
+			return params, fmt.Errorf("%w: %v", ErrInvalidPaymentSearch, err)
+		}
+		params.MinAmount = minAmount
+	}
+	if search.MaxAmount != nil {
+		maxAmount, err := search.MaxAmount.Int64Value()
+		if err != nil {
+			return params, fmt.Errorf("%w: %v", ErrInvalidPaymentSearch, err)
+		}
+		params.MaxAmount = maxAmount
+	}
+
+	if search.TxnRefNo != "" {
+		params.TxnRefNo = common.StringToText(search.TxnRefNo)
+	}
+	if search.RRN != "" {
+		params.GatewayRrn = common.StringToText(search.RRN)
+	}
+
+	if search.Cursor != "" {
+		createdAt, id, err := decodePaymentCursor(search.Cursor)
+		if err != nil {
+			return params, err
+		}
+		params.AfterCreatedAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		params.AfterID = pgtype.UUID{Bytes: id, Valid: true}
+	}
+
+	return params, nil
+}
+
+// encodePaymentCursor points just past a row in (created_at, id) order
+func encodePaymentCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodePaymentCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPaymentSearch)
+	}
+
+	timestamp, rawID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPaymentSearch)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPaymentSearch)
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("%w: malformed cursor", ErrInvalidPaymentSearch)
+	}
+
+	return createdAt, id, nil
+}
+
+// paymentExportRecord is one CSV line, in paymentExportHeader order
+func paymentExportRecord(p AdminPayment) []string {
+	return []string{
+		p.CreatedAt.In(common.PKT).Format(time.RFC3339),
+		p.TxnRefNo,
+		p.BillRefID,
+		p.UserID.String(),
+		csvSafe(p.UserName),
+		csvSafe(p.UserEmail),
+		string(p.PaymentMethod),
+		string(p.Status),
+		p.Amount.MinorString(),
+		p.Amount.Decimal(),
+		p.GatewayRRN,
+		p.ResponseCode,
+		p.UpdatedAt.In(common.PKT).Format(time.RFC3339),
+		p.MerchantProfile,
+	}
+}
+
+// csvSafe stops spreadsheet apps from treating user-supplied text as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func toAdminPayment(row payment.SearchGatewayTransactionsRow) AdminPayment {
+	return AdminPayment{
+		ID:              row.ID,
+		UserID:          row.UserID,
+		UserName:        row.UserName,
+		UserEmail:       row.UserEmail,
+		TxnRefNo:        row.TxnRefNo,
+		BillRefID:       row.BillRefID,
+		PaymentMethod:   PaymentMethod(row.PaymentMethod),
+		Status:          PaymentStatus(row.Status),
+		Amount:          row.Amount,
+		GatewayRRN:      common.TextToString(row.GatewayRrn),
+		ResponseCode:    common.TextToString(row.ResponseCode),
+		InquiryAttempts: row.InquiryAttempts,
+		MerchantProfile: row.MerchantProfile,
+		CreatedAt:       row.CreatedAt,
+		UpdatedAt:       row.UpdatedAt,
+	}
+}
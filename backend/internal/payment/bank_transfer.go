@@ -0,0 +1,478 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidBankTransfer Validation errors (400) - show to user
+	ErrInvalidBankTransfer = errors.New("invalid bank transfer")
+	ErrInvalidReceipt      = errors.New("receipt must be a JPEG, PNG or WebP image of at most 5 MB")
+
+	// ErrReviewReasonRequired Rejecting needs a reason the user will see (400)
+	ErrReviewReasonRequired  = errors.New("a reason is required to reject a bank transfer")
+	ErrInvalidReviewDecision = errors.New("review decision must be APPROVED or REJECTED")
+
+	// ErrDuplicateBankReference Reference already belongs to a transfer that was not rejected (409)
+	ErrDuplicateBankReference = errors.New("bank reference has already been submitted")
+
+	// ErrBankTransferReviewed Transfer was already approved or rejected (409)
+	ErrBankTransferReviewed = errors.New("bank transfer has already been reviewed")
+
+	// ErrNotGatewayTransaction Bank transfers are settled by review, not by asking a gateway (409)
+	ErrNotGatewayTransaction = errors.New("bank transfers are settled by admin review")
+
+	// ErrBankTransferNotFound Unknown bank transfer id (404)
+	ErrBankTransferNotFound = errors.New("bank transfer not found")
+)
+
+const (
+	// bankTransferReviewWindow is how long a submitted transfer waits for review.
+	// The sweeper leaves bank transfers alone; this only bounds expires_at.
+	bankTransferReviewWindow = 7 * 24 * time.Hour
+
+	// maxReceiptSize bounds an uploaded receipt image
+	maxReceiptSize = 5 << 20
+
+	// bankReferenceConstraint is the index that keeps a bank reference from being reused
+	bankReferenceConstraint = "idx_bank_transfers_reference"
+)
+
+// receiptExtensions maps the receipt types we accept to the extension they are stored under
+var receiptExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Bank Transfers
+// =============================================================================
+
+// SubmitBankTransfer records a top-up the user paid into the university's bank
+// account. The transaction stays PENDING until an admin checks the receipt.
+// Repeating a submission with the same idempotency key returns the original transfer.
+func (s *Service) SubmitBankTransfer(ctx context.Context, sub BankTransferSubmission, receipt io.Reader) (*BankTransfer, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	if sub.IdempotencyKey == uuid.Nil {
+		return nil, fmt.Errorf("%w: idempotency_key is required", ErrInvalidBankTransfer)
+	}
+	if sub.Amount.Currency() != money.PKR || !sub.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: got %s", ErrInvalidAmount, sub.Amount)
+	}
+	bankReference, err := normalizeBankReference(sub.BankReference)
+	if err != nil {
+		return nil, err
+	}
+	image, contentType, err := readReceipt(receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin bank transfer transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", sub.IdempotencyKey.String())
+	if err != nil {
+		log.Printf("failed to acquire advisory lock: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToAcquireLock, err)
+	}
+
+	existing, err := paymentQ.GetByIdempotencyKey(ctx, sub.IdempotencyKey)
+	if err == nil {
+		return s.existingBankTransfer(ctx, paymentQ, existing, userID)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error checking idempotency key: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if err := auditLimitChecks(userID.String(), sub.Amount, s.limits.checkAmountLimits(sub.Amount)); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "topup:"+userID.String())
+	if err != nil {
+		log.Printf("failed to acquire top-up lock for %s: %v", userID, err)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToAcquireLock, err)
+	}
+
+	if _, err := s.enforceUsageLimits(ctx, paymentQ, userID, sub.Amount); err != nil {
+		return nil, err
+	}
+
+	billRefNo, err := GenerateBillRefNo()
+	if err != nil {
+		log.Printf("error generating bill reference: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+
+	txnRefNo, err := GenerateTxnRefNo()
+	if err != nil {
+		log.Printf("error generating transaction reference: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+
+	intent, err := s.ensureIntent(ctx, paymentQ, CreateIntentRequest{
+		Purpose:          PurposeTopUp,
+		PurposeReference: sub.IdempotencyKey.String(),
+		Amount:           sub.Amount,
+		PayerID:          userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.transitionIntent(ctx, paymentQ, intent.ID, IntentProcessing); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	gatewayTxn, err := paymentQ.CreateGatewayTransaction(ctx, payment.CreateGatewayTransactionParams{
+		UserID:          userID,
+		IdempotencyKey:  sub.IdempotencyKey,
+		BillRefID:       billRefNo,
+		TxnRefNo:        txnRefNo,
+		PaymentMethod:   string(PaymentMethodBankTransfer),
+		Status:          payment.CurrentStatus(PaymentStatusPending),
+		Amount:          sub.Amount,
+		ExpiresAt:       transactionExpiry(PaymentMethodBankTransfer, now),
+		MerchantProfile: DefaultMerchantProfile,
+		PaymentIntentID: intent.ID,
+	})
+	if err != nil {
+		log.Printf("failed to create bank transfer transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+
+	// The receipt is written before the row that points at it and removed
+	// again if the row is never committed
+	receiptKey := receiptObjectKey(gatewayTxn.ID, contentType, now)
+	if err := s.receipts.Put(ctx, receiptKey, bytes.NewReader(image)); err != nil {
+		log.Printf("failed to store receipt for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			if err := s.receipts.Delete(context.Background(), receiptKey); err != nil {
+				log.Printf("failed to remove receipt %s: %v", receiptKey, err)
+			}
+		}
+	}()
+
+	transfer, err := paymentQ.CreateBankTransfer(ctx, payment.CreateBankTransferParams{
+		GatewayTransactionID: gatewayTxn.ID,
+		BankReference:        bankReference,
+		ReceiptKey:           receiptKey,
+		ReceiptContentType:   contentType,
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == bankReferenceConstraint {
+			return nil, ErrDuplicateBankReference
+		}
+		log.Printf("failed to create bank transfer for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+
+	s.recordEvent(ctx, paymentQ, gatewayTxn, gatewayEvent{
+		Gateway: s.gatewayNameFor(PaymentMethodBankTransfer),
+		Kind:    GatewayEventInitiate,
+		Exchange: gateway.Exchange{Request: map[string]string{
+			"bank_reference": bankReference,
+			"receipt_type":   contentType,
+		}},
+	})
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit bank transfer %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+	committed = true
+
+	return toBankTransfer(transfer, gatewayTxn), nil
+}
+
+// ListBankTransfers returns transfers in one review state, oldest first, so
+// the pending queue is worked in the order users paid
+func (s *Service) ListBankTransfers(ctx context.Context, status BankTransferReview, limit, offset int32) ([]BankTransfer, error) {
+	switch status {
+	case BankTransferPending, BankTransferApproved, BankTransferRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown review status %q", ErrInvalidBankTransfer, status)
+	}
+
+	rows, err := s.q.ListBankTransfers(ctx, payment.ListBankTransfersParams{
+		ReviewStatus: payment.BankTransferReview(status),
+		PageSize:     limit,
+		PageOffset:   offset,
+	})
+	if err != nil {
+		log.Printf("failed to list bank transfers: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	transfers := make([]BankTransfer, 0, len(rows))
+	for _, row := range rows {
+		transfers = append(transfers, bankTransferFromQueue(row))
+	}
+	return transfers, nil
+}
+
+// OpenBankTransferReceipt returns the receipt image of a transfer and its
+// content type; the caller closes it
+func (s *Service) OpenBankTransferReceipt(ctx context.Context, transferID uuid.UUID) (io.ReadCloser, string, error) {
+	transfer, err := s.q.GetBankTransfer(ctx, transferID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", ErrBankTransferNotFound
+	} else if err != nil {
+		log.Printf("failed to load bank transfer %s: %v", transferID, err)
+		return nil, "", fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	receipt, err := s.receipts.Open(ctx, transfer.ReceiptKey)
+	if err != nil {
+		log.Printf("failed to open receipt %s of bank transfer %s: %v", transfer.ReceiptKey, transferID, err)
+		return nil, "", fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+	return receipt, transfer.ReceiptContentType, nil
+}
+
+// ReviewBankTransfer approves or rejects a pending transfer. Approval settles
+// the top-up as SUCCESS exactly like a gateway confirmation; rejection fails it
+// with the reason shown to the user. The admin and reason are kept in the
+// transaction's event history.
+func (s *Service) ReviewBankTransfer(ctx context.Context, transferID uuid.UUID, req ReviewBankTransferRequest) (*BankTransfer, error) {
+	adminID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	status, err := reviewOutcome(req.Decision, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin bank transfer review: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	transfer, err := paymentQ.ReviewBankTransfer(ctx, payment.ReviewBankTransferParams{
+		ReviewStatus: payment.BankTransferReview(req.Decision),
+		ReviewedBy:   pgtype.UUID{Bytes: adminID, Valid: true},
+		ReviewReason: common.StringToText(reason),
+		ID:           transferID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := paymentQ.GetBankTransfer(ctx, transferID); err == nil {
+			return nil, ErrBankTransferReviewed
+		}
+		return nil, ErrBankTransferNotFound
+	} else if err != nil {
+		log.Printf("failed to review bank transfer %s: %v", transferID, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	gatewayTxn, err := paymentQ.GetTransactionByID(ctx, transfer.GatewayTransactionID)
+	if err != nil {
+		log.Printf("failed to load transaction of bank transfer %s: %v", transferID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	gatewayTxn, err = s.finalizeTransaction(ctx, tx, gatewayTxn, status, gatewayOutcome{
+		Source:       GatewayEventManual,
+		ResponseCode: string(req.Decision),
+		RRN:          transfer.BankReference,
+		Response: map[string]string{
+			"decision": string(req.Decision),
+			"reason":   reason,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.recordEvent(ctx, paymentQ, gatewayTxn, gatewayEvent{
+		Gateway: s.gatewayNameFor(PaymentMethodBankTransfer),
+		Kind:    GatewayEventManual,
+		Exchange: gateway.Exchange{Request: map[string]string{
+			"action":      "review_bank_transfer",
+			"decision":    string(req.Decision),
+			"reason":      reason,
+			"reviewed_by": adminID.String(),
+		}},
+		ResponseCode: string(req.Decision),
+		RRN:          transfer.BankReference,
+	})
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit review of bank transfer %s: %v", transferID, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	log.Printf("bank transfer %s (%s) %s by admin %s: %s", transferID, gatewayTxn.TxnRefNo, req.Decision, adminID, reason)
+
+	return toBankTransfer(transfer, gatewayTxn), nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Bank Transfers
+// =============================================================================
+
+// existingBankTransfer answers a repeated submission with the transfer it created
+func (s *Service) existingBankTransfer(
+	ctx context.Context,
+	paymentQ *payment.Queries,
+	existing payment.GikiWalletGatewayTransaction,
+	userID uuid.UUID,
+) (*BankTransfer, error) {
+	if existing.UserID != userID || existing.PaymentMethod != string(PaymentMethodBankTransfer) {
+		return nil, fmt.Errorf("%w: idempotency key already used for another payment", ErrInvalidBankTransfer)
+	}
+
+	transfer, err := paymentQ.GetBankTransferByTransaction(ctx, existing.ID)
+	if err != nil {
+		log.Printf("failed to load bank transfer of %s: %v", existing.TxnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return toBankTransfer(transfer, existing), nil
+}
+
+// =============================================================================
+// HELPERS - Bank Transfers
+// =============================================================================
+
+// normalizeBankReference trims and upper-cases a bank reference so the same
+// reference typed twice is caught as a duplicate
+func normalizeBankReference(reference string) (string, error) {
+	reference = strings.ToUpper(strings.TrimSpace(reference))
+	if reference == "" || len(reference) > 100 {
+		return "", fmt.Errorf("%w: bank_reference must be 1 to 100 characters", ErrInvalidBankTransfer)
+	}
+	return reference, nil
+}
+
+// readReceipt reads an uploaded receipt and works out its type from its
+// contents, not from what the client claimed
+func readReceipt(receipt io.Reader) ([]byte, string, error) {
+	image, err := io.ReadAll(io.LimitReader(receipt, maxReceiptSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidReceipt, err)
+	}
+	if len(image) == 0 || len(image) > maxReceiptSize {
+		return nil, "", ErrInvalidReceipt
+	}
+
+	contentType := http.DetectContentType(image)
+	if _, ok := receiptExtensions[contentType]; !ok {
+		return nil, "", fmt.Errorf("%w: got %s", ErrInvalidReceipt, contentType)
+	}
+	return image, contentType, nil
+}
+
+// receiptObjectKey is where the receipt of a transaction is stored
+func receiptObjectKey(gatewayTxnID uuid.UUID, contentType string, now time.Time) string {
+	return fmt.Sprintf("receipts/%s/%s%s", now.UTC().Format("2006/01"), gatewayTxnID, receiptExtensions[contentType])
+}
+
+// reviewOutcome is the transaction status a review decision settles the top-up with
+func reviewOutcome(decision BankTransferReview, reason string) (PaymentStatus, error) {
+	switch decision {
+	case BankTransferApproved:
+		return PaymentStatusSuccess, nil
+	case BankTransferRejected:
+		if reason == "" {
+			return "", ErrReviewReasonRequired
+		}
+		return PaymentStatusFailed, nil
+	default:
+		return "", ErrInvalidReviewDecision
+	}
+}
+
+func toBankTransfer(transfer payment.GikiWalletBankTransfer, gatewayTxn payment.GikiWalletGatewayTransaction) *BankTransfer {
+	result := &BankTransfer{
+		ID:                 transfer.ID,
+		TxnRefNo:           gatewayTxn.TxnRefNo,
+		UserID:             gatewayTxn.UserID,
+		Amount:             gatewayTxn.Amount,
+		BankReference:      transfer.BankReference,
+		ReceiptContentType: transfer.ReceiptContentType,
+		ReviewStatus:       BankTransferReview(transfer.ReviewStatus),
+		ReviewReason:       common.TextToString(transfer.ReviewReason),
+		CreatedAt:          transfer.CreatedAt,
+	}
+	if transfer.ReviewedBy.Valid {
+		reviewedBy := uuid.UUID(transfer.ReviewedBy.Bytes)
+		result.ReviewedBy = &reviewedBy
+	}
+	if transfer.ReviewedAt.Valid {
+		reviewedAt := transfer.ReviewedAt.Time
+		result.ReviewedAt = &reviewedAt
+	}
+	return result
+}
+
+func bankTransferFromQueue(row payment.ListBankTransfersRow) BankTransfer {
+	result := BankTransfer{
+		ID:                 row.ID,
+		TxnRefNo:           row.TxnRefNo,
+		UserID:             row.UserID,
+		UserName:           row.UserName,
+		UserEmail:          row.UserEmail,
+		Amount:             row.Amount,
+		BankReference:      row.BankReference,
+		ReceiptContentType: row.ReceiptContentType,
+		ReviewStatus:       BankTransferReview(row.ReviewStatus),
+		ReviewReason:       common.TextToString(row.ReviewReason),
+		CreatedAt:          row.CreatedAt,
+	}
+	if row.ReviewedBy.Valid {
+		reviewedBy := uuid.UUID(row.ReviewedBy.Bytes)
+		result.ReviewedBy = &reviewedBy
+	}
+	if row.ReviewedAt.Valid {
+		reviewedAt := row.ReviewedAt.Time
+		result.ReviewedAt = &reviewedAt
+	}
+	return result
+}
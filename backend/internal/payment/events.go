@@ -0,0 +1,155 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// gatewayEvent is one exchange with a gateway about a transaction
+type gatewayEvent struct {
+	Gateway      string
+	Kind         GatewayEventKind
+	Exchange     gateway.Exchange
+	ResponseCode string
+	RRN          string
+	Err          error // transport or verification failure, if any
+}
+
+// gatewayOutcome is the gateway answer that settled a transaction.
+// Empty fields leave what is already stored on the row.
+type gatewayOutcome struct {
+	Source       GatewayEventKind // exchange that produced the answer
+	ResponseCode string
+	RRN          string
+	Response     any // redacted reply, stored as raw_response
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Event History
+// =============================================================================
+
+// GetTransactionTimeline returns a transaction with every recorded gateway exchange, oldest first
+func (s *Service) GetTransactionTimeline(ctx context.Context, txnRefNo string) (*TransactionTimeline, error) {
+	gatewayTxn, err := s.q.GetTransactionByTxnRefNo(ctx, txnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	events, err := s.q.ListGatewayEvents(ctx, gatewayTxn.ID)
+	if err != nil {
+		log.Printf("failed to list gateway events for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	timeline := &TransactionTimeline{
+		ID:              gatewayTxn.ID,
+		TxnRefNo:        gatewayTxn.TxnRefNo,
+		BillRefID:       gatewayTxn.BillRefID,
+		UserID:          gatewayTxn.UserID,
+		PaymentMethod:   PaymentMethod(gatewayTxn.PaymentMethod),
+		Status:          PaymentStatus(gatewayTxn.Status),
+		Amount:          gatewayTxn.Amount,
+		GatewayRRN:      common.TextToString(gatewayTxn.GatewayRrn),
+		ResponseCode:    common.TextToString(gatewayTxn.ResponseCode),
+		MerchantProfile: gatewayTxn.MerchantProfile,
+		CreatedAt:       gatewayTxn.CreatedAt,
+		UpdatedAt:       gatewayTxn.UpdatedAt,
+		Events:          make([]GatewayEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		timeline.Events = append(timeline.Events, toGatewayEvent(event))
+	}
+	return timeline, nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Event History
+// =============================================================================
+
+// recordEvent appends an exchange to the transaction's history. A failure is
+// only logged; inside a database transaction it still aborts that transaction
+// like any other failed statement.
+func (s *Service) recordEvent(
+	ctx context.Context,
+	paymentQ *payment.Queries,
+	gatewayTxn payment.GikiWalletGatewayTransaction,
+	event gatewayEvent,
+) {
+	params := payment.RecordGatewayEventParams{
+		GatewayTransactionID: gatewayTxn.ID,
+		Gateway:              event.Gateway,
+		Kind:                 payment.GatewayEventKind(event.Kind),
+		Request:              marshalPayload(event.Exchange.Request),
+		Response:             marshalPayload(event.Exchange.Response),
+		ResponseCode:         common.StringToText(event.ResponseCode),
+		GatewayRrn:           common.StringToText(event.RRN),
+	}
+	if event.Err != nil {
+		params.Error = common.StringToText(event.Err.Error())
+	}
+
+	if err := paymentQ.RecordGatewayEvent(ctx, params); err != nil {
+		log.Printf("failed to record %s event for %s: %v", event.Kind, gatewayTxn.TxnRefNo, err)
+	}
+}
+
+// =============================================================================
+// HELPERS - Event History
+// =============================================================================
+
+// inquiryOutcome picks the fields of an inquiry reply that describe the payment itself
+func inquiryOutcome(inquiryResult gateway.InquiryResponse) gatewayOutcome {
+	responseCode := inquiryResult.PaymentResponseCode
+	if responseCode == "" {
+		responseCode = inquiryResult.ResponseCode
+	}
+
+	return gatewayOutcome{
+		Source:       GatewayEventInquiry,
+		ResponseCode: responseCode,
+		RRN:          inquiryResult.RRN,
+		Response:     inquiryResult.Exchange.Response,
+	}
+}
+
+// marshalPayload encodes a payload for a JSONB column, or nil when there is none
+func marshalPayload(payload any) []byte {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("failed to encode gateway payload: %v", err)
+		return nil
+	}
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+func toGatewayEvent(event payment.GikiWalletGatewayTransactionEvent) GatewayEvent {
+	return GatewayEvent{
+		ID:           event.ID,
+		Kind:         GatewayEventKind(event.Kind),
+		Gateway:      event.Gateway,
+		Request:      event.Request,
+		Response:     event.Response,
+		ResponseCode: common.TextToString(event.ResponseCode),
+		GatewayRRN:   common.TextToString(event.GatewayRrn),
+		Error:        common.TextToString(event.Error),
+		CreatedAt:    event.CreatedAt,
+	}
+}
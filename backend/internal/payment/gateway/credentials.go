@@ -0,0 +1,226 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// CONSTANTS
+// =============================================================================
+
+// credentialPollInterval is how often a watched credentials file is re-read
+const credentialPollInterval = 15 * time.Second
+
+// ErrInvalidCredentials is returned for a credentials file or rotation that cannot be used
+var ErrInvalidCredentials = errors.New("invalid gateway credentials")
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// JazzCashCredentials is one version of a merchant account's JazzCash secrets
+type JazzCashCredentials struct {
+	Version       string `json:"version"`
+	MerchantID    string `json:"merchant_id"`
+	Password      string `json:"password"`
+	IntegritySalt string `json:"integrity_salt"`
+	MerchantMPIN  string `json:"merchant_mpin"`
+}
+
+// CredentialStore holds the credentials a client signs with and, for a grace
+// window after a rotation, the version they replaced. Outgoing requests are
+// always signed with the active version; incoming hashes verify against either,
+// so callbacks and IPNs signed just before a rotation are not rejected.
+type CredentialStore struct {
+	mu        sync.RWMutex
+	active    JazzCashCredentials
+	previous  *JazzCashCredentials
+	retiredAt time.Time // when previous stopped being active
+	grace     time.Duration
+	lastRaw   []byte // file contents last applied by Watch
+}
+
+// credentialFile is the JSON layout of a credentials file. previous and
+// rotated_at let a restart inside the grace window keep accepting the old version.
+type credentialFile struct {
+	Active    *JazzCashCredentials `json:"active"`
+	Previous  *JazzCashCredentials `json:"previous,omitempty"`
+	RotatedAt time.Time            `json:"rotated_at,omitempty"` // defaults to when the file is loaded
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// NewCredentialStore creates a store whose only version is active, e.g.
+// credentials read from the environment
+func NewCredentialStore(active JazzCashCredentials, grace time.Duration) *CredentialStore {
+	return &CredentialStore{active: active, grace: grace}
+}
+
+// LoadCredentialStore creates a store from the credentials file at path
+func LoadCredentialStore(path string, grace time.Duration) (*CredentialStore, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	file, err := parseCredentialFile(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	store := NewCredentialStore(*file.Active, grace)
+	store.lastRaw = raw
+	if file.Previous != nil && file.Previous.Version != file.Active.Version {
+		store.previous = file.Previous
+		store.retiredAt = file.RotatedAt
+		if store.retiredAt.IsZero() {
+			store.retiredAt = time.Now()
+		}
+	}
+	return store, nil
+}
+
+// =============================================================================
+// PUBLIC STORE METHODS
+// =============================================================================
+
+// Active returns the version new requests are signed with
+func (s *CredentialStore) Active() JazzCashCredentials {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Verifying returns every version an incoming hash may be signed with, active first
+func (s *CredentialStore) Verifying(now time.Time) []JazzCashCredentials {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := []JazzCashCredentials{s.active}
+	if s.previous != nil && now.Before(s.retiredAt.Add(s.grace)) {
+		versions = append(versions, *s.previous)
+	}
+	return versions
+}
+
+// Rotate makes next the active version. The version it replaces keeps
+// verifying incoming hashes for the grace window. Rotating to the active
+// version is a no-op; changing secrets without a new version is refused.
+func (s *CredentialStore) Rotate(next JazzCashCredentials) error {
+	if err := validateCredentials(next); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next.Version == s.active.Version {
+		if next != s.active {
+			return fmt.Errorf("%w: version %s changed without a new version", ErrInvalidCredentials, next.Version)
+		}
+		return nil
+	}
+
+	previous := s.active
+	s.previous = &previous
+	s.retiredAt = time.Now()
+	s.active = next
+	return nil
+}
+
+// Watch re-reads the credentials file at path until ctx is cancelled and
+// rotates to its active version whenever it changes. A file that cannot be
+// used is logged and the current credentials are kept.
+func (s *CredentialStore) Watch(ctx context.Context, path string) {
+	ticker := time.NewTicker(credentialPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reload(path); err != nil {
+				log.Printf("credentials file %s not applied: %v", path, err)
+			}
+		}
+	}
+}
+
+// =============================================================================
+// HELPERS - Credentials
+// =============================================================================
+
+// reload applies the credentials file at path if it changed since the last read
+func (s *CredentialStore) reload(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	s.mu.RLock()
+	unchanged := bytes.Equal(raw, s.lastRaw)
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	file, err := parseCredentialFile(raw)
+	if err != nil {
+		return err
+	}
+	from := s.Active().Version
+	if err := s.Rotate(*file.Active); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.lastRaw = raw
+	s.mu.Unlock()
+
+	if from != file.Active.Version {
+		log.Printf("JazzCash credentials rotated from version %s to %s; %s verifies for another %s",
+			from, file.Active.Version, from, s.grace)
+	}
+	return nil
+}
+
+// parseCredentialFile decodes a credentials file and validates every version in it
+func parseCredentialFile(raw []byte) (credentialFile, error) {
+	var file credentialFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return file, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if file.Active == nil {
+		return file, fmt.Errorf("%w: no active credentials", ErrInvalidCredentials)
+	}
+	if err := validateCredentials(*file.Active); err != nil {
+		return file, err
+	}
+	if file.Previous != nil {
+		if err := validateCredentials(*file.Previous); err != nil {
+			return file, err
+		}
+	}
+	return file, nil
+}
+
+// validateCredentials rejects a version missing anything requests are signed with
+func validateCredentials(creds JazzCashCredentials) error {
+	switch {
+	case creds.Version == "":
+		return fmt.Errorf("%w: version is required", ErrInvalidCredentials)
+	case creds.MerchantID == "" || creds.Password == "" || creds.IntegritySalt == "":
+		return fmt.Errorf("%w: version %s needs merchant_id, password and integrity_salt", ErrInvalidCredentials, creds.Version)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCredentials(version, salt string) JazzCashCredentials {
+	return JazzCashCredentials{
+		Version:       version,
+		MerchantID:    "TEST_MERCHANT",
+		Password:      "TEST_PASSWORD",
+		IntegritySalt: salt,
+		MerchantMPIN:  "TEST_MPIN",
+	}
+}
+
+func TestCredentialStore_RotateKeepsPreviousForGrace(t *testing.T) {
+	fields := JazzCashFields{"pp_TxnRefNo": "TEST_TXN", "pp_ResponseCode": "000"}
+	oldHash, _ := jazzCashSecureHash(fields, "old_salt")
+
+	tests := []struct {
+		name      string
+		grace     time.Duration
+		wantValid bool
+	}{
+		{"inside grace", time.Hour, true},
+		{"no grace", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewCredentialStore(testCredentials("v1", "old_salt"), tt.grace)
+			if err := store.Rotate(testCredentials("v2", "new_salt")); err != nil {
+				t.Fatalf("Rotate() error = %v", err)
+			}
+			client := &JazzCashClient{credentials: store}
+
+			// New requests are signed with the new salt only
+			newHash, _ := jazzCashSecureHash(fields, "new_salt")
+			if got, _ := client.JazzcashSecureHash(fields); got != newHash {
+				t.Errorf("JazzcashSecureHash() signed with the previous salt")
+			}
+			if err := client.verifyFieldsHash(fields, newHash); err != nil {
+				t.Errorf("verifyFieldsHash(new) error = %v", err)
+			}
+
+			err := client.verifyFieldsHash(fields, oldHash)
+			if tt.wantValid && err != nil {
+				t.Errorf("verifyFieldsHash(old) error = %v, want accepted inside the grace window", err)
+			}
+			if !tt.wantValid && err == nil {
+				t.Error("verifyFieldsHash(old) accepted a hash after the grace window")
+			}
+		})
+	}
+}
+
+func TestCredentialStore_RotateSameVersion(t *testing.T) {
+	store := NewCredentialStore(testCredentials("v1", "salt"), time.Hour)
+
+	if err := store.Rotate(testCredentials("v1", "salt")); err != nil {
+		t.Errorf("Rotate(unchanged) error = %v", err)
+	}
+	if got := store.Verifying(time.Now()); len(got) != 1 {
+		t.Errorf("Verifying() after a no-op rotation has %d versions, want 1", len(got))
+	}
+
+	if err := store.Rotate(testCredentials("v1", "other_salt")); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Rotate(changed secrets, same version) error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if err := store.Rotate(JazzCashCredentials{Version: "v2"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Rotate(incomplete) error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if got := store.Active().IntegritySalt; got != "salt" {
+		t.Errorf("Active() salt = %q after refused rotations, want salt", got)
+	}
+}
+
+func TestCredentialStore_FileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jazzcash.json")
+	write := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`{
+		"active": {"version": "2024-07", "merchant_id": "M1", "password": "P1", "integrity_salt": "salt_july", "merchant_mpin": "1234"},
+		"previous": {"version": "2024-06", "merchant_id": "M1", "password": "P0", "integrity_salt": "salt_june"},
+		"rotated_at": "` + time.Now().Add(-30*time.Minute).Format(time.RFC3339) + `"
+	}`)
+
+	store, err := LoadCredentialStore(path, time.Hour)
+	if err != nil {
+		t.Fatalf("LoadCredentialStore() error = %v", err)
+	}
+	if got := store.Verifying(time.Now()); len(got) != 2 || got[1].Version != "2024-06" {
+		t.Fatalf("Verifying() = %+v, want 2024-07 then 2024-06", got)
+	}
+	if got := store.Verifying(time.Now().Add(31 * time.Minute)); len(got) != 1 {
+		t.Errorf("Verifying() past rotated_at+grace has %d versions, want 1", len(got))
+	}
+
+	// A broken edit keeps the current credentials
+	write(`{"active": {"version": "2024-08"}}`)
+	if err := store.reload(path); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("reload(incomplete) error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if got := store.Active().Version; got != "2024-07" {
+		t.Errorf("Active() version = %s after a broken edit, want 2024-07", got)
+	}
+
+	write(`{"active": {"version": "2024-08", "merchant_id": "M1", "password": "P2", "integrity_salt": "salt_august"}}`)
+	if err := store.reload(path); err != nil {
+		t.Fatalf("reload() error = %v", err)
+	}
+	got := store.Verifying(time.Now())
+	if len(got) != 2 || got[0].Version != "2024-08" || got[1].Version != "2024-07" {
+		t.Errorf("Verifying() after rotation = %+v, want 2024-08 then 2024-07", got)
+	}
+}
@@ -0,0 +1,402 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// CONSTANTS - Field name constants
+// =============================================================================
+
+const (
+	EasypaisaFieldOrderID           = "orderId"
+	EasypaisaFieldStoreID           = "storeId"
+	EasypaisaFieldAmount            = "transactionAmount"
+	EasypaisaFieldTransactionType   = "transactionType"
+	EasypaisaFieldMobileAccountNo   = "mobileAccountNo"
+	EasypaisaFieldAccountNum        = "accountNum"
+	EasypaisaFieldEmailAddress      = "emailAddress"
+	EasypaisaFieldHash              = "merchantHashedReq"
+	EasypaisaFieldResponseCode      = "responseCode"
+	EasypaisaFieldResponseDesc      = "responseDesc"
+	EasypaisaFieldTransactionID     = "transactionId"
+	EasypaisaFieldTransactionStatus = "transactionStatus"
+
+	// EasypaisaFieldCredentials carries base64(username:password); it is sent
+	// as a header and must never be stored if a payload ever holds it
+	EasypaisaFieldCredentials = "Credentials"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+type EasypaisaFields map[string]string
+
+var (
+	_ Gateway         = (*EasypaisaClient)(nil)
+	_ BreakerReporter = (*EasypaisaClient)(nil)
+)
+
+// EasypaisaName is the gateway name used in routes and stored records
+const EasypaisaName = "EASYPAISA"
+
+// Easypaisa response codes the adapter acts on
+const (
+	easypaisaCodeSuccess     = "0000"
+	easypaisaCodeSystemError = "0001"
+)
+
+// easypaisaTransactionTypeMA selects the mobile account (wallet) channel
+const easypaisaTransactionTypeMA = "MA"
+
+// EasypaisaClient talks to the Easypaisa (Easypay) merchant REST API.
+// The merchant authenticates with a Credentials header, and every request
+// carries merchantHashedReq, an AES digest of its fields under the store's hash key.
+type EasypaisaClient struct {
+	storeID     string
+	username    string
+	password    string
+	hashKey     string
+	initiateURL string
+	inquiryURL  string
+	httpClient  *http.Client
+	resilience  ResilienceConfig
+	breaker     *CircuitBreaker
+}
+
+// =============================================================================
+// CONSTRUCTOR
+// =============================================================================
+
+func NewEasypaisaClient(
+	storeID string,
+	username string,
+	password string,
+	hashKey string,
+	initiateURL string,
+	inquiryURL string,
+	resilience ResilienceConfig,
+) *EasypaisaClient {
+	return &EasypaisaClient{
+		storeID:     storeID,
+		username:    username,
+		password:    password,
+		hashKey:     hashKey,
+		initiateURL: initiateURL,
+		inquiryURL:  inquiryURL,
+		httpClient: &http.Client{
+			Timeout: 45 * time.Second, // customer approves on the phone while the call is open
+		},
+		resilience: resilience,
+		breaker:    NewCircuitBreaker(resilience.FailureThreshold, resilience.OpenTimeout),
+	}
+}
+
+// =============================================================================
+// PUBLIC API METHODS - Gateway interface implementation
+// =============================================================================
+
+func (c *EasypaisaClient) Name() string {
+	return EasypaisaName
+}
+
+// SubmitMWallet initiates a mobile account payment. Easypaisa pushes an
+// approval prompt to the customer's phone and answers once it is settled.
+func (c *EasypaisaClient) SubmitMWallet(ctx context.Context, req MWalletInitiateRequest) (MWalletInitiateResponse, error) {
+	if err := requirePKR(req.Amount); err != nil {
+		return MWalletInitiateResponse{}, err
+	}
+
+	fields := EasypaisaFields{
+		EasypaisaFieldOrderID:         req.TxnRefNo,
+		EasypaisaFieldStoreID:         c.storeID,
+		EasypaisaFieldAmount:          req.Amount.Decimal(), // Easypaisa takes rupees
+		EasypaisaFieldTransactionType: easypaisaTransactionTypeMA,
+		EasypaisaFieldMobileAccountNo: req.MobileNumber,
+	}
+
+	hash, err := c.EasypaisaHash(fields)
+	if err != nil {
+		return MWalletInitiateResponse{}, err
+	}
+	fields[EasypaisaFieldHash] = hash
+
+	responseMap, err := c.postSigned(ctx, c.initiateURL, fields)
+	exchange := newExchange(fields, responseMap)
+	if err != nil {
+		return MWalletInitiateResponse{Exchange: exchange}, fmt.Errorf("initiate API: %w", err)
+	}
+
+	responseCode := stringField(responseMap, EasypaisaFieldResponseCode)
+
+	return MWalletInitiateResponse{
+		Status:       mapEasypaisaResponseCode(responseCode),
+		ResponseCode: responseCode,
+		Message:      easypaisaMessage(responseCode, stringField(responseMap, EasypaisaFieldResponseDesc)),
+		RRN:          stringField(responseMap, EasypaisaFieldTransactionID),
+		Raw:          responseMap,
+		Exchange:     exchange,
+	}, nil
+}
+
+// Inquiry asks Easypaisa for the current state of an order
+func (c *EasypaisaClient) Inquiry(ctx context.Context, req InquiryRequest) (InquiryResponse, error) {
+	fields := EasypaisaFields{
+		EasypaisaFieldOrderID:    req.TxnRefNo,
+		EasypaisaFieldStoreID:    c.storeID,
+		EasypaisaFieldAccountNum: c.storeID,
+	}
+
+	hash, err := c.EasypaisaHash(fields)
+	if err != nil {
+		return InquiryResponse{}, err
+	}
+	fields[EasypaisaFieldHash] = hash
+
+	// Inquiries only read state, so they are safe to retry
+	responseMap, err := withRetry(ctx, c.resilience, func(ctx context.Context) (map[string]any, error) {
+		return c.postSigned(ctx, c.inquiryURL, fields)
+	})
+	exchange := newExchange(fields, responseMap)
+	if err != nil {
+		return InquiryResponse{Exchange: exchange}, fmt.Errorf("inquiry API: %w", err)
+	}
+
+	responseCode := stringField(responseMap, EasypaisaFieldResponseCode)
+	transactionStatus := stringField(responseMap, EasypaisaFieldTransactionStatus)
+
+	// The API call can succeed while the payment itself has not
+	status := mapEasypaisaResponseCode(responseCode)
+	if responseCode == easypaisaCodeSuccess {
+		status = mapEasypaisaTransactionStatus(transactionStatus)
+	}
+
+	return InquiryResponse{
+		Status:              status,
+		ResponseCode:        responseCode,
+		PaymentResponseCode: transactionStatus,
+		Message:             easypaisaMessage(responseCode, stringField(responseMap, EasypaisaFieldResponseDesc)),
+		RRN:                 stringField(responseMap, EasypaisaFieldTransactionID),
+		Raw:                 responseMap,
+		Exchange:            exchange,
+	}, nil
+}
+
+// InitiateCard is not offered: card payments go through JazzCash
+func (c *EasypaisaClient) InitiateCard(ctx context.Context, req CardInitiateRequest) (CardInitiateResponse, error) {
+	return CardInitiateResponse{}, fmt.Errorf("%w: Easypaisa card checkout", ErrUnsupported)
+}
+
+// ParseAndVerifyCardCallback is not offered: card payments go through JazzCash
+func (c *EasypaisaClient) ParseAndVerifyCardCallback(ctx context.Context, form map[string]string) (CardCallback, error) {
+	return CardCallback{}, fmt.Errorf("%w: Easypaisa card callback", ErrUnsupported)
+}
+
+// CallbackTxnRefNo returns nothing: Easypaisa posts no callbacks here
+func (c *EasypaisaClient) CallbackTxnRefNo(fields map[string]string) string {
+	return ""
+}
+
+// Refund is not offered by the Easypaisa merchant API; refunds are raised with Easypaisa directly
+func (c *EasypaisaClient) Refund(ctx context.Context, req RefundRequest) (RefundResponse, error) {
+	return RefundResponse{}, fmt.Errorf("%w: Easypaisa refunds", ErrUnsupported)
+}
+
+// BreakerStatus reports whether calls to Easypaisa are currently failing fast
+func (c *EasypaisaClient) BreakerStatus() BreakerStatus {
+	return c.breaker.Status()
+}
+
+// =============================================================================
+// HELPERS - HTTP
+// =============================================================================
+
+// postSigned posts signed fields as JSON through the circuit breaker
+func (c *EasypaisaClient) postSigned(ctx context.Context, url string, fields EasypaisaFields) (map[string]any, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	responseMap, err := c.post(ctx, url, fields)
+	c.breaker.Record(err)
+	return responseMap, err
+}
+
+// post makes one HTTP call with the merchant credentials. Failures to reach
+// Easypaisa and server errors are marked transient; a reply whose hash does
+// not match is returned with an error.
+func (c *EasypaisaClient) post(ctx context.Context, url string, fields EasypaisaFields) (map[string]any, error) {
+	jsonBody, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(EasypaisaFieldCredentials, c.credentials())
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &transientError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &transientError{err: fmt.Errorf("returned status %d", resp.StatusCode)}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+
+	var responseMap map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if err := c.verifyResponseHash(responseMap); err != nil {
+		return responseMap, fmt.Errorf("response hash verification failed: %w", err)
+	}
+
+	return responseMap, nil
+}
+
+// credentials is the Credentials header value: base64(username:password)
+func (c *EasypaisaClient) credentials() string {
+	return base64.StdEncoding.EncodeToString([]byte(c.username + ":" + c.password))
+}
+
+// =============================================================================
+// HELPERS - Hash computation and Verification
+// =============================================================================
+
+// EasypaisaHash computes merchantHashedReq: the non-empty fields sorted by
+// name as "key=value" pairs joined with &, AES-ECB encrypted (PKCS#5 padding)
+// under the store hash key and base64 encoded
+func (c *EasypaisaClient) EasypaisaHash(fields EasypaisaFields) (string, error) {
+	block, err := aes.NewCipher([]byte(c.hashKey))
+	if err != nil {
+		return "", fmt.Errorf("invalid Easypaisa hash key: %w", err)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k, v := range fields {
+		if k != EasypaisaFieldHash && v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var message strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			message.WriteString("&")
+		}
+		message.WriteString(k + "=" + fields[k])
+	}
+
+	// PKCS#5 padding up to the block size
+	plain := []byte(message.String())
+	padding := block.BlockSize() - len(plain)%block.BlockSize()
+	plain = append(plain, bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	// ECB: every block is encrypted on its own
+	encrypted := make([]byte, len(plain))
+	for start := 0; start < len(plain); start += block.BlockSize() {
+		block.Encrypt(encrypted[start:start+block.BlockSize()], plain[start:start+block.BlockSize()])
+	}
+
+	return base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+// VerifyEasypaisaHash checks merchantHashedReq against the other fields
+func (c *EasypaisaClient) VerifyEasypaisaHash(fields EasypaisaFields) error {
+	receivedHash := fields[EasypaisaFieldHash]
+	if receivedHash == "" {
+		return fmt.Errorf("missing %s", EasypaisaFieldHash)
+	}
+
+	expectedHash, err := c.EasypaisaHash(fields)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(receivedHash), []byte(expectedHash)) {
+		return fmt.Errorf("hash mismatch")
+	}
+	return nil
+}
+
+// verifyResponseHash verifies merchantHashedReq in an Easypaisa reply
+func (c *EasypaisaClient) verifyResponseHash(responseMap map[string]any) error {
+	fields := make(EasypaisaFields, len(responseMap))
+	for k, v := range responseMap {
+		fields[k] = fmt.Sprintf("%v", v)
+	}
+	return c.VerifyEasypaisaHash(fields)
+}
+
+// =============================================================================
+// HELPERS - Response mappers
+// =============================================================================
+
+// mapEasypaisaResponseCode maps the API response code onto the shared Status
+func mapEasypaisaResponseCode(responseCode string) Status {
+	switch responseCode {
+	case easypaisaCodeSuccess:
+		return StatusSuccess
+	case easypaisaCodeSystemError, "":
+		// Easypaisa could not say what happened; inquiry will settle it
+		return StatusUnknown
+	default:
+		return StatusFailed
+	}
+}
+
+// mapEasypaisaTransactionStatus maps the inquiry transactionStatus onto the shared Status
+func mapEasypaisaTransactionStatus(transactionStatus string) Status {
+	switch strings.ToUpper(transactionStatus) {
+	case "PAID":
+		return StatusSuccess
+	case "PENDING", "INITIATED":
+		return StatusPending
+	case "FAILED", "DROPPED", "EXPIRED", "REVERSED", "BLOCKED":
+		return StatusFailed
+	default:
+		return StatusUnknown
+	}
+}
+
+// easypaisaMessage prefers Easypaisa's own description of a response code
+func easypaisaMessage(responseCode, description string) string {
+	if description != "" {
+		return description
+	}
+	switch mapEasypaisaResponseCode(responseCode) {
+	case StatusSuccess:
+		return "Transaction completed successfully"
+	case StatusUnknown:
+		return "Transaction status could not be confirmed. Please wait for confirmation"
+	default:
+		return fmt.Sprintf("Transaction failed with code: %s. Please contact support", responseCode)
+	}
+}
+
+// stringField reads a string value from a decoded JSON reply
+func stringField(responseMap map[string]any, key string) string {
+	value, _ := responseMap[key].(string)
+	return value
+}
@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEasypaisaHash(t *testing.T) {
+	client := &EasypaisaClient{hashKey: "0123456789ABCDEF"}
+
+	fields := EasypaisaFields{
+		EasypaisaFieldOrderID:         "TEST_TXN_123",
+		EasypaisaFieldStoreID:         "TEST_STORE",
+		EasypaisaFieldAmount:          "500.00",
+		EasypaisaFieldTransactionType: "MA",
+		EasypaisaFieldMobileAccountNo: "03451234567",
+	}
+
+	hash, err := client.EasypaisaHash(fields)
+	if err != nil {
+		t.Fatalf("EasypaisaHash() error = %v", err)
+	}
+	if hash == "" {
+		t.Fatal("EasypaisaHash() returned empty hash")
+	}
+
+	// Empty values and an existing hash do not take part
+	withExtras := EasypaisaFields{EasypaisaFieldEmailAddress: "", EasypaisaFieldHash: "EXISTING_HASH"}
+	for k, v := range fields {
+		withExtras[k] = v
+	}
+	if got, _ := client.EasypaisaHash(withExtras); got != hash {
+		t.Errorf("EasypaisaHash() with empty and hash fields = %s, want %s", got, hash)
+	}
+
+	changed := EasypaisaFields{EasypaisaFieldAmount: "5000.00"}
+	for k, v := range fields {
+		if k != EasypaisaFieldAmount {
+			changed[k] = v
+		}
+	}
+	if got, _ := client.EasypaisaHash(changed); got == hash {
+		t.Error("EasypaisaHash() did not change with the amount")
+	}
+}
+
+func TestEasypaisaHash_InvalidKey(t *testing.T) {
+	client := &EasypaisaClient{hashKey: "short"}
+
+	if _, err := client.EasypaisaHash(EasypaisaFields{EasypaisaFieldOrderID: "TEST_TXN_123"}); err == nil {
+		t.Error("EasypaisaHash() error = nil, want invalid key error")
+	}
+}
+
+func TestVerifyEasypaisaHash(t *testing.T) {
+	client := &EasypaisaClient{hashKey: "0123456789ABCDEF"}
+
+	fields := EasypaisaFields{
+		EasypaisaFieldOrderID: "TEST_TXN_123",
+		EasypaisaFieldStoreID: "TEST_STORE",
+	}
+	hash, err := client.EasypaisaHash(fields)
+	if err != nil {
+		t.Fatalf("EasypaisaHash() error = %v", err)
+	}
+
+	fields[EasypaisaFieldHash] = hash
+	if err := client.VerifyEasypaisaHash(fields); err != nil {
+		t.Errorf("VerifyEasypaisaHash() error = %v", err)
+	}
+
+	fields[EasypaisaFieldOrderID] = "TEST_TXN_456"
+	if err := client.VerifyEasypaisaHash(fields); err == nil {
+		t.Error("VerifyEasypaisaHash() accepted a tampered order ID")
+	}
+
+	delete(fields, EasypaisaFieldHash)
+	if err := client.VerifyEasypaisaHash(fields); err == nil {
+		t.Error("VerifyEasypaisaHash() accepted a request without a hash")
+	}
+}
+
+func TestMapEasypaisaStatus(t *testing.T) {
+	tests := []struct {
+		transactionStatus string
+		want              Status
+	}{
+		{"PAID", StatusSuccess},
+		{"paid", StatusSuccess},
+		{"PENDING", StatusPending},
+		{"INITIATED", StatusPending},
+		{"FAILED", StatusFailed},
+		{"EXPIRED", StatusFailed},
+		{"REVERSED", StatusFailed},
+		{"SOMETHING_NEW", StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := mapEasypaisaTransactionStatus(tt.transactionStatus); got != tt.want {
+			t.Errorf("mapEasypaisaTransactionStatus(%q) = %v, want %v", tt.transactionStatus, got, tt.want)
+		}
+	}
+
+	codes := map[string]Status{
+		"0000": StatusSuccess,
+		"0001": StatusUnknown,
+		"":     StatusUnknown,
+		"0013": StatusFailed,
+	}
+	for code, want := range codes {
+		if got := mapEasypaisaResponseCode(code); got != want {
+			t.Errorf("mapEasypaisaResponseCode(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestEasypaisa_UnsupportedOperations(t *testing.T) {
+	client := &EasypaisaClient{}
+
+	if _, err := client.Refund(context.Background(), RefundRequest{TxnRefNo: "TEST_TXN_123"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Refund() error = %v, want %v", err, ErrUnsupported)
+	}
+	if _, err := client.InitiateCard(context.Background(), CardInitiateRequest{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("InitiateCard() error = %v, want %v", err, ErrUnsupported)
+	}
+}
@@ -1,6 +1,11 @@
 package gateway
 
-import "context"
+import (
+	"context"
+	"errors"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
+)
 
 // =============================================================================
 // CONSTANTS - Status constants
@@ -13,14 +18,25 @@ const (
 	StatusUnknown Status = "UNKNOWN"
 )
 
+// ErrUnsupported is returned by gateways for operations they do not offer
+var ErrUnsupported = errors.New("operation not supported by gateway")
+
 // =============================================================================
 // TYPES
 // =============================================================================
 
 type Status string
 
+// Exchange is one request to the gateway and the reply it got, with secrets
+// redacted so it can be stored. Gateway methods fill it in even when they
+// return an error, as long as the request was built.
+type Exchange struct {
+	Request  map[string]string
+	Response map[string]any // nil when no reply was decoded
+}
+
 type MWalletInitiateRequest struct {
-	AmountPaisa       string
+	Amount            money.Money
 	BillRefID         string
 	TxnRefNo          string
 	Description       string
@@ -33,13 +49,15 @@ type MWalletInitiateRequest struct {
 type MWalletInitiateResponse struct {
 	Status       Status
 	ResponseCode string
-	Message      string
+	Message      string // in the language of the request context
+	RetryAllowed bool   // whether the customer may try again after a failure
 	RRN          string // pp_RetreivalReferenceNo
 	Raw          map[string]any
+	Exchange     Exchange
 }
 
 type CardInitiateRequest struct {
-	AmountPaisa       int64
+	Amount            money.Money
 	BillRefID         string
 	TxnRefNo          string
 	Description       string
@@ -49,8 +67,9 @@ type CardInitiateRequest struct {
 }
 
 type CardInitiateResponse struct {
-	PostURL string            // JazzCash merchantform URL
-	Fields  map[string]string // pp_* form fields inc. pp_SecureHash
+	PostURL  string            // JazzCash merchantform URL
+	Fields   map[string]string // pp_* form fields inc. pp_SecureHash
+	Exchange Exchange          // the form without secrets; the browser posts it, so there is no reply
 }
 
 type InquiryRequest struct {
@@ -61,25 +80,66 @@ type InquiryResponse struct {
 	Status              Status
 	ResponseCode        string
 	PaymentResponseCode string
-	Message             string
+	Message             string // in the language of the request context
+	RetryAllowed        bool   // whether the customer may try again after a failure
 	RRN                 string
 	Raw                 map[string]any
+	Exchange            Exchange
 }
 
 // CardCallback Card callback payload (ReturnURL POST) after redirect
 type CardCallback struct {
 	TxnRefNo        string
+	Status          Status
 	ResponseCode    string
 	ResponseMessage string
 	RRN             string
+	Amount          money.Money       // amount the customer was charged
 	Fields          map[string]string // full pp_* payload
 }
 
+// RefundChannel selects the gateway refund API matching the original payment
+type RefundChannel string
+
+const (
+	RefundChannelWallet RefundChannel = "WALLET"
+	RefundChannelCard   RefundChannel = "CARD"
+)
+
+type RefundRequest struct {
+	Channel  RefundChannel
+	TxnRefNo string      // reference of the original payment
+	Amount   money.Money // may be less than the original amount for a partial refund
+}
+
+type RefundResponse struct {
+	Status       Status
+	ResponseCode string
+	Message      string
+	Raw          map[string]any
+	Exchange     Exchange
+}
+
+// Notification is a verified server-to-server payment notification (IPN)
+type Notification struct {
+	TxnRefNo        string
+	Status          Status
+	ResponseCode    string
+	ResponseMessage string
+	RRN             string
+	Amount          money.Money
+	DedupKey        string            // identical for every retry of the same notification
+	Fields          map[string]string // full payload as received
+}
+
 // =============================================================================
 // Interfaces
 // =============================================================================
 
 type Gateway interface {
+	// Name identifies the gateway in routes, logs and stored records
+	Name() string
+
 	SubmitMWallet(ctx context.Context, req MWalletInitiateRequest) (MWalletInitiateResponse, error)
 	InitiateCard(ctx context.Context, req CardInitiateRequest) (CardInitiateResponse, error)
 
@@ -87,4 +147,23 @@ type Gateway interface {
 
 	// ParseAndVerifyCardCallback For card ReturnURL/callback validation
 	ParseAndVerifyCardCallback(ctx context.Context, form map[string]string) (CardCallback, error)
+
+	// CallbackTxnRefNo reads the transaction reference from a callback or
+	// notification before it is verified, so the caller can pick the merchant
+	// credentials to verify it with. Empty when there is none.
+	CallbackTxnRefNo(fields map[string]string) string
+
+	// Refund returns all or part of a completed payment to the customer
+	Refund(ctx context.Context, req RefundRequest) (RefundResponse, error)
+}
+
+// NotificationReceiver is implemented by gateways that push payment results
+// to the merchant instead of waiting to be asked
+type NotificationReceiver interface {
+	// ParseAndVerifyNotification checks the payload signature and extracts the result
+	ParseAndVerifyNotification(ctx context.Context, payload map[string]string) (Notification, error)
+
+	// AcknowledgeNotification builds the response body the gateway expects once a
+	// notification has been accepted
+	AcknowledgeNotification() (map[string]string, error)
 }
@@ -10,9 +10,10 @@ import (
 	"fmt"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
 )
 
 // =============================================================================
@@ -35,6 +36,11 @@ const (
 	FieldTxnExpiryDateTime = "pp_TxnExpiryDateTime"
 	FieldReturnURL         = "pp_ReturnURL"
 	FieldSecureHash        = "pp_SecureHash"
+	FieldTxnCurrency       = "pp_TxnCurrency"
+	FieldResponseCode      = "pp_ResponseCode"
+	FieldResponseMessage   = "pp_ResponseMessage"
+	FieldRetrievalRefNo    = "pp_RetreivalReferenceNo"
+	FieldMerchantMPIN      = "pp_MerchantMPIN"
 )
 
 // =============================================================================
@@ -43,17 +49,34 @@ const (
 
 type JazzCashFields map[string]string
 
+var (
+	_ Gateway              = (*JazzCashClient)(nil)
+	_ NotificationReceiver = (*JazzCashClient)(nil)
+	_ BreakerReporter      = (*JazzCashClient)(nil)
+)
+
+// JazzCashName is the gateway name used in IPN routes and stored records
+const JazzCashName = "JAZZCASH"
+
+// IPN acknowledgement JazzCash expects once a notification is accepted
+const (
+	ipnAckResponseCode    = "000"
+	ipnAckResponseMessage = "IPN received successfully"
+)
+
 type JazzCashClient struct {
-	merchantID       string
-	password         string
-	integritySalt    string
-	merchantMPIN     string
+	credentials      *CredentialStore // Merchant secrets, rotated without a restart
 	cardCallbackURL  string
 	baseURL          string
 	walletPaymentURL string
 	cardPaymentURL   string
 	statusInquiryURL string
+	walletRefundURL  string
+	cardRefundURL    string
 	httpClient       *http.Client // For making API calls
+	resilience       ResilienceConfig
+	breaker          *CircuitBreaker      // Shared by every call so an outage trips it once
+	codes            *ResponseCodeCatalog // What each pp_ResponseCode means
 }
 
 // =============================================================================
@@ -61,29 +84,32 @@ type JazzCashClient struct {
 // =============================================================================
 
 func NewJazzCashClient(
-	merchantID string,
-	password string,
-	integritySalt string,
-	merchantMPIN string,
+	credentials *CredentialStore,
 	cardCallbackURL string,
 	baseURL string,
 	walletPaymentURL string,
 	cardPaymentURL string,
 	statusInquiryURL string,
+	walletRefundURL string,
+	cardRefundURL string,
+	resilience ResilienceConfig,
+	codes *ResponseCodeCatalog, // nil uses JazzCashResponseCodes()
 ) *JazzCashClient {
 	return &JazzCashClient{
-		merchantID:       merchantID,
-		password:         password,
-		integritySalt:    integritySalt,
-		merchantMPIN:     merchantMPIN,
+		credentials:      credentials,
 		cardCallbackURL:  cardCallbackURL,
 		baseURL:          baseURL,
 		walletPaymentURL: walletPaymentURL,
 		cardPaymentURL:   cardPaymentURL,
 		statusInquiryURL: statusInquiryURL,
+		walletRefundURL:  walletRefundURL,
+		cardRefundURL:    cardRefundURL,
 		httpClient: &http.Client{
 			Timeout: 45 * time.Second, // HTTP timeout
 		},
+		resilience: resilience,
+		breaker:    NewCircuitBreaker(resilience.FailureThreshold, resilience.OpenTimeout),
+		codes:      codes,
 	}
 }
 
@@ -91,11 +117,20 @@ func NewJazzCashClient(
 // PUBLIC API METHODS - Gateway interface implementation
 // =============================================================================
 
+func (c *JazzCashClient) Name() string {
+	return JazzCashName
+}
+
 func (c *JazzCashClient) SubmitMWallet(ctx context.Context, req MWalletInitiateRequest) (MWalletInitiateResponse, error) {
-	fields := c.buildMWalletFields(req)
+	if err := requirePKR(req.Amount); err != nil {
+		return MWalletInitiateResponse{}, err
+	}
+
+	creds := c.credentials.Active()
+	fields := c.buildMWalletFields(req, creds)
 
 	// find the secure hash
-	secureHash, err := c.JazzcashSecureHash(fields)
+	secureHash, err := jazzCashSecureHash(fields, creds.IntegritySalt)
 
 	if err != nil {
 		return MWalletInitiateResponse{}, err
@@ -103,116 +138,277 @@ func (c *JazzCashClient) SubmitMWallet(ctx context.Context, req MWalletInitiateR
 
 	fields[FieldSecureHash] = secureHash
 
-	// convert fields to json body
-	jsonBody, err := json.Marshal(fields)
+	responseMap, err := c.postSigned(ctx, c.walletPaymentURL, fields)
+	exchange := newExchange(fields, responseMap)
 	if err != nil {
-		return MWalletInitiateResponse{}, err
+		return MWalletInitiateResponse{Exchange: exchange}, fmt.Errorf("MWallet API: %w", err)
 	}
 
-	// create a https post request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.statusInquiryURL, bytes.NewBuffer(jsonBody))
+	resp := c.mapMWalletResponse(responseMap, LanguageFromContext(ctx))
+	resp.Exchange = exchange
+	return resp, nil
+}
+
+func (c *JazzCashClient) Inquiry(ctx context.Context, req InquiryRequest) (InquiryResponse, error) {
+	creds := c.credentials.Active()
+	fields := c.buildInquiryFields(req.TxnRefNo, creds)
+
+	secureHash, err := jazzCashSecureHash(fields, creds.IntegritySalt)
 
 	if err != nil {
-		return MWalletInitiateResponse{}, err
+		return InquiryResponse{}, err
 	}
 
-	// set headers content-type
-	httpReq.Header.Set("Content-Type", "application/json")
+	fields[FieldSecureHash] = secureHash
 
-	// make http request
-	resp, err := c.httpClient.Do(httpReq)
+	// Inquiries only read state, so they are safe to retry
+	responseMap, err := withRetry(ctx, c.resilience, func(ctx context.Context) (map[string]any, error) {
+		return c.postSigned(ctx, c.statusInquiryURL, fields)
+	})
+	exchange := newExchange(fields, responseMap)
+	if err != nil {
+		return InquiryResponse{Exchange: exchange}, fmt.Errorf("inquiry API: %w", err)
+	}
+
+	// Map response to InquiryResponse struct
+	resp := c.mapInquiryResponse(responseMap, LanguageFromContext(ctx))
+	resp.Exchange = exchange
+	return resp, nil
+}
 
+// InitiateCard builds the signed form the browser posts to the JazzCash hosted card page
+func (c *JazzCashClient) InitiateCard(ctx context.Context, req CardInitiateRequest) (CardInitiateResponse, error) {
+	if req.ReturnURL == "" {
+		req.ReturnURL = c.cardCallbackURL
+	}
+	if err := requirePKR(req.Amount); err != nil {
+		return CardInitiateResponse{}, err
+	}
+
+	// No call is made here, but the hosted page is JazzCash too: don't send customers to it while it is down
+	if c.breaker.Status().State == BreakerOpen {
+		return CardInitiateResponse{}, ErrCircuitOpen
+	}
+
+	creds := c.credentials.Active()
+	fields := c.buildCardFields(req, creds)
+
+	secureHash, err := jazzCashSecureHash(fields, creds.IntegritySalt)
 	if err != nil {
-		return MWalletInitiateResponse{}, err
+		return CardInitiateResponse{}, err
 	}
-	defer resp.Body.Close()
 
-	// check http status
-	if resp.StatusCode != http.StatusOK {
-		return MWalletInitiateResponse{}, fmt.Errorf("inquiry API returned status %d", resp.StatusCode)
+	fields[FieldSecureHash] = secureHash
+
+	return CardInitiateResponse{
+		PostURL:  c.cardPaymentURL,
+		Fields:   fields,
+		Exchange: newExchange(fields, nil),
+	}, nil
+}
+
+// CallbackTxnRefNo reads pp_TxnRefNo from an unverified card callback or IPN
+func (c *JazzCashClient) CallbackTxnRefNo(fields map[string]string) string {
+	return fields[FieldTxnRefNo]
+}
+
+// ParseAndVerifyCardCallback validates the pp_* form JazzCash posts to the ReturnURL
+func (c *JazzCashClient) ParseAndVerifyCardCallback(ctx context.Context, form map[string]string) (CardCallback, error) {
+	receivedHash := form[FieldSecureHash]
+	if receivedHash == "" {
+		return CardCallback{}, fmt.Errorf("missing pp_SecureHash in callback")
 	}
 
-	// parse json resp
+	if err := c.verifyFieldsHash(form, receivedHash); err != nil {
+		return CardCallback{}, fmt.Errorf("callback hash verification failed: %w", err)
+	}
 
-	var responseMap map[string]any
+	txnRefNo := form[FieldTxnRefNo]
+	if txnRefNo == "" {
+		return CardCallback{}, fmt.Errorf("missing pp_TxnRefNo in callback")
+	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
-		return MWalletInitiateResponse{}, fmt.Errorf("failed to decode response: %w", err)
+	amount, err := money.ParseMinor(form[FieldAmount], money.PKR)
+	if err != nil {
+		return CardCallback{}, fmt.Errorf("invalid pp_Amount in callback: %w", err)
 	}
 
-	// verify response hash
+	responseCode := form[FieldResponseCode]
 
-	if err := c.verifyResponseHash(responseMap); err != nil {
-		return MWalletInitiateResponse{}, fmt.Errorf("response hash verification failed: %w", err)
+	return CardCallback{
+		TxnRefNo:        txnRefNo,
+		Status:          c.responseCodes().Status(responseCode),
+		ResponseCode:    responseCode,
+		ResponseMessage: form[FieldResponseMessage],
+		RRN:             form[FieldRetrievalRefNo],
+		Amount:          amount,
+		Fields:          form,
+	}, nil
+}
+
+// Refund asks JazzCash to return amount of an earlier MWallet or card payment
+func (c *JazzCashClient) Refund(ctx context.Context, req RefundRequest) (RefundResponse, error) {
+	if err := requirePKR(req.Amount); err != nil {
+		return RefundResponse{}, err
+	}
+
+	var refundURL string
+	switch req.Channel {
+	case RefundChannelWallet:
+		refundURL = c.walletRefundURL
+	case RefundChannelCard:
+		refundURL = c.cardRefundURL
+	default:
+		return RefundResponse{}, fmt.Errorf("%w: refund channel %q", ErrUnsupported, req.Channel)
 	}
 
-	return c.mapMWalletResponse(responseMap), nil
+	creds := c.credentials.Active()
+	fields := c.buildRefundFields(req, creds)
+
+	secureHash, err := jazzCashSecureHash(fields, creds.IntegritySalt)
+	if err != nil {
+		return RefundResponse{}, err
+	}
+	fields[FieldSecureHash] = secureHash
+
+	responseMap, err := c.postSigned(ctx, refundURL, fields)
+	exchange := newExchange(fields, responseMap)
+	if err != nil {
+		return RefundResponse{Exchange: exchange}, fmt.Errorf("refund API: %w", err)
+	}
+
+	resp := c.mapRefundResponse(responseMap, LanguageFromContext(ctx))
+	resp.Exchange = exchange
+	return resp, nil
 }
 
-func (c *JazzCashClient) Inquiry(ctx context.Context, txnRefNo string) (InquiryResponse, error) {
-	fields := c.buildInquiryFields(txnRefNo)
+// BreakerStatus reports whether calls to JazzCash are currently failing fast
+func (c *JazzCashClient) BreakerStatus() BreakerStatus {
+	return c.breaker.Status()
+}
 
-	secureHash, err := c.JazzcashSecureHash(fields)
+// =============================================================================
+// PUBLIC API METHODS - IPN (NotificationReceiver implementation)
+// =============================================================================
+
+// ParseAndVerifyNotification validates the pp_* payload JazzCash posts to the IPN URL
+func (c *JazzCashClient) ParseAndVerifyNotification(ctx context.Context, payload map[string]string) (Notification, error) {
+	receivedHash := payload[FieldSecureHash]
+	if receivedHash == "" {
+		return Notification{}, fmt.Errorf("missing pp_SecureHash in notification")
+	}
+
+	if err := c.verifyFieldsHash(payload, receivedHash); err != nil {
+		return Notification{}, fmt.Errorf("notification hash verification failed: %w", err)
+	}
+
+	txnRefNo := payload[FieldTxnRefNo]
+	if txnRefNo == "" {
+		return Notification{}, fmt.Errorf("missing pp_TxnRefNo in notification")
+	}
 
+	amount, err := money.ParseMinor(payload[FieldAmount], money.PKR)
 	if err != nil {
-		return InquiryResponse{}, err
+		return Notification{}, fmt.Errorf("invalid pp_Amount in notification: %w", err)
 	}
 
-	fields[FieldSecureHash] = secureHash
+	responseCode := payload[FieldResponseCode]
 
-	// convert fields to json body
-	jsonBody, err := json.Marshal(fields)
+	return Notification{
+		TxnRefNo:        txnRefNo,
+		Status:          c.responseCodes().Status(responseCode),
+		ResponseCode:    responseCode,
+		ResponseMessage: payload[FieldResponseMessage],
+		RRN:             payload[FieldRetrievalRefNo],
+		Amount:          amount,
+		DedupKey:        strings.ToUpper(receivedHash),
+		Fields:          payload,
+	}, nil
+}
+
+// AcknowledgeNotification returns the signed pp_* body JazzCash expects in reply to an IPN
+func (c *JazzCashClient) AcknowledgeNotification() (map[string]string, error) {
+	fields := JazzCashFields{
+		FieldResponseCode:    ipnAckResponseCode,
+		FieldResponseMessage: ipnAckResponseMessage,
+	}
+
+	secureHash, err := c.JazzcashSecureHash(fields)
 	if err != nil {
-		return InquiryResponse{}, err
+		return nil, fmt.Errorf("failed to sign IPN acknowledgement: %w", err)
 	}
+	fields[FieldSecureHash] = secureHash
 
-	// create a https post request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.statusInquiryURL, bytes.NewBuffer(jsonBody))
+	return fields, nil
+}
 
+// =============================================================================
+// HELPERS - HTTP
+// =============================================================================
+
+// postSigned posts signed fields as JSON through the circuit breaker and returns
+// the hash-verified response. A reply that decodes but fails verification is
+// still returned with the error so it can be recorded.
+func (c *JazzCashClient) postSigned(ctx context.Context, url string, fields JazzCashFields) (map[string]any, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, err
+	}
+
+	responseMap, err := c.post(ctx, url, fields)
+	c.breaker.Record(err)
+	return responseMap, err
+}
+
+// post makes one HTTP call. Failures to reach JazzCash and server errors are
+// marked transient so they can be retried and counted by the breaker.
+func (c *JazzCashClient) post(ctx context.Context, url string, fields JazzCashFields) (map[string]any, error) {
+	jsonBody, err := json.Marshal(fields)
 	if err != nil {
-		return InquiryResponse{}, err
+		return nil, err
 	}
 
-	// set headers content-type
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, err
+	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
-	// make http request
 	resp, err := c.httpClient.Do(httpReq)
-
 	if err != nil {
-		return InquiryResponse{}, err
+		return nil, &transientError{err: err}
 	}
 	defer resp.Body.Close()
 
-	// check http status
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &transientError{err: fmt.Errorf("returned status %d", resp.StatusCode)}
+	}
 	if resp.StatusCode != http.StatusOK {
-		return InquiryResponse{}, fmt.Errorf("inquiry API returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("returned status %d", resp.StatusCode)
 	}
 
-	// parse json resp
-
 	var responseMap map[string]any
-
 	if err := json.NewDecoder(resp.Body).Decode(&responseMap); err != nil {
-		return InquiryResponse{}, fmt.Errorf("failed to decode response: %w", err)
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// verify response hash
-
 	if err := c.verifyResponseHash(responseMap); err != nil {
-		return InquiryResponse{}, fmt.Errorf("response hash verification failed: %w", err)
+		return responseMap, fmt.Errorf("response hash verification failed: %w", err)
 	}
 
-	// 11. Map response to InquiryResponse struct
-	return c.mapInquiryResponse(responseMap), nil
-
+	return responseMap, nil
 }
 
 // =============================================================================
 // HELPERS - Secure hash computation and Verification
 // =============================================================================
 
+// JazzcashSecureHash signs requestData with the active integrity salt
 func (c *JazzCashClient) JazzcashSecureHash(requestData JazzCashFields) (string, error) {
+	return jazzCashSecureHash(requestData, c.credentials.Active().IntegritySalt)
+}
+
+// jazzCashSecureHash signs requestData with integritySalt
+func jazzCashSecureHash(requestData JazzCashFields, integritySalt string) (string, error) {
 
 	ppFields := make(map[string]string)
 
@@ -248,11 +444,11 @@ func (c *JazzCashClient) JazzcashSecureHash(requestData JazzCashFields) (string,
 
 	// prepend the integrity salt with & to the message
 
-	saltedMessage := c.integritySalt + "&" + message.String()
+	saltedMessage := integritySalt + "&" + message.String()
 
 	// make the HMAC hash from the salted message with secret integrity salt
 
-	mac := hmac.New(sha256.New, []byte(c.integritySalt))
+	mac := hmac.New(sha256.New, []byte(integritySalt))
 	mac.Write([]byte(saltedMessage))
 	hash := mac.Sum(nil)
 
@@ -276,18 +472,30 @@ func (c *JazzCashClient) verifyResponseHash(responseMap map[string]any) error {
 		}
 	}
 
-	// Compute expected hash
-	expectedHash, err := c.JazzcashSecureHash(fields)
-	if err != nil {
-		return fmt.Errorf("failed to compute expected hash: %w", err)
-	}
+	return c.verifyFieldsHash(fields, receivedHash)
+}
 
-	// Verify
-	if receivedHash != expectedHash {
-		return fmt.Errorf("hash mismatch: received %s, expected %s", receivedHash, expectedHash)
+// verifyFieldsHash compares receivedHash against the hash of fields under the
+// active credentials and, during a rotation's grace window, the previous ones
+func (c *JazzCashClient) verifyFieldsHash(fields JazzCashFields, receivedHash string) error {
+	var expectedHash string
+	for i, creds := range c.credentials.Verifying(time.Now()) {
+		// Compute expected hash
+		hash, err := jazzCashSecureHash(fields, creds.IntegritySalt)
+		if err != nil {
+			return fmt.Errorf("failed to compute expected hash: %w", err)
+		}
+		if i == 0 {
+			expectedHash = hash
+		}
+
+		// Verify
+		if hmac.Equal([]byte(strings.ToUpper(receivedHash)), []byte(hash)) {
+			return nil
+		}
 	}
 
-	return nil
+	return fmt.Errorf("hash mismatch: received %s, expected %s", receivedHash, expectedHash)
 }
 
 // =============================================================================
@@ -295,15 +503,15 @@ func (c *JazzCashClient) verifyResponseHash(responseMap map[string]any) error {
 // =============================================================================
 
 // Build MWallet payload fields
-func (c *JazzCashClient) buildMWalletFields(req MWalletInitiateRequest) JazzCashFields {
+func (c *JazzCashClient) buildMWalletFields(req MWalletInitiateRequest, creds JazzCashCredentials) JazzCashFields {
 	fields := make(JazzCashFields)
 
 	fields[FieldVersion] = "2.0"
 	fields[FieldTxnType] = "MWALLET"
 	fields[FieldLanguage] = "EN"
-	fields[FieldMerchantID] = c.merchantID
-	fields[FieldPassword] = c.password
-	fields[FieldAmount] = req.AmountPaisa
+	fields[FieldMerchantID] = creds.MerchantID
+	fields[FieldPassword] = creds.Password
+	fields[FieldAmount] = req.Amount.MinorString()
 	fields[FieldBillReference] = req.BillRefID
 	fields[FieldTxnRefNo] = req.TxnRefNo
 	fields[FieldDescription] = req.Description
@@ -316,15 +524,16 @@ func (c *JazzCashClient) buildMWalletFields(req MWalletInitiateRequest) JazzCash
 }
 
 // Build Card payload fields
-func (c *JazzCashClient) buildCardFields(req CardInitiateRequest) JazzCashFields {
+func (c *JazzCashClient) buildCardFields(req CardInitiateRequest, creds JazzCashCredentials) JazzCashFields {
 	fields := make(JazzCashFields)
 
 	fields[FieldVersion] = "1.1"
 	fields[FieldTxnType] = "CARDPAYMENT"
 	fields[FieldLanguage] = "EN"
-	fields[FieldMerchantID] = c.merchantID
-	fields[FieldPassword] = c.password
-	fields[FieldAmount] = strconv.FormatInt(req.AmountPaisa, 10)
+	fields[FieldMerchantID] = creds.MerchantID
+	fields[FieldPassword] = creds.Password
+	fields[FieldAmount] = req.Amount.MinorString()
+	fields[FieldTxnCurrency] = "PKR"
 	fields[FieldBillReference] = req.BillRefID
 	fields[FieldTxnRefNo] = req.TxnRefNo
 	fields[FieldDescription] = req.Description
@@ -335,51 +544,54 @@ func (c *JazzCashClient) buildCardFields(req CardInitiateRequest) JazzCashFields
 	return fields
 }
 
-func (c *JazzCashClient) buildInquiryFields(txnRefNo string) JazzCashFields {
+func (c *JazzCashClient) buildInquiryFields(txnRefNo string, creds JazzCashCredentials) JazzCashFields {
 	fields := make(JazzCashFields)
 
 	fields[FieldTxnRefNo] = txnRefNo
-	fields[FieldMerchantID] = c.merchantID
-	fields[FieldPassword] = c.password
+	fields[FieldMerchantID] = creds.MerchantID
+	fields[FieldPassword] = creds.Password
 
 	return fields
 }
 
-// =============================================================================
-// HELPERS - Response mappers
-// =============================================================================
-
-// mapResponseCodeToStatus maps JazzCash response code to Status enum
+// Build refund payload fields. The wallet refund API also wants the merchant MPIN.
+func (c *JazzCashClient) buildRefundFields(req RefundRequest, creds JazzCashCredentials) JazzCashFields {
+	fields := make(JazzCashFields)
 
-func mapResponseCodeToStatus(responseCode string) Status {
-	// Key success codes
-	switch responseCode {
-	case "000", "121", "200":
-		return StatusSuccess
+	fields[FieldTxnRefNo] = req.TxnRefNo
+	fields[FieldAmount] = req.Amount.MinorString()
+	fields[FieldTxnCurrency] = "PKR"
+	fields[FieldMerchantID] = creds.MerchantID
+	fields[FieldPassword] = creds.Password
+	if req.Channel == RefundChannelWallet {
+		fields[FieldMerchantMPIN] = creds.MerchantMPIN
 	}
 
-	// Common failure codes (map the ones that are explicitly "Failed" in docs)
-	switch responseCode {
-	case "101", "105", "110", "111", "112", "115", "118", "999", "199":
-		return StatusFailed
-	}
+	return fields
+}
 
-	// Pending codes
-	switch responseCode {
-	case "157", "124", "210":
-		return StatusPending
-	}
+// =============================================================================
+// HELPERS - Response mappers
+// =============================================================================
 
-	// 4xx range - typically failed transactions
-	if len(responseCode) == 3 && responseCode[0] == '4' {
-		return StatusFailed
+// responseCodes returns the client's catalog, or the built-in one for clients
+// not made by NewJazzCashClient
+func (c *JazzCashClient) responseCodes() *ResponseCodeCatalog {
+	if c.codes == nil {
+		return JazzCashResponseCodes()
 	}
+	return c.codes
+}
 
-	// Default: unknown (log for debugging)
-	return StatusUnknown
+// requirePKR rejects amounts gateways cannot charge: they only take positive PKR
+func requirePKR(amount money.Money) error {
+	if amount.Currency() != money.PKR || !amount.IsPositive() {
+		return fmt.Errorf("%w: cannot charge %s", money.ErrInvalidAmount, amount)
+	}
+	return nil
 }
 
-func (c *JazzCashClient) mapInquiryResponse(responseMap map[string]any) InquiryResponse {
+func (c *JazzCashClient) mapInquiryResponse(responseMap map[string]any, lang Language) InquiryResponse {
 	resp := InquiryResponse{
 		Raw: responseMap,
 	}
@@ -397,21 +609,21 @@ func (c *JazzCashClient) mapInquiryResponse(responseMap map[string]any) InquiryR
 		statusCode = apiCode
 	}
 
-	// Map to status
-	resp.Status = mapResponseCodeToStatus(statusCode)
-
-	// Convert response code to user-friendly message
-	resp.Message = getUserFriendlyMessage(statusCode)
+	// Map to status and a message in the customer's language
+	code := c.responseCodes().Lookup(statusCode)
+	resp.Status = code.Status
+	resp.RetryAllowed = code.RetryAllowed
+	resp.Message = code.Message(statusCode, lang)
 
 	// Extract RRN
-	if rrn, ok := responseMap["pp_RetreivalReferenceNo"].(string); ok {
+	if rrn, ok := responseMap[FieldRetrievalRefNo].(string); ok {
 		resp.RRN = rrn
 	}
 
 	return resp
 }
 
-func (c *JazzCashClient) mapMWalletResponse(responseMap map[string]any) MWalletInitiateResponse {
+func (c *JazzCashClient) mapMWalletResponse(responseMap map[string]any, lang Language) MWalletInitiateResponse {
 	resp := MWalletInitiateResponse{
 		Raw: responseMap,
 	}
@@ -419,11 +631,11 @@ func (c *JazzCashClient) mapMWalletResponse(responseMap map[string]any) MWalletI
 	responseCode, _ := responseMap["pp_ResponseCode"].(string)
 	resp.ResponseCode = responseCode
 
-	// Map to status
-	resp.Status = mapResponseCodeToStatus(responseCode)
-
-	// Convert response code to user-friendly message
-	resp.Message = getUserFriendlyMessage(responseCode)
+	// Map to status and a message in the customer's language
+	code := c.responseCodes().Lookup(responseCode)
+	resp.Status = code.Status
+	resp.RetryAllowed = code.RetryAllowed
+	resp.Message = code.Message(responseCode, lang)
 
 	// Extract RRN
 	if rrn, ok := responseMap["pp_RetreivalReferenceNo"].(string); ok {
@@ -434,91 +646,21 @@ func (c *JazzCashClient) mapMWalletResponse(responseMap map[string]any) MWalletI
 
 }
 
-// =============================================================================
-// HELPERS - Response Code
-// =============================================================================
+func (c *JazzCashClient) mapRefundResponse(responseMap map[string]any, lang Language) RefundResponse {
+	resp := RefundResponse{
+		Raw: responseMap,
+	}
 
-// getUserFriendlyMessage maps JazzCash response codes to user-friendly messages
-func getUserFriendlyMessage(responseCode string) string {
-	// Key codes that users need to know about
-	switch responseCode {
-	// Success codes
-	case "000":
-		return "Transaction completed successfully"
-	case "121":
-		return "Transaction confirmed successfully"
-	case "200":
-		return "Transaction approved"
-
-	// MWallet specific errors
-	case "024":
-		return "Incorrect MPIN. Please try again with the correct MPIN"
-	case "001":
-		return "Transaction limit exceeded. Please contact your bank"
-	case "002":
-		return "Account not found. Please verify your account details"
-	case "003":
-		return "Account is inactive. Please contact JazzCash support"
-	case "004":
-		return "Insufficient balance. Please add funds to your account"
-
-	// Card specific errors
-	case "415":
-		return "3D Secure verification failed. Please check your 3D Secure ID"
-	case "416":
-		return "CVV verification failed. Please check your CVV and try again"
-	case "424":
-		return "Incorrect CVV. Please enter the correct CVV and try again"
-	case "102":
-		return "Card is blocked. Please contact your bank"
-	case "404":
-		return "Card has expired. Please use a valid card"
-	case "405":
-		return "Insufficient balance on card. Please check your card balance"
-	case "419":
-		return "Card is not enrolled in 3D Secure. Please contact your bank to activate 3D Secure"
-
-	// Common errors
-	case "101":
-		return "Invalid merchant credentials"
-	case "105":
-		return "Transaction exceeds limit. Please contact support"
-	case "110":
-		return "Invalid transaction value"
-	case "111":
-		return "Transaction not allowed"
-	case "112":
-		return "Transaction was cancelled"
-	case "115":
-		return "Security verification failed. Please try again"
-	case "116":
-		return "Transaction has expired. Please initiate a new transaction"
-	case "134":
-		return "Transaction timed out. Please try again"
-	case "999":
-		return "Transaction failed due to a technical issue. Please try again later"
-
-	// Pending codes
-	case "157":
-		return "Transaction is pending. Please wait for confirmation"
-	case "124":
-		return "Order is pending. Waiting for payment confirmation"
-	case "210":
-		return "Authorization pending. Please wait"
-
-	// User cancellations
-	case "410", "412":
-		return "Transaction was cancelled by you"
-
-	// Maintenance
-	case "127", "118":
-		return "Service is temporarily under maintenance. Please try again later"
-
-	// Default: Return raw code for debugging (or a generic message)
-	default:
-		if responseCode != "" {
-			return fmt.Sprintf("Transaction failed with code: %s. Please contact support", responseCode)
-		}
-		return "Transaction failed. Please try again"
+	responseCode, _ := responseMap["pp_ResponseCode"].(string)
+	resp.ResponseCode = responseCode
+	resp.Status = c.responseCodes().Status(responseCode)
+
+	// Refund replies carry their own message; fall back to ours when it is missing
+	if message, ok := responseMap["pp_ResponseMessage"].(string); ok && message != "" {
+		resp.Message = message
+	} else {
+		resp.Message = c.responseCodes().Message(responseCode, lang)
 	}
+
+	return resp
 }
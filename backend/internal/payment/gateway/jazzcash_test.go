@@ -1,12 +1,16 @@
 package gateway
 
 import (
+	"context"
+	"strings"
 	"testing"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
 )
 
 func TestJazzcashSecureHash(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	tests := []struct {
@@ -82,7 +86,7 @@ func TestJazzcashSecureHash(t *testing.T) {
 
 func TestJazzcashSecureHash_FieldOrdering(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	// Same fields in different order should produce same hash
@@ -117,7 +121,7 @@ func TestJazzcashSecureHash_FieldOrdering(t *testing.T) {
 
 func TestJazzcashSecureHash_ExcludesSecureHash(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	fieldsWithoutHash := JazzCashFields{
@@ -151,7 +155,7 @@ func TestJazzcashSecureHash_ExcludesSecureHash(t *testing.T) {
 
 func TestVerifyResponseHash(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	// Create a valid response with correct hash
@@ -183,7 +187,7 @@ func TestVerifyResponseHash(t *testing.T) {
 
 func TestVerifyResponseHash_InvalidHash(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	response := map[string]any{
@@ -201,7 +205,7 @@ func TestVerifyResponseHash_InvalidHash(t *testing.T) {
 
 func TestVerifyResponseHash_MissingHash(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	response := map[string]any{
@@ -219,7 +223,7 @@ func TestVerifyResponseHash_MissingHash(t *testing.T) {
 
 func TestVerifyResponseHash_EmptyResponse(t *testing.T) {
 	client := &JazzCashClient{
-		integritySalt: "test_salt_123",
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
 	}
 
 	response := map[string]any{}
@@ -237,3 +241,153 @@ func toString(v any) string {
 	}
 	return ""
 }
+
+func TestInitiateCard_SignsForm(t *testing.T) {
+	client := NewJazzCashClient(
+		NewCredentialStore(JazzCashCredentials{
+			Version:       "test",
+			MerchantID:    "TEST_MERCHANT",
+			Password:      "TEST_PASSWORD",
+			IntegritySalt: "test_salt_123",
+			MerchantMPIN:  "TEST_MPIN",
+		}, 0),
+		"https://wallet.giki.edu.pk/payments/jazzcash/card/return",
+		"https://sandbox.jazzcash.com.pk",
+		"https://sandbox.jazzcash.com.pk/mwallet",
+		"https://sandbox.jazzcash.com.pk/merchantform",
+		"https://sandbox.jazzcash.com.pk/inquire",
+		"https://sandbox.jazzcash.com.pk/mwallet-refund",
+		"https://sandbox.jazzcash.com.pk/card-refund",
+		DefaultResilienceConfig(),
+		nil,
+	)
+
+	resp, err := client.InitiateCard(context.Background(), CardInitiateRequest{
+		Amount:            money.Paisa(50000),
+		BillRefID:         "TEST_BILL",
+		TxnRefNo:          "TEST_TXN",
+		Description:       "Test payment",
+		TxnDateTime:       "20240101120000",
+		TxnExpiryDateTime: "20240101130000",
+	})
+	if err != nil {
+		t.Fatalf("InitiateCard() error = %v", err)
+	}
+
+	if resp.PostURL != "https://sandbox.jazzcash.com.pk/merchantform" {
+		t.Errorf("InitiateCard() PostURL = %v, want card payment URL", resp.PostURL)
+	}
+
+	if resp.Fields[FieldAmount] != "50000" {
+		t.Errorf("InitiateCard() pp_Amount = %v, want 50000", resp.Fields[FieldAmount])
+	}
+
+	if resp.Fields[FieldReturnURL] != "https://wallet.giki.edu.pk/payments/jazzcash/card/return" {
+		t.Errorf("InitiateCard() pp_ReturnURL = %v, want configured return URL", resp.Fields[FieldReturnURL])
+	}
+
+	if err := client.verifyFieldsHash(resp.Fields, resp.Fields[FieldSecureHash]); err != nil {
+		t.Errorf("InitiateCard() produced a form that does not verify: %v", err)
+	}
+}
+
+func TestParseAndVerifyCardCallback(t *testing.T) {
+	client := &JazzCashClient{
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
+	}
+
+	form := map[string]string{
+		"pp_Amount":               "50000",
+		"pp_TxnRefNo":             "TEST_TXN",
+		"pp_ResponseCode":         "000",
+		"pp_ResponseMessage":      "Thank you for Using JazzCash",
+		"pp_RetreivalReferenceNo": "RRN123",
+	}
+
+	hash, err := client.JazzcashSecureHash(form)
+	if err != nil {
+		t.Fatalf("JazzcashSecureHash() error = %v", err)
+	}
+	form["pp_SecureHash"] = hash
+
+	callback, err := client.ParseAndVerifyCardCallback(context.Background(), form)
+	if err != nil {
+		t.Fatalf("ParseAndVerifyCardCallback() error = %v", err)
+	}
+
+	if callback.Status != StatusSuccess {
+		t.Errorf("ParseAndVerifyCardCallback() status = %v, want %v", callback.Status, StatusSuccess)
+	}
+
+	if callback.TxnRefNo != "TEST_TXN" || callback.RRN != "RRN123" {
+		t.Errorf("ParseAndVerifyCardCallback() = %+v, want TEST_TXN/RRN123", callback)
+	}
+
+	// Tampering with the amount must break the hash
+	form["pp_Amount"] = "5000000"
+	if _, err := client.ParseAndVerifyCardCallback(context.Background(), form); err == nil {
+		t.Errorf("ParseAndVerifyCardCallback() with tampered amount should return error")
+	}
+}
+
+func TestParseAndVerifyNotification(t *testing.T) {
+	client := &JazzCashClient{
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
+	}
+
+	payload := map[string]string{
+		"pp_Amount":               "50000",
+		"pp_TxnRefNo":             "TEST_TXN",
+		"pp_ResponseCode":         "121",
+		"pp_ResponseMessage":      "Transaction has been completed",
+		"pp_RetreivalReferenceNo": "RRN123",
+	}
+
+	hash, err := client.JazzcashSecureHash(payload)
+	if err != nil {
+		t.Fatalf("JazzcashSecureHash() error = %v", err)
+	}
+	payload["pp_SecureHash"] = strings.ToLower(hash)
+
+	notification, err := client.ParseAndVerifyNotification(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("ParseAndVerifyNotification() error = %v", err)
+	}
+
+	if notification.Status != StatusSuccess {
+		t.Errorf("ParseAndVerifyNotification() status = %v, want %v", notification.Status, StatusSuccess)
+	}
+
+	// Retries carry the same hash whatever its case, so the dedup key must too
+	if notification.DedupKey != hash {
+		t.Errorf("ParseAndVerifyNotification() dedup key = %s, want %s", notification.DedupKey, hash)
+	}
+
+	if !notification.Amount.Equal(money.Paisa(50000)) {
+		t.Errorf("ParseAndVerifyNotification() amount = %s, want PKR 500.00", notification.Amount)
+	}
+
+	delete(payload, "pp_SecureHash")
+	if _, err := client.ParseAndVerifyNotification(context.Background(), payload); err == nil {
+		t.Errorf("ParseAndVerifyNotification() without hash should return error")
+	}
+}
+
+func TestAcknowledgeNotification(t *testing.T) {
+	client := &JazzCashClient{
+		credentials: NewCredentialStore(JazzCashCredentials{IntegritySalt: "test_salt_123"}, 0),
+	}
+
+	ack, err := client.AcknowledgeNotification()
+	if err != nil {
+		t.Fatalf("AcknowledgeNotification() error = %v", err)
+	}
+
+	if ack["pp_ResponseCode"] != "000" {
+		t.Errorf("AcknowledgeNotification() response code = %s, want 000", ack["pp_ResponseCode"])
+	}
+
+	if err := client.verifyFieldsHash(ack, ack["pp_SecureHash"]); err != nil {
+		t.Errorf("AcknowledgeNotification() signature invalid: %v", err)
+	}
+}
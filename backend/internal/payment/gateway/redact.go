@@ -0,0 +1,52 @@
+package gateway
+
+// redactedValue replaces secret values in stored payloads
+const redactedValue = "[REDACTED]"
+
+// secretFields are payload keys that must never be stored or logged
+var secretFields = map[string]bool{
+	FieldPassword:     true,
+	FieldMerchantMPIN: true,
+
+	EasypaisaFieldCredentials: true,
+}
+
+// RedactFields returns a copy of fields with secret values replaced
+func RedactFields(fields map[string]string) map[string]string {
+	if fields == nil {
+		return nil
+	}
+
+	redacted := make(map[string]string, len(fields))
+	for key, value := range fields {
+		if secretFields[key] && value != "" {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// RedactResponse returns a copy of a decoded reply with secret values replaced
+func RedactResponse(response map[string]any) map[string]any {
+	if response == nil {
+		return nil
+	}
+
+	redacted := make(map[string]any, len(response))
+	for key, value := range response {
+		if secretFields[key] {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// newExchange records a request and its reply without secrets
+func newExchange(request map[string]string, response map[string]any) Exchange {
+	return Exchange{
+		Request:  RedactFields(request),
+		Response: RedactResponse(response),
+	}
+}
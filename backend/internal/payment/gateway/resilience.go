@@ -0,0 +1,240 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// CONSTANTS - Breaker states
+// =============================================================================
+
+type BreakerState string
+
+const (
+	// BreakerClosed Calls go through normally
+	BreakerClosed BreakerState = "CLOSED"
+	// BreakerOpen The gateway is considered down and calls fail fast
+	BreakerOpen BreakerState = "OPEN"
+	// BreakerHalfOpen The cool-down has passed and one probe call is let through
+	BreakerHalfOpen BreakerState = "HALF_OPEN"
+)
+
+// ErrCircuitOpen is returned without contacting the gateway while it is considered down
+var ErrCircuitOpen = errors.New("gateway circuit breaker is open")
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// ResilienceConfig tunes retries and the circuit breaker around gateway HTTP calls
+type ResilienceConfig struct {
+	// InquiryAttempts is how many times an inquiry is tried before giving up.
+	// Only inquiries are retried: submissions and refunds move money and are
+	// never repeated automatically.
+	InquiryAttempts int
+	// InquiryTimeout bounds each inquiry attempt
+	InquiryTimeout time.Duration
+	// RetryBaseDelay doubles after every failed attempt, up to RetryMaxDelay
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+
+	// FailureThreshold consecutive transport failures open the breaker
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before a probe is allowed
+	OpenTimeout time.Duration
+}
+
+// BreakerStatus is a snapshot of a circuit breaker for health reporting
+type BreakerStatus struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	OpenedAt            time.Time // zero while closed
+	RetryAt             time.Time // when an open breaker lets a probe through; zero while closed
+}
+
+// BreakerReporter is implemented by gateways that guard their calls with a circuit breaker
+type BreakerReporter interface {
+	BreakerStatus() BreakerStatus
+}
+
+// CircuitBreaker stops calls to a gateway after repeated transport failures
+// and lets a single probe through once OpenTimeout has passed
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	state            BreakerState
+	failures         int
+	openedAt         time.Time
+	probing          bool
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+}
+
+// transientError marks failures worth retrying and counting against the breaker:
+// the gateway could not be reached or answered with a server error
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// DefaultResilienceConfig returns the settings used in production
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		InquiryAttempts:  3,
+		InquiryTimeout:   10 * time.Second,
+		RetryBaseDelay:   500 * time.Millisecond,
+		RetryMaxDelay:    4 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+	}
+}
+
+// NewCircuitBreaker creates a closed breaker
+func NewCircuitBreaker(failureThreshold int, openTimeout time.Duration) *CircuitBreaker {
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return &CircuitBreaker{
+		state:            BreakerClosed,
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		now:              time.Now,
+	}
+}
+
+// =============================================================================
+// CIRCUIT BREAKER METHODS
+// =============================================================================
+
+// Allow reports whether a call may go ahead. Once an open breaker has cooled
+// down exactly one caller is let through as a probe; everyone else keeps
+// getting ErrCircuitOpen until that probe succeeds.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case BreakerClosed:
+		return nil
+	case BreakerHalfOpen:
+		if !b.probing {
+			b.probing = true
+			return nil
+		}
+	}
+	return ErrCircuitOpen
+}
+
+// Record updates the breaker with the outcome of an allowed call.
+// Only transient failures count; a gateway that answers is up even when it declines.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if !isTransient(err) {
+		b.state = BreakerClosed
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerOpen || b.failures >= b.failureThreshold {
+		// A failed probe restarts the cool-down
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Status returns a snapshot of the breaker
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		State:               b.currentState(),
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state == BreakerOpen {
+		status.OpenedAt = b.openedAt
+		status.RetryAt = b.openedAt.Add(b.openTimeout)
+	}
+	return status
+}
+
+// currentState reports an open breaker whose cool-down has passed as half-open.
+// Callers must hold b.mu.
+func (b *CircuitBreaker) currentState() BreakerState {
+	if b.state == BreakerOpen && !b.now().Before(b.openedAt.Add(b.openTimeout)) {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// =============================================================================
+// HELPERS - Retry
+// =============================================================================
+
+// isTransient reports whether err means the gateway could not be reached
+func isTransient(err error) bool {
+	var transient *transientError
+	return errors.As(err, &transient)
+}
+
+// retryDelay returns the pause before retry number attempt (1-based):
+// base, 2×base, 4×base, ... capped at max, with up to 20% jitter so
+// replicas do not retry in lockstep
+func retryDelay(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay/5+1)
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error or
+// attempts run out. Each attempt gets its own timeout.
+func withRetry[T any](ctx context.Context, config ResilienceConfig, fn func(ctx context.Context) (T, error)) (T, error) {
+	attempts := max(config.InquiryAttempts, 1)
+
+	var result T
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if config.InquiryTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, config.InquiryTimeout)
+		}
+		result, err = fn(attemptCtx)
+		cancel()
+
+		if err == nil || !isTransient(err) || attempt == attempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("%w (after %d attempts)", err, attempt)
+		case <-time.After(retryDelay(attempt, config.RetryBaseDelay, config.RetryMaxDelay)):
+		}
+	}
+	return result, err
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, 30*time.Second)
+	breaker.now = func() time.Time { return now }
+
+	outage := &transientError{err: errors.New("connection refused")}
+
+	// Failures below the threshold keep it closed
+	for i := 0; i < 2; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Allow() error = %v before threshold", err)
+		}
+		breaker.Record(outage)
+	}
+	if state := breaker.Status().State; state != BreakerClosed {
+		t.Fatalf("state = %v after 2 failures, want %v", state, BreakerClosed)
+	}
+
+	// A declined payment is an answer, not an outage
+	breaker.Record(errors.New("response hash verification failed"))
+	if failures := breaker.Status().ConsecutiveFailures; failures != 0 {
+		t.Fatalf("ConsecutiveFailures = %d after an answer, want 0", failures)
+	}
+
+	for i := 0; i < 3; i++ {
+		breaker.Record(outage)
+	}
+	status := breaker.Status()
+	if status.State != BreakerOpen {
+		t.Fatalf("state = %v after 3 failures, want %v", status.State, BreakerOpen)
+	}
+	if !status.RetryAt.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("RetryAt = %v, want %v", status.RetryAt, now.Add(30*time.Second))
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() error = %v while open, want %v", err, ErrCircuitOpen)
+	}
+
+	// After the cool-down exactly one probe goes through
+	now = now.Add(31 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow() error = %v for probe", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() error = %v during probe, want %v", err, ErrCircuitOpen)
+	}
+
+	// A failed probe reopens it for another cool-down
+	breaker.Record(outage)
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Allow() error = %v after failed probe, want %v", err, ErrCircuitOpen)
+	}
+
+	// A successful probe closes it
+	now = now.Add(31 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Allow() error = %v for second probe", err)
+	}
+	breaker.Record(nil)
+	if state := breaker.Status().State; state != BreakerClosed {
+		t.Errorf("state = %v after successful probe, want %v", state, BreakerClosed)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond, 120 * time.Millisecond},
+		{2, 200 * time.Millisecond, 240 * time.Millisecond},
+		{3, 400 * time.Millisecond, 480 * time.Millisecond},
+		{10, time.Second, 1200 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		got := retryDelay(tt.attempt, 100*time.Millisecond, time.Second)
+		if got < tt.min || got > tt.max {
+			t.Errorf("retryDelay(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+		}
+	}
+}
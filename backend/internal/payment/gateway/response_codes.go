@@ -0,0 +1,296 @@
+package gateway
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// =============================================================================
+// CONSTANTS - Languages
+// =============================================================================
+
+// Language selects which user message a catalog entry returns
+type Language string
+
+const (
+	LanguageEnglish Language = "en"
+	LanguageUrdu    Language = "ur"
+)
+
+// ErrInvalidResponseCodes is returned for a catalog file that cannot be used
+var ErrInvalidResponseCodes = errors.New("invalid response code catalog")
+
+//go:embed jazzcash_codes.json
+var jazzCashCodesJSON []byte
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// ResponseCode is what a gateway response code means
+type ResponseCode struct {
+	Status       Status              `json:"status"`
+	RetryAllowed bool                `json:"retry_allowed"` // whether the customer should be offered a retry
+	Description  string              `json:"description"`   // for ops and logs, never shown to customers
+	Messages     map[Language]string `json:"messages"`      // customer-facing, {code} is replaced by the code
+}
+
+// ResponseCodeCatalog maps a gateway's response codes to statuses and
+// messages. Keys are exact codes or patterns where x matches any one digit,
+// e.g. 4xx; exact codes win, then the pattern with the fewest x.
+type ResponseCodeCatalog struct {
+	codes    map[string]ResponseCode
+	patterns []string
+	unknown  ResponseCode // code not in the catalog
+	missing  ResponseCode // reply without a code
+}
+
+// responseCodeFile is the JSON layout of a catalog file
+type responseCodeFile struct {
+	Codes   map[string]ResponseCode `json:"codes"`
+	Unknown *ResponseCode           `json:"unknown"`
+	Missing *ResponseCode           `json:"missing"`
+}
+
+type languageKey struct{}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+var jazzCashCodes = sync.OnceValue(func() *ResponseCodeCatalog {
+	catalog, err := ParseResponseCodeCatalog(jazzCashCodesJSON)
+	if err != nil {
+		panic(fmt.Sprintf("embedded JazzCash response codes: %v", err))
+	}
+	return catalog
+})
+
+// JazzCashResponseCodes returns the catalog built into the binary
+func JazzCashResponseCodes() *ResponseCodeCatalog {
+	return jazzCashCodes()
+}
+
+// LoadJazzCashResponseCodes returns the built-in catalog with the entries of
+// the file at path laid over it, so ops can add or correct a code without a
+// release. An empty path returns the built-in catalog.
+func LoadJazzCashResponseCodes(path string) (*ResponseCodeCatalog, error) {
+	if path == "" {
+		return JazzCashResponseCodes(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResponseCodes, err)
+	}
+	overrides, err := parseResponseCodeFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return JazzCashResponseCodes().merge(overrides), nil
+}
+
+// ParseResponseCodeCatalog reads a complete catalog, which must define both
+// the unknown and missing entries
+func ParseResponseCodeCatalog(data []byte) (*ResponseCodeCatalog, error) {
+	file, err := parseResponseCodeFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if file.Unknown == nil || file.Missing == nil {
+		return nil, fmt.Errorf("%w: unknown and missing entries are required", ErrInvalidResponseCodes)
+	}
+
+	catalog := &ResponseCodeCatalog{
+		codes:   map[string]ResponseCode{},
+		unknown: *file.Unknown,
+		missing: *file.Missing,
+	}
+	return catalog.merge(file), nil
+}
+
+// =============================================================================
+// PUBLIC CATALOG METHODS
+// =============================================================================
+
+// Lookup returns the entry for code, falling back to the unknown entry
+func (c *ResponseCodeCatalog) Lookup(code string) ResponseCode {
+	if code == "" {
+		return c.missing
+	}
+	if entry, ok := c.codes[code]; ok {
+		return entry
+	}
+	for _, pattern := range c.patterns {
+		if matchesCodePattern(pattern, code) {
+			return c.codes[pattern]
+		}
+	}
+	return c.unknown
+}
+
+// Status maps code to the shared Status
+func (c *ResponseCodeCatalog) Status(code string) Status {
+	return c.Lookup(code).Status
+}
+
+// Message returns the customer message for code in lang
+func (c *ResponseCodeCatalog) Message(code string, lang Language) string {
+	return c.Lookup(code).Message(code, lang)
+}
+
+// Message returns the entry's message in lang, or in English when the entry
+// has no translation
+func (r ResponseCode) Message(code string, lang Language) string {
+	message, ok := r.Messages[lang]
+	if !ok || message == "" {
+		message = r.Messages[LanguageEnglish]
+	}
+	return strings.ReplaceAll(message, "{code}", code)
+}
+
+// =============================================================================
+// PUBLIC HELPERS - Languages
+// =============================================================================
+
+// ParseAcceptLanguage picks the supported language the client prefers most
+// from an Accept-Language header, defaulting to English
+func ParseAcceptLanguage(header string) Language {
+	best, bestQ := LanguageEnglish, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch lang := Language(primary); lang {
+		case LanguageEnglish, LanguageUrdu:
+			if q > bestQ {
+				best, bestQ = lang, q
+			}
+		}
+	}
+	return best
+}
+
+// WithLanguage returns a context whose gateway replies carry messages in lang
+func WithLanguage(ctx context.Context, lang Language) context.Context {
+	return context.WithValue(ctx, languageKey{}, lang)
+}
+
+// LanguageFromContext returns the language set by WithLanguage, or English
+func LanguageFromContext(ctx context.Context) Language {
+	if lang, ok := ctx.Value(languageKey{}).(Language); ok {
+		return lang
+	}
+	return LanguageEnglish
+}
+
+// =============================================================================
+// HELPERS - Catalog
+// =============================================================================
+
+// parseResponseCodeFile decodes and validates a catalog file, complete or partial
+func parseResponseCodeFile(data []byte) (responseCodeFile, error) {
+	var file responseCodeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("%w: %v", ErrInvalidResponseCodes, err)
+	}
+
+	for code, entry := range file.Codes {
+		if err := validateResponseCode(code, entry); err != nil {
+			return file, err
+		}
+	}
+	if file.Unknown != nil {
+		if err := validateResponseCode("unknown", *file.Unknown); err != nil {
+			return file, err
+		}
+	}
+	if file.Missing != nil {
+		if err := validateResponseCode("missing", *file.Missing); err != nil {
+			return file, err
+		}
+	}
+	return file, nil
+}
+
+// validateResponseCode rejects entries that would map a code to nothing usable
+func validateResponseCode(code string, entry ResponseCode) error {
+	switch entry.Status {
+	case StatusSuccess, StatusFailed, StatusPending, StatusUnknown:
+	default:
+		return fmt.Errorf("%w: %s has status %q", ErrInvalidResponseCodes, code, entry.Status)
+	}
+	if entry.Messages[LanguageEnglish] == "" {
+		return fmt.Errorf("%w: %s has no English message", ErrInvalidResponseCodes, code)
+	}
+	return nil
+}
+
+// merge returns a copy of c with the entries of file replacing its own
+func (c *ResponseCodeCatalog) merge(file responseCodeFile) *ResponseCodeCatalog {
+	merged := &ResponseCodeCatalog{
+		codes:   make(map[string]ResponseCode, len(c.codes)+len(file.Codes)),
+		unknown: c.unknown,
+		missing: c.missing,
+	}
+	for code, entry := range c.codes {
+		merged.codes[code] = entry
+	}
+	for code, entry := range file.Codes {
+		merged.codes[code] = entry
+	}
+	if file.Unknown != nil {
+		merged.unknown = *file.Unknown
+	}
+	if file.Missing != nil {
+		merged.missing = *file.Missing
+	}
+
+	for code := range merged.codes {
+		if strings.Contains(code, "x") {
+			merged.patterns = append(merged.patterns, code)
+		}
+	}
+	sort.Slice(merged.patterns, func(i, j int) bool {
+		a, b := merged.patterns[i], merged.patterns[j]
+		if wildA, wildB := strings.Count(a, "x"), strings.Count(b, "x"); wildA != wildB {
+			return wildA < wildB
+		}
+		return a < b
+	})
+	return merged
+}
+
+// matchesCodePattern reports whether code fits pattern, where x matches one digit
+func matchesCodePattern(pattern, code string) bool {
+	if len(pattern) != len(code) {
+		return false
+	}
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == 'x' {
+			if code[i] < '0' || code[i] > '9' {
+				return false
+			}
+		} else if pattern[i] != code[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJazzCashResponseCodes_Status(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected Status
+	}{
+		{"000", StatusSuccess},
+		{"121", StatusSuccess},
+		{"200", StatusSuccess},
+		{"157", StatusPending},
+		{"124", StatusPending},
+		{"210", StatusPending},
+		{"101", StatusFailed},
+		{"999", StatusFailed},
+		{"405", StatusFailed}, // listed
+		{"431", StatusFailed}, // 4xx pattern
+		{"134", StatusUnknown},
+		{"777", StatusUnknown}, // not in the catalog
+		{"", StatusUnknown},
+	}
+
+	codes := JazzCashResponseCodes()
+	for _, tt := range tests {
+		if got := codes.Status(tt.code); got != tt.expected {
+			t.Errorf("Status(%q) = %v, want %v", tt.code, got, tt.expected)
+		}
+	}
+}
+
+func TestJazzCashResponseCodes_Messages(t *testing.T) {
+	codes := JazzCashResponseCodes()
+
+	if got := codes.Message("004", LanguageEnglish); got != "Insufficient balance. Please add funds to your account" {
+		t.Errorf("Message(004, en) = %q", got)
+	}
+	if got := codes.Message("004", LanguageUrdu); got == codes.Message("004", LanguageEnglish) || got == "" {
+		t.Errorf("Message(004, ur) = %q, want an Urdu message", got)
+	}
+	if got := codes.Message("777", LanguageEnglish); got != "Transaction failed with code: 777. Please contact support" {
+		t.Errorf("Message(777, en) = %q", got)
+	}
+	if got := codes.Message("", LanguageEnglish); got != "Transaction failed. Please try again" {
+		t.Errorf("Message(\"\", en) = %q", got)
+	}
+	if !codes.Lookup("024").RetryAllowed || codes.Lookup("101").RetryAllowed {
+		t.Error("RetryAllowed: want 024 (wrong MPIN) retryable and 101 (bad merchant credentials) not")
+	}
+
+	// Every entry must at least have English, and every built-in one Urdu too
+	for code, entry := range codes.codes {
+		if entry.Messages[LanguageUrdu] == "" {
+			t.Errorf("code %s has no Urdu message", code)
+		}
+	}
+}
+
+func TestLoadJazzCashResponseCodes_Override(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "codes.json")
+	override := `{"codes": {
+		"777": {"status": "FAILED", "retry_allowed": true, "description": "New decline", "messages": {"en": "Declined"}},
+		"000": {"status": "SUCCESS", "description": "Reworded", "messages": {"en": "Paid", "ur": "ادا ہو گیا"}}
+	}}`
+	if err := os.WriteFile(path, []byte(override), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	codes, err := LoadJazzCashResponseCodes(path)
+	if err != nil {
+		t.Fatalf("LoadJazzCashResponseCodes() error = %v", err)
+	}
+
+	if got := codes.Status("777"); got != StatusFailed {
+		t.Errorf("Status(777) = %v, want %v", got, StatusFailed)
+	}
+	// No Urdu in the override, so English is used
+	if got := codes.Message("777", LanguageUrdu); got != "Declined" {
+		t.Errorf("Message(777, ur) = %q, want Declined", got)
+	}
+	if got := codes.Message("000", LanguageEnglish); got != "Paid" {
+		t.Errorf("Message(000, en) = %q, want Paid", got)
+	}
+	// Untouched entries and the built-in catalog itself are unchanged
+	if got := codes.Status("157"); got != StatusPending {
+		t.Errorf("Status(157) = %v, want %v", got, StatusPending)
+	}
+	if got := JazzCashResponseCodes().Status("777"); got != StatusUnknown {
+		t.Errorf("built-in Status(777) = %v, want %v", got, StatusUnknown)
+	}
+}
+
+func TestParseResponseCodeCatalog_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"not json", `codes`},
+		{"bad status", `{"codes": {"000": {"status": "PAID", "messages": {"en": "ok"}}}, "unknown": {"status": "UNKNOWN", "messages": {"en": "?"}}, "missing": {"status": "UNKNOWN", "messages": {"en": "?"}}}`},
+		{"no english", `{"codes": {"000": {"status": "SUCCESS", "messages": {"ur": "ٹھیک"}}}, "unknown": {"status": "UNKNOWN", "messages": {"en": "?"}}, "missing": {"status": "UNKNOWN", "messages": {"en": "?"}}}`},
+		{"no fallbacks", `{"codes": {"000": {"status": "SUCCESS", "messages": {"en": "ok"}}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseResponseCodeCatalog([]byte(tt.input)); !errors.Is(err, ErrInvalidResponseCodes) {
+				t.Errorf("ParseResponseCodeCatalog() error = %v, want %v", err, ErrInvalidResponseCodes)
+			}
+		})
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected Language
+	}{
+		{"", LanguageEnglish},
+		{"ur", LanguageUrdu},
+		{"ur-PK,ur;q=0.9,en;q=0.8", LanguageUrdu},
+		{"en-US,en;q=0.9,ur;q=0.5", LanguageEnglish},
+		{"fr, ur;q=0.4", LanguageUrdu},
+		{"fr, de", LanguageEnglish},
+		{"ur;q=bad, en;q=0.1", LanguageEnglish},
+	}
+
+	for _, tt := range tests {
+		if got := ParseAcceptLanguage(tt.header); got != tt.expected {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestMapMWalletResponse_Language(t *testing.T) {
+	client := &JazzCashClient{}
+	reply := map[string]any{"pp_ResponseCode": "157"}
+
+	ctx := WithLanguage(context.Background(), LanguageUrdu)
+	resp := client.mapMWalletResponse(reply, LanguageFromContext(ctx))
+
+	if resp.Status != StatusPending {
+		t.Errorf("Status = %v, want %v", resp.Status, StatusPending)
+	}
+	if want := JazzCashResponseCodes().Message("157", LanguageUrdu); resp.Message != want {
+		t.Errorf("Message = %q, want %q", resp.Message, want)
+	}
+}
@@ -1,28 +1,63 @@
 package payment
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
 	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
 )
 
 type Handler struct {
 	service *Service
+	config  HandlerConfig
 }
 
-func NewHandler(service *Service) *Handler {
+// HandlerConfig holds the browser-facing URLs of the card checkout and the
+// broker status streams subscribe to
+type HandlerConfig struct {
+	CardReturnPath string        // route JazzCash posts the card result to
+	CardResultURL  string        // frontend page the browser lands on afterwards
+	Statuses       *StatusBroker // source of live status changes for StreamPaymentStatus
+}
+
+// statusStreamHeartbeat is how often an idle status stream is kept alive and
+// re-checked against the database, in case a notification was missed
+const statusStreamHeartbeat = 15 * time.Second
+
+func NewHandler(service *Service, config HandlerConfig) *Handler {
 	return &Handler{
 		service: service,
+		config:  config,
 	}
 }
 
+// CardReturnPath is the route CardReturn must be mounted on
+func (h *Handler) CardReturnPath() string {
+	return h.config.CardReturnPath
+}
+
 func (h *Handler) TopUp(w http.ResponseWriter, r *http.Request) {
 	var params TopUpRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		if errors.Is(err, money.ErrInvalidAmount) || errors.Is(err, money.ErrUnsupportedCurrency) {
+			h.handleServiceError(w, err)
+			return
+		}
 		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
@@ -35,7 +70,7 @@ func (h *Handler) TopUp(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback(r.Context())
 
-	response, err := h.service.InitiatePayment(r.Context(), tx, params)
+	response, err := h.service.InitiatePayment(withLanguage(w, r), tx, params)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -47,11 +82,6 @@ func (h *Handler) TopUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Start polling for pending MWallet transactions
-	if response.PaymentMethod == PaymentMethodMWallet && response.Status == PaymentStatusPending {
-		go h.service.startPollingForTransaction(response.TxnRefNo)
-	}
-
 	// Return appropriate status based on payment result
 	switch response.Status {
 	case PaymentStatusSuccess:
@@ -63,6 +93,739 @@ func (h *Handler) TopUp(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetIntent returns one of the user's payment intents
+func (h *Handler) GetIntent(w http.ResponseWriter, r *http.Request) {
+	intentID, err := uuid.Parse(chi.URLParam(r, "intentID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid payment intent id")
+		return
+	}
+
+	intent, err := h.service.GetIntent(r.Context(), intentID)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, intent)
+}
+
+// PayIntent starts a gateway payment of one of the user's payment intents,
+// e.g. a ticket created by the transport module
+func (h *Handler) PayIntent(w http.ResponseWriter, r *http.Request) {
+	intentID, err := uuid.Parse(chi.URLParam(r, "intentID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid payment intent id")
+		return
+	}
+
+	var params PayIntentRequest
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	tx, err := h.service.dbPool.Begin(r.Context())
+	if err != nil {
+		log.Printf("DATABASE ERROR: %v", err)
+		common.ResponseWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	response, err := h.service.PayIntent(withLanguage(w, r), tx, intentID, params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if err := tx.Commit(r.Context()); err != nil {
+		log.Printf("failed to commit transaction: %v", err)
+		common.ResponseWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	switch response.Status {
+	case PaymentStatusSuccess, PaymentStatusFailed:
+		common.ResponseWithJSON(w, http.StatusOK, response)
+	default:
+		common.ResponseWithJSON(w, http.StatusAccepted, response)
+	}
+}
+
+// GetPaymentStatus returns the current status of one of the user's top-ups
+func (h *Handler) GetPaymentStatus(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetPaymentStatus(r.Context(), chi.URLParam(r, "txnRefNo"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, result)
+}
+
+// StreamPaymentStatus sends the status of one of the user's top-ups as
+// Server-Sent Events: the current status first, then every change, and closes
+// the stream once the status is final
+func (h *Handler) StreamPaymentStatus(w http.ResponseWriter, r *http.Request) {
+	txnRefNo := chi.URLParam(r, "txnRefNo")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		common.ResponseWithError(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// Subscribe before reading the status so a change in between is not lost
+	updates, unsubscribe := h.config.Statuses.Subscribe(txnRefNo)
+	defer unsubscribe()
+
+	current, err := h.service.GetPaymentStatus(r.Context(), txnRefNo)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	status := current.Status
+	writeStatusEvent(w, flusher, StatusUpdate{TxnRefNo: txnRefNo, Status: status})
+
+	heartbeat := time.NewTicker(statusStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for !isFinalStatus(status) {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.config.Statuses.Done():
+			return
+
+		case update := <-updates:
+			if update.Status == status {
+				continue
+			}
+			status = update.Status
+			writeStatusEvent(w, flusher, update)
+
+		case <-heartbeat.C:
+			latest, err := h.service.GetPaymentStatus(r.Context(), txnRefNo)
+			if err != nil {
+				return
+			}
+			if latest.Status != status {
+				status = latest.Status
+				writeStatusEvent(w, flusher, StatusUpdate{TxnRefNo: txnRefNo, Status: status})
+				continue
+			}
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// GatewayHealth tells the frontend which gateways are taking payments, for an outage banner
+func (h *Handler) GatewayHealth(w http.ResponseWriter, r *http.Request) {
+	common.ResponseWithJSON(w, http.StatusOK, h.service.GatewayHealth())
+}
+
+// CardReturn receives the browser POST from the JazzCash hosted card page
+// and sends the customer on to the frontend result page
+func (h *Handler) CardReturn(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		log.Printf("invalid card return form: %v", err)
+		h.redirectToResult(w, r, "", PaymentStatusUnknown)
+		return
+	}
+
+	form := make(map[string]string, len(r.PostForm))
+	for key := range r.PostForm {
+		form[key] = r.PostForm.Get(key)
+	}
+
+	result, err := h.service.HandleCardCallback(r.Context(), form)
+	if err != nil {
+		log.Printf("card return failed: %v", err)
+		h.redirectToResult(w, r, form[gateway.FieldTxnRefNo], PaymentStatusUnknown)
+		return
+	}
+
+	h.redirectToResult(w, r, result.TxnRefNo, result.Status)
+}
+
+// Notification receives server-to-server payment notifications (IPN) from the
+// gateway named in the route and replies with the acknowledgement it expects
+func (h *Handler) Notification(w http.ResponseWriter, r *http.Request) {
+	gatewayName := strings.ToUpper(chi.URLParam(r, "gateway"))
+
+	payload, err := readNotificationPayload(r)
+	if err != nil {
+		log.Printf("invalid %s notification body: %v", gatewayName, err)
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.service.HandleNotification(r.Context(), gatewayName, payload); err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	ack, err := h.service.AcknowledgeNotification(r.Context(), gatewayName, payload)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, ack)
+}
+
+// readNotificationPayload accepts both JSON and form-encoded notification bodies
+func readNotificationPayload(r *http.Request) (map[string]string, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber() // keep amounts exactly as signed
+
+		var body map[string]any
+		if err := decoder.Decode(&body); err != nil {
+			return nil, err
+		}
+
+		payload := make(map[string]string, len(body))
+		for key, value := range body {
+			payload[key] = fmt.Sprintf("%v", value)
+		}
+		return payload, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	payload := make(map[string]string, len(r.PostForm))
+	for key := range r.PostForm {
+		payload[key] = r.PostForm.Get(key)
+	}
+	return payload, nil
+}
+
+// RequestRefund starts a refund of the transaction in the route (admin only)
+func (h *Handler) RequestRefund(w http.ResponseWriter, r *http.Request) {
+	var params RefundCreateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		if errors.Is(err, money.ErrInvalidAmount) || errors.Is(err, money.ErrUnsupportedCurrency) {
+			h.handleServiceError(w, err)
+			return
+		}
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	refund, err := h.service.RequestRefund(r.Context(), chi.URLParam(r, "txnRefNo"), params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// 202 until the worker hears back from the gateway
+	status := http.StatusAccepted
+	if refund.Status != RefundStatusPending {
+		status = http.StatusOK
+	}
+	common.ResponseWithJSON(w, status, refund)
+}
+
+// ListRefunds lists refunds of the transaction in the route (admin only)
+func (h *Handler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	refunds, err := h.service.ListRefunds(r.Context(), chi.URLParam(r, "txnRefNo"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, refunds)
+}
+
+// GetTransactionTimeline shows every gateway exchange for one transaction (admin only)
+func (h *Handler) GetTransactionTimeline(w http.ResponseWriter, r *http.Request) {
+	timeline, err := h.service.GetTransactionTimeline(r.Context(), chi.URLParam(r, "txnRefNo"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, timeline)
+}
+
+// GetRefund reports the progress of one refund (admin only)
+func (h *Handler) GetRefund(w http.ResponseWriter, r *http.Request) {
+	refundID, err := uuid.Parse(chi.URLParam(r, "refundID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid refund id")
+		return
+	}
+
+	refund, err := h.service.GetRefund(r.Context(), refundID)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, refund)
+}
+
+// ListUnknownRefunds lists refunds with no definite answer from the gateway, oldest first (admin only)
+func (h *Handler) ListUnknownRefunds(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	refunds, err := h.service.ListUnknownRefunds(r.Context(), limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, refunds)
+}
+
+// ResolveRefund settles an UNKNOWN refund by hand with a reason (admin only)
+func (h *Handler) ResolveRefund(w http.ResponseWriter, r *http.Request) {
+	refundID, err := uuid.Parse(chi.URLParam(r, "refundID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid refund id")
+		return
+	}
+
+	var params ResolveRefundRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	refund, err := h.service.ResolveRefund(r.Context(), refundID, params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, refund)
+}
+
+// maxSettlementUpload caps the size of an uploaded settlement file
+const maxSettlementUpload = 20 << 20
+
+// ImportSettlement accepts a multipart settlement CSV upload ("file") and returns
+// the discrepancy report (admin only). Optional form fields: gateway,
+// period_start and period_end (YYYY-MM-DD, inclusive).
+func (h *Handler) ImportSettlement(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := auth.GetUserIDFromContext(r.Context())
+	if !ok {
+		h.handleServiceError(w, ErrUserIDNotFound)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSettlementUpload)
+	if err := r.ParseMultipartForm(maxSettlementUpload); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid upload")
+		return
+	}
+
+	file, fileHeader, err := r.FormFile("file")
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Settlement file is required")
+		return
+	}
+	defer file.Close()
+
+	periodStart, periodEnd, err := ParseSettlementPeriod(r.FormValue("period_start"), r.FormValue("period_end"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	gatewayName := strings.ToUpper(r.FormValue("gateway"))
+	if gatewayName == "" {
+		gatewayName = gateway.JazzCashName
+	}
+
+	report, err := h.service.ImportSettlement(r.Context(), SettlementImport{
+		Gateway:     gatewayName,
+		SourceName:  fileHeader.Filename,
+		ImportedBy:  adminID,
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+	}, file)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusCreated, report)
+}
+
+// ListSettlementRuns lists imported settlement files, newest first (admin only)
+func (h *Handler) ListSettlementRuns(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	runs, err := h.service.ListSettlementRuns(r.Context(), limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, runs)
+}
+
+// GetSettlementReport returns one run and its discrepancies (admin only)
+func (h *Handler) GetSettlementReport(w http.ResponseWriter, r *http.Request) {
+	runID, err := uuid.Parse(chi.URLParam(r, "runID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid settlement run id")
+		return
+	}
+
+	report, err := h.service.GetSettlementReport(r.Context(), runID)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, report)
+}
+
+// ResolveDiscrepancy marks a discrepancy as handled with a note (admin only)
+func (h *Handler) ResolveDiscrepancy(w http.ResponseWriter, r *http.Request) {
+	discrepancyID, err := uuid.Parse(chi.URLParam(r, "discrepancyID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid discrepancy id")
+		return
+	}
+
+	var params ResolveDiscrepancyRequest
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	discrepancy, err := h.service.ResolveDiscrepancy(r.Context(), discrepancyID, params.Note)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, discrepancy)
+}
+
+// SearchPayments lists gateway transactions for the payments console (admin only).
+// Query parameters: user_id, status (comma-separated), method, from and to
+// (YYYY-MM-DD, inclusive, PKT), min_paisa, max_paisa, txn_ref_no, rrn, cursor, limit.
+func (h *Handler) SearchPayments(w http.ResponseWriter, r *http.Request) {
+	search, err := parsePaymentSearch(r)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	result, err := h.service.SearchPayments(r.Context(), search)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, result)
+}
+
+// ExportPayments streams every transaction matching the SearchPayments filters as CSV (admin only)
+func (h *Handler) ExportPayments(w http.ResponseWriter, r *http.Request) {
+	search, err := parsePaymentSearch(r)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Filters are validated above, so failures from here on are mid-stream
+	filename := fmt.Sprintf("payments-%s.csv", time.Now().In(common.PKT).Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	if err := h.service.ExportPaymentsCSV(r.Context(), search, w); err != nil {
+		log.Printf("payments export aborted: %v", err)
+	}
+}
+
+// ReinquireTransaction asks the gateway about a transaction that has not succeeded yet (admin only)
+func (h *Handler) ReinquireTransaction(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.ReinquireTransaction(withLanguage(w, r), chi.URLParam(r, "txnRefNo"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, result)
+}
+
+// ResolveTransaction settles a stuck UNKNOWN transaction by hand with a reason (admin only)
+func (h *Handler) ResolveTransaction(w http.ResponseWriter, r *http.Request) {
+	var params ResolveTransactionRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	result, err := h.service.ResolveTransaction(r.Context(), chi.URLParam(r, "txnRefNo"), params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, result)
+}
+
+// ListLateSuccesses lists FAILED transactions the gateway later reported as paid, newest first (admin only)
+func (h *Handler) ListLateSuccesses(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	lateSuccesses, err := h.service.ListLateSuccesses(r.Context(), limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, lateSuccesses)
+}
+
+// maxBankTransferUpload caps a bank transfer submission: the receipt plus its form fields
+const maxBankTransferUpload = maxReceiptSize + 1<<20
+
+// SubmitBankTransfer accepts a top-up paid by bank transfer as a multipart form
+// with idempotency_key, amount_paisa, bank_reference and the receipt image as
+// "receipt". The top-up stays pending until an admin reviews it.
+func (h *Handler) SubmitBankTransfer(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBankTransferUpload)
+	if err := r.ParseMultipartForm(maxBankTransferUpload); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid upload")
+		return
+	}
+
+	receipt, _, err := r.FormFile("receipt")
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Receipt image is required")
+		return
+	}
+	defer receipt.Close()
+
+	idempotencyKey, err := uuid.Parse(r.FormValue("idempotency_key"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid idempotency key")
+		return
+	}
+
+	amount, err := money.ParseMinor(r.FormValue("amount_paisa"), money.PKR)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	transfer, err := h.service.SubmitBankTransfer(r.Context(), BankTransferSubmission{
+		IdempotencyKey: idempotencyKey,
+		Amount:         amount,
+		BankReference:  r.FormValue("bank_reference"),
+	}, receipt)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusAccepted, transfer)
+}
+
+// ListBankTransfers lists bank transfers in one review state, oldest first (admin only).
+// Query parameters: status (PENDING by default, APPROVED or REJECTED), limit, offset.
+func (h *Handler) ListBankTransfers(w http.ResponseWriter, r *http.Request) {
+	status := BankTransferReview(strings.ToUpper(r.URL.Query().Get("status")))
+	if status == "" {
+		status = BankTransferPending
+	}
+	limit, offset := pageParams(r)
+
+	transfers, err := h.service.ListBankTransfers(r.Context(), status, limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, transfers)
+}
+
+// GetBankTransferReceipt streams the receipt image of a bank transfer (admin only)
+func (h *Handler) GetBankTransferReceipt(w http.ResponseWriter, r *http.Request) {
+	transferID, err := uuid.Parse(chi.URLParam(r, "transferID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid bank transfer id")
+		return
+	}
+
+	receipt, contentType, err := h.service.OpenBankTransferReceipt(r.Context(), transferID)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+	defer receipt.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(w, receipt); err != nil {
+		log.Printf("failed to send receipt of bank transfer %s: %v", transferID, err)
+	}
+}
+
+// ReviewBankTransfer approves or rejects a pending bank transfer (admin only)
+func (h *Handler) ReviewBankTransfer(w http.ResponseWriter, r *http.Request) {
+	transferID, err := uuid.Parse(chi.URLParam(r, "transferID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid bank transfer id")
+		return
+	}
+
+	var params ReviewBankTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	transfer, err := h.service.ReviewBankTransfer(r.Context(), transferID, params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, transfer)
+}
+
+// parsePaymentSearch reads the payments console filters from the query string
+func parsePaymentSearch(r *http.Request) (PaymentSearch, error) {
+	query := r.URL.Query()
+	search := PaymentSearch{
+		Method:   PaymentMethod(strings.ToUpper(query.Get("method"))),
+		TxnRefNo: strings.TrimSpace(query.Get("txn_ref_no")),
+		RRN:      strings.TrimSpace(query.Get("rrn")),
+		Cursor:   query.Get("cursor"),
+	}
+
+	if raw := query.Get("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			return search, fmt.Errorf("%w: invalid user_id", ErrInvalidPaymentSearch)
+		}
+		search.UserID = &userID
+	}
+
+	for _, status := range strings.Split(query.Get("status"), ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			search.Statuses = append(search.Statuses, PaymentStatus(strings.ToUpper(status)))
+		}
+	}
+
+	if raw := query.Get("from"); raw != "" {
+		from, err := time.ParseInLocation("2006-01-02", raw, common.PKT)
+		if err != nil {
+			return search, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidPaymentSearch)
+		}
+		search.From = from
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := time.ParseInLocation("2006-01-02", raw, common.PKT)
+		if err != nil {
+			return search, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidPaymentSearch)
+		}
+		search.To = to.AddDate(0, 0, 1)
+	}
+
+	for key, target := range map[string]**money.Money{"min_paisa": &search.MinAmount, "max_paisa": &search.MaxAmount} {
+		if raw := query.Get(key); raw != "" {
+			amount, err := money.ParseMinor(raw, money.PKR)
+			if err != nil {
+				return search, fmt.Errorf("%w: %s must be a whole number of paisa", ErrInvalidPaymentSearch, key)
+			}
+			*target = &amount
+		}
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return search, fmt.Errorf("%w: limit must be a positive number", ErrInvalidPaymentSearch)
+		}
+		search.Limit = int32(min(limit, maxPaymentPageSize))
+	}
+
+	return search, nil
+}
+
+// withLanguage carries the request's Accept-Language to the gateway so reply
+// messages come back in the customer's language
+func withLanguage(w http.ResponseWriter, r *http.Request) context.Context {
+	lang := gateway.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", string(lang))
+	w.Header().Add("Vary", "Accept-Language")
+	return gateway.WithLanguage(r.Context(), lang)
+}
+
+// pageParams reads limit/offset query parameters with sane defaults
+func pageParams(r *http.Request) (int32, int32) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return int32(limit), int32(offset)
+}
+
+// redirectToResult sends the browser to the frontend card result page
+func (h *Handler) redirectToResult(w http.ResponseWriter, r *http.Request, txnRefNo string, status PaymentStatus) {
+	target, err := url.Parse(h.config.CardResultURL)
+	if err != nil {
+		log.Printf("invalid card result url %q: %v", h.config.CardResultURL, err)
+		common.ResponseWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	query := target.Query()
+	query.Set("status", string(status))
+	if txnRefNo != "" {
+		query.Set("txn_ref_no", txnRefNo)
+	}
+	target.RawQuery = query.Encode()
+
+	http.Redirect(w, r, target.String(), http.StatusSeeOther)
+}
+
+// writeStatusEvent writes one "status" Server-Sent Event
+func writeStatusEvent(w http.ResponseWriter, flusher http.Flusher, update StatusUpdate) {
+	data, err := json.Marshal(update)
+	if err != nil {
+		log.Printf("failed to encode status event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+// isFinalStatus reports whether a transaction can no longer change status
+func isFinalStatus(status PaymentStatus) bool {
+	return status == PaymentStatusSuccess || status == PaymentStatusFailed
+}
+
 // handleServiceError maps service errors to HTTP responses
 func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
 	switch {
@@ -73,6 +836,85 @@ func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
 		common.ResponseWithError(w, http.StatusBadRequest, "Invalid CNIC format. Please enter the last 6 digits of your CNIC.")
 	case errors.Is(err, ErrInvalidPaymentMethod):
 		common.ResponseWithError(w, http.StatusBadRequest, "Invalid payment method selected.")
+	case errors.Is(err, ErrInvalidAmount), errors.Is(err, money.ErrInvalidAmount), errors.Is(err, money.ErrUnsupportedCurrency):
+		common.ResponseWithError(w, http.StatusBadRequest, "Amount must be a positive PKR amount given in paisa, e.g. {\"minor_units\": 50000, \"currency\": \"PKR\"}.")
+	case errors.Is(err, ErrInvalidCallback):
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid payment callback.")
+	case errors.Is(err, ErrInvalidRefundRequest):
+		common.ResponseWithError(w, http.StatusBadRequest, "Refund needs an idempotency key, a positive amount and a reason.")
+	case errors.Is(err, ErrInvalidRefundAmount):
+		common.ResponseWithError(w, http.StatusBadRequest, "Refund amount exceeds the amount left to refund.")
+	case errors.Is(err, ErrInvalidRefundResolution):
+		common.ResponseWithError(w, http.StatusBadRequest, "A refund is resolved as SUCCESS, FAILED or PENDING (to submit it again), with a reason.")
+
+	case errors.Is(err, ErrAmountBelowMinimum), errors.Is(err, ErrAmountAboveMaximum):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrInvalidPaymentSearch):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrResolutionReasonRequired):
+		common.ResponseWithError(w, http.StatusBadRequest, "A reason is required to resolve a transaction manually.")
+	case errors.Is(err, ErrInvalidResolutionStatus):
+		common.ResponseWithError(w, http.StatusBadRequest, "A transaction can only be resolved as SUCCESS or FAILED.")
+
+	case errors.Is(err, ErrInvalidSettlementFile):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrResolutionNoteRequired):
+		common.ResponseWithError(w, http.StatusBadRequest, "A resolution note is required.")
+
+	case errors.Is(err, ErrInvalidBankTransfer), errors.Is(err, ErrInvalidReceipt):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrInvalidIntent):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrReviewReasonRequired):
+		common.ResponseWithError(w, http.StatusBadRequest, "A reason is required to reject a bank transfer.")
+	case errors.Is(err, ErrInvalidReviewDecision):
+		common.ResponseWithError(w, http.StatusBadRequest, "A bank transfer can only be APPROVED or REJECTED.")
+
+	// Top-up caps (403) and velocity (429)
+	case errors.Is(err, ErrDailyLimitExceeded):
+		common.ResponseWithError(w, http.StatusForbidden, "Daily top-up limit reached. Please try again tomorrow or top up a smaller amount.")
+	case errors.Is(err, ErrMonthlyLimitExceeded):
+		common.ResponseWithError(w, http.StatusForbidden, "Monthly top-up limit reached. Please try again next month or top up a smaller amount.")
+	case errors.Is(err, ErrTooManyAttempts):
+		common.ResponseWithError(w, http.StatusTooManyRequests, "Too many top-up attempts. Please wait a while before trying again.")
+
+	// Conflict (409)
+	case errors.Is(err, ErrTooManyPending):
+		common.ResponseWithError(w, http.StatusConflict, "You already have a top-up in progress. Please wait for it to complete.")
+	case errors.Is(err, ErrRefundNotAllowed):
+		common.ResponseWithError(w, http.StatusConflict, "Only successful transactions can be refunded.")
+	case errors.Is(err, ErrRefundExceedsBalance):
+		common.ResponseWithError(w, http.StatusConflict, "The user's wallet balance does not cover this refund.")
+	case errors.Is(err, ErrRefundNotUnknown):
+		common.ResponseWithError(w, http.StatusConflict, "Only refunds in UNKNOWN status can be resolved manually.")
+	case errors.Is(err, ErrDiscrepancyResolved):
+		common.ResponseWithError(w, http.StatusConflict, "Discrepancy has already been resolved.")
+	case errors.Is(err, ErrTransactionAlreadySucceeded):
+		common.ResponseWithError(w, http.StatusConflict, "Transaction has already succeeded.")
+	case errors.Is(err, ErrTransactionNotUnknown):
+		common.ResponseWithError(w, http.StatusConflict, "Only transactions in UNKNOWN status can be resolved manually.")
+	case errors.Is(err, ErrDuplicateBankReference):
+		common.ResponseWithError(w, http.StatusConflict, "This bank reference has already been submitted.")
+	case errors.Is(err, ErrBankTransferReviewed):
+		common.ResponseWithError(w, http.StatusConflict, "Bank transfer has already been reviewed.")
+	case errors.Is(err, ErrNotGatewayTransaction):
+		common.ResponseWithError(w, http.StatusConflict, "Bank transfers are settled by review, not by the gateway.")
+	case errors.Is(err, ErrIntentNotPayable), errors.Is(err, ErrIntentConflict), errors.Is(err, ErrIntentNotCancellable):
+		common.ResponseWithError(w, http.StatusConflict, err.Error())
+
+	// Not found (404)
+	case errors.Is(err, ErrTransactionNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Transaction not found.")
+	case errors.Is(err, ErrInvalidGateway):
+		common.ResponseWithError(w, http.StatusNotFound, "Unknown payment gateway.")
+	case errors.Is(err, ErrRefundNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Refund not found.")
+	case errors.Is(err, ErrSettlementNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Settlement record not found.")
+	case errors.Is(err, ErrBankTransferNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Bank transfer not found.")
+	case errors.Is(err, ErrIntentNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Payment intent not found.")
 
 	// Gateway unreachable (502)
 	case errors.Is(err, ErrGatewayUnavailable):
@@ -0,0 +1,501 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidIntent Validation errors (400) - show to user
+	ErrInvalidIntent = errors.New("invalid payment intent")
+
+	// ErrIntentNotPayable Intent is being paid, already paid or cancelled (409)
+	ErrIntentNotPayable = errors.New("payment intent cannot be paid now")
+
+	// ErrIntentConflict Purpose reference already has an intent with other terms (409)
+	ErrIntentConflict = errors.New("payment intent already exists with a different amount or payer")
+
+	// ErrIntentNotCancellable Only unpaid intents without a payment in flight can be cancelled (409)
+	ErrIntentNotCancellable = errors.New("payment intent cannot be cancelled")
+
+	// ErrIntentNotFound Unknown intent, or one the caller does not pay for (404)
+	ErrIntentNotFound = errors.New("payment intent not found")
+
+	// errIntentStateChanged The intent was not in a state the transition starts from
+	errIntentStateChanged = errors.New("payment intent state changed")
+)
+
+const (
+	// maxFulfillmentBackoff caps the delay between completion handler retries
+	maxFulfillmentBackoff = time.Hour
+)
+
+// intentTransitions lists, for each state, the states an intent may enter it from
+var intentTransitions = map[IntentState][]IntentState{
+	IntentProcessing: {IntentRequiresPayment, IntentFailed},
+	IntentFailed:     {IntentProcessing},
+	// FAILED and CANCELLED are included because a gateway can still report an
+	// attempt as paid after it was given up on; the money has to go somewhere
+	IntentSucceeded: {IntentProcessing, IntentFailed, IntentCancelled},
+	IntentFulfilled: {IntentSucceeded},
+	IntentCancelled: {IntentRequiresPayment, IntentFailed},
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Payment Intents
+// =============================================================================
+
+// CreateIntent asks a user to pay for something a module sells, inside the
+// module's own database transaction. Creating an intent again for the same
+// purpose reference returns the existing one if the amount and payer match.
+func (s *Service) CreateIntent(ctx context.Context, tx pgx.Tx, req CreateIntentRequest) (*PaymentIntent, error) {
+	intent, err := s.ensureIntent(ctx, s.q.WithTx(tx), req)
+	if err != nil {
+		return nil, err
+	}
+	return toPaymentIntent(intent), nil
+}
+
+// GetIntent returns one of the caller's payment intents
+func (s *Service) GetIntent(ctx context.Context, intentID uuid.UUID) (*PaymentIntent, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	intent, err := s.q.GetPaymentIntent(ctx, intentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIntentNotFound
+	} else if err != nil {
+		log.Printf("failed to load payment intent %s: %v", intentID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if intent.PayerID != userID {
+		return nil, ErrIntentNotFound
+	}
+	return toPaymentIntent(intent), nil
+}
+
+// PayIntent starts a gateway payment of an intent for its payer. An intent
+// whose last attempt failed may be paid again with a new idempotency key;
+// repeating a request with the same key returns that attempt.
+func (s *Service) PayIntent(ctx context.Context, tx pgx.Tx, intentID uuid.UUID, req PayIntentRequest) (*TopUpResult, error) {
+	paymentQ := s.q.WithTx(tx)
+
+	if _, err := s.providers.Provider(req.Method); err != nil {
+		return nil, err
+	}
+	if req.IdempotencyKey == uuid.Nil {
+		return nil, fmt.Errorf("%w: idempotency_key is required", ErrInvalidIntent)
+	}
+
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", req.IdempotencyKey.String())
+	if err != nil {
+		log.Printf("failed to acquire advisory lock: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToAcquireLock, err)
+	}
+
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	existingPayment, err := paymentQ.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err == nil {
+		if existingPayment.PaymentIntentID != intentID || existingPayment.UserID != userID {
+			return nil, fmt.Errorf("%w: idempotency key already used for another payment", ErrInvalidIntent)
+		}
+		return s.handleExistingTransaction(ctx, tx, existingPayment)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error checking idempotency key: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	// Locked so two attempts cannot start on the same intent
+	intent, err := paymentQ.GetPaymentIntentForUpdate(ctx, intentID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrIntentNotFound
+	} else if err != nil {
+		log.Printf("failed to lock payment intent %s: %v", intentID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	if intent.PayerID != userID {
+		return nil, ErrIntentNotFound
+	}
+
+	// Top-ups are paid through InitiatePayment, which applies the top-up limits
+	if IntentPurpose(intent.Purpose) == PurposeTopUp {
+		return nil, fmt.Errorf("%w: top-ups are paid through /payments/topup", ErrIntentNotPayable)
+	}
+
+	// Each purpose can settle into its own merchant account
+	merchantProfile, provider, err := s.providers.Resolve(req.Method, intent.Purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startPayment(ctx, tx, intent, TopUpRequest{
+		IdempotencyKey: req.IdempotencyKey,
+		Amount:         intent.Amount,
+		Method:         req.Method,
+		PhoneNumber:    req.PhoneNumber,
+		CNICLast6:      req.CNICLast6,
+	}, merchantProfile, provider)
+}
+
+// CancelIntent withdraws an intent that is not paid and has no payment in
+// flight, e.g. when the seat it was reserving is released. Its purpose
+// reference can then be given a new intent.
+func (s *Service) CancelIntent(ctx context.Context, tx pgx.Tx, intentID uuid.UUID) (*PaymentIntent, error) {
+	paymentQ := s.q.WithTx(tx)
+
+	intent, err := s.transitionIntent(ctx, paymentQ, intentID, IntentCancelled)
+	if errors.Is(err, errIntentStateChanged) {
+		if _, err := paymentQ.GetPaymentIntent(ctx, intentID); err == nil {
+			return nil, ErrIntentNotCancellable
+		}
+		return nil, ErrIntentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return toPaymentIntent(intent), nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Payment Intents
+// =============================================================================
+
+// ensureIntent creates an intent, or returns the live one for the same
+// purpose reference when its amount and payer match
+func (s *Service) ensureIntent(ctx context.Context, paymentQ *payment.Queries, req CreateIntentRequest) (payment.GikiWalletPaymentIntent, error) {
+	req.PurposeReference = strings.TrimSpace(req.PurposeReference)
+	if req.Purpose == "" || req.PurposeReference == "" || req.PayerID == uuid.Nil {
+		return payment.GikiWalletPaymentIntent{}, fmt.Errorf("%w: purpose, purpose reference and payer are required", ErrInvalidIntent)
+	}
+	if req.Amount.Currency() != money.PKR || !req.Amount.IsPositive() {
+		return payment.GikiWalletPaymentIntent{}, fmt.Errorf("%w: got %s", ErrInvalidAmount, req.Amount)
+	}
+
+	existing, err := paymentQ.GetActivePaymentIntent(ctx, payment.GetActivePaymentIntentParams{
+		Purpose:          string(req.Purpose),
+		PurposeReference: req.PurposeReference,
+	})
+	if err == nil {
+		if !existing.Amount.Equal(req.Amount) || existing.PayerID != req.PayerID {
+			return existing, fmt.Errorf("%w: %s %s", ErrIntentConflict, req.Purpose, req.PurposeReference)
+		}
+		return existing, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("failed to look up %s intent %s: %v", req.Purpose, req.PurposeReference, err)
+		return existing, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	intent, err := paymentQ.CreatePaymentIntent(ctx, payment.CreatePaymentIntentParams{
+		Purpose:          string(req.Purpose),
+		PurposeReference: req.PurposeReference,
+		Amount:           req.Amount,
+		PayerID:          req.PayerID,
+		Metadata:         marshalPayload(req.Metadata),
+	})
+	if err != nil {
+		log.Printf("failed to create %s intent %s: %v", req.Purpose, req.PurposeReference, err)
+		return intent, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+	return intent, nil
+}
+
+// startPayment moves an intent to PROCESSING and submits a new gateway
+// transaction for it to provider
+func (s *Service) startPayment(
+	ctx context.Context,
+	tx pgx.Tx,
+	intent payment.GikiWalletPaymentIntent,
+	payload TopUpRequest,
+	merchantProfile string,
+	provider Provider,
+) (*TopUpResult, error) {
+	paymentQ := s.q.WithTx(tx)
+
+	if _, err := s.transitionIntent(ctx, paymentQ, intent.ID, IntentProcessing); err != nil {
+		if errors.Is(err, errIntentStateChanged) {
+			return nil, fmt.Errorf("%w: intent is %s", ErrIntentNotPayable, intent.State)
+		}
+		return nil, err
+	}
+
+	billRefNo, err := GenerateBillRefNo()
+	if err != nil {
+		log.Printf("error generating bill reference: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+
+	txnRefNo, err := GenerateTxnRefNo()
+	if err != nil {
+		log.Printf("error generating transaction reference: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+
+	// Create transaction record; providers send its expiry to the gateway
+	gatewayTxn, err := paymentQ.CreateGatewayTransaction(ctx, payment.CreateGatewayTransactionParams{
+		UserID:          intent.PayerID,
+		IdempotencyKey:  payload.IdempotencyKey,
+		BillRefID:       billRefNo,
+		TxnRefNo:        txnRefNo,
+		PaymentMethod:   string(payload.Method),
+		Status:          payment.CurrentStatus(PaymentStatusPending),
+		Amount:          payload.Amount,
+		ExpiresAt:       transactionExpiry(payload.Method, time.Now()),
+		MerchantProfile: merchantProfile,
+		PaymentIntentID: intent.ID,
+	})
+	if err != nil {
+		log.Printf("failed to create gateway transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+
+	// Hand off to the provider registered for this method
+	result, err := provider.Initiate(ctx, InitiateRequest{
+		Transaction: gatewayTxn,
+		Payload:     payload,
+	})
+	if err != nil && !errors.Is(err, errInitiateUnknown) {
+		// Nothing reached the gateway; the transaction rolls back with tx,
+		// so there is no row to record the event against
+		log.Printf("%s initiate failed for %s: %v", payload.Method, txnRefNo, err)
+		return nil, err
+	}
+
+	// The event commits with the transaction, including a failed submit
+	if result.Exchange.Request != nil {
+		s.recordEvent(ctx, paymentQ, gatewayTxn, gatewayEvent{
+			Gateway:      provider.Gateway().Name(),
+			Kind:         GatewayEventInitiate,
+			Exchange:     result.Exchange,
+			ResponseCode: result.ResponseCode,
+			RRN:          result.RRN,
+			Err:          err,
+		})
+	}
+	if err != nil {
+		// The gateway may have taken the payment; the transaction commits
+		// as UNKNOWN and the reconciler settles it by inquiry
+		log.Printf("%s initiate for %s has no definite answer, leaving it UNKNOWN: %v", payload.Method, txnRefNo, err)
+		result.Status = gateway.StatusUnknown
+	}
+
+	return s.applyInitiateResult(ctx, tx, gatewayTxn, payload, result)
+}
+
+// settleIntent moves the intent of a transaction that just became final to
+// SUCCEEDED or FAILED, and runs the completion handler of a paid intent
+func (s *Service) settleIntent(ctx context.Context, tx pgx.Tx, gatewayTxn payment.GikiWalletGatewayTransaction) error {
+	state := IntentFailed
+	if gatewayTxn.Status == payment.CurrentStatus(PaymentStatusSuccess) {
+		state = IntentSucceeded
+	}
+
+	intent, err := s.transitionIntent(ctx, s.q.WithTx(tx), gatewayTxn.PaymentIntentID, state)
+	if errors.Is(err, errIntentStateChanged) {
+		if state == IntentSucceeded {
+			log.Printf("DUPLICATE PAYMENT: transaction %s (%s) paid intent %s, which was already paid; refund one of them",
+				gatewayTxn.TxnRefNo, gatewayTxn.Amount, gatewayTxn.PaymentIntentID)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if state == IntentSucceeded {
+		s.fulfillIntent(ctx, tx, intent)
+	}
+	return nil
+}
+
+// fulfillIntent runs the completion handler of a SUCCEEDED intent in a
+// savepoint of tx. A failing handler never undoes the payment itself: its
+// writes are rolled back, the failure is recorded and the intent is retried.
+func (s *Service) fulfillIntent(ctx context.Context, tx pgx.Tx, intent payment.GikiWalletPaymentIntent) {
+	handler, ok := s.purposes.Handler(IntentPurpose(intent.Purpose))
+	if !ok {
+		log.Printf("no handler for %s intents; intent %s stays SUCCEEDED", intent.Purpose, intent.ID)
+		return
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin fulfillment of intent %s: %v", intent.ID, err)
+		return
+	}
+	defer savepoint.Rollback(ctx)
+
+	err = handler.Fulfill(ctx, savepoint, *toPaymentIntent(intent))
+	if err == nil {
+		_, err = s.q.WithTx(savepoint).MarkPaymentIntentFulfilled(ctx, intent.ID)
+	}
+	if err == nil {
+		err = savepoint.Commit(ctx)
+	}
+	if err == nil {
+		log.Printf("intent %s (%s %s) fulfilled", intent.ID, intent.Purpose, intent.PurposeReference)
+		return
+	}
+
+	savepoint.Rollback(ctx)
+	log.Printf("fulfillment of intent %s (%s %s) failed (attempt %d): %v",
+		intent.ID, intent.Purpose, intent.PurposeReference, intent.FulfillmentAttempts+1, err)
+
+	recordErr := s.q.WithTx(tx).RecordFulfillmentFailure(ctx, payment.RecordFulfillmentFailureParams{
+		NextFulfillmentAt: pgtype.Timestamptz{Time: time.Now().Add(fulfillmentBackoff(intent.FulfillmentAttempts)), Valid: true},
+		LastError:         common.StringToText(err.Error()),
+		ID:                intent.ID,
+	})
+	if recordErr != nil {
+		log.Printf("failed to record fulfillment failure of intent %s: %v", intent.ID, recordErr)
+	}
+}
+
+// fulfillDueIntents retries up to batch SUCCEEDED intents whose handler failed
+// before. Each runs in its own database transaction; intents locked by another
+// replica are skipped.
+func (s *Service) fulfillDueIntents(ctx context.Context, batch int32) {
+	purposes := s.purposes.Purposes()
+	if len(purposes) == 0 {
+		return
+	}
+	names := make([]string, len(purposes))
+	for i, purpose := range purposes {
+		names[i] = string(purpose)
+	}
+
+	for i := int32(0); i < batch && ctx.Err() == nil; i++ {
+		if !s.fulfillNextIntent(ctx, names) {
+			return
+		}
+	}
+}
+
+// fulfillNextIntent claims and fulfills one due intent, reporting whether there was one
+func (s *Service) fulfillNextIntent(ctx context.Context, purposes []string) bool {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin intent fulfillment: %v", err)
+		return false
+	}
+	defer tx.Rollback(ctx)
+
+	intent, err := s.q.WithTx(tx).ClaimUnfulfilledIntent(ctx, purposes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	} else if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("failed to claim unfulfilled intent: %v", err)
+		}
+		return false
+	}
+
+	s.fulfillIntent(ctx, tx, intent)
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit fulfillment of intent %s: %v", intent.ID, err)
+		return false
+	}
+	return true
+}
+
+// transitionIntent moves an intent to state if it is in a state that may
+// precede it, returning errIntentStateChanged otherwise
+func (s *Service) transitionIntent(
+	ctx context.Context,
+	paymentQ *payment.Queries,
+	intentID uuid.UUID,
+	state IntentState,
+) (payment.GikiWalletPaymentIntent, error) {
+	from := intentTransitions[state]
+	fromStates := make([]string, len(from))
+	for i, previous := range from {
+		fromStates[i] = string(previous)
+	}
+
+	intent, err := paymentQ.TransitionPaymentIntent(ctx, payment.TransitionPaymentIntentParams{
+		State:      payment.PaymentIntentState(state),
+		ID:         intentID,
+		FromStates: fromStates,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return intent, fmt.Errorf("%w: intent %s cannot become %s", errIntentStateChanged, intentID, state)
+	} else if err != nil {
+		log.Printf("failed to move intent %s to %s: %v", intentID, state, err)
+		return intent, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+	return intent, nil
+}
+
+// =============================================================================
+// HELPERS - Payment Intents
+// =============================================================================
+
+// canTransition reports whether an intent in from may move to to
+func canTransition(from, to IntentState) bool {
+	for _, previous := range intentTransitions[to] {
+		if previous == from {
+			return true
+		}
+	}
+	return false
+}
+
+// fulfillmentBackoff is the delay before retrying a completion handler that
+// has failed attempts times: 30s doubling up to an hour
+func fulfillmentBackoff(attempts int32) time.Duration {
+	delay := 30 * time.Second
+	for i := int32(0); i < attempts && delay < maxFulfillmentBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxFulfillmentBackoff)
+}
+
+func toPaymentIntent(intent payment.GikiWalletPaymentIntent) *PaymentIntent {
+	result := &PaymentIntent{
+		ID:                  intent.ID,
+		Purpose:             IntentPurpose(intent.Purpose),
+		PurposeReference:    intent.PurposeReference,
+		Amount:              intent.Amount,
+		PayerID:             intent.PayerID,
+		State:               IntentState(intent.State),
+		FulfillmentAttempts: intent.FulfillmentAttempts,
+		LastError:           common.TextToString(intent.LastError),
+		CreatedAt:           intent.CreatedAt,
+		UpdatedAt:           intent.UpdatedAt,
+	}
+	if len(intent.Metadata) > 0 {
+		if err := json.Unmarshal(intent.Metadata, &result.Metadata); err != nil {
+			log.Printf("failed to decode metadata of intent %s: %v", intent.ID, err)
+		}
+	}
+	if intent.FulfilledAt.Valid {
+		fulfilledAt := intent.FulfilledAt.Time
+		result.FulfilledAt = &fulfilledAt
+	}
+	return result
+}
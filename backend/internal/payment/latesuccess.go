@@ -0,0 +1,103 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Late Successes
+// =============================================================================
+
+// ListLateSuccesses returns FAILED transactions that were later paid, newest first
+func (s *Service) ListLateSuccesses(ctx context.Context, limit, offset int32) ([]LateSuccess, error) {
+	rows, err := s.q.ListLateSuccesses(ctx, payment.ListLateSuccessesParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("failed to list late successes: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	lateSuccesses := make([]LateSuccess, 0, len(rows))
+	for _, row := range rows {
+		lateSuccesses = append(lateSuccesses, LateSuccess{
+			ID:                   row.ID,
+			TxnRefNo:             row.TxnRefNo,
+			PaymentMethod:        PaymentMethod(row.PaymentMethod),
+			Amount:               row.Amount,
+			Source:               GatewayEventKind(row.Source),
+			PreviousResponseCode: common.TextToString(row.PreviousResponseCode),
+			ResponseCode:         common.TextToString(row.ResponseCode),
+			GatewayRRN:           common.TextToString(row.GatewayRrn),
+			FailedAt:             row.FailedAt,
+			CreatedAt:            row.CreatedAt,
+		})
+	}
+	return lateSuccesses, nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Late Successes
+// =============================================================================
+
+// applyLateSuccess moves a FAILED transaction to SUCCESS because the gateway
+// has since reported it paid, e.g. a customer who approved after expiry.
+//
+// This is the only path out of FAILED. The status change, its audit row and
+// the payment intent's success are written in the caller's database
+// transaction, like any other finalization, and commit or roll back with it.
+func (s *Service) applyLateSuccess(
+	ctx context.Context,
+	tx pgx.Tx,
+	paymentQ *payment.Queries,
+	failed payment.GikiWalletGatewayTransaction,
+	outcome gatewayOutcome,
+) (payment.GikiWalletGatewayTransaction, error) {
+	updated, err := paymentQ.MarkLateSuccess(ctx, payment.MarkLateSuccessParams{
+		GatewayRrn:   common.StringToText(outcome.RRN),
+		ResponseCode: common.StringToText(outcome.ResponseCode),
+		RawResponse:  marshalPayload(outcome.Response),
+		TxnRefNo:     failed.TxnRefNo,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another path got there first
+		current, err := paymentQ.GetTransactionByTxnRefNo(ctx, failed.TxnRefNo)
+		if err != nil {
+			log.Printf("failed to reload transaction %s: %v", failed.TxnRefNo, err)
+			return failed, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+		return current, nil
+	} else if err != nil {
+		log.Printf("failed to mark late success for %s: %v", failed.TxnRefNo, err)
+		return failed, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	_, err = paymentQ.RecordLateSuccess(ctx, payment.RecordLateSuccessParams{
+		GatewayTransactionID: failed.ID,
+		Source:               payment.GatewayEventKind(outcome.Source),
+		PreviousResponseCode: failed.ResponseCode,
+		ResponseCode:         updated.ResponseCode,
+		GatewayRrn:           updated.GatewayRrn,
+		FailedAt:             failed.UpdatedAt,
+	})
+	if err != nil {
+		log.Printf("failed to record late success for %s: %v", failed.TxnRefNo, err)
+		return failed, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	if err := s.settleIntent(ctx, tx, updated); err != nil {
+		return failed, err
+	}
+
+	log.Printf("LATE SUCCESS: transaction %s (%s) was FAILED since %s and is now SUCCESS per %s, rrn=%q",
+		failed.TxnRefNo, failed.Amount, failed.UpdatedAt.In(common.PKT).Format("2006-01-02 15:04:05"), outcome.Source, common.TextToString(updated.GatewayRrn))
+	return updated, nil
+}
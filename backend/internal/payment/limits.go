@@ -0,0 +1,231 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrAmountBelowMinimum Per-transaction limits (400)
+	ErrAmountBelowMinimum = errors.New("top-up amount is below the minimum")
+	ErrAmountAboveMaximum = errors.New("top-up amount is above the maximum")
+
+	// ErrDailyLimitExceeded Period caps (403)
+	ErrDailyLimitExceeded   = errors.New("daily top-up limit reached")
+	ErrMonthlyLimitExceeded = errors.New("monthly top-up limit reached")
+
+	// ErrTooManyAttempts Velocity (429)
+	ErrTooManyAttempts = errors.New("too many top-up attempts")
+
+	// ErrTooManyPending Unsettled top-ups (409)
+	ErrTooManyPending = errors.New("too many top-ups still pending")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// TopUpLimits bounds what a user may top up. A zero cap, attempt or pending
+// limit means no limit; amounts must always be positive.
+//
+// Daily and monthly caps count SUCCESS, PENDING and UNKNOWN transactions, since
+// anything not yet failed may still be charged.
+type TopUpLimits struct {
+	MinPerTransaction  money.Money               // smallest single top-up
+	MaxPerTransaction  money.Money               // largest single top-up
+	DailyCap           money.Money               // per user per calendar day
+	MonthlyCap         money.Money               // per user per calendar month
+	UserTypes          map[string]UserTypeLimits // overrides by users.user_type
+	MaxAttemptsPerHour int64                     // initiates in the last hour, failed ones included
+	MaxPending         int64                     // PENDING/UNKNOWN transactions at once
+	Location           *time.Location            // where calendar days start, Pakistan time by default
+}
+
+// UserTypeLimits replaces the default caps for one user type; zero keeps the default
+type UserTypeLimits struct {
+	DailyCap   money.Money
+	MonthlyCap money.Money
+}
+
+// topUpUsage is what a user has already done in the current windows
+type topUpUsage struct {
+	UserType         string
+	DailyTotal       money.Money
+	MonthlyTotal     money.Money
+	AttemptsLastHour int64
+	PendingCount     int64
+}
+
+// limitCheck is the outcome of one rule, logged for audit whether it passed or not
+type limitCheck struct {
+	Rule     string
+	Limit    string
+	Observed string
+	Err      error
+}
+
+// =============================================================================
+// LIMIT CHECKS
+// =============================================================================
+
+// checkAmountLimits applies the per-transaction bounds, which need no history
+func (l TopUpLimits) checkAmountLimits(amount money.Money) []limitCheck {
+	checks := []limitCheck{}
+
+	if !l.MinPerTransaction.IsZero() {
+		check := limitCheck{Rule: "min_per_transaction", Limit: l.MinPerTransaction.String(), Observed: amount.String()}
+		if below, err := amount.Cmp(l.MinPerTransaction); err != nil || below < 0 {
+			check.Err = fmt.Errorf("%w: %s requested, minimum is %s", ErrAmountBelowMinimum, amount, l.MinPerTransaction)
+		}
+		checks = append(checks, check)
+	}
+
+	if !l.MaxPerTransaction.IsZero() {
+		check := limitCheck{Rule: "max_per_transaction", Limit: l.MaxPerTransaction.String(), Observed: amount.String()}
+		if above, err := amount.Cmp(l.MaxPerTransaction); err != nil || above > 0 {
+			check.Err = fmt.Errorf("%w: %s requested, maximum is %s", ErrAmountAboveMaximum, amount, l.MaxPerTransaction)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkUsageLimits applies the caps that depend on what the user already did
+func (l TopUpLimits) checkUsageLimits(amount money.Money, usage topUpUsage) []limitCheck {
+	dailyCap, monthlyCap := l.capsFor(usage.UserType)
+	checks := []limitCheck{}
+
+	if !dailyCap.IsZero() {
+		checks = append(checks, capCheck("daily_cap", ErrDailyLimitExceeded, dailyCap, usage.DailyTotal, amount))
+	}
+	if !monthlyCap.IsZero() {
+		checks = append(checks, capCheck("monthly_cap", ErrMonthlyLimitExceeded, monthlyCap, usage.MonthlyTotal, amount))
+	}
+
+	if l.MaxAttemptsPerHour > 0 {
+		check := limitCheck{Rule: "attempts_per_hour", Limit: fmt.Sprint(l.MaxAttemptsPerHour), Observed: fmt.Sprint(usage.AttemptsLastHour)}
+		if usage.AttemptsLastHour >= l.MaxAttemptsPerHour {
+			check.Err = fmt.Errorf("%w: %d in the last hour, limit is %d", ErrTooManyAttempts, usage.AttemptsLastHour, l.MaxAttemptsPerHour)
+		}
+		checks = append(checks, check)
+	}
+
+	if l.MaxPending > 0 {
+		check := limitCheck{Rule: "max_pending", Limit: fmt.Sprint(l.MaxPending), Observed: fmt.Sprint(usage.PendingCount)}
+		if usage.PendingCount >= l.MaxPending {
+			check.Err = fmt.Errorf("%w: %d pending, limit is %d", ErrTooManyPending, usage.PendingCount, l.MaxPending)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// capsFor returns the daily and monthly caps for a user type
+func (l TopUpLimits) capsFor(userType string) (money.Money, money.Money) {
+	dailyCap, monthlyCap := l.DailyCap, l.MonthlyCap
+	if override, ok := l.UserTypes[userType]; ok {
+		if !override.DailyCap.IsZero() {
+			dailyCap = override.DailyCap
+		}
+		if !override.MonthlyCap.IsZero() {
+			monthlyCap = override.MonthlyCap
+		}
+	}
+	return dailyCap, monthlyCap
+}
+
+// windows returns the starts of the current day, month and hour at now
+func (l TopUpLimits) windows(now time.Time) (day, month, hour time.Time) {
+	location := l.Location
+	if location == nil {
+		location = common.PKT
+	}
+	local := now.In(location)
+	day = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+	month = time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, location)
+	hour = now.Add(-time.Hour)
+	return day, month, hour
+}
+
+// capCheck fails when used + amount would go over limit
+func capCheck(rule string, sentinel error, limit, used, amount money.Money) limitCheck {
+	check := limitCheck{Rule: rule, Limit: limit.String(), Observed: used.String()}
+
+	total, err := used.Add(amount)
+	if err != nil {
+		check.Err = fmt.Errorf("%w: %v", sentinel, err)
+		return check
+	}
+	if over, err := total.Cmp(limit); err != nil || over > 0 {
+		check.Err = fmt.Errorf("%w: %s used of %s, %s requested", sentinel, used, limit, amount)
+	}
+	return check
+}
+
+// =============================================================================
+// SERVICE HELPERS
+// =============================================================================
+
+// enforceUsageLimits loads the user's recent top-ups and applies the caps,
+// returning the usage for callers that route on the user type. The caller
+// holds the user's top-up lock, so concurrent initiates cannot both squeeze
+// under a cap.
+func (s *Service) enforceUsageLimits(ctx context.Context, paymentQ *payment.Queries, userID uuid.UUID, amount money.Money) (topUpUsage, error) {
+	day, month, hour := s.limits.windows(time.Now())
+	windowStart := month
+	if hour.Before(windowStart) {
+		windowStart = hour
+	}
+
+	row, err := paymentQ.GetTopUpUsage(ctx, payment.GetTopUpUsageParams{
+		DayStart:    day,
+		MonthStart:  month,
+		HourStart:   hour,
+		WindowStart: windowStart,
+		UserID:      userID,
+	})
+	if err != nil {
+		log.Printf("failed to load top-up usage for %s: %v", userID, err)
+		return topUpUsage{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	usage := topUpUsage{
+		UserType:         row.UserType,
+		DailyTotal:       money.Paisa(row.DailyTotal),
+		MonthlyTotal:     money.Paisa(row.MonthlyTotal),
+		AttemptsLastHour: row.AttemptsLastHour,
+		PendingCount:     row.PendingCount,
+	}
+	return usage, auditLimitChecks(userID.String(), amount, s.limits.checkUsageLimits(amount, usage))
+}
+
+// auditLimitChecks logs every check and returns the first failure
+func auditLimitChecks(subject string, amount money.Money, checks []limitCheck) error {
+	var firstErr error
+	for _, check := range checks {
+		result := "pass"
+		if check.Err != nil {
+			result = "deny"
+			if firstErr == nil {
+				firstErr = check.Err
+			}
+		}
+		log.Printf("topup limit audit: user=%s rule=%s amount=%q limit=%q observed=%q result=%s",
+			subject, check.Rule, amount, check.Limit, check.Observed, result)
+	}
+	return firstErr
+}
@@ -1,12 +1,25 @@
 package payment
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/money"
+)
 
 type PaymentMethod string
 
 const (
-	PaymentMethodMWallet PaymentMethod = "MWALLET"
-	PaymentMethodCard    PaymentMethod = "CARD"
+	PaymentMethodMWallet   PaymentMethod = "MWALLET"
+	PaymentMethodCard      PaymentMethod = "CARD"
+	PaymentMethodEasypaisa PaymentMethod = "EASYPAISA"
+
+	// PaymentMethodBankTransfer is settled by an admin checking the receipt, not by a gateway
+	PaymentMethodBankTransfer PaymentMethod = "BANK_TRANSFER"
 )
 
 type PaymentStatus string
@@ -21,25 +34,55 @@ const (
 // TopUpRequest Frontend → backend
 type TopUpRequest struct {
 	IdempotencyKey uuid.UUID     `json:"idempotency_key"`
-	Amount         int64         `json:"amount"` // smallest unit (e.g., paisa)
+	Amount         money.Money   `json:"amount"`
 	Method         PaymentMethod `json:"method"`
 	PhoneNumber    string        `json:"phone_number,omitempty"`
 	CNICLast6      string        `json:"cnic_last6,omitempty"`
 }
 
+// UnmarshalJSON also takes the legacy body, whose amount is a bare number of
+// whole rupees as clients sent it before amounts carried their currency
+func (r *TopUpRequest) UnmarshalJSON(data []byte) error {
+	type topUpRequest TopUpRequest
+	var wire struct {
+		topUpRequest
+		Amount json.RawMessage `json:"amount"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*r = TopUpRequest(wire.topUpRequest)
+
+	raw := strings.TrimSpace(string(wire.Amount))
+	switch {
+	case raw == "" || raw == "null":
+		return nil
+	case strings.HasPrefix(raw, "{"):
+		return json.Unmarshal(wire.Amount, &r.Amount)
+	}
+
+	rupees, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: legacy amount must be whole rupees, got %s", money.ErrInvalidAmount, raw)
+	}
+	r.Amount, err = money.Rupees(rupees)
+	return err
+}
+
 // TopUpResult Backend → frontend
 type TopUpResult struct {
 	ID            uuid.UUID     `json:"id"`         // gateway_transactions.id
 	TxnRefNo      string        `json:"txn_ref_no"` // gateway_transactions.txn_ref_no
 	Status        PaymentStatus `json:"status"`
-	Message       string        `json:"message,omitempty"`
+	Message       string        `json:"message,omitempty"` // in the request's Accept-Language where the gateway code is known
+	RetryAllowed  bool          `json:"retry_allowed,omitempty"`
 	PaymentMethod PaymentMethod `json:"paymentMethod"`
 
 	// CARD redirect flow
 	Redirect *RedirectPayload `json:"redirect,omitempty"`
 
 	// Useful for UI
-	Amount int64 `json:"amount,omitempty"`
+	Amount money.Money `json:"amount"`
 }
 
 type RedirectPayload struct {
@@ -47,3 +90,314 @@ type RedirectPayload struct {
 	Fields    map[string]string `json:"fields"`               // pp_* fields including pp_SecureHash
 	ReturnURL string            `json:"return_url,omitempty"` // optional: for debugging/UI
 }
+
+// GatewayHealth Backend → frontend: whether a gateway is taking payments right now
+type GatewayHealth struct {
+	Gateway         string          `json:"gateway"`
+	MerchantProfile string          `json:"merchant_profile"` // each profile has its own client and breaker
+	Methods         []PaymentMethod `json:"methods"`
+	Available       bool            `json:"available"`
+	State           string          `json:"state"`              // circuit breaker state
+	RetryAt         *time.Time      `json:"retry_at,omitempty"` // when an unavailable gateway is tried again
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending RefundStatus = "PENDING"
+	RefundStatusSuccess RefundStatus = "SUCCESS"
+	RefundStatusFailed  RefundStatus = "FAILED"
+	RefundStatusUnknown RefundStatus = "UNKNOWN" // sent, but the gateway's answer was lost
+)
+
+// RefundCreateRequest Admin → backend
+type RefundCreateRequest struct {
+	IdempotencyKey uuid.UUID   `json:"idempotency_key"`
+	Amount         money.Money `json:"amount"` // at most what is left unrefunded
+	Reason         string      `json:"reason"`
+}
+
+// RefundResult Backend → admin
+type RefundResult struct {
+	ID              uuid.UUID    `json:"id"`
+	TxnRefNo        string       `json:"txn_ref_no"`
+	Amount          money.Money  `json:"amount"`
+	Status          RefundStatus `json:"status"`
+	Reason          string       `json:"reason"`
+	RequestedBy     uuid.UUID    `json:"requested_by"`
+	Attempts        int32        `json:"attempts"`
+	ResponseCode    string       `json:"response_code,omitempty"`
+	ResponseMessage string       `json:"response_message,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	ProcessedAt     *time.Time   `json:"processed_at,omitempty"`
+}
+
+// RefundHold Payment → refund ledger: the money a refund takes back from
+// what the refunded payment paid for
+type RefundHold struct {
+	RefundID uuid.UUID
+	Purpose  IntentPurpose
+	PayerID  uuid.UUID
+	Amount   money.Money
+}
+
+// ResolveRefundRequest Admin → backend: settle an UNKNOWN refund after checking with the gateway
+type ResolveRefundRequest struct {
+	Status RefundStatus `json:"status"` // SUCCESS, FAILED, or PENDING to submit it again
+	Reason string       `json:"reason"`
+}
+
+type DiscrepancyKind string
+
+const (
+	DiscrepancyPaidNotSuccess    DiscrepancyKind = "PAID_NOT_SUCCESS"
+	DiscrepancySuccessNotSettled DiscrepancyKind = "SUCCESS_NOT_SETTLED"
+	DiscrepancyAmountMismatch    DiscrepancyKind = "AMOUNT_MISMATCH"
+)
+
+// SettlementRun Backend → admin: one imported settlement file
+type SettlementRun struct {
+	ID               uuid.UUID  `json:"id"`
+	Gateway          string     `json:"gateway"`
+	SourceName       string     `json:"source_name"`
+	ImportedBy       *uuid.UUID `json:"imported_by,omitempty"` // nil for CLI imports
+	PeriodStart      time.Time  `json:"period_start"`
+	PeriodEnd        time.Time  `json:"period_end"`
+	RowCount         int32      `json:"row_count"`
+	MatchedCount     int32      `json:"matched_count"`
+	DiscrepancyCount int32      `json:"discrepancy_count"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// SettlementDiscrepancy Backend → admin: one mismatch found by a run
+type SettlementDiscrepancy struct {
+	ID             uuid.UUID       `json:"id"`
+	Kind           DiscrepancyKind `json:"kind"`
+	TxnRefNo       string          `json:"txn_ref_no,omitempty"`
+	GatewayRRN     string          `json:"gateway_rrn,omitempty"`
+	LocalStatus    PaymentStatus   `json:"local_status,omitempty"`
+	LocalAmount    *money.Money    `json:"local_amount,omitempty"`
+	GatewayAmount  *money.Money    `json:"gateway_amount,omitempty"`
+	ResolvedAt     *time.Time      `json:"resolved_at,omitempty"`
+	ResolvedBy     *uuid.UUID      `json:"resolved_by,omitempty"`
+	ResolutionNote string          `json:"resolution_note,omitempty"`
+}
+
+// SettlementReport Backend → admin: a run with its discrepancies
+type SettlementReport struct {
+	Run           SettlementRun           `json:"run"`
+	Discrepancies []SettlementDiscrepancy `json:"discrepancies"`
+}
+
+// ResolveDiscrepancyRequest Admin → backend
+type ResolveDiscrepancyRequest struct {
+	Note string `json:"note"`
+}
+
+type GatewayEventKind string
+
+const (
+	GatewayEventInitiate GatewayEventKind = "INITIATE"
+	GatewayEventInquiry  GatewayEventKind = "INQUIRY"
+	GatewayEventCallback GatewayEventKind = "CALLBACK"
+	GatewayEventIPN      GatewayEventKind = "IPN"
+	GatewayEventRefund   GatewayEventKind = "REFUND"
+	GatewayEventManual   GatewayEventKind = "MANUAL" // admin action, not a gateway exchange
+)
+
+// GatewayEvent Backend → admin: one exchange with the gateway, secrets redacted
+type GatewayEvent struct {
+	ID           uuid.UUID        `json:"id"`
+	Kind         GatewayEventKind `json:"kind"`
+	Gateway      string           `json:"gateway"`
+	Request      json.RawMessage  `json:"request,omitempty"`
+	Response     json.RawMessage  `json:"response,omitempty"`
+	ResponseCode string           `json:"response_code,omitempty"`
+	GatewayRRN   string           `json:"gateway_rrn,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+}
+
+// TransactionTimeline Backend → admin: a transaction and every gateway exchange about it
+type TransactionTimeline struct {
+	ID              uuid.UUID      `json:"id"`
+	TxnRefNo        string         `json:"txn_ref_no"`
+	BillRefID       string         `json:"bill_ref_id"`
+	UserID          uuid.UUID      `json:"user_id"`
+	PaymentMethod   PaymentMethod  `json:"payment_method"`
+	Status          PaymentStatus  `json:"status"`
+	Amount          money.Money    `json:"amount"`
+	GatewayRRN      string         `json:"gateway_rrn,omitempty"`
+	ResponseCode    string         `json:"response_code,omitempty"`
+	MerchantProfile string         `json:"merchant_profile"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	Events          []GatewayEvent `json:"events"`
+}
+
+// PaymentSearch Admin → backend: payments console filters; zero fields match everything
+type PaymentSearch struct {
+	UserID    *uuid.UUID
+	Statuses  []PaymentStatus
+	Method    PaymentMethod
+	From      time.Time // created at or after, inclusive
+	To        time.Time // created before, exclusive
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	TxnRefNo  string
+	RRN       string
+	Cursor    string // NextCursor of the previous page
+	Limit     int32
+}
+
+// AdminPayment Backend → admin: one gateway transaction in the payments console
+type AdminPayment struct {
+	ID              uuid.UUID     `json:"id"`
+	UserID          uuid.UUID     `json:"user_id"`
+	UserName        string        `json:"user_name"`
+	UserEmail       string        `json:"user_email"`
+	TxnRefNo        string        `json:"txn_ref_no"`
+	BillRefID       string        `json:"bill_ref_id"`
+	PaymentMethod   PaymentMethod `json:"payment_method"`
+	Status          PaymentStatus `json:"status"`
+	Amount          money.Money   `json:"amount"`
+	GatewayRRN      string        `json:"gateway_rrn,omitempty"`
+	ResponseCode    string        `json:"response_code,omitempty"`
+	InquiryAttempts int32         `json:"inquiry_attempts"`
+	MerchantProfile string        `json:"merchant_profile"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// PaymentTotals Backend → admin: count and amount of everything matching a search
+type PaymentTotals struct {
+	Count    int64                         `json:"count"`
+	Amount   money.Money                   `json:"amount"`
+	ByStatus map[PaymentStatus]StatusTotal `json:"by_status"`
+}
+
+type StatusTotal struct {
+	Count  int64       `json:"count"`
+	Amount money.Money `json:"amount"`
+}
+
+// PaymentSearchResult Backend → admin: one page of a search
+type PaymentSearchResult struct {
+	Payments   []AdminPayment `json:"payments"`
+	Totals     PaymentTotals  `json:"totals"`
+	NextCursor string         `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// ResolveTransactionRequest Admin → backend: settle a stuck UNKNOWN transaction by hand
+type ResolveTransactionRequest struct {
+	Status PaymentStatus `json:"status"` // SUCCESS or FAILED
+	Reason string        `json:"reason"`
+}
+
+// LateSuccess Backend → admin: a FAILED transaction the gateway later reported as paid
+type LateSuccess struct {
+	ID                   uuid.UUID        `json:"id"`
+	TxnRefNo             string           `json:"txn_ref_no"`
+	PaymentMethod        PaymentMethod    `json:"payment_method"`
+	Amount               money.Money      `json:"amount"`
+	Source               GatewayEventKind `json:"source"` // exchange that reported the success
+	PreviousResponseCode string           `json:"previous_response_code,omitempty"`
+	ResponseCode         string           `json:"response_code,omitempty"`
+	GatewayRRN           string           `json:"gateway_rrn,omitempty"`
+	FailedAt             time.Time        `json:"failed_at"`
+	CreatedAt            time.Time        `json:"created_at"`
+}
+
+type BankTransferReview string
+
+const (
+	BankTransferPending  BankTransferReview = "PENDING"
+	BankTransferApproved BankTransferReview = "APPROVED"
+	BankTransferRejected BankTransferReview = "REJECTED"
+)
+
+// BankTransferSubmission Frontend → backend: a top-up paid by bank transfer.
+// Sent as multipart form fields with the receipt image as "receipt".
+type BankTransferSubmission struct {
+	IdempotencyKey uuid.UUID
+	Amount         money.Money
+	BankReference  string
+}
+
+// BankTransfer Backend → frontend/admin: a bank transfer top-up and its review
+type BankTransfer struct {
+	ID                 uuid.UUID          `json:"id"`
+	TxnRefNo           string             `json:"txn_ref_no"` // status is also available through /payments/{txnRefNo}
+	UserID             uuid.UUID          `json:"user_id"`
+	UserName           string             `json:"user_name,omitempty"`  // admin queue only
+	UserEmail          string             `json:"user_email,omitempty"` // admin queue only
+	Amount             money.Money        `json:"amount"`
+	BankReference      string             `json:"bank_reference"`
+	ReceiptContentType string             `json:"receipt_content_type"`
+	ReviewStatus       BankTransferReview `json:"review_status"`
+	ReviewedBy         *uuid.UUID         `json:"reviewed_by,omitempty"`
+	ReviewReason       string             `json:"review_reason,omitempty"`
+	ReviewedAt         *time.Time         `json:"reviewed_at,omitempty"`
+	CreatedAt          time.Time          `json:"created_at"`
+}
+
+// ReviewBankTransferRequest Admin → backend: approve or reject a bank transfer
+type ReviewBankTransferRequest struct {
+	Decision BankTransferReview `json:"decision"` // APPROVED or REJECTED
+	Reason   string             `json:"reason"`   // required when rejecting
+}
+
+// IntentPurpose names what a payment intent pays for. Each purpose has one
+// completion handler in the PurposeRegistry.
+type IntentPurpose string
+
+const (
+	// PurposeTopUp adds the amount to the payer's wallet
+	PurposeTopUp IntentPurpose = "TOPUP"
+)
+
+type IntentState string
+
+const (
+	IntentRequiresPayment IntentState = "REQUIRES_PAYMENT" // created, no attempt yet
+	IntentProcessing      IntentState = "PROCESSING"       // a gateway transaction is in flight
+	IntentFailed          IntentState = "FAILED"           // last attempt failed; another may be made
+	IntentSucceeded       IntentState = "SUCCEEDED"        // paid, completion handler not yet done
+	IntentFulfilled       IntentState = "FULFILLED"        // paid and completed
+	IntentCancelled       IntentState = "CANCELLED"        // withdrawn by the owning module
+)
+
+// PaymentIntent Backend → frontend/modules: something a user is asked to pay for
+type PaymentIntent struct {
+	ID                  uuid.UUID         `json:"id"`
+	Purpose             IntentPurpose     `json:"purpose"`
+	PurposeReference    string            `json:"purpose_reference"` // owning module's id, e.g. a ticket id
+	Amount              money.Money       `json:"amount"`
+	PayerID             uuid.UUID         `json:"payer_id"`
+	State               IntentState       `json:"state"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+	FulfillmentAttempts int32             `json:"fulfillment_attempts"`
+	LastError           string            `json:"last_error,omitempty"`
+	FulfilledAt         *time.Time        `json:"fulfilled_at,omitempty"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// CreateIntentRequest Module → payment: ask a user to pay for something.
+// Metadata carries purpose-specific details, e.g. a guest's name on a ticket.
+type CreateIntentRequest struct {
+	Purpose          IntentPurpose
+	PurposeReference string
+	Amount           money.Money
+	PayerID          uuid.UUID
+	Metadata         map[string]string
+}
+
+// PayIntentRequest Frontend → backend: pay an intent through a gateway.
+// The amount is the intent's.
+type PayIntentRequest struct {
+	IdempotencyKey uuid.UUID     `json:"idempotency_key"`
+	Method         PaymentMethod `json:"method"`
+	PhoneNumber    string        `json:"phone_number,omitempty"`
+	CNICLast6      string        `json:"cnic_last6,omitempty"`
+}
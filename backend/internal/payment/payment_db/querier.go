@@ -8,17 +8,76 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
-	ClearPollingStatus(ctx context.Context, txnRefNo string) error
+	//- refund worker
+	ClaimDueRefunds(ctx context.Context, arg ClaimDueRefundsParams) ([]GikiWalletRefundRequest, error)
+	//- reconciliation worker
+	ClaimDueTransactions(ctx context.Context, arg ClaimDueTransactionsParams) ([]GikiWalletGatewayTransaction, error)
+	//- expiry sweeper
+	ClaimExpiredTransactions(ctx context.Context, arg ClaimExpiredTransactionsParams) ([]GikiWalletGatewayTransaction, error)
+	ClaimUnfulfilledIntent(ctx context.Context, purposes []string) (GikiWalletPaymentIntent, error)
+	CompleteSettlementRun(ctx context.Context, arg CompleteSettlementRunParams) (GikiWalletSettlementRun, error)
+	//- bank transfer top-ups
+	CreateBankTransfer(ctx context.Context, arg CreateBankTransferParams) (GikiWalletBankTransfer, error)
 	CreateGatewayTransaction(ctx context.Context, arg CreateGatewayTransactionParams) (GikiWalletGatewayTransaction, error)
+	//- payment intents
+	CreatePaymentIntent(ctx context.Context, arg CreatePaymentIntentParams) (GikiWalletPaymentIntent, error)
+	CreateRefundRequest(ctx context.Context, arg CreateRefundRequestParams) (GikiWalletRefundRequest, error)
+	CreateSettlementDiscrepancy(ctx context.Context, arg CreateSettlementDiscrepancyParams) (GikiWalletSettlementDiscrepancy, error)
+	CreateSettlementRun(ctx context.Context, arg CreateSettlementRunParams) (GikiWalletSettlementRun, error)
+	FinalizeGatewayTransaction(ctx context.Context, arg FinalizeGatewayTransactionParams) (GikiWalletGatewayTransaction, error)
+	GetActivePaymentIntent(ctx context.Context, arg GetActivePaymentIntentParams) (GikiWalletPaymentIntent, error)
+	GetBankTransfer(ctx context.Context, id uuid.UUID) (GikiWalletBankTransfer, error)
+	GetBankTransferByTransaction(ctx context.Context, gatewayTransactionID uuid.UUID) (GikiWalletBankTransfer, error)
 	GetByIdempotencyKey(ctx context.Context, idempotencyKey uuid.UUID) (GikiWalletGatewayTransaction, error)
+	GetPaymentIntent(ctx context.Context, id uuid.UUID) (GikiWalletPaymentIntent, error)
+	GetPaymentIntentForUpdate(ctx context.Context, id uuid.UUID) (GikiWalletPaymentIntent, error)
 	GetPendingTransaction(ctx context.Context, userID uuid.UUID) (GikiWalletGatewayTransaction, error)
+	GetRefundByIdempotencyKey(ctx context.Context, idempotencyKey uuid.UUID) (GikiWalletRefundRequest, error)
+	GetRefundRequest(ctx context.Context, id uuid.UUID) (GikiWalletRefundRequest, error)
+	GetSettlementDiscrepancy(ctx context.Context, id uuid.UUID) (GikiWalletSettlementDiscrepancy, error)
+	GetSettlementRun(ctx context.Context, id uuid.UUID) (GikiWalletSettlementRun, error)
+	//- top-up limits
+	GetTopUpUsage(ctx context.Context, arg GetTopUpUsageParams) (GetTopUpUsageRow, error)
+	GetTransactionByGatewayRRN(ctx context.Context, gatewayRrn pgtype.Text) (GikiWalletGatewayTransaction, error)
+	GetTransactionByID(ctx context.Context, id uuid.UUID) (GikiWalletGatewayTransaction, error)
 	GetTransactionByTxnRefNo(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error)
-	UpdateGatewayTransactionStatus(ctx context.Context, arg UpdateGatewayTransactionStatusParams) error
-	//- update polling status
-	UpdatePollingStatus(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error)
+	GetTransactionForUpdate(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error)
+	ListBankTransfers(ctx context.Context, arg ListBankTransfersParams) ([]ListBankTransfersRow, error)
+	ListGatewayEvents(ctx context.Context, gatewayTransactionID uuid.UUID) ([]GikiWalletGatewayTransactionEvent, error)
+	ListIntentTransactions(ctx context.Context, paymentIntentID uuid.UUID) ([]GikiWalletGatewayTransaction, error)
+	ListLateSuccesses(ctx context.Context, arg ListLateSuccessesParams) ([]ListLateSuccessesRow, error)
+	ListRefundsForTransaction(ctx context.Context, gatewayTransactionID uuid.UUID) ([]GikiWalletRefundRequest, error)
+	ListSettlementDiscrepancies(ctx context.Context, runID uuid.UUID) ([]GikiWalletSettlementDiscrepancy, error)
+	ListSettlementRuns(ctx context.Context, arg ListSettlementRunsParams) ([]GikiWalletSettlementRun, error)
+	ListSuccessfulTransactionsBetween(ctx context.Context, arg ListSuccessfulTransactionsBetweenParams) ([]GikiWalletGatewayTransaction, error)
+	//- refund review
+	ListUnknownRefunds(ctx context.Context, arg ListUnknownRefundsParams) ([]GikiWalletRefundRequest, error)
+	//- late successes
+	MarkLateSuccess(ctx context.Context, arg MarkLateSuccessParams) (GikiWalletGatewayTransaction, error)
+	MarkPaymentIntentFulfilled(ctx context.Context, id uuid.UUID) (GikiWalletPaymentIntent, error)
+	MarkRefundHoldReleased(ctx context.Context, id uuid.UUID) error
+	MarkTransactionUnknown(ctx context.Context, id uuid.UUID) (GikiWalletGatewayTransaction, error)
+	RecordFulfillmentFailure(ctx context.Context, arg RecordFulfillmentFailureParams) error
+	//- gateway event history
+	RecordGatewayEvent(ctx context.Context, arg RecordGatewayEventParams) error
+	//- IPN notifications
+	RecordGatewayNotification(ctx context.Context, arg RecordGatewayNotificationParams) (GikiWalletGatewayNotification, error)
+	RecordLateSuccess(ctx context.Context, arg RecordLateSuccessParams) (GikiWalletGatewayLateSuccess, error)
+	RecordRefundAttempt(ctx context.Context, arg RecordRefundAttemptParams) (GikiWalletRefundRequest, error)
+	RescheduleInquiry(ctx context.Context, arg RescheduleInquiryParams) error
+	ResolveSettlementDiscrepancy(ctx context.Context, arg ResolveSettlementDiscrepancyParams) (GikiWalletSettlementDiscrepancy, error)
+	ResolveUnknownRefund(ctx context.Context, arg ResolveUnknownRefundParams) (GikiWalletRefundRequest, error)
+	ResolveUnknownTransaction(ctx context.Context, arg ResolveUnknownTransactionParams) (GikiWalletGatewayTransaction, error)
+	ReviewBankTransfer(ctx context.Context, arg ReviewBankTransferParams) (GikiWalletBankTransfer, error)
+	//- admin payments console
+	SearchGatewayTransactions(ctx context.Context, arg SearchGatewayTransactionsParams) ([]SearchGatewayTransactionsRow, error)
+	SumCommittedRefunds(ctx context.Context, gatewayTransactionID uuid.UUID) (int64, error)
+	SummarizeGatewayTransactions(ctx context.Context, arg SummarizeGatewayTransactionsParams) ([]SummarizeGatewayTransactionsRow, error)
+	TransitionPaymentIntent(ctx context.Context, arg TransitionPaymentIntentParams) (GikiWalletPaymentIntent, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,35 +7,97 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const clearPollingStatus = `-- name: ClearPollingStatus :exec
+const claimDueTransactions = `-- name: ClaimDueTransactions :many
+
 UPDATE giki_wallet.gateway_transactions
-SET is_polling = FALSE
-WHERE txn_ref_no = $1
+SET lease_owner = $1::text,
+    lease_expires_at = NOW() + $2::int * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM giki_wallet.gateway_transactions
+    WHERE status IN ('PENDING', 'UNKNOWN')
+        AND payment_method <> 'BANK_TRANSFER'
+        AND next_inquiry_at <= NOW()
+        AND expires_at > NOW()
+        AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    ORDER BY next_inquiry_at
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id
 `
 
-func (q *Queries) ClearPollingStatus(ctx context.Context, txnRefNo string) error {
-	_, err := q.db.Exec(ctx, clearPollingStatus, txnRefNo)
-	return err
+type ClaimDueTransactionsParams struct {
+	LeaseOwner   string `json:"lease_owner"`
+	LeaseSeconds int32  `json:"lease_seconds"`
+	BatchSize    int32  `json:"batch_size"`
+}
+
+// - reconciliation worker
+func (q *Queries) ClaimDueTransactions(ctx context.Context, arg ClaimDueTransactionsParams) ([]GikiWalletGatewayTransaction, error) {
+	rows, err := q.db.Query(ctx, claimDueTransactions, arg.LeaseOwner, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GikiWalletGatewayTransaction
+	for rows.Next() {
+		var i GikiWalletGatewayTransaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.IdempotencyKey,
+			&i.BillRefID,
+			&i.TxnRefNo,
+			&i.PaymentMethod,
+			&i.GatewayRrn,
+			&i.Status,
+			&i.Amount,
+			&i.RawResponse,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LeaseOwner,
+			&i.LeaseExpiresAt,
+			&i.NextInquiryAt,
+			&i.InquiryAttempts,
+			&i.ResponseCode,
+			&i.ExpiresAt,
+			&i.MerchantProfile,
+			&i.PaymentIntentID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const createGatewayTransaction = `-- name: CreateGatewayTransaction :one
-INSERT INTO giki_wallet.gateway_transactions(user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, status, amount)
-VALUES ($1, $2,$3,$4, $5, $6, $7)
-RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, is_polling, created_at, updated_at
+INSERT INTO giki_wallet.gateway_transactions(user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, status, amount, expires_at, merchant_profile, payment_intent_id)
+VALUES ($1, $2,$3,$4, $5, $6, $7, $8, $9, $10)
+RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id
 `
 
 type CreateGatewayTransactionParams struct {
-	UserID         uuid.UUID     `json:"user_id"`
-	IdempotencyKey uuid.UUID     `json:"idempotency_key"`
-	BillRefID      string        `json:"bill_ref_id"`
-	TxnRefNo       string        `json:"txn_ref_no"`
-	PaymentMethod  string        `json:"payment_method"`
-	Status         CurrentStatus `json:"status"`
-	Amount         int64         `json:"amount"`
+	UserID          uuid.UUID     `json:"user_id"`
+	IdempotencyKey  uuid.UUID     `json:"idempotency_key"`
+	BillRefID       string        `json:"bill_ref_id"`
+	TxnRefNo        string        `json:"txn_ref_no"`
+	PaymentMethod   string        `json:"payment_method"`
+	Status          CurrentStatus `json:"status"`
+	Amount          money.Money   `json:"amount"`
+	ExpiresAt       time.Time     `json:"expires_at"`
+	MerchantProfile string        `json:"merchant_profile"`
+	PaymentIntentID uuid.UUID     `json:"payment_intent_id"`
 }
 
 func (q *Queries) CreateGatewayTransaction(ctx context.Context, arg CreateGatewayTransactionParams) (GikiWalletGatewayTransaction, error) {
@@ -47,6 +109,64 @@ func (q *Queries) CreateGatewayTransaction(ctx context.Context, arg CreateGatewa
 		arg.PaymentMethod,
 		arg.Status,
 		arg.Amount,
+		arg.ExpiresAt,
+		arg.MerchantProfile,
+		arg.PaymentIntentID,
+	)
+	var i GikiWalletGatewayTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.IdempotencyKey,
+		&i.BillRefID,
+		&i.TxnRefNo,
+		&i.PaymentMethod,
+		&i.GatewayRrn,
+		&i.Status,
+		&i.Amount,
+		&i.RawResponse,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
+	)
+	return i, err
+}
+
+const finalizeGatewayTransaction = `-- name: FinalizeGatewayTransaction :one
+UPDATE giki_wallet.gateway_transactions
+SET status = $1,
+    gateway_rrn = COALESCE($2::varchar, gateway_rrn),
+    response_code = COALESCE($3::varchar, response_code),
+    raw_response = COALESCE($4::jsonb, raw_response),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE txn_ref_no = $5 AND status IN ('PENDING', 'UNKNOWN')
+RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id
+`
+
+type FinalizeGatewayTransactionParams struct {
+	Status       CurrentStatus `json:"status"`
+	GatewayRrn   pgtype.Text   `json:"gateway_rrn"`
+	ResponseCode pgtype.Text   `json:"response_code"`
+	RawResponse  []byte        `json:"raw_response"`
+	TxnRefNo     string        `json:"txn_ref_no"`
+}
+
+func (q *Queries) FinalizeGatewayTransaction(ctx context.Context, arg FinalizeGatewayTransactionParams) (GikiWalletGatewayTransaction, error) {
+	row := q.db.QueryRow(ctx, finalizeGatewayTransaction,
+		arg.Status,
+		arg.GatewayRrn,
+		arg.ResponseCode,
+		arg.RawResponse,
+		arg.TxnRefNo,
 	)
 	var i GikiWalletGatewayTransaction
 	err := row.Scan(
@@ -60,16 +180,23 @@ func (q *Queries) CreateGatewayTransaction(ctx context.Context, arg CreateGatewa
 		&i.Status,
 		&i.Amount,
 		&i.RawResponse,
-		&i.IsPolling,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
 	)
 	return i, err
 }
 
 const getByIdempotencyKey = `-- name: GetByIdempotencyKey :one
 
-SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, is_polling, created_at, updated_at FROM giki_wallet.gateway_transactions
+SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id FROM giki_wallet.gateway_transactions
 WHERE idempotency_key = $1
 `
 
@@ -87,16 +214,23 @@ func (q *Queries) GetByIdempotencyKey(ctx context.Context, idempotencyKey uuid.U
 		&i.Status,
 		&i.Amount,
 		&i.RawResponse,
-		&i.IsPolling,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
 	)
 	return i, err
 }
 
 const getPendingTransaction = `-- name: GetPendingTransaction :one
 
-SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, is_polling, created_at, updated_at FROM giki_wallet.gateway_transactions
+SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id FROM giki_wallet.gateway_transactions
 WHERE user_id = $1
     AND status IN ('PENDING', 'UNKNOWN')
 LIMIT 1
@@ -116,21 +250,87 @@ func (q *Queries) GetPendingTransaction(ctx context.Context, userID uuid.UUID) (
 		&i.Status,
 		&i.Amount,
 		&i.RawResponse,
-		&i.IsPolling,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
 	)
 	return i, err
 }
 
-const getTransactionByTxnRefNo = `-- name: GetTransactionByTxnRefNo :one
+const getTopUpUsage = `-- name: GetTopUpUsage :one
 
-SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, is_polling, created_at, updated_at from giki_wallet.gateway_transactions
-WHERE txn_ref_no = $1
+SELECT
+    u.user_type,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE t.status IN ('SUCCESS', 'PENDING', 'UNKNOWN') AND t.created_at >= $1::timestamptz
+    ), 0)::bigint AS daily_total,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE t.status IN ('SUCCESS', 'PENDING', 'UNKNOWN') AND t.created_at >= $2::timestamptz
+    ), 0)::bigint AS monthly_total,
+    COUNT(t.id) FILTER (WHERE t.created_at >= $3::timestamptz)::bigint AS attempts_last_hour,
+    COUNT(t.id) FILTER (
+        WHERE t.status IN ('PENDING', 'UNKNOWN') AND t.payment_method <> 'BANK_TRANSFER'
+    )::bigint AS pending_count
+FROM giki_wallet.users u
+LEFT JOIN giki_wallet.gateway_transactions t
+    ON t.user_id = u.id
+    AND (t.created_at >= $4::timestamptz OR t.status IN ('PENDING', 'UNKNOWN'))
+    -- Payments for tickets and other purposes are not top-ups
+    AND t.payment_intent_id IN (SELECT i.id FROM giki_wallet.payment_intents i WHERE i.purpose = 'TOPUP' AND i.payer_id = u.id)
+WHERE u.id = $5
+GROUP BY u.user_type
 `
 
-func (q *Queries) GetTransactionByTxnRefNo(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error) {
-	row := q.db.QueryRow(ctx, getTransactionByTxnRefNo, txnRefNo)
+type GetTopUpUsageParams struct {
+	DayStart    time.Time `json:"day_start"`
+	MonthStart  time.Time `json:"month_start"`
+	HourStart   time.Time `json:"hour_start"`
+	WindowStart time.Time `json:"window_start"`
+	UserID      uuid.UUID `json:"user_id"`
+}
+
+type GetTopUpUsageRow struct {
+	UserType         string `json:"user_type"`
+	DailyTotal       int64  `json:"daily_total"`
+	MonthlyTotal     int64  `json:"monthly_total"`
+	AttemptsLastHour int64  `json:"attempts_last_hour"`
+	PendingCount     int64  `json:"pending_count"`
+}
+
+// - top-up limits
+func (q *Queries) GetTopUpUsage(ctx context.Context, arg GetTopUpUsageParams) (GetTopUpUsageRow, error) {
+	row := q.db.QueryRow(ctx, getTopUpUsage,
+		arg.DayStart,
+		arg.MonthStart,
+		arg.HourStart,
+		arg.WindowStart,
+		arg.UserID,
+	)
+	var i GetTopUpUsageRow
+	err := row.Scan(
+		&i.UserType,
+		&i.DailyTotal,
+		&i.MonthlyTotal,
+		&i.AttemptsLastHour,
+		&i.PendingCount,
+	)
+	return i, err
+}
+
+const getTransactionByID = `-- name: GetTransactionByID :one
+SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id FROM giki_wallet.gateway_transactions
+WHERE id = $1
+`
+
+func (q *Queries) GetTransactionByID(ctx context.Context, id uuid.UUID) (GikiWalletGatewayTransaction, error) {
+	row := q.db.QueryRow(ctx, getTransactionByID, id)
 	var i GikiWalletGatewayTransaction
 	err := row.Scan(
 		&i.ID,
@@ -143,38 +343,64 @@ func (q *Queries) GetTransactionByTxnRefNo(ctx context.Context, txnRefNo string)
 		&i.Status,
 		&i.Amount,
 		&i.RawResponse,
-		&i.IsPolling,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
 	)
 	return i, err
 }
 
-const updateGatewayTransactionStatus = `-- name: UpdateGatewayTransactionStatus :exec
-UPDATE giki_wallet.gateway_transactions SET status = $1 WHERE txn_ref_no = $2
-`
+const getTransactionByTxnRefNo = `-- name: GetTransactionByTxnRefNo :one
 
-type UpdateGatewayTransactionStatusParams struct {
-	Status   CurrentStatus `json:"status"`
-	TxnRefNo string        `json:"txn_ref_no"`
-}
+SELECT id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id from giki_wallet.gateway_transactions
+WHERE txn_ref_no = $1
+`
 
-func (q *Queries) UpdateGatewayTransactionStatus(ctx context.Context, arg UpdateGatewayTransactionStatusParams) error {
-	_, err := q.db.Exec(ctx, updateGatewayTransactionStatus, arg.Status, arg.TxnRefNo)
-	return err
+func (q *Queries) GetTransactionByTxnRefNo(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error) {
+	row := q.db.QueryRow(ctx, getTransactionByTxnRefNo, txnRefNo)
+	var i GikiWalletGatewayTransaction
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.IdempotencyKey,
+		&i.BillRefID,
+		&i.TxnRefNo,
+		&i.PaymentMethod,
+		&i.GatewayRrn,
+		&i.Status,
+		&i.Amount,
+		&i.RawResponse,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
+	)
+	return i, err
 }
 
-const updatePollingStatus = `-- name: UpdatePollingStatus :one
-
+const markTransactionUnknown = `-- name: MarkTransactionUnknown :one
 UPDATE giki_wallet.gateway_transactions
-SET is_polling = TRUE
-WHERE txn_ref_no = $1 AND is_polling = FALSE
-RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, is_polling, created_at, updated_at
+SET status = 'UNKNOWN',
+    updated_at = NOW()
+WHERE id = $1 AND status = 'PENDING'
+RETURNING id, user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, gateway_rrn, status, amount, raw_response, created_at, updated_at, lease_owner, lease_expires_at, next_inquiry_at, inquiry_attempts, response_code, expires_at, merchant_profile, payment_intent_id
 `
 
-// - update polling status
-func (q *Queries) UpdatePollingStatus(ctx context.Context, txnRefNo string) (GikiWalletGatewayTransaction, error) {
-	row := q.db.QueryRow(ctx, updatePollingStatus, txnRefNo)
+func (q *Queries) MarkTransactionUnknown(ctx context.Context, id uuid.UUID) (GikiWalletGatewayTransaction, error) {
+	row := q.db.QueryRow(ctx, markTransactionUnknown, id)
 	var i GikiWalletGatewayTransaction
 	err := row.Scan(
 		&i.ID,
@@ -187,9 +413,75 @@ func (q *Queries) UpdatePollingStatus(ctx context.Context, txnRefNo string) (Gik
 		&i.Status,
 		&i.Amount,
 		&i.RawResponse,
-		&i.IsPolling,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.LeaseOwner,
+		&i.LeaseExpiresAt,
+		&i.NextInquiryAt,
+		&i.InquiryAttempts,
+		&i.ResponseCode,
+		&i.ExpiresAt,
+		&i.MerchantProfile,
+		&i.PaymentIntentID,
+	)
+	return i, err
+}
+
+const recordGatewayNotification = `-- name: RecordGatewayNotification :one
+
+INSERT INTO giki_wallet.gateway_notifications (gateway, txn_ref_no, dedup_key, response_code, payload)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (gateway, dedup_key) DO NOTHING
+RETURNING id, gateway, txn_ref_no, dedup_key, response_code, payload, received_at
+`
+
+type RecordGatewayNotificationParams struct {
+	Gateway      string `json:"gateway"`
+	TxnRefNo     string `json:"txn_ref_no"`
+	DedupKey     string `json:"dedup_key"`
+	ResponseCode string `json:"response_code"`
+	Payload      []byte `json:"payload"`
+}
+
+// - IPN notifications
+func (q *Queries) RecordGatewayNotification(ctx context.Context, arg RecordGatewayNotificationParams) (GikiWalletGatewayNotification, error) {
+	row := q.db.QueryRow(ctx, recordGatewayNotification,
+		arg.Gateway,
+		arg.TxnRefNo,
+		arg.DedupKey,
+		arg.ResponseCode,
+		arg.Payload,
+	)
+	var i GikiWalletGatewayNotification
+	err := row.Scan(
+		&i.ID,
+		&i.Gateway,
+		&i.TxnRefNo,
+		&i.DedupKey,
+		&i.ResponseCode,
+		&i.Payload,
+		&i.ReceivedAt,
 	)
 	return i, err
 }
+
+const rescheduleInquiry = `-- name: RescheduleInquiry :exec
+UPDATE giki_wallet.gateway_transactions
+SET inquiry_attempts = inquiry_attempts + 1,
+    next_inquiry_at = $1,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $2 AND lease_owner = $3::text
+`
+
+type RescheduleInquiryParams struct {
+	NextInquiryAt time.Time `json:"next_inquiry_at"`
+	ID            uuid.UUID `json:"id"`
+	LeaseOwner    string    `json:"lease_owner"`
+}
+
+func (q *Queries) RescheduleInquiry(ctx context.Context, arg RescheduleInquiryParams) error {
+	_, err := q.db.Exec(ctx, rescheduleInquiry, arg.NextInquiryAt, arg.ID, arg.LeaseOwner)
+	return err
+}
@@ -0,0 +1,226 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// Provider initiates payments for a single PaymentMethod on top of a gateway
+type Provider interface {
+	// Gateway returns the gateway that owns transactions created by this provider
+	Gateway() gateway.Gateway
+
+	// Initiate submits a freshly created transaction to the gateway
+	Initiate(ctx context.Context, req InitiateRequest) (InitiateResult, error)
+}
+
+// InitiateRequest is what the service hands to a provider after creating the transaction row
+type InitiateRequest struct {
+	Transaction payment.GikiWalletGatewayTransaction
+	Payload     TopUpRequest
+}
+
+// InitiateResult is the provider-neutral outcome of an initiate call
+type InitiateResult struct {
+	Status       gateway.Status
+	ResponseCode string
+	Message      string // in the language of the request context
+	RetryAllowed bool   // whether the customer may try again after a failure
+	RRN          string
+
+	// Exchange is what was sent to the gateway and what it replied. It is set
+	// alongside an error too once a request was made.
+	Exchange gateway.Exchange
+
+	// Redirect is set by providers that complete on a hosted page
+	Redirect *RedirectPayload
+}
+
+// submitError is the error of a provider whose submit failed. Submits move
+// money and are not safe to repeat, so unless the submit is known never to
+// have reached the gateway, the gateway may have taken the payment and the
+// outcome is unknown rather than failed; see errInitiateUnknown.
+func submitError(err error) error {
+	if errors.Is(err, gateway.ErrCircuitOpen) || errors.Is(err, money.ErrInvalidAmount) {
+		return fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+	}
+	return fmt.Errorf("%w: %v", errInitiateUnknown, err)
+}
+
+// DefaultMerchantProfile is the merchant account used when no route applies
+const DefaultMerchantProfile = "default"
+
+// Registry maps payment methods to the provider that serves them, per merchant
+// profile. Each profile is a separate merchant account at the gateway, with
+// its own credentials; business contexts are routed to a profile.
+type Registry struct {
+	mu        sync.RWMutex
+	providers map[string]map[PaymentMethod]Provider // by merchant profile
+	routes    map[string]string                     // business context → merchant profile
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// NewRegistry creates an empty provider registry
+func NewRegistry() *Registry {
+	return &Registry{
+		providers: make(map[string]map[PaymentMethod]Provider),
+		routes:    make(map[string]string),
+	}
+}
+
+// =============================================================================
+// REGISTRY METHODS
+// =============================================================================
+
+// Register binds a provider to a payment method in the default profile,
+// replacing any previous binding
+func (r *Registry) Register(method PaymentMethod, provider Provider) {
+	r.RegisterProfile(DefaultMerchantProfile, method, provider)
+}
+
+// RegisterProfile binds a provider to a payment method in a merchant profile,
+// replacing any previous binding
+func (r *Registry) RegisterProfile(profile string, method PaymentMethod, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.providers[profile] == nil {
+		r.providers[profile] = make(map[PaymentMethod]Provider)
+	}
+	r.providers[profile][method] = provider
+}
+
+// Route sends new payments made in a business context (e.g. a user type) to profile
+func (r *Registry) Route(businessContext, profile string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[businessContext] = profile
+}
+
+// Provider returns the default profile's provider for method
+func (r *Registry) Provider(method PaymentMethod) (Provider, error) {
+	return r.ProviderFor(method, DefaultMerchantProfile)
+}
+
+// ProviderFor returns the provider registered for method in profile. It is
+// used for existing transactions, which must stay with their profile.
+func (r *Registry) ProviderFor(method PaymentMethod, profile string) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[profile][method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in merchant profile %q", ErrInvalidPaymentMethod, method, profile)
+	}
+	return provider, nil
+}
+
+// Resolve picks the profile and provider for a new payment in businessContext.
+// A routed profile that does not offer method falls back to the default one.
+func (r *Registry) Resolve(method PaymentMethod, businessContext string) (string, Provider, error) {
+	r.mu.RLock()
+	profile, ok := r.routes[businessContext]
+	r.mu.RUnlock()
+
+	if ok {
+		if provider, err := r.ProviderFor(method, profile); err == nil {
+			return profile, provider, nil
+		}
+	}
+	provider, err := r.Provider(method)
+	if err != nil {
+		return "", nil, err
+	}
+	return DefaultMerchantProfile, provider, nil
+}
+
+// Profiles returns the registered merchant profiles in a stable order
+func (r *Registry) Profiles() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	profiles := make([]string, 0, len(r.providers))
+	for profile := range r.providers {
+		profiles = append(profiles, profile)
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// Methods returns the payment methods of every profile in a stable order
+func (r *Registry) Methods() []PaymentMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[PaymentMethod]bool)
+	methods := []PaymentMethod{}
+	for _, providers := range r.providers {
+		for method := range providers {
+			if !seen[method] {
+				seen[method] = true
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i] < methods[j] })
+	return methods
+}
+
+// MethodsForGateway returns the payment methods whose provider uses the gateway called name
+func (r *Registry) MethodsForGateway(name string) []PaymentMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[PaymentMethod]bool)
+	var methods []PaymentMethod
+	for _, providers := range r.providers {
+		for method, provider := range providers {
+			if !seen[method] && provider.Gateway().Name() == name {
+				seen[method] = true
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i] < methods[j] })
+	return methods
+}
+
+// Gateway returns the gateway called name as configured in profile
+func (r *Registry) Gateway(name, profile string) (gateway.Gateway, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, provider := range r.providers[profile] {
+		if gw := provider.Gateway(); gw.Name() == name {
+			return gw, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidGateway, name)
+}
+
+// Receiver returns the notification receiver of the gateway called name in profile
+func (r *Registry) Receiver(name, profile string) (gateway.NotificationReceiver, error) {
+	gw, err := r.Gateway(name, profile)
+	if err != nil {
+		return nil, err
+	}
+	receiver, ok := gw.(gateway.NotificationReceiver)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGateway, name)
+	}
+	return receiver, nil
+}
@@ -0,0 +1,60 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+)
+
+// cardCheckoutWindow is how long the hosted card page accepts the transaction
+const cardCheckoutWindow = time.Hour
+
+// CardProvider starts JazzCash hosted card checkouts
+type CardProvider struct {
+	gw        gateway.Gateway
+	returnURL string
+}
+
+// NewCardProvider creates a provider for PaymentMethodCard. returnURL is where
+// JazzCash posts the result once the customer leaves the hosted page.
+func NewCardProvider(gw gateway.Gateway, returnURL string) *CardProvider {
+	return &CardProvider{
+		gw:        gw,
+		returnURL: returnURL,
+	}
+}
+
+func (p *CardProvider) Gateway() gateway.Gateway {
+	return p.gw
+}
+
+// Initiate builds the signed form the frontend posts to the hosted card page
+func (p *CardProvider) Initiate(ctx context.Context, req InitiateRequest) (InitiateResult, error) {
+	cardResponse, err := p.gw.InitiateCard(ctx, gateway.CardInitiateRequest{
+		Amount:            req.Payload.Amount,
+		BillRefID:         req.Transaction.BillRefID,
+		TxnRefNo:          req.Transaction.TxnRefNo,
+		Description:       "GIKI Wallet Top Up",
+		ReturnURL:         p.returnURL,
+		TxnDateTime:       time.Now().In(common.PKT).Format("20060102150405"),
+		TxnExpiryDateTime: req.Transaction.ExpiresAt.In(common.PKT).Format("20060102150405"),
+	})
+	if err != nil {
+		return InitiateResult{}, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+	}
+
+	// Nothing is charged until the customer completes the hosted page
+	return InitiateResult{
+		Status:   gateway.StatusPending,
+		Message:  "Continue to JazzCash to complete your card payment",
+		Exchange: cardResponse.Exchange,
+		Redirect: &RedirectPayload{
+			PostURL:   cardResponse.PostURL,
+			Fields:    cardResponse.Fields,
+			ReturnURL: p.returnURL,
+		},
+	}, nil
+}
@@ -0,0 +1,50 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+)
+
+// EasypaisaProvider submits Easypaisa mobile account payments
+type EasypaisaProvider struct {
+	gw gateway.Gateway
+}
+
+// NewEasypaisaProvider creates a provider for PaymentMethodEasypaisa
+func NewEasypaisaProvider(gw gateway.Gateway) *EasypaisaProvider {
+	return &EasypaisaProvider{gw: gw}
+}
+
+func (p *EasypaisaProvider) Gateway() gateway.Gateway {
+	return p.gw
+}
+
+// Initiate validates the account number and submits the mobile account transaction.
+// Easypaisa identifies the customer by phone number alone, so no CNIC is needed.
+func (p *EasypaisaProvider) Initiate(ctx context.Context, req InitiateRequest) (InitiateResult, error) {
+	phoneNumber, err := NormalizePhoneNumber(req.Payload.PhoneNumber)
+	if err != nil {
+		return InitiateResult{}, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	maResponse, err := p.gw.SubmitMWallet(ctx, gateway.MWalletInitiateRequest{
+		Amount:       req.Payload.Amount,
+		BillRefID:    req.Transaction.BillRefID,
+		TxnRefNo:     req.Transaction.TxnRefNo,
+		Description:  "GIKI Wallet Top Up",
+		MobileNumber: phoneNumber,
+	})
+	if err != nil {
+		return InitiateResult{Exchange: maResponse.Exchange}, submitError(err)
+	}
+
+	return InitiateResult{
+		Status:       maResponse.Status,
+		ResponseCode: maResponse.ResponseCode,
+		Message:      maResponse.Message,
+		RRN:          maResponse.RRN,
+		Exchange:     maResponse.Exchange,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+)
+
+// MWalletProvider submits JazzCash mobile wallet payments
+type MWalletProvider struct {
+	gw gateway.Gateway
+}
+
+// NewMWalletProvider creates a provider for PaymentMethodMWallet
+func NewMWalletProvider(gw gateway.Gateway) *MWalletProvider {
+	return &MWalletProvider{gw: gw}
+}
+
+func (p *MWalletProvider) Gateway() gateway.Gateway {
+	return p.gw
+}
+
+// Initiate validates the wallet details and submits the MWallet transaction
+func (p *MWalletProvider) Initiate(ctx context.Context, req InitiateRequest) (InitiateResult, error) {
+	// Validate and normalize input
+	phoneNumber, err := NormalizePhoneNumber(req.Payload.PhoneNumber)
+	if err != nil {
+		return InitiateResult{}, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+	}
+
+	cnic, err := NormalizeCNICLast6(req.Payload.CNICLast6)
+	if err != nil {
+		return InitiateResult{}, fmt.Errorf("%w: %v", ErrInvalidCNIC, err)
+	}
+
+	// Build request; JazzCash reads both times as PKT
+	txnDateTime := time.Now().In(common.PKT).Format("20060102150405")
+	txnExpiryDateTime := req.Transaction.ExpiresAt.In(common.PKT).Format("20060102150405")
+
+	mwRequest := gateway.MWalletInitiateRequest{
+		Amount:            req.Payload.Amount,
+		BillRefID:         req.Transaction.BillRefID,
+		TxnRefNo:          req.Transaction.TxnRefNo,
+		Description:       "GIKI Wallet Top Up",
+		MobileNumber:      phoneNumber,
+		CNICLast6:         cnic,
+		TxnDateTime:       txnDateTime,
+		TxnExpiryDateTime: txnExpiryDateTime,
+	}
+
+	// Call gateway
+	mwResponse, err := p.gw.SubmitMWallet(ctx, mwRequest)
+	if err != nil {
+		return InitiateResult{Exchange: mwResponse.Exchange}, submitError(err)
+	}
+
+	return InitiateResult{
+		Status:       mwResponse.Status,
+		ResponseCode: mwResponse.ResponseCode,
+		Message:      mwResponse.Message,
+		RetryAllowed: mwResponse.RetryAllowed,
+		RRN:          mwResponse.RRN,
+		Exchange:     mwResponse.Exchange,
+	}, nil
+}
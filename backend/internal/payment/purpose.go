@@ -0,0 +1,80 @@
+package payment
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// PurposeHandler completes what a payment intent paid for, e.g. credits a
+// wallet or confirms a seat reservation
+type PurposeHandler interface {
+	// Fulfill runs inside the database transaction that marks the intent
+	// FULFILLED, so its writes commit exactly when the intent does. An error
+	// rolls them back and the intent is retried later with backoff, so Fulfill
+	// must not call anything that cannot be rolled back.
+	Fulfill(ctx context.Context, tx pgx.Tx, intent PaymentIntent) error
+}
+
+// PurposeHandlerFunc adapts a function to PurposeHandler
+type PurposeHandlerFunc func(ctx context.Context, tx pgx.Tx, intent PaymentIntent) error
+
+// Fulfill calls f
+func (f PurposeHandlerFunc) Fulfill(ctx context.Context, tx pgx.Tx, intent PaymentIntent) error {
+	return f(ctx, tx, intent)
+}
+
+// PurposeRegistry maps intent purposes to their completion handler. Modules
+// register at startup; a purpose without a handler can be paid but stays
+// SUCCEEDED until one is registered.
+type PurposeRegistry struct {
+	mu       sync.RWMutex
+	handlers map[IntentPurpose]PurposeHandler
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// NewPurposeRegistry creates an empty purpose registry
+func NewPurposeRegistry() *PurposeRegistry {
+	return &PurposeRegistry{handlers: make(map[IntentPurpose]PurposeHandler)}
+}
+
+// =============================================================================
+// REGISTRY METHODS
+// =============================================================================
+
+// Register binds the completion handler of purpose, replacing any previous one
+func (r *PurposeRegistry) Register(purpose IntentPurpose, handler PurposeHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[purpose] = handler
+}
+
+// Handler returns the completion handler of purpose
+func (r *PurposeRegistry) Handler(purpose IntentPurpose) (PurposeHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[purpose]
+	return handler, ok
+}
+
+// Purposes lists every purpose with a handler, sorted
+func (r *PurposeRegistry) Purposes() []IntentPurpose {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	purposes := make([]IntentPurpose, 0, len(r.handlers))
+	for purpose := range r.handlers {
+		purposes = append(purposes, purpose)
+	}
+	sort.Slice(purposes, func(i, j int) bool { return purposes[i] < purposes[j] })
+	return purposes
+}
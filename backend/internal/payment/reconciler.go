@@ -0,0 +1,234 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// Reconciler settles unexpired PENDING/UNKNOWN gateway transactions and
+// PENDING refunds in the background, and retries paid intents whose completion
+// handler failed. Expired transactions are left to the Sweeper.
+//
+// Rows are claimed with a lease (lease_owner, lease_expires_at) rather than a
+// flag, so several API replicas can run a reconciler side by side and a row
+// claimed by a crashed process becomes due again once its lease runs out.
+// Gateways that push notifications usually settle a row first; the reconciler
+// covers the ones whose notification never arrives.
+type Reconciler struct {
+	service  *Service
+	owner    string
+	interval time.Duration
+	lease    time.Duration
+	batch    int32
+}
+
+// ReconcilerConfig tunes the reconciliation loop
+type ReconcilerConfig struct {
+	Interval  time.Duration // how often due rows are claimed
+	Lease     time.Duration // how long a claim is held before another replica may take it
+	BatchSize int32         // rows claimed per tick
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// DefaultReconcilerConfig returns the settings used in production
+func DefaultReconcilerConfig() ReconcilerConfig {
+	return ReconcilerConfig{
+		Interval:  2 * time.Second,
+		Lease:     30 * time.Second,
+		BatchSize: 20,
+	}
+}
+
+// NewReconciler creates a reconciler that inquires through service's providers
+func NewReconciler(service *Service, config ReconcilerConfig) *Reconciler {
+	return &Reconciler{
+		service:  service,
+		owner:    reconcilerOwnerID(),
+		interval: config.Interval,
+		lease:    config.Lease,
+		batch:    config.BatchSize,
+	}
+}
+
+// =============================================================================
+// PUBLIC RECONCILER METHODS
+// =============================================================================
+
+// Run claims and reconciles due transactions until ctx is cancelled.
+// In-flight inquiries are allowed to finish before Run returns.
+func (r *Reconciler) Run(ctx context.Context) {
+	log.Printf("reconciler %s started", r.owner)
+	defer log.Printf("reconciler %s stopped", r.owner)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+// =============================================================================
+// PRIVATE RECONCILER METHODS
+// =============================================================================
+
+// tick claims one batch of due rows and reconciles them concurrently
+func (r *Reconciler) tick(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("reconciler panic: %v", rec)
+		}
+	}()
+
+	claimed, err := r.service.q.ClaimDueTransactions(ctx, payment.ClaimDueTransactionsParams{
+		LeaseOwner:   r.owner,
+		LeaseSeconds: int32(r.lease / time.Second),
+		BatchSize:    r.batch,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("failed to claim due transactions: %v", err)
+		}
+		return
+	}
+
+	refunds, err := r.service.q.ClaimDueRefunds(ctx, payment.ClaimDueRefundsParams{
+		LeaseOwner:   r.owner,
+		LeaseSeconds: int32(r.lease / time.Second),
+		BatchSize:    r.batch,
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Printf("failed to claim due refunds: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for _, gatewayTxn := range claimed {
+		wg.Add(1)
+		go func(gatewayTxn payment.GikiWalletGatewayTransaction) {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Printf("reconciler panic on %s: %v", gatewayTxn.TxnRefNo, rec)
+				}
+			}()
+			r.reconcile(ctx, gatewayTxn)
+		}(gatewayTxn)
+	}
+	for _, refund := range refunds {
+		wg.Add(1)
+		go func(refund payment.GikiWalletRefundRequest) {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Printf("reconciler panic on refund %s: %v", refund.ID, rec)
+				}
+			}()
+
+			refundCtx, cancel := context.WithTimeout(ctx, r.lease)
+			defer cancel()
+			r.service.processRefund(refundCtx, r.owner, refund)
+		}(refund)
+	}
+	wg.Wait()
+
+	r.service.fulfillDueIntents(ctx, r.batch)
+}
+
+// reconcile performs one inquiry for a claimed row and either finalizes it
+// or schedules the next attempt
+func (r *Reconciler) reconcile(ctx context.Context, gatewayTxn payment.GikiWalletGatewayTransaction) {
+	// Inquiry must not outlive the lease, or another replica could pick the row up mid-call
+	inquiryCtx, cancel := context.WithTimeout(ctx, r.lease)
+	defer cancel()
+
+	if err := r.service.rateLimiter.Acquire(inquiryCtx); err != nil {
+		r.service.rescheduleInquiry(gatewayTxn, r.owner)
+		return
+	}
+	inquiryResult, err := r.service.inquire(inquiryCtx, gatewayTxn)
+	r.service.rateLimiter.Release()
+
+	status := PaymentStatusUnknown
+	if err != nil {
+		log.Printf("inquiry failed for %s (attempt %d): %v", gatewayTxn.TxnRefNo, gatewayTxn.InquiryAttempts+1, err)
+	} else {
+		status = gatewayStatusToPaymentStatus(inquiryResult.Status)
+	}
+
+	switch status {
+	case PaymentStatusSuccess, PaymentStatusFailed:
+		// Finalizing is not bound to the request context so shutdown cannot lose a settled result
+		if _, err := r.service.finalizeAndCommit(context.Background(), gatewayTxn, status, inquiryOutcome(inquiryResult)); err != nil {
+			log.Printf("failed to finalize %s as %s: %v", gatewayTxn.TxnRefNo, status, err)
+		}
+	default:
+		r.service.rescheduleInquiry(gatewayTxn, r.owner)
+	}
+}
+
+// rescheduleInquiry releases owner's lease and pushes the next inquiry out by the backoff delay
+func (s *Service) rescheduleInquiry(gatewayTxn payment.GikiWalletGatewayTransaction, owner string) {
+	err := s.q.RescheduleInquiry(context.Background(), payment.RescheduleInquiryParams{
+		NextInquiryAt: time.Now().Add(inquiryBackoff(gatewayTxn.InquiryAttempts)),
+		ID:            gatewayTxn.ID,
+		LeaseOwner:    owner,
+	})
+	if err != nil {
+		log.Printf("failed to reschedule inquiry for %s: %v", gatewayTxn.TxnRefNo, err)
+	}
+}
+
+// =============================================================================
+// HELPERS - Reconciliation
+// =============================================================================
+
+const (
+	inquiryBackoffBase = 5 * time.Second
+	inquiryBackoffMax  = 5 * time.Minute
+)
+
+// inquiryBackoff returns the delay before the next inquiry after attempts
+// previous ones: 5s, 10s, 20s, ... capped at five minutes
+func inquiryBackoff(attempts int32) time.Duration {
+	delay := inquiryBackoffBase
+	for i := int32(0); i < attempts && delay < inquiryBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > inquiryBackoffMax {
+		return inquiryBackoffMax
+	}
+	return delay
+}
+
+// reconcilerOwnerID identifies this process in lease_owner
+func reconcilerOwnerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
@@ -0,0 +1,544 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidRefundRequest Validation errors (400) - show to user
+	ErrInvalidRefundRequest = errors.New("invalid refund request")
+	ErrInvalidRefundAmount  = errors.New("refund amount exceeds what is left to refund")
+
+	// ErrInvalidRefundResolution Manual refund resolution needs a reason and an outcome (400)
+	ErrInvalidRefundResolution = errors.New("refund resolution needs a reason and a status of SUCCESS, FAILED or PENDING")
+
+	// ErrRefundNotAllowed Transaction is not in a refundable state (409)
+	ErrRefundNotAllowed = errors.New("transaction cannot be refunded")
+
+	// ErrRefundExceedsBalance The payer no longer holds the money being refunded (409)
+	ErrRefundExceedsBalance = errors.New("refund exceeds the payer's wallet balance")
+
+	// ErrRefundNotUnknown Only UNKNOWN refunds are resolved by hand (409)
+	ErrRefundNotUnknown = errors.New("only UNKNOWN refunds can be resolved manually")
+
+	// ErrRefundNotFound Unknown refund id (404)
+	ErrRefundNotFound = errors.New("refund not found")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// RefundLedger takes refunded money back out of the ledger. Both methods run
+// inside the payment module's database transaction, so the ledger moves
+// exactly when the refund's status does.
+type RefundLedger interface {
+	// HoldRefund debits the refund when it is requested, before the gateway
+	// pays anything out. It returns ErrRefundExceedsBalance when the payer no
+	// longer has the money.
+	HoldRefund(ctx context.Context, tx pgx.Tx, hold RefundHold) error
+
+	// ReleaseRefund gives back the hold of a refund that ended FAILED
+	ReleaseRefund(ctx context.Context, tx pgx.Tx, hold RefundHold) error
+}
+
+// maxRefundAttempts bounds resubmissions of a refund the gateway never received
+const maxRefundAttempts = 5
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Refunds
+// =============================================================================
+
+// RequestRefund queues a full or partial refund of a SUCCESS transaction.
+// The reconciliation worker submits it to the gateway; see processRefund.
+// Repeating a request with the same idempotency key returns the original refund.
+func (s *Service) RequestRefund(ctx context.Context, txnRefNo string, req RefundCreateRequest) (*RefundResult, error) {
+	adminID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.IdempotencyKey == uuid.Nil || req.Reason == "" || !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: idempotency_key, a positive amount and a reason are required", ErrInvalidRefundRequest)
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin refund transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	// Locking the payment serialises concurrent refunds so they cannot overshoot its amount
+	gatewayTxn, err := paymentQ.GetTransactionForUpdate(ctx, txnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to lock transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	existing, err := paymentQ.GetRefundByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err == nil {
+		if existing.GatewayTransactionID != gatewayTxn.ID {
+			return nil, fmt.Errorf("%w: idempotency key already used for another transaction", ErrInvalidRefundRequest)
+		}
+		return toRefundResult(existing, gatewayTxn), nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("error checking refund idempotency key: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if gatewayTxn.Status != payment.CurrentStatus(PaymentStatusSuccess) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrRefundNotAllowed, txnRefNo, gatewayTxn.Status)
+	}
+	// There is no gateway to send the money back through; bank transfers are returned by hand
+	if PaymentMethod(gatewayTxn.PaymentMethod) == PaymentMethodBankTransfer {
+		return nil, fmt.Errorf("%w: %s was paid by bank transfer", ErrRefundNotAllowed, txnRefNo)
+	}
+
+	committed, err := paymentQ.SumCommittedRefunds(ctx, gatewayTxn.ID)
+	if err != nil {
+		log.Printf("failed to sum refunds for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	// SumCommittedRefunds adds up amount columns, so it is paisa too
+	remaining, err := gatewayTxn.Amount.Sub(money.Paisa(committed))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+	if cmp, err := req.Amount.Cmp(remaining); err != nil || cmp > 0 {
+		return nil, fmt.Errorf("%w: %s requested, %s remaining", ErrInvalidRefundAmount, req.Amount, remaining)
+	}
+
+	refund, err := paymentQ.CreateRefundRequest(ctx, payment.CreateRefundRequestParams{
+		GatewayTransactionID: gatewayTxn.ID,
+		IdempotencyKey:       req.IdempotencyKey,
+		RequestedBy:          adminID,
+		Reason:               req.Reason,
+		Amount:               req.Amount,
+	})
+	if err != nil {
+		log.Printf("failed to create refund for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+
+	intent, err := paymentQ.GetPaymentIntent(ctx, gatewayTxn.PaymentIntentID)
+	if err != nil {
+		log.Printf("failed to load intent of %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	// The hold commits with the refund, so money is never sent back twice:
+	// once by the gateway and once from the wallet it was credited to
+	if err := s.refunds.HoldRefund(ctx, tx, refundHold(refund, intent)); err != nil {
+		log.Printf("failed to hold refund of %s for %s: %v", refund.Amount, txnRefNo, err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit refund: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	log.Printf("refund %s of %s queued for %s by %s", refund.ID, refund.Amount, txnRefNo, adminID)
+	return toRefundResult(refund, gatewayTxn), nil
+}
+
+// GetRefund returns the current state of one refund
+func (s *Service) GetRefund(ctx context.Context, refundID uuid.UUID) (*RefundResult, error) {
+	refund, err := s.q.GetRefundRequest(ctx, refundID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRefundNotFound
+	} else if err != nil {
+		log.Printf("failed to load refund %s: %v", refundID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	gatewayTxn, err := s.q.GetTransactionByID(ctx, refund.GatewayTransactionID)
+	if err != nil {
+		log.Printf("failed to load transaction for refund %s: %v", refundID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	return toRefundResult(refund, gatewayTxn), nil
+}
+
+// ListRefunds returns every refund requested against a transaction, oldest first
+func (s *Service) ListRefunds(ctx context.Context, txnRefNo string) ([]RefundResult, error) {
+	gatewayTxn, err := s.q.GetTransactionByTxnRefNo(ctx, txnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	refunds, err := s.q.ListRefundsForTransaction(ctx, gatewayTxn.ID)
+	if err != nil {
+		log.Printf("failed to list refunds for %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	results := make([]RefundResult, 0, len(refunds))
+	for _, refund := range refunds {
+		results = append(results, *toRefundResult(refund, gatewayTxn))
+	}
+	return results, nil
+}
+
+// ListUnknownRefunds returns refunds waiting for an admin to check them with
+// the gateway, oldest first
+func (s *Service) ListUnknownRefunds(ctx context.Context, limit, offset int32) ([]RefundResult, error) {
+	refunds, err := s.q.ListUnknownRefunds(ctx, payment.ListUnknownRefundsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("failed to list unknown refunds: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	results := make([]RefundResult, 0, len(refunds))
+	for _, refund := range refunds {
+		gatewayTxn, err := s.q.GetTransactionByID(ctx, refund.GatewayTransactionID)
+		if err != nil {
+			log.Printf("failed to load transaction for refund %s: %v", refund.ID, err)
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+		results = append(results, *toRefundResult(refund, gatewayTxn))
+	}
+	return results, nil
+}
+
+// ResolveRefund settles an UNKNOWN refund once an admin has asked the gateway
+// what became of it: SUCCESS or FAILED as the gateway reports, or PENDING when
+// the gateway confirms it never processed the refund, which submits it again.
+// FAILED gives the refund's hold back to the payer.
+// The admin and reason are kept in the transaction's event history.
+func (s *Service) ResolveRefund(ctx context.Context, refundID uuid.UUID, req ResolveRefundRequest) (*RefundResult, error) {
+	adminID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	switch req.Status {
+	case RefundStatusSuccess, RefundStatusFailed, RefundStatusPending:
+	default:
+		return nil, ErrInvalidRefundResolution
+	}
+	if reason == "" {
+		return nil, ErrInvalidRefundResolution
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin refund resolution transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	refund, err := paymentQ.ResolveUnknownRefund(ctx, payment.ResolveUnknownRefundParams{
+		Status:          payment.RefundStatus(req.Status),
+		ResponseMessage: common.StringToText(reason),
+		ID:              refundID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := paymentQ.GetRefundRequest(ctx, refundID); err == nil {
+			return nil, ErrRefundNotUnknown
+		}
+		return nil, ErrRefundNotFound
+	} else if err != nil {
+		log.Printf("failed to resolve refund %s: %v", refundID, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	gatewayTxn, err := paymentQ.GetTransactionByID(ctx, refund.GatewayTransactionID)
+	if err != nil {
+		log.Printf("failed to load transaction for refund %s: %v", refundID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if req.Status == RefundStatusFailed {
+		if err := s.releaseRefund(ctx, tx, refund, gatewayTxn); err != nil {
+			return nil, err
+		}
+	}
+
+	s.recordEvent(ctx, paymentQ, gatewayTxn, gatewayEvent{
+		Gateway: s.gatewayNameFor(PaymentMethod(gatewayTxn.PaymentMethod)),
+		Kind:    GatewayEventManual,
+		Exchange: gateway.Exchange{Request: map[string]string{
+			"action":          "resolve_refund",
+			"refund_id":       refundID.String(),
+			"previous_status": string(RefundStatusUnknown),
+			"status":          string(req.Status),
+			"reason":          reason,
+			"resolved_by":     adminID.String(),
+		}},
+	})
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit resolution of refund %s: %v", refundID, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	log.Printf("refund %s for %s resolved as %s by admin %s: %s", refundID, gatewayTxn.TxnRefNo, req.Status, adminID, reason)
+	return toRefundResult(refund, gatewayTxn), nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Refund Processing
+// =============================================================================
+
+// processRefund submits one claimed refund to the gateway and records the outcome,
+// releasing the refund's hold in the same transaction when it ends FAILED. A hold
+// that cannot be released then is left for the worker; see retryRefundRelease.
+// A refund is only submitted again when it is certain the gateway never got it;
+// see refundOutcome. Anything else without a final answer becomes UNKNOWN and
+// waits for an admin, because a second submission could pay out twice.
+func (s *Service) processRefund(ctx context.Context, leaseOwner string, refund payment.GikiWalletRefundRequest) {
+	gatewayTxn, err := s.q.GetTransactionByID(ctx, refund.GatewayTransactionID)
+	if err != nil {
+		log.Printf("failed to load transaction for refund %s: %v", refund.ID, err)
+		return
+	}
+
+	// Claimed again only because its hold was not given back when it failed
+	if RefundStatus(refund.Status) == RefundStatusFailed {
+		s.retryRefundRelease(ctx, refund, gatewayTxn)
+		return
+	}
+
+	provider, err := s.providerFor(gatewayTxn)
+	if err != nil {
+		log.Printf("no provider for refund %s: %v", refund.ID, err)
+		return
+	}
+
+	var response gateway.RefundResponse
+
+	if err := s.rateLimiter.Acquire(ctx); err != nil {
+		return
+	}
+	response, err = provider.Gateway().Refund(ctx, gateway.RefundRequest{
+		Channel:  refundChannel(PaymentMethod(gatewayTxn.PaymentMethod)),
+		TxnRefNo: gatewayTxn.TxnRefNo,
+		Amount:   refund.Amount,
+	})
+	s.rateLimiter.Release()
+
+	s.recordEvent(context.Background(), s.q, gatewayTxn, gatewayEvent{
+		Gateway:      provider.Gateway().Name(),
+		Kind:         GatewayEventRefund,
+		Exchange:     response.Exchange,
+		ResponseCode: response.ResponseCode,
+		Err:          err,
+	})
+
+	status := refundOutcome(response, err)
+	if err != nil {
+		log.Printf("refund %s attempt %d failed: %v", refund.ID, refund.Attempts+1, err)
+		response.Message = err.Error()
+	}
+
+	if status == RefundStatusPending && refund.Attempts+1 >= maxRefundAttempts {
+		log.Printf("refund %s gave up after %d attempts", refund.ID, refund.Attempts+1)
+		status = RefundStatusFailed
+	}
+	if status == RefundStatusUnknown {
+		log.Printf("REFUND NEEDS REVIEW: refund %s of %s for %s has no definite answer from %s; check with the gateway before resolving it",
+			refund.ID, refund.Amount, gatewayTxn.TxnRefNo, provider.Gateway().Name())
+	}
+
+	// Recording is not bound to ctx so shutdown cannot lose a gateway answer
+	recordCtx := context.Background()
+	tx, err := s.dbPool.Begin(recordCtx)
+	if err != nil {
+		log.Printf("failed to begin recording of refund %s attempt: %v", refund.ID, err)
+		return
+	}
+	defer tx.Rollback(recordCtx)
+
+	recorded, err := s.q.WithTx(tx).RecordRefundAttempt(recordCtx, payment.RecordRefundAttemptParams{
+		Status:          payment.RefundStatus(status),
+		ResponseCode:    common.StringToText(response.ResponseCode),
+		ResponseMessage: common.StringToText(response.Message),
+		RawResponse:     marshalPayload(response.Exchange.Response),
+		NextAttemptAt:   time.Now().Add(inquiryBackoff(refund.Attempts)),
+		ID:              refund.ID,
+		LeaseOwner:      leaseOwner,
+	})
+	if err != nil {
+		log.Printf("failed to record refund %s attempt: %v", refund.ID, err)
+		return
+	}
+
+	if status == RefundStatusFailed {
+		// In a savepoint, so a failed release cannot lose the gateway's answer
+		if err := s.releaseRefundInSavepoint(recordCtx, tx, recorded, gatewayTxn); err != nil {
+			log.Printf("REFUND HOLD NOT RELEASED: refund %s of %s for %s failed but its hold was not given back; the worker retries: %v",
+				refund.ID, refund.Amount, gatewayTxn.TxnRefNo, err)
+		}
+	}
+
+	if err := tx.Commit(recordCtx); err != nil {
+		log.Printf("failed to commit refund %s attempt: %v", refund.ID, err)
+		return
+	}
+
+	log.Printf("refund %s for %s is %s (%s)", refund.ID, gatewayTxn.TxnRefNo, status, response.ResponseCode)
+}
+
+// retryRefundRelease gives back the hold of a FAILED refund whose release did
+// not go through when it failed. On error the lease runs out and the refund is
+// claimed again.
+func (s *Service) retryRefundRelease(ctx context.Context, refund payment.GikiWalletRefundRequest, gatewayTxn payment.GikiWalletGatewayTransaction) {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin release of refund %s: %v", refund.ID, err)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	if err := s.releaseRefund(ctx, tx, refund, gatewayTxn); err != nil {
+		log.Printf("REFUND HOLD NOT RELEASED: refund %s of %s for %s: %v", refund.ID, refund.Amount, gatewayTxn.TxnRefNo, err)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit release of refund %s: %v", refund.ID, err)
+		return
+	}
+
+	log.Printf("hold of failed refund %s for %s released", refund.ID, gatewayTxn.TxnRefNo)
+}
+
+// releaseRefundInSavepoint runs releaseRefund in a savepoint of tx, so an error
+// leaves the rest of tx intact
+func (s *Service) releaseRefundInSavepoint(ctx context.Context, tx pgx.Tx, refund payment.GikiWalletRefundRequest, gatewayTxn payment.GikiWalletGatewayTransaction) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer savepoint.Rollback(ctx)
+
+	if err := s.releaseRefund(ctx, savepoint, refund, gatewayTxn); err != nil {
+		return err
+	}
+	return savepoint.Commit(ctx)
+}
+
+// releaseRefund gives back the ledger hold of a refund that ended FAILED and
+// marks it released
+func (s *Service) releaseRefund(ctx context.Context, tx pgx.Tx, refund payment.GikiWalletRefundRequest, gatewayTxn payment.GikiWalletGatewayTransaction) error {
+	paymentQ := s.q.WithTx(tx)
+
+	intent, err := paymentQ.GetPaymentIntent(ctx, gatewayTxn.PaymentIntentID)
+	if err != nil {
+		log.Printf("failed to load intent of %s: %v", gatewayTxn.TxnRefNo, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if err := s.refunds.ReleaseRefund(ctx, tx, refundHold(refund, intent)); err != nil {
+		log.Printf("failed to release hold of refund %s: %v", refund.ID, err)
+		return err
+	}
+
+	if err := paymentQ.MarkRefundHoldReleased(ctx, refund.ID); err != nil {
+		log.Printf("failed to mark hold of refund %s released: %v", refund.ID, err)
+		return fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+	return nil
+}
+
+// =============================================================================
+// HELPERS - Refunds
+// =============================================================================
+
+// refundHold describes what a refund takes back from the intent it refunds
+func refundHold(refund payment.GikiWalletRefundRequest, intent payment.GikiWalletPaymentIntent) RefundHold {
+	return RefundHold{
+		RefundID: refund.ID,
+		Purpose:  IntentPurpose(intent.Purpose),
+		PayerID:  intent.PayerID,
+		Amount:   refund.Amount,
+	}
+}
+
+// refundOutcome maps the result of one refund submission to the refund's next
+// status. Only errors raised before anything was sent are retried (PENDING);
+// a transport error or timeout may come after the gateway accepted the refund,
+// so those and unsettled answers are UNKNOWN.
+func refundOutcome(response gateway.RefundResponse, err error) RefundStatus {
+	switch {
+	case errors.Is(err, gateway.ErrUnsupported):
+		// Retrying will not help; the refund has to be made outside the gateway
+		return RefundStatusFailed
+	case errors.Is(err, gateway.ErrCircuitOpen):
+		return RefundStatusPending
+	case err != nil:
+		return RefundStatusUnknown
+	}
+
+	switch response.Status {
+	case gateway.StatusSuccess:
+		return RefundStatusSuccess
+	case gateway.StatusFailed:
+		return RefundStatusFailed
+	default:
+		return RefundStatusUnknown
+	}
+}
+
+// refundChannel picks the gateway refund API for the original payment method
+func refundChannel(method PaymentMethod) gateway.RefundChannel {
+	if method == PaymentMethodCard {
+		return gateway.RefundChannelCard
+	}
+	return gateway.RefundChannelWallet
+}
+
+func toRefundResult(refund payment.GikiWalletRefundRequest, gatewayTxn payment.GikiWalletGatewayTransaction) *RefundResult {
+	result := &RefundResult{
+		ID:              refund.ID,
+		TxnRefNo:        gatewayTxn.TxnRefNo,
+		Amount:          refund.Amount,
+		Status:          RefundStatus(refund.Status),
+		Reason:          refund.Reason,
+		RequestedBy:     refund.RequestedBy,
+		Attempts:        refund.Attempts,
+		ResponseCode:    common.TextToString(refund.ResponseCode),
+		ResponseMessage: common.TextToString(refund.ResponseMessage),
+		CreatedAt:       refund.CreatedAt,
+	}
+	if refund.ProcessedAt.Valid {
+		processedAt := refund.ProcessedAt.Time
+		result.ProcessedAt = &processedAt
+	}
+	return result
+}
@@ -3,18 +3,21 @@ package payment
 import (
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math/big"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
 	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
 	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/hash-walker/giki-wallet/internal/storage"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -28,10 +31,21 @@ var (
 	ErrInvalidPaymentMethod = errors.New("unsupported payment method")
 	ErrInvalidPhoneNumber   = errors.New("invalid phone number format")
 	ErrInvalidCNIC          = errors.New("invalid CNIC format")
+	ErrInvalidAmount        = errors.New("top-up amount must be a positive PKR amount")
+
+	// ErrInvalidCallback Gateway callback failed verification (400)
+	ErrInvalidCallback = errors.New("invalid gateway callback")
+
+	// ErrInvalidGateway No registered gateway accepts notifications under this name (404)
+	ErrInvalidGateway = errors.New("unknown payment gateway")
 
 	// ErrGatewayUnavailable Gateway unreachable (502) - generic message
 	ErrGatewayUnavailable = errors.New("payment gateway unavailable")
 
+	// errInitiateUnknown A submit failed after it may have reached the gateway;
+	// startPayment keeps the transaction UNKNOWN instead of returning it
+	errInitiateUnknown = errors.New("payment gateway gave no definite answer")
+
 	// ErrInternal Internal errors (500) - generic message, log details
 	ErrInternal            = errors.New("internal error")
 	ErrUserIDNotFound      = errors.New("user id not found in context")
@@ -39,6 +53,7 @@ var (
 	ErrTransactionCreation = errors.New("failed to create transaction")
 	ErrTransactionUpdate   = errors.New("failed to update transaction status")
 	ErrDatabaseQuery       = errors.New("database query failed")
+	ErrTransactionNotFound = errors.New("transaction not found")
 )
 
 // =============================================================================
@@ -47,10 +62,14 @@ var (
 
 // Service handles payment business logic
 type Service struct {
-	q             *payment.Queries
-	dbPool        *pgxpool.Pool
-	gatewayClient *gateway.JazzCashClient
-	rateLimiter   *RateLimiter
+	q           *payment.Queries
+	dbPool      *pgxpool.Pool
+	providers   *Registry
+	rateLimiter *RateLimiter
+	limits      TopUpLimits
+	receipts    storage.Storage
+	purposes    *PurposeRegistry
+	refunds     RefundLedger
 }
 
 // RateLimiter limits concurrent API calls to external services
@@ -62,13 +81,19 @@ type RateLimiter struct {
 // CONSTRUCTORS
 // =============================================================================
 
-// NewService creates a new payment service
-func NewService(dbPool *pgxpool.Pool, gatewayClient *gateway.JazzCashClient, rateLimiter *RateLimiter) *Service {
+// NewService creates a new payment service that enforces limits on every top-up,
+// keeps bank transfer receipts in receipts, completes paid intents through purposes
+// and takes refunded money back through refunds
+func NewService(dbPool *pgxpool.Pool, providers *Registry, purposes *PurposeRegistry, refunds RefundLedger, rateLimiter *RateLimiter, limits TopUpLimits, receipts storage.Storage) *Service {
 	return &Service{
-		q:             payment.New(dbPool),
-		dbPool:        dbPool,
-		gatewayClient: gatewayClient,
-		rateLimiter:   rateLimiter,
+		q:           payment.New(dbPool),
+		dbPool:      dbPool,
+		providers:   providers,
+		rateLimiter: rateLimiter,
+		limits:      limits,
+		receipts:    receipts,
+		purposes:    purposes,
+		refunds:     refunds,
 	}
 }
 
@@ -115,6 +140,16 @@ func (s *Service) InitiatePayment(ctx context.Context, tx pgx.Tx, payload TopUpR
 	idempotencyKey := payload.IdempotencyKey
 	paymentQ := s.q.WithTx(tx)
 
+	// Reject unknown methods before touching the database; the merchant
+	// profile is chosen once the user is known
+	if _, err := s.providers.Provider(payload.Method); err != nil {
+		return nil, err
+	}
+
+	if payload.Amount.Currency() != money.PKR || !payload.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: got %s", ErrInvalidAmount, payload.Amount)
+	}
+
 	// Acquire advisory lock for idempotency
 	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", idempotencyKey.String())
 	if err != nil {
@@ -135,144 +170,410 @@ func (s *Service) InitiatePayment(ctx context.Context, tx pgx.Tx, payload TopUpR
 		if existingPayment.Status == payment.CurrentStatus(PaymentStatusFailed) {
 			// Previous transaction failed - proceed to create new
 		} else {
-			return s.handleExistingTransaction(ctx, paymentQ, existingPayment)
+			return s.handleExistingTransaction(ctx, tx, existingPayment)
 		}
 	} else if !errors.Is(err, pgx.ErrNoRows) {
 		log.Printf("error checking idempotency key: %v", err)
 		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
 	}
 
-	// Generate reference numbers
-	billRefNo, err := GenerateBillRefNo()
+	// Limits apply to new attempts only, so replays above are never refused
+	if err := auditLimitChecks(userID.String(), payload.Amount, s.limits.checkAmountLimits(payload.Amount)); err != nil {
+		return nil, err
+	}
+
+	// Serialize this user's initiates so two requests cannot both fit under a cap
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "topup:"+userID.String())
 	if err != nil {
-		log.Printf("error generating bill reference: %v", err)
-		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+		log.Printf("failed to acquire top-up lock for %s: %v", userID, err)
+		return nil, fmt.Errorf("%w: %v", ErrFailedToAcquireLock, err)
 	}
 
-	txnRefNo, err := GenerateTxnRefNo()
+	usage, err := s.enforceUsageLimits(ctx, paymentQ, userID, payload.Amount)
 	if err != nil {
-		log.Printf("error generating transaction reference: %v", err)
-		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+		return nil, err
 	}
 
-	// Create transaction record
-	gatewayTxn, err := paymentQ.CreateGatewayTransaction(ctx, payment.CreateGatewayTransactionParams{
-		UserID:         userID,
-		IdempotencyKey: payload.IdempotencyKey,
-		BillRefID:      billRefNo,
-		TxnRefNo:       txnRefNo,
-		PaymentMethod:  string(payload.Method),
-		Status:         payment.CurrentStatus(PaymentStatusPending),
-		Amount:         payload.Amount,
+	// Each business context settles into its own merchant account
+	merchantProfile, provider, err := s.providers.Resolve(payload.Method, usage.UserType)
+	if err != nil {
+		return nil, err
+	}
+
+	// A top-up is an intent to pay into the user's own wallet
+	intent, err := s.ensureIntent(ctx, paymentQ, CreateIntentRequest{
+		Purpose:          PurposeTopUp,
+		PurposeReference: idempotencyKey.String(),
+		Amount:           payload.Amount,
+		PayerID:          userID,
 	})
 	if err != nil {
-		log.Printf("failed to create gateway transaction: %v", err)
-		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+		return nil, err
 	}
 
-	// Route to payment method handler
-	switch payload.Method {
-	case PaymentMethodMWallet:
-		return s.initiateMWalletPayment(ctx, paymentQ, gatewayTxn, payload, billRefNo, txnRefNo)
-	default:
-		return nil, fmt.Errorf("%w: %s", ErrInvalidPaymentMethod, payload.Method)
+	return s.startPayment(ctx, tx, intent, payload, merchantProfile, provider)
+}
+
+// HandleCardCallback verifies the JazzCash ReturnURL post and finalizes the card transaction
+func (s *Service) HandleCardCallback(ctx context.Context, form map[string]string) (*TopUpResult, error) {
+	defaultProvider, err := s.providers.Provider(PaymentMethodCard)
+	if err != nil {
+		return nil, err
 	}
+
+	// Verified with the credentials of the merchant profile the transaction was created under
+	profile := s.callbackProfile(ctx, defaultProvider.Gateway(), form)
+	provider, err := s.providers.ProviderFor(PaymentMethodCard, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := provider.Gateway().ParseAndVerifyCardCallback(ctx, form)
+	if err != nil {
+		log.Printf("card callback rejected: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCallback, err)
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin card callback transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	existing, err := paymentQ.GetTransactionByTxnRefNo(ctx, callback.TxnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("card callback for unknown transaction %s", callback.TxnRefNo)
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", callback.TxnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	// The hash proves JazzCash sent it, but not that it belongs to this transaction
+	var rejected error
+	if existing.PaymentMethod != string(PaymentMethodCard) {
+		rejected = fmt.Errorf("%w: %s is not a card transaction", ErrInvalidCallback, existing.TxnRefNo)
+	} else if !callback.Amount.Equal(existing.Amount) {
+		rejected = fmt.Errorf("%w: amount %s does not match transaction %s", ErrInvalidCallback, callback.Amount, existing.TxnRefNo)
+	}
+
+	// Recorded outside the database transaction so rejected callbacks are kept too
+	callbackPayload := gateway.RedactFields(callback.Fields)
+	s.recordEvent(ctx, s.q, existing, gatewayEvent{
+		Gateway:      provider.Gateway().Name(),
+		Kind:         GatewayEventCallback,
+		Exchange:     gateway.Exchange{Request: callbackPayload},
+		ResponseCode: callback.ResponseCode,
+		RRN:          callback.RRN,
+		Err:          rejected,
+	})
+	if rejected != nil {
+		return nil, rejected
+	}
+
+	// Anything short of a final answer stays pending for inquiry to settle
+	paymentStatus := gatewayStatusToPaymentStatus(callback.Status)
+	if paymentStatus == PaymentStatusSuccess || paymentStatus == PaymentStatusFailed {
+		existing, err = s.finalizeTransaction(ctx, tx, existing, paymentStatus, gatewayOutcome{
+			Source:       GatewayEventCallback,
+			ResponseCode: callback.ResponseCode,
+			RRN:          callback.RRN,
+			Response:     callbackPayload,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit card callback: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	return &TopUpResult{
+		ID:            existing.ID,
+		TxnRefNo:      existing.TxnRefNo,
+		Status:        PaymentStatus(existing.Status),
+		Message:       callback.ResponseMessage,
+		PaymentMethod: PaymentMethodCard,
+		Amount:        existing.Amount,
+	}, nil
 }
 
-// =============================================================================
-// PRIVATE SERVICE METHODS - Payment Initiation
-// =============================================================================
+// HandleNotification verifies a server-to-server notification from gatewayName
+// and applies it to the transaction. Repeats of an already recorded notification
+// are accepted without being applied again.
+func (s *Service) HandleNotification(ctx context.Context, gatewayName string, payload map[string]string) error {
+	gw, err := s.providers.Gateway(gatewayName, DefaultMerchantProfile)
+	if err != nil {
+		return err
+	}
 
-// initiateMWalletPayment handles JazzCash MWallet payment initiation
-func (s *Service) initiateMWalletPayment(
-	ctx context.Context,
-	paymentQ *payment.Queries,
-	gatewayTxn payment.GikiWalletGatewayTransaction,
-	payload TopUpRequest,
-	billRefNo, txnRefNo string,
-) (*TopUpResult, error) {
-	// Validate and normalize input
-	phoneNumber, err := NormalizePhoneNumber(payload.PhoneNumber)
+	// Verified with the credentials of the merchant profile the transaction was created under
+	receiver, err := s.providers.Receiver(gatewayName, s.callbackProfile(ctx, gw, payload))
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidPhoneNumber, err)
+		return err
 	}
 
-	cnic, err := NormalizeCNICLast6(payload.CNICLast6)
+	notification, err := receiver.ParseAndVerifyNotification(ctx, payload)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrInvalidCNIC, err)
+		log.Printf("%s notification rejected: %v", gatewayName, err)
+		return fmt.Errorf("%w: %v", ErrInvalidCallback, err)
 	}
 
-	// Build request
-	txnDateTime := time.Now().Format("20060102150405")
-	txnExpiryDateTime := time.Now().Add(24 * time.Hour).Format("20060102150405")
+	notificationPayload := gateway.RedactFields(notification.Fields)
+	rawPayload, err := json.Marshal(notificationPayload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInternal, err)
+	}
 
-	mwRequest := gateway.MWalletInitiateRequest{
-		AmountPaisa:       AmountToPaisa(payload.Amount),
-		BillRefID:         billRefNo,
-		TxnRefNo:          txnRefNo,
-		Description:       "GIKI Wallet Top Up",
-		MobileNumber:      phoneNumber,
-		CNICLast6:         cnic,
-		TxnDateTime:       txnDateTime,
-		TxnExpiryDateTime: txnExpiryDateTime,
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin notification transaction: %v", err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
 	}
+	defer tx.Rollback(ctx)
 
-	// Call gateway
-	mwResponse, err := s.gatewayClient.SubmitMWallet(ctx, mwRequest)
+	paymentQ := s.q.WithTx(tx)
+
+	_, err = paymentQ.RecordGatewayNotification(ctx, payment.RecordGatewayNotificationParams{
+		Gateway:      gatewayName,
+		TxnRefNo:     notification.TxnRefNo,
+		DedupKey:     notification.DedupKey,
+		ResponseCode: notification.ResponseCode,
+		Payload:      rawPayload,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("duplicate %s notification for %s ignored", gatewayName, notification.TxnRefNo)
+		return nil
+	} else if err != nil {
+		log.Printf("failed to record notification for %s: %v", notification.TxnRefNo, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	existing, err := paymentQ.GetTransactionByTxnRefNo(ctx, notification.TxnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("%s notification for unknown transaction %s", gatewayName, notification.TxnRefNo)
+		return ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", notification.TxnRefNo, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	// The signature proves who sent it, not that it belongs to this transaction
+	provider, err := s.providerFor(existing)
 	if err != nil {
-		log.Printf("gateway MWallet initiate failed: %v", err)
-		return nil, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
+		return err
+	}
+	var rejected error
+	if provider.Gateway().Name() != gatewayName {
+		rejected = fmt.Errorf("%w: %s is not a %s transaction", ErrInvalidCallback, existing.TxnRefNo, gatewayName)
+	} else if !notification.Amount.Equal(existing.Amount) {
+		rejected = fmt.Errorf("%w: amount %s does not match transaction %s", ErrInvalidCallback, notification.Amount, existing.TxnRefNo)
+	}
+
+	// Recorded outside the database transaction so rejected notifications are kept too
+	s.recordEvent(ctx, s.q, existing, gatewayEvent{
+		Gateway:      gatewayName,
+		Kind:         GatewayEventIPN,
+		Exchange:     gateway.Exchange{Request: notificationPayload},
+		ResponseCode: notification.ResponseCode,
+		RRN:          notification.RRN,
+		Err:          rejected,
+	})
+	if rejected != nil {
+		return rejected
+	}
+
+	paymentStatus := gatewayStatusToPaymentStatus(notification.Status)
+	if paymentStatus == PaymentStatusSuccess || paymentStatus == PaymentStatusFailed {
+		_, err := s.finalizeTransaction(ctx, tx, existing, paymentStatus, gatewayOutcome{
+			Source:       GatewayEventIPN,
+			ResponseCode: notification.ResponseCode,
+			RRN:          notification.RRN,
+			Response:     notificationPayload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit notification: %v", err)
+		return fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	return nil
+}
+
+// AcknowledgeNotification returns the body gatewayName expects for an accepted
+// notification, signed for the merchant profile the notification was about
+func (s *Service) AcknowledgeNotification(ctx context.Context, gatewayName string, payload map[string]string) (map[string]string, error) {
+	gw, err := s.providers.Gateway(gatewayName, DefaultMerchantProfile)
+	if err != nil {
+		return nil, err
+	}
+	receiver, err := s.providers.Receiver(gatewayName, s.callbackProfile(ctx, gw, payload))
+	if err != nil {
+		return nil, err
+	}
+
+	ack, err := receiver.AcknowledgeNotification()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInternal, err)
+	}
+	return ack, nil
+}
+
+// GetPaymentStatus returns the stored status of one of the caller's transactions.
+// Transactions of other users are reported as not found.
+func (s *Service) GetPaymentStatus(ctx context.Context, txnRefNo string) (*TopUpResult, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	gatewayTxn, err := s.q.GetTransactionByTxnRefNo(ctx, txnRefNo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	} else if err != nil {
+		log.Printf("failed to load transaction %s: %v", txnRefNo, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if gatewayTxn.UserID != userID {
+		return nil, ErrTransactionNotFound
 	}
 
+	return &TopUpResult{
+		ID:            gatewayTxn.ID,
+		TxnRefNo:      gatewayTxn.TxnRefNo,
+		Status:        PaymentStatus(gatewayTxn.Status),
+		PaymentMethod: PaymentMethod(gatewayTxn.PaymentMethod),
+		Amount:        gatewayTxn.Amount,
+	}, nil
+}
+
+// GatewayHealth reports the circuit breaker state of every registered gateway,
+// per merchant profile, so the frontend can warn before a top-up fails fast
+func (s *Service) GatewayHealth() []GatewayHealth {
+	var health []GatewayHealth
+	byGateway := make(map[string]int)
+
+	for _, profile := range s.providers.Profiles() {
+		for _, method := range s.providers.Methods() {
+			provider, err := s.providers.ProviderFor(method, profile)
+			if err != nil {
+				continue
+			}
+			health = appendGatewayHealth(health, byGateway, profile, method, provider.Gateway())
+		}
+	}
+
+	return health
+}
+
+// appendGatewayHealth adds method to the entry for gw in profile, creating the entry on first use
+func appendGatewayHealth(health []GatewayHealth, byGateway map[string]int, profile string, method PaymentMethod, gw gateway.Gateway) []GatewayHealth {
+	key := profile + "/" + gw.Name()
+	if i, ok := byGateway[key]; ok {
+		health[i].Methods = append(health[i].Methods, method)
+		return health
+	}
+
+	entry := GatewayHealth{
+		Gateway:         gw.Name(),
+		MerchantProfile: profile,
+		Methods:         []PaymentMethod{method},
+		Available:       true,
+		State:           string(gateway.BreakerClosed),
+	}
+	if reporter, ok := gw.(gateway.BreakerReporter); ok {
+		status := reporter.BreakerStatus()
+		entry.State = string(status.State)
+		entry.Available = status.State != gateway.BreakerOpen
+		if !status.RetryAt.IsZero() {
+			retryAt := status.RetryAt
+			entry.RetryAt = &retryAt
+		}
+	}
+
+	byGateway[key] = len(health)
+	return append(health, entry)
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Payment Initiation
+// =============================================================================
+
+// applyInitiateResult records the provider outcome and builds the response
+func (s *Service) applyInitiateResult(
+	ctx context.Context,
+	tx pgx.Tx,
+	gatewayTxn payment.GikiWalletGatewayTransaction,
+	payload TopUpRequest,
+	result InitiateResult,
+) (*TopUpResult, error) {
+	txnRefNo := gatewayTxn.TxnRefNo
+
 	// Process response
-	paymentStatus := gatewayStatusToPaymentStatus(mwResponse.Status)
+	paymentStatus := gatewayStatusToPaymentStatus(result.Status)
+	outcome := gatewayOutcome{
+		Source:       GatewayEventInitiate,
+		ResponseCode: result.ResponseCode,
+		RRN:          result.RRN,
+		Response:     result.Exchange.Response,
+	}
 
 	switch paymentStatus {
 	case PaymentStatusSuccess:
-		err := paymentQ.UpdateGatewayTransactionStatus(ctx, payment.UpdateGatewayTransactionStatusParams{
-			Status:   payment.CurrentStatus(PaymentStatusSuccess),
-			TxnRefNo: txnRefNo,
-		})
-		if err != nil {
-			log.Printf("failed to update transaction status to SUCCESS: %v", err)
-			return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+		if _, err := s.finalizeTransaction(ctx, tx, gatewayTxn, PaymentStatusSuccess, outcome); err != nil {
+			return nil, err
 		}
 
 		return &TopUpResult{
 			ID:            gatewayTxn.ID,
 			TxnRefNo:      txnRefNo,
 			Status:        PaymentStatusSuccess,
-			Message:       mwResponse.Message,
-			PaymentMethod: PaymentMethodMWallet,
+			Message:       result.Message,
+			PaymentMethod: payload.Method,
 			Amount:        payload.Amount,
 		}, nil
 
 	case PaymentStatusPending, PaymentStatusUnknown:
+		if paymentStatus == PaymentStatusUnknown {
+			if _, err := s.q.WithTx(tx).MarkTransactionUnknown(ctx, gatewayTxn.ID); err != nil {
+				log.Printf("failed to mark %s UNKNOWN: %v", txnRefNo, err)
+				return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+			}
+		}
+
 		return &TopUpResult{
 			ID:            gatewayTxn.ID,
 			TxnRefNo:      txnRefNo,
 			Status:        PaymentStatusPending,
-			Message:       mwResponse.Message,
-			PaymentMethod: PaymentMethodMWallet,
+			Message:       result.Message,
+			PaymentMethod: payload.Method,
+			Redirect:      result.Redirect,
 			Amount:        payload.Amount,
 		}, nil
 
 	default:
-		err := paymentQ.UpdateGatewayTransactionStatus(ctx, payment.UpdateGatewayTransactionStatusParams{
-			Status:   payment.CurrentStatus(PaymentStatusFailed),
-			TxnRefNo: txnRefNo,
-		})
-		if err != nil {
-			log.Printf("failed to update transaction status to FAILED: %v", err)
-			return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+		if _, err := s.finalizeTransaction(ctx, tx, gatewayTxn, PaymentStatusFailed, outcome); err != nil {
+			return nil, err
 		}
 
 		return &TopUpResult{
 			ID:            gatewayTxn.ID,
 			TxnRefNo:      txnRefNo,
 			Status:        PaymentStatusFailed,
-			Message:       mwResponse.Message,
-			PaymentMethod: PaymentMethodMWallet,
+			Message:       result.Message,
+			RetryAllowed:  result.RetryAllowed,
+			PaymentMethod: payload.Method,
 			Amount:        payload.Amount,
 		}, nil
 	}
@@ -285,7 +586,7 @@ func (s *Service) initiateMWalletPayment(
 // handleExistingTransaction checks and returns status of an existing transaction
 func (s *Service) handleExistingTransaction(
 	ctx context.Context,
-	paymentQ *payment.Queries,
+	tx pgx.Tx,
 	existing payment.GikiWalletGatewayTransaction,
 ) (*TopUpResult, error) {
 	switch existing.Status {
@@ -299,7 +600,7 @@ func (s *Service) handleExistingTransaction(
 		}, nil
 
 	case payment.CurrentStatus(PaymentStatusPending), payment.CurrentStatus(PaymentStatusUnknown):
-		return s.checkPendingTransactionStatus(ctx, paymentQ, existing)
+		return s.checkPendingTransactionStatus(ctx, tx, existing)
 
 	default:
 		return &TopUpResult{
@@ -315,10 +616,10 @@ func (s *Service) handleExistingTransaction(
 // checkPendingTransactionStatus queries gateway for current status of pending transaction
 func (s *Service) checkPendingTransactionStatus(
 	ctx context.Context,
-	paymentQ *payment.Queries,
+	tx pgx.Tx,
 	existing payment.GikiWalletGatewayTransaction,
 ) (*TopUpResult, error) {
-	inquiryResult, err := s.gatewayClient.Inquiry(ctx, existing.TxnRefNo)
+	inquiryResult, err := s.inquire(ctx, existing)
 	if err != nil {
 		log.Printf("inquiry API failed for existing transaction %s: %v", existing.TxnRefNo, err)
 		return nil, fmt.Errorf("%w: %v", ErrGatewayUnavailable, err)
@@ -328,44 +629,22 @@ func (s *Service) checkPendingTransactionStatus(
 
 	switch paymentStatus {
 	case PaymentStatusSuccess, PaymentStatusFailed:
-		err := paymentQ.UpdateGatewayTransactionStatus(ctx, payment.UpdateGatewayTransactionStatusParams{
-			Status:   payment.CurrentStatus(paymentStatus),
-			TxnRefNo: existing.TxnRefNo,
-		})
+		updated, err := s.finalizeTransaction(ctx, tx, existing, paymentStatus, inquiryOutcome(inquiryResult))
 		if err != nil {
-			log.Printf("failed to update transaction status: %v", err)
-			return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+			return nil, err
 		}
 
 		return &TopUpResult{
 			TxnRefNo:      existing.TxnRefNo,
-			Status:        paymentStatus,
+			Status:        PaymentStatus(updated.Status),
 			Message:       inquiryResult.Message,
+			RetryAllowed:  updated.Status == payment.CurrentStatus(PaymentStatusFailed) && inquiryResult.RetryAllowed,
 			PaymentMethod: PaymentMethod(existing.PaymentMethod),
 			Amount:        existing.Amount,
 		}, nil
 
 	case PaymentStatusPending, PaymentStatusUnknown:
-		// Check timeout
-		if time.Since(existing.CreatedAt) > 120*time.Second {
-			err := paymentQ.UpdateGatewayTransactionStatus(ctx, payment.UpdateGatewayTransactionStatusParams{
-				Status:   payment.CurrentStatus(PaymentStatusFailed),
-				TxnRefNo: existing.TxnRefNo,
-			})
-			if err != nil {
-				log.Printf("failed to update transaction status: %v", err)
-				return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
-			}
-
-			return &TopUpResult{
-				TxnRefNo:      existing.TxnRefNo,
-				Status:        PaymentStatusFailed,
-				Message:       "Transaction has timed out. Please try again.",
-				PaymentMethod: PaymentMethod(existing.PaymentMethod),
-				Amount:        existing.Amount,
-			}, nil
-		}
-
+		// Still open; the sweeper closes it once it has expired at the gateway
 		return &TopUpResult{
 			TxnRefNo:      existing.TxnRefNo,
 			Status:        PaymentStatusPending,
@@ -386,102 +665,149 @@ func (s *Service) checkPendingTransactionStatus(
 }
 
 // =============================================================================
-// PRIVATE SERVICE METHODS - Background Polling
+// PRIVATE SERVICE METHODS - State Transitions
 // =============================================================================
 
-// startPollingForTransaction polls gateway for transaction status updates
-func (s *Service) startPollingForTransaction(txRefNo string) {
+// finalizeTransaction moves a PENDING/UNKNOWN transaction to a final status,
+// stores the gateway answer that settled it and settles its payment intent in
+// the same database transaction. If the transaction was already final it is
+// returned unchanged, so repeated callbacks are harmless, except that a
+// success reported for a FAILED transaction goes to applyLateSuccess.
+func (s *Service) finalizeTransaction(
+	ctx context.Context,
+	tx pgx.Tx,
+	existing payment.GikiWalletGatewayTransaction,
+	status PaymentStatus,
+	outcome gatewayOutcome,
+) (payment.GikiWalletGatewayTransaction, error) {
+	paymentQ := s.q.WithTx(tx)
 
-	conn, err := s.dbPool.Acquire(context.Background())
-	if err != nil {
-		log.Printf("failed to acquire db connection for polling: %v", err)
-		return
+	updated, err := paymentQ.FinalizeGatewayTransaction(ctx, payment.FinalizeGatewayTransactionParams{
+		Status:       payment.CurrentStatus(status),
+		GatewayRrn:   common.StringToText(outcome.RRN),
+		ResponseCode: common.StringToText(outcome.ResponseCode),
+		RawResponse:  marshalPayload(outcome.Response),
+		TxnRefNo:     existing.TxnRefNo,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		current, err := paymentQ.GetTransactionByTxnRefNo(ctx, existing.TxnRefNo)
+		if err != nil {
+			log.Printf("failed to reload transaction %s: %v", existing.TxnRefNo, err)
+			return existing, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+		if current.Status == payment.CurrentStatus(PaymentStatusFailed) && status == PaymentStatusSuccess {
+			return s.applyLateSuccess(ctx, tx, paymentQ, current, outcome)
+		}
+		log.Printf("transaction %s already %s, ignoring %s", existing.TxnRefNo, current.Status, status)
+		return current, nil
+	} else if err != nil {
+		log.Printf("failed to update transaction %s to %s: %v", existing.TxnRefNo, status, err)
+		return existing, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
 	}
-	defer conn.Release()
-
-	paymentQ := payment.New(conn)
 
-	// Acquire polling lock
-	_, err = paymentQ.UpdatePollingStatus(context.Background(), txRefNo)
-	if err != nil {
-		log.Printf("polling already started or transaction not found: %v", err)
-		return
+	if err := s.settleIntent(ctx, tx, updated); err != nil {
+		return existing, err
 	}
 
-	pollCtx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
-	defer cancel()
+	return updated, nil
+}
 
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
+// finalizeAndCommit finalizes a transaction in a database transaction of its
+// own, for callers such as the reconciler that are not already in one
+func (s *Service) finalizeAndCommit(
+	ctx context.Context,
+	existing payment.GikiWalletGatewayTransaction,
+	status PaymentStatus,
+	outcome gatewayOutcome,
+) (payment.GikiWalletGatewayTransaction, error) {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin finalizing %s: %v", existing.TxnRefNo, err)
+		return existing, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
 
-	for {
-		select {
-		case <-pollCtx.Done():
-			s.handlePollingTimeout(paymentQ, txRefNo)
-			return
+	updated, err := s.finalizeTransaction(ctx, tx, existing, status, outcome)
+	if err != nil {
+		return existing, err
+	}
 
-		case <-ticker.C:
-			if done := s.pollTransactionOnce(pollCtx, paymentQ, txRefNo); done {
-				return
-			}
-		}
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit %s as %s: %v", existing.TxnRefNo, status, err)
+		return existing, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
 	}
+	return updated, nil
 }
 
-// handlePollingTimeout handles timeout during polling
-func (s *Service) handlePollingTimeout(paymentQ *payment.Queries, txRefNo string) {
-	cleanupCtx := context.Background()
+// =============================================================================
+// PRIVATE SERVICE METHODS - Gateway Access
+// =============================================================================
 
-	err := paymentQ.UpdateGatewayTransactionStatus(cleanupCtx, payment.UpdateGatewayTransactionStatusParams{
-		Status:   payment.CurrentStatus(PaymentStatusFailed),
-		TxnRefNo: txRefNo,
-	})
-	if err != nil {
-		log.Printf("failed to update status on timeout: %v", err)
-	}
+// providerFor returns the provider of the merchant profile gatewayTxn was created under
+func (s *Service) providerFor(gatewayTxn payment.GikiWalletGatewayTransaction) (Provider, error) {
+	return s.providers.ProviderFor(PaymentMethod(gatewayTxn.PaymentMethod), gatewayTxn.MerchantProfile)
+}
 
-	if err := paymentQ.ClearPollingStatus(cleanupCtx, txRefNo); err != nil {
-		log.Printf("failed to clear polling status: %v", err)
+// callbackProfile picks the merchant profile whose credentials verify an
+// unverified callback or notification: the profile of the transaction it
+// names, or the default one when there is no such transaction
+func (s *Service) callbackProfile(ctx context.Context, gw gateway.Gateway, fields map[string]string) string {
+	txnRefNo := gw.CallbackTxnRefNo(fields)
+	if txnRefNo == "" {
+		return DefaultMerchantProfile
 	}
-}
 
-// pollTransactionOnce performs a single polling iteration, returns true if polling should stop
-func (s *Service) pollTransactionOnce(ctx context.Context, paymentQ *payment.Queries, txRefNo string) bool {
-	// Acquire rate limit token
-	if err := s.rateLimiter.Acquire(ctx); err != nil {
-		log.Printf("rate limiter acquire failed: %v", err)
-		return true
+	gatewayTxn, err := s.q.GetTransactionByTxnRefNo(ctx, txnRefNo)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("failed to load transaction %s for callback verification: %v", txnRefNo, err)
+		}
+		return DefaultMerchantProfile
 	}
+	return gatewayTxn.MerchantProfile
+}
 
-	// Call inquiry API
-	inquiryResult, err := s.gatewayClient.Inquiry(ctx, txRefNo)
+// inquire asks the gateway that owns the transaction for its current status
+// and records the exchange in the transaction's history
+func (s *Service) inquire(ctx context.Context, gatewayTxn payment.GikiWalletGatewayTransaction) (gateway.InquiryResponse, error) {
+	provider, err := s.providerFor(gatewayTxn)
 	if err != nil {
-		log.Printf("inquiry API failed (will retry): %v", err)
-		s.rateLimiter.Release()
-		return false
+		return gateway.InquiryResponse{}, err
 	}
 
-	status := gatewayStatusToPaymentStatus(inquiryResult.Status)
+	inquiryResult, err := provider.Gateway().Inquiry(ctx, gateway.InquiryRequest{TxnRefNo: gatewayTxn.TxnRefNo})
 
-	switch status {
-	case PaymentStatusSuccess, PaymentStatusFailed:
-		err := paymentQ.UpdateGatewayTransactionStatus(ctx, payment.UpdateGatewayTransactionStatusParams{
-			Status:   payment.CurrentStatus(status),
-			TxnRefNo: txRefNo,
-		})
-		if err != nil {
-			log.Printf("failed to update status to %s: %v", status, err)
-		}
+	// Recorded even when ctx has expired so timeouts show up in the history
+	outcome := inquiryOutcome(inquiryResult)
+	s.recordEvent(context.Background(), s.q, gatewayTxn, gatewayEvent{
+		Gateway:      provider.Gateway().Name(),
+		Kind:         GatewayEventInquiry,
+		Exchange:     inquiryResult.Exchange,
+		ResponseCode: outcome.ResponseCode,
+		RRN:          outcome.RRN,
+		Err:          err,
+	})
 
-		if err := paymentQ.ClearPollingStatus(ctx, txRefNo); err != nil {
-			log.Printf("failed to clear polling status: %v", err)
-		}
-		s.rateLimiter.Release()
-		return true
+	return inquiryResult, err
+}
 
+// =============================================================================
+// HELPERS - Expiry
+// =============================================================================
+
+// walletExpiryWindow is how long a wallet transaction stays payable at the gateway
+const walletExpiryWindow = 24 * time.Hour
+
+// transactionExpiry is when a transaction created at now stops being payable.
+// It is stored as expires_at and sent as the gateway's expiry time.
+func transactionExpiry(method PaymentMethod, now time.Time) time.Time {
+	switch method {
+	case PaymentMethodCard:
+		return now.Add(cardCheckoutWindow)
+	case PaymentMethodBankTransfer:
+		return now.Add(bankTransferReviewWindow)
 	default:
-		s.rateLimiter.Release()
-		return false
+		return now.Add(walletExpiryWindow)
 	}
 }
 
@@ -584,11 +910,3 @@ func NormalizeCNICLast6(cnic string) (string, error) {
 	return "", fmt.Errorf("CNIC must have at least 6 digits, got %d", len(digits))
 }
 
-// =============================================================================
-// HELPERS - Amount Conversion
-// =============================================================================
-
-// AmountToPaisa converts rupees to paisa as string
-func AmountToPaisa(amount int64) string {
-	return strconv.FormatInt(amount*100, 10)
-}
@@ -2,13 +2,25 @@ package payment
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
 	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
 	paymentdb "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
 	"github.com/hash-walker/giki-wallet/internal/payment/testutils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Test utility functions
@@ -125,44 +137,6 @@ func TestNormalizeCNICLast6(t *testing.T) {
 	}
 }
 
-func TestAmountToPaisa(t *testing.T) {
-	tests := []struct {
-		name   string
-		amount int64
-		want   string
-	}{
-		{
-			name:   "500 rupees",
-			amount: 500,
-			want:   "50000",
-		},
-		{
-			name:   "1000 rupees",
-			amount: 1000,
-			want:   "100000",
-		},
-		{
-			name:   "zero",
-			amount: 0,
-			want:   "0",
-		},
-		{
-			name:   "1 rupee",
-			amount: 1,
-			want:   "100",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := AmountToPaisa(tt.amount)
-			if got != tt.want {
-				t.Errorf("AmountToPaisa() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
-
 func TestGatewayStatusToPaymentStatus(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -224,14 +198,14 @@ func TestInitiateMWalletPayment_Success(t *testing.T) {
 		BillRefID:      "TEST_BILL_123",
 		PaymentMethod:  "MWALLET",
 		Status:         paymentdb.CurrentStatus("PENDING"),
-		Amount:         50000,
+		Amount:         money.Paisa(50000),
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
 
 	payload := TopUpRequest{
 		IdempotencyKey: uuid.New(),
-		Amount:         500,
+		Amount:         money.Paisa(50000),
 		Method:         PaymentMethodMWallet,
 		PhoneNumber:    "03123456789",
 		CNICLast6:      "123456",
@@ -239,13 +213,11 @@ func TestInitiateMWalletPayment_Success(t *testing.T) {
 
 	// Create a minimal service to test gateway interaction
 	// Note: This tests the gateway call logic, not the full service flow
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	// Test the gateway call directly
 	mwRequest := gateway.MWalletInitiateRequest{
-		AmountPaisa:       AmountToPaisa(payload.Amount),
+		Amount:            payload.Amount,
 		BillRefID:         gatewayTxn.BillRefID,
 		TxnRefNo:          gatewayTxn.TxnRefNo,
 		Description:       "GIKI Wallet Top Up",
@@ -255,7 +227,7 @@ func TestInitiateMWalletPayment_Success(t *testing.T) {
 		TxnExpiryDateTime: time.Now().Add(24 * time.Hour).Format("20060102150405"),
 	}
 
-	mwResponse, err := service.gatewayClient.SubmitMWallet(ctx, mwRequest)
+	mwResponse, err := service.mwalletGateway(t).SubmitMWallet(ctx, mwRequest)
 	if err != nil {
 		t.Fatalf("SubmitMWallet() error = %v", err)
 	}
@@ -275,13 +247,11 @@ func TestInitiateMWalletPayment_Pending(t *testing.T) {
 	mockServer.SetMWalletScenario(testutils.ScenarioPending)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
 	mwRequest := gateway.MWalletInitiateRequest{
-		AmountPaisa:       "50000",
+		Amount:            money.Paisa(50000),
 		BillRefID:         "TEST_BILL",
 		TxnRefNo:          "TEST_TXN",
 		Description:       "Test",
@@ -291,7 +261,7 @@ func TestInitiateMWalletPayment_Pending(t *testing.T) {
 		TxnExpiryDateTime: time.Now().Add(24 * time.Hour).Format("20060102150405"),
 	}
 
-	mwResponse, err := service.gatewayClient.SubmitMWallet(ctx, mwRequest)
+	mwResponse, err := service.mwalletGateway(t).SubmitMWallet(ctx, mwRequest)
 	if err != nil {
 		t.Fatalf("SubmitMWallet() error = %v", err)
 	}
@@ -311,13 +281,11 @@ func TestInitiateMWalletPayment_Failed(t *testing.T) {
 	mockServer.SetMWalletScenario(testutils.ScenarioFailed)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
 	mwRequest := gateway.MWalletInitiateRequest{
-		AmountPaisa:       "50000",
+		Amount:            money.Paisa(50000),
 		BillRefID:         "TEST_BILL",
 		TxnRefNo:          "TEST_TXN",
 		Description:       "Test",
@@ -327,7 +295,7 @@ func TestInitiateMWalletPayment_Failed(t *testing.T) {
 		TxnExpiryDateTime: time.Now().Add(24 * time.Hour).Format("20060102150405"),
 	}
 
-	mwResponse, err := service.gatewayClient.SubmitMWallet(ctx, mwRequest)
+	mwResponse, err := service.mwalletGateway(t).SubmitMWallet(ctx, mwRequest)
 	if err != nil {
 		t.Fatalf("SubmitMWallet() error = %v", err)
 	}
@@ -347,12 +315,10 @@ func TestInquiry_Success(t *testing.T) {
 	mockServer.SetInquiryScenario(testutils.ScenarioSuccess)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
-	inquiryResult, err := service.gatewayClient.Inquiry(ctx, "TEST_TXN_123")
+	inquiryResult, err := service.inquire(ctx, testTransaction("TEST_TXN_123"))
 	if err != nil {
 		t.Fatalf("Inquiry() error = %v", err)
 	}
@@ -372,12 +338,10 @@ func TestInquiry_Pending(t *testing.T) {
 	mockServer.SetInquiryScenario(testutils.ScenarioPending)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
-	inquiryResult, err := service.gatewayClient.Inquiry(ctx, "TEST_TXN_123")
+	inquiryResult, err := service.inquire(ctx, testTransaction("TEST_TXN_123"))
 	if err != nil {
 		t.Fatalf("Inquiry() error = %v", err)
 	}
@@ -393,12 +357,10 @@ func TestInquiry_Failed(t *testing.T) {
 	mockServer.SetInquiryScenario(testutils.ScenarioFailed)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
-	inquiryResult, err := service.gatewayClient.Inquiry(ctx, "TEST_TXN_123")
+	inquiryResult, err := service.inquire(ctx, testTransaction("TEST_TXN_123"))
 	if err != nil {
 		t.Fatalf("Inquiry() error = %v", err)
 	}
@@ -408,6 +370,103 @@ func TestInquiry_Failed(t *testing.T) {
 	}
 }
 
+func TestRefund(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario testutils.Scenario
+		channel  gateway.RefundChannel
+		expected gateway.Status
+	}{
+		{"wallet refund success", testutils.ScenarioSuccess, gateway.RefundChannelWallet, gateway.StatusSuccess},
+		{"card refund success", testutils.ScenarioSuccess, gateway.RefundChannelCard, gateway.StatusSuccess},
+		{"refund rejected", testutils.ScenarioFailed, gateway.RefundChannelWallet, gateway.StatusFailed},
+		{"refund pending", testutils.ScenarioPending, gateway.RefundChannelCard, gateway.StatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockGatewayServer("test_salt_123")
+			defer mockServer.Close()
+			mockServer.SetRefundScenario(tt.scenario)
+
+			gatewayClient := mockServer.CreateTestJazzCashClient()
+
+			refundResult, err := gatewayClient.Refund(context.Background(), gateway.RefundRequest{
+				Channel:  tt.channel,
+				TxnRefNo: "TEST_TXN_123",
+				Amount:   money.Paisa(25000),
+			})
+			if err != nil {
+				t.Fatalf("Refund() error = %v", err)
+			}
+
+			if refundResult.Status != tt.expected {
+				t.Errorf("Refund() status = %v, want %v", refundResult.Status, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRefund_ResponseLostAfterAccept covers a refund the gateway made but whose
+// answer never arrived: it must wait for review, not go back in the queue
+func TestRefund_ResponseLostAfterAccept(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.SetRefundScenario(testutils.ScenarioSuccess)
+	mockServer.LoseNextRefundResponses(1)
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+
+	response, err := gatewayClient.Refund(context.Background(), gateway.RefundRequest{
+		Channel:  gateway.RefundChannelWallet,
+		TxnRefNo: "TEST_TXN_123",
+		Amount:   money.Paisa(25000),
+	})
+	if err == nil {
+		t.Fatal("Refund() error = nil, want a transport error")
+	}
+	if got := mockServer.RefundsAccepted(); got != 1 {
+		t.Fatalf("gateway accepted %d refunds, want 1", got)
+	}
+
+	if got := refundOutcome(response, err); got != RefundStatusUnknown {
+		t.Errorf("refundOutcome() = %v, want %v", got, RefundStatusUnknown)
+	}
+}
+
+func TestRefundOutcome(t *testing.T) {
+	tests := []struct {
+		name     string
+		response gateway.RefundResponse
+		err      error
+		expected RefundStatus
+	}{
+		{"success", gateway.RefundResponse{Status: gateway.StatusSuccess}, nil, RefundStatusSuccess},
+		{"rejected", gateway.RefundResponse{Status: gateway.StatusFailed}, nil, RefundStatusFailed},
+		{"unsettled answer", gateway.RefundResponse{Status: gateway.StatusPending}, nil, RefundStatusUnknown},
+		{"timeout", gateway.RefundResponse{}, fmt.Errorf("refund API: %w", context.DeadlineExceeded), RefundStatusUnknown},
+		{"circuit open, never sent", gateway.RefundResponse{}, fmt.Errorf("refund API: %w", gateway.ErrCircuitOpen), RefundStatusPending},
+		{"unsupported", gateway.RefundResponse{}, fmt.Errorf("%w: Easypaisa refunds", gateway.ErrUnsupported), RefundStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refundOutcome(tt.response, tt.err); got != tt.expected {
+				t.Errorf("refundOutcome() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRefundChannel(t *testing.T) {
+	if got := refundChannel(PaymentMethodCard); got != gateway.RefundChannelCard {
+		t.Errorf("refundChannel(CARD) = %v, want %v", got, gateway.RefundChannelCard)
+	}
+	if got := refundChannel(PaymentMethodMWallet); got != gateway.RefundChannelWallet {
+		t.Errorf("refundChannel(MWALLET) = %v, want %v", got, gateway.RefundChannelWallet)
+	}
+}
+
 // TestHashVerification tests that the mock gateway produces valid hashes that pass verification
 func TestHashVerification_MockGateway(t *testing.T) {
 	mockServer := testutils.NewMockGatewayServer("test_salt_123")
@@ -415,13 +474,11 @@ func TestHashVerification_MockGateway(t *testing.T) {
 	mockServer.SetMWalletScenario(testutils.ScenarioSuccess)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
 	mwRequest := gateway.MWalletInitiateRequest{
-		AmountPaisa:       "50000",
+		Amount:            money.Paisa(50000),
 		BillRefID:         "TEST_BILL",
 		TxnRefNo:          "TEST_TXN",
 		Description:       "Test",
@@ -432,7 +489,7 @@ func TestHashVerification_MockGateway(t *testing.T) {
 	}
 
 	// This will fail if hash verification fails
-	mwResponse, err := service.gatewayClient.SubmitMWallet(ctx, mwRequest)
+	mwResponse, err := service.mwalletGateway(t).SubmitMWallet(ctx, mwRequest)
 	if err != nil {
 		t.Fatalf("SubmitMWallet() failed - hash verification may have failed: %v", err)
 	}
@@ -455,14 +512,12 @@ func TestHashVerification_Inquiry(t *testing.T) {
 	mockServer.SetInquiryScenario(testutils.ScenarioSuccess)
 
 	gatewayClient := mockServer.CreateTestJazzCashClient()
-	service := &Service{
-		gatewayClient: gatewayClient,
-	}
+	service := newTestService(gatewayClient)
 
 	ctx := context.Background()
 
 	// This will fail if hash verification fails
-	inquiryResult, err := service.gatewayClient.Inquiry(ctx, "TEST_TXN_123")
+	inquiryResult, err := service.inquire(ctx, testTransaction("TEST_TXN_123"))
 	if err != nil {
 		t.Fatalf("Inquiry() failed - hash verification may have failed: %v", err)
 	}
@@ -478,6 +533,1149 @@ func TestHashVerification_Inquiry(t *testing.T) {
 	}
 }
 
+func TestMWalletProvider_Initiate(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.SetMWalletScenario(testutils.ScenarioPending)
+
+	provider := NewMWalletProvider(mockServer.CreateTestJazzCashClient())
+
+	gatewayTxn := testTransaction("TEST_TXN_123")
+	gatewayTxn.ExpiresAt = time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC)
+
+	result, err := provider.Initiate(context.Background(), InitiateRequest{
+		Transaction: gatewayTxn,
+		Payload: TopUpRequest{
+			Amount:      money.Paisa(50000),
+			Method:      PaymentMethodMWallet,
+			PhoneNumber: "+923123456789",
+			CNICLast6:   "12345-1234567-1",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Initiate() error = %v", err)
+	}
+
+	// The stored expiry is what JazzCash is told, in Pakistan time
+	if got := result.Exchange.Request[gateway.FieldTxnExpiryDateTime]; got != "20240102120000" {
+		t.Errorf("Initiate() %s = %q, want 20240102120000", gateway.FieldTxnExpiryDateTime, got)
+	}
+
+	if result.Status != gateway.StatusPending {
+		t.Errorf("Initiate() status = %v, want %v", result.Status, gateway.StatusPending)
+	}
+
+	if result.ResponseCode != "157" {
+		t.Errorf("Initiate() responseCode = %v, want 157", result.ResponseCode)
+	}
+}
+
+// TestMWalletProvider_TimeoutAfterAccept checks that a submit the gateway took
+// but never answered is left unknown for the reconciler, not failed
+func TestMWalletProvider_TimeoutAfterAccept(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	if _, err := mockServer.AddScript(testutils.ScenarioScript{
+		PhoneNumber: "03123456789",
+		Result:      testutils.ScenarioSuccess,
+		DelayMillis: 300,
+	}); err != nil {
+		t.Fatalf("AddScript() error = %v", err)
+	}
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+	provider := NewMWalletProvider(gatewayClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	result, err := provider.Initiate(ctx, InitiateRequest{
+		Transaction: testTransaction("TEST_TXN_123"),
+		Payload: TopUpRequest{
+			Amount:      money.Paisa(50000),
+			Method:      PaymentMethodMWallet,
+			PhoneNumber: "+923123456789",
+			CNICLast6:   "12345-1234567-1",
+		},
+	})
+	if !errors.Is(err, errInitiateUnknown) {
+		t.Fatalf("Initiate() error = %v, want %v", err, errInitiateUnknown)
+	}
+	if result.Exchange.Request == nil {
+		t.Error("Initiate() exchange has no request, want the submit recorded")
+	}
+
+	// The gateway accepted the submit, so an inquiry settles it
+	if got := len(mockServer.Transactions()); got != 1 {
+		t.Fatalf("gateway saw %d transactions, want 1", got)
+	}
+	inquiryResult, err := newTestService(gatewayClient).inquire(context.Background(), testTransaction("TEST_TXN_123"))
+	if err != nil {
+		t.Fatalf("Inquiry() error = %v", err)
+	}
+	if inquiryResult.Status != gateway.StatusSuccess {
+		t.Errorf("inquiry status = %v, want %v", inquiryResult.Status, gateway.StatusSuccess)
+	}
+}
+
+func TestMWalletProvider_InvalidPhone(t *testing.T) {
+	provider := NewMWalletProvider(nil)
+
+	_, err := provider.Initiate(context.Background(), InitiateRequest{
+		Transaction: testTransaction("TEST_TXN_123"),
+		Payload: TopUpRequest{
+			Amount:      money.Paisa(50000),
+			Method:      PaymentMethodMWallet,
+			PhoneNumber: "123",
+			CNICLast6:   "123456",
+		},
+	})
+	if !errors.Is(err, ErrInvalidPhoneNumber) {
+		t.Errorf("Initiate() error = %v, want %v", err, ErrInvalidPhoneNumber)
+	}
+}
+
+func TestRegistry_UnknownMethod(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(PaymentMethodMWallet, NewMWalletProvider(nil))
+
+	if _, err := registry.Provider(PaymentMethodMWallet); err != nil {
+		t.Errorf("Provider(MWALLET) error = %v", err)
+	}
+
+	_, err := registry.Provider(PaymentMethod("BITCOIN"))
+	if !errors.Is(err, ErrInvalidPaymentMethod) {
+		t.Errorf("Provider(BITCOIN) error = %v, want %v", err, ErrInvalidPaymentMethod)
+	}
+}
+
+func TestRegistry_MerchantProfiles(t *testing.T) {
+	defaultWallet := NewMWalletProvider(nil)
+	studentWallet := NewMWalletProvider(nil)
+	easypaisa := NewEasypaisaProvider(nil)
+
+	registry := NewRegistry()
+	registry.Register(PaymentMethodMWallet, defaultWallet)
+	registry.Register(PaymentMethodEasypaisa, easypaisa)
+	registry.RegisterProfile("student_transport", PaymentMethodMWallet, studentWallet)
+	registry.Route("student", "student_transport")
+
+	tests := []struct {
+		name            string
+		method          PaymentMethod
+		businessContext string
+		wantProfile     string
+		wantProvider    Provider
+	}{
+		{"routed", PaymentMethodMWallet, "student", "student_transport", studentWallet},
+		{"unrouted", PaymentMethodMWallet, "employee", DefaultMerchantProfile, defaultWallet},
+		{"method missing from routed profile", PaymentMethodEasypaisa, "student", DefaultMerchantProfile, easypaisa},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, provider, err := registry.Resolve(tt.method, tt.businessContext)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if profile != tt.wantProfile || provider != tt.wantProvider {
+				t.Errorf("Resolve() = %s/%p, want %s/%p", profile, provider, tt.wantProfile, tt.wantProvider)
+			}
+		})
+	}
+
+	// Existing transactions never fall back to another merchant account
+	if _, err := registry.ProviderFor(PaymentMethodEasypaisa, "student_transport"); !errors.Is(err, ErrInvalidPaymentMethod) {
+		t.Errorf("ProviderFor(EASYPAISA, student_transport) error = %v, want %v", err, ErrInvalidPaymentMethod)
+	}
+	if got := strings.Join(registry.Profiles(), ","); got != "default,student_transport" {
+		t.Errorf("Profiles() = %q", got)
+	}
+	if got := registry.Methods(); len(got) != 2 {
+		t.Errorf("Methods() = %v, want MWALLET and EASYPAISA once each", got)
+	}
+}
+
+func TestInquiryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int32
+		expected time.Duration
+	}{
+		{"first retry", 0, 5 * time.Second},
+		{"second retry", 1, 10 * time.Second},
+		{"fourth retry", 3, 40 * time.Second},
+		{"capped", 6, 5 * time.Minute},
+		{"far past cap", 1000, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inquiryBackoff(tt.attempts); got != tt.expected {
+				t.Errorf("inquiryBackoff(%d) = %v, want %v", tt.attempts, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestTopUpHandler_LegacyAmount posts the body clients sent before amounts
+// carried their currency: a bare number of whole rupees
+func TestTopUpHandler_LegacyAmount(t *testing.T) {
+	const legacyBody = `{"idempotency_key":"6f1c1f0e-3c1a-4c55-9d59-2f8f5b0c7a11","amount":500,"method":"MWALLET","phone_number":"03123456789","cnic_last6":"123456"}`
+
+	var params TopUpRequest
+	if err := json.Unmarshal([]byte(legacyBody), &params); err != nil {
+		t.Fatalf("Unmarshal(legacy body) error = %v", err)
+	}
+	if !params.Amount.Equal(money.Paisa(50000)) || params.Method != PaymentMethodMWallet || params.CNICLast6 != "123456" {
+		t.Errorf("Unmarshal(legacy body) = %+v, want PKR 500.00 by MWALLET", params)
+	}
+
+	// Nothing listens on the pool's port, so a body that decodes ends at the database
+	pool, err := pgxpool.New(context.Background(), "postgres://wallet@127.0.0.1:1/wallet?connect_timeout=1")
+	if err != nil {
+		t.Fatalf("pgxpool.New() error = %v", err)
+	}
+	defer pool.Close()
+	handler := NewHandler(&Service{dbPool: pool}, HandlerConfig{})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"legacy amount", legacyBody, http.StatusInternalServerError},
+		{"current amount", strings.Replace(legacyBody, `"amount":500`, `"amount":{"minor_units":50000,"currency":"PKR"}`, 1), http.StatusInternalServerError},
+		{"fractional legacy amount", strings.Replace(legacyBody, `"amount":500`, `"amount":500.5`, 1), http.StatusBadRequest},
+		{"legacy amount as string", strings.Replace(legacyBody, `"amount":500`, `"amount":"500"`, 1), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/payments/topup", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.TopUp(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("TopUp() status = %d, want %d: %s", rec.Code, tt.expected, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTransactionExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if got, want := transactionExpiry(PaymentMethodMWallet, now), now.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("transactionExpiry(MWALLET) = %v, want %v", got, want)
+	}
+	if got, want := transactionExpiry(PaymentMethodCard, now), now.Add(cardCheckoutWindow); !got.Equal(want) {
+		t.Errorf("transactionExpiry(CARD) = %v, want %v", got, want)
+	}
+	if got, want := transactionExpiry(PaymentMethodBankTransfer, now), now.Add(bankTransferReviewWindow); !got.Equal(want) {
+		t.Errorf("transactionExpiry(BANK_TRANSFER) = %v, want %v", got, want)
+	}
+}
+
+func TestExpiredStatus(t *testing.T) {
+	tests := []struct {
+		inquired PaymentStatus
+		expected PaymentStatus
+	}{
+		{PaymentStatusSuccess, PaymentStatusSuccess},
+		{PaymentStatusFailed, PaymentStatusFailed},
+		{PaymentStatusPending, PaymentStatusFailed},
+		{PaymentStatusUnknown, PaymentStatusFailed},
+	}
+
+	for _, tt := range tests {
+		if got := expiredStatus(tt.inquired); got != tt.expected {
+			t.Errorf("expiredStatus(%s) = %s, want %s", tt.inquired, got, tt.expected)
+		}
+	}
+}
+
+func TestParseSettlementCSV(t *testing.T) {
+	file := `Transaction Date,TxnRefNo,Retrieval Reference No,Transaction Amount,Status
+2024-01-01 10:00:00,GIKITU20240101AAA,RRN1,500.00,Completed
+2024-01-02 11:30:00,GIKITU20240102BBB,RRN2,"1,000.00",Failed
+,,,1500.00,
+`
+
+	rows, err := ParseSettlementCSV(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("ParseSettlementCSV() error = %v", err)
+	}
+
+	// Totals line without references is skipped
+	if len(rows) != 2 {
+		t.Fatalf("ParseSettlementCSV() returned %d rows, want 2", len(rows))
+	}
+
+	if rows[0].TxnRefNo != "GIKITU20240101AAA" || rows[0].RRN != "RRN1" || !rows[0].Amount.Equal(money.Paisa(50000)) || !rows[0].Paid {
+		t.Errorf("ParseSettlementCSV() row 0 = %+v", rows[0])
+	}
+
+	if !rows[1].Amount.Equal(money.Paisa(100000)) || rows[1].Paid {
+		t.Errorf("ParseSettlementCSV() row 1 = %+v, want unpaid 100000", rows[1])
+	}
+
+	start, end := settlementPeriod(rows)
+	if start.Format("2006-01-02") != "2024-01-01" || end.Format("2006-01-02") != "2024-01-03" {
+		t.Errorf("settlementPeriod() = %v - %v, want 2024-01-01 - 2024-01-03", start, end)
+	}
+
+	if _, err := ParseSettlementCSV(strings.NewReader("Foo,Bar\n1,2\n")); !errors.Is(err, ErrInvalidSettlementFile) {
+		t.Errorf("ParseSettlementCSV() without known columns error = %v, want %v", err, ErrInvalidSettlementFile)
+	}
+}
+
+func TestCompareSettlementRow(t *testing.T) {
+	success := testTransaction("TEST_TXN_OK")
+	success.Status = paymentdb.CurrentStatus("SUCCESS")
+	success.Amount = money.Paisa(50000)
+
+	failed := testTransaction("TEST_TXN_FAIL")
+	failed.Status = paymentdb.CurrentStatus("FAILED")
+	failed.Amount = money.Paisa(50000)
+
+	tests := []struct {
+		name     string
+		row      SettlementRow
+		local    *paymentdb.GikiWalletGatewayTransaction
+		expected []DiscrepancyKind
+	}{
+		{"matched", SettlementRow{Amount: money.Paisa(50000), Paid: true}, &success, nil},
+		{"paid but failed locally", SettlementRow{Amount: money.Paisa(50000), Paid: true}, &failed, []DiscrepancyKind{DiscrepancyPaidNotSuccess}},
+		{"paid but unknown locally", SettlementRow{Amount: money.Paisa(50000), Paid: true}, nil, []DiscrepancyKind{DiscrepancyPaidNotSuccess}},
+		{"amount mismatch", SettlementRow{Amount: money.Paisa(40000), Paid: true}, &success, []DiscrepancyKind{DiscrepancyAmountMismatch}},
+		{"unpaid rows are ignored", SettlementRow{Amount: money.Paisa(50000), Paid: false}, &failed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareSettlementRow(tt.row, tt.local)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("compareSettlementRow() = %v, want %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("compareSettlementRow() = %v, want %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestInquiry_RecordsRedactedEvent(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.SetInquiryScenario(testutils.ScenarioSuccess)
+
+	db := &recordingDB{}
+	service := newTestService(mockServer.CreateTestJazzCashClient())
+	service.q = paymentdb.New(db)
+
+	txn := testTransaction("TEST_TXN_123")
+	if _, err := service.inquire(context.Background(), txn); err != nil {
+		t.Fatalf("inquire() error = %v", err)
+	}
+
+	if len(db.execs) != 1 {
+		t.Fatalf("inquire() recorded %d events, want 1", len(db.execs))
+	}
+
+	args := db.execs[0]
+	if args[0] != txn.ID {
+		t.Errorf("event gateway_transaction_id = %v, want %v", args[0], txn.ID)
+	}
+	if args[2] != paymentdb.GatewayEventKind(GatewayEventInquiry) {
+		t.Errorf("event kind = %v, want %v", args[2], GatewayEventInquiry)
+	}
+
+	request := string(args[3].([]byte))
+	if strings.Contains(request, "TEST_PASSWORD") {
+		t.Errorf("event request leaks pp_Password: %s", request)
+	}
+	if !strings.Contains(request, "TEST_TXN_123") {
+		t.Errorf("event request = %s, want it to contain the txn ref", request)
+	}
+	if response, _ := args[4].([]byte); len(response) == 0 {
+		t.Error("event response is empty")
+	}
+}
+
+func TestRedactFields(t *testing.T) {
+	fields := map[string]string{
+		gateway.FieldPassword:     "secret",
+		gateway.FieldMerchantMPIN: "1234",
+		gateway.FieldTxnRefNo:     "TEST_TXN_123",
+
+		gateway.EasypaisaFieldCredentials: "VEVTVDpURVNU",
+	}
+
+	redacted := gateway.RedactFields(fields)
+
+	if redacted[gateway.FieldPassword] == "secret" || redacted[gateway.FieldMerchantMPIN] == "1234" ||
+		redacted[gateway.EasypaisaFieldCredentials] == "VEVTVDpURVNU" {
+		t.Errorf("RedactFields() kept secrets: %v", redacted)
+	}
+	if redacted[gateway.FieldTxnRefNo] != "TEST_TXN_123" {
+		t.Errorf("RedactFields() changed pp_TxnRefNo to %q", redacted[gateway.FieldTxnRefNo])
+	}
+	if fields[gateway.FieldPassword] != "secret" {
+		t.Error("RedactFields() modified its input")
+	}
+}
+
+func TestInquiry_RetriesTransientFailures(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.SetInquiryScenario(testutils.ScenarioSuccess)
+	mockServer.FailNextRequests(2)
+
+	service := newTestService(mockServer.CreateTestJazzCashClient())
+
+	inquiryResult, err := service.inquire(context.Background(), testTransaction("TEST_TXN_123"))
+	if err != nil {
+		t.Fatalf("inquire() error = %v", err)
+	}
+	if inquiryResult.Status != gateway.StatusSuccess {
+		t.Errorf("inquire() status = %v, want %v", inquiryResult.Status, gateway.StatusSuccess)
+	}
+	if got := mockServer.RequestCount(); got != 3 {
+		t.Errorf("gateway received %d requests, want 3", got)
+	}
+}
+
+func TestSubmitMWallet_NotRetried(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.FailNextRequests(1)
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+
+	_, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{
+		Amount:   money.Paisa(10000),
+		TxnRefNo: "TEST_TXN_123",
+	})
+	if err == nil {
+		t.Fatal("SubmitMWallet() error = nil, want the 503")
+	}
+	if got := mockServer.RequestCount(); got != 1 {
+		t.Errorf("gateway received %d requests, want 1", got)
+	}
+}
+
+// TestJazzCashClient_Endpoints checks that each call is posted to its own API
+func TestJazzCashClient_Endpoints(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+	ctx := context.Background()
+
+	if _, err := gatewayClient.SubmitMWallet(ctx, gateway.MWalletInitiateRequest{
+		Amount:       money.Paisa(10000),
+		TxnRefNo:     "TEST_TXN_123",
+		MobileNumber: "03123456789",
+		CNICLast6:    "123456",
+	}); err != nil {
+		t.Fatalf("SubmitMWallet() error = %v", err)
+	}
+	if _, err := gatewayClient.Inquiry(ctx, gateway.InquiryRequest{TxnRefNo: "TEST_TXN_123"}); err != nil {
+		t.Fatalf("Inquiry() error = %v", err)
+	}
+	for _, channel := range []gateway.RefundChannel{gateway.RefundChannelWallet, gateway.RefundChannelCard} {
+		if _, err := gatewayClient.Refund(ctx, gateway.RefundRequest{
+			Channel:  channel,
+			TxnRefNo: "TEST_TXN_123",
+			Amount:   money.Paisa(10000),
+		}); err != nil {
+			t.Fatalf("Refund(%v) error = %v", channel, err)
+		}
+	}
+
+	want := []string{
+		"/ApplicationAPI/API/2.0/Purchase/DoMWalletTransaction",
+		"/ApplicationAPI/API/PaymentInquiry/Inquire",
+		"/ApplicationAPI/API/Purchase/domwalletrefundtransaction",
+		"/ApplicationAPI/API/authorize/Refund",
+	}
+	got := mockServer.RequestPaths()
+	if len(got) != len(want) {
+		t.Fatalf("gateway received %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d went to %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCircuitBreaker_FailsFast(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.FailNextRequests(1000)
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+	service := newTestService(gatewayClient)
+	threshold := testutils.TestResilienceConfig().FailureThreshold
+
+	for i := 0; i < threshold; i++ {
+		if _, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(50000), TxnRefNo: "TEST_TXN_123"}); err == nil {
+			t.Fatal("SubmitMWallet() error = nil during outage")
+		}
+	}
+
+	_, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(50000), TxnRefNo: "TEST_TXN_123"})
+	if !errors.Is(err, gateway.ErrCircuitOpen) {
+		t.Fatalf("SubmitMWallet() error = %v, want %v", err, gateway.ErrCircuitOpen)
+	}
+	if got := mockServer.RequestCount(); got != threshold {
+		t.Errorf("gateway received %d requests, want %d", got, threshold)
+	}
+
+	health := service.GatewayHealth()
+	if len(health) != 1 || health[0].Available || health[0].RetryAt == nil {
+		t.Errorf("GatewayHealth() = %+v, want one unavailable gateway with a retry time", health)
+	}
+}
+
+func TestStatusBroker_Publish(t *testing.T) {
+	broker := NewStatusBroker(nil)
+
+	updates, unsubscribe := broker.Subscribe("TEST_TXN_123")
+	other, unsubscribeOther := broker.Subscribe("TEST_TXN_456")
+	defer unsubscribeOther()
+
+	// An unread update is replaced by the newer one rather than blocking
+	broker.publish(StatusUpdate{TxnRefNo: "TEST_TXN_123", Status: PaymentStatusUnknown})
+	broker.publish(StatusUpdate{TxnRefNo: "TEST_TXN_123", Status: PaymentStatusSuccess})
+
+	select {
+	case update := <-updates:
+		if update.Status != PaymentStatusSuccess {
+			t.Errorf("received status %v, want %v", update.Status, PaymentStatusSuccess)
+		}
+	default:
+		t.Fatal("no update received")
+	}
+
+	select {
+	case update := <-other:
+		t.Errorf("subscriber of another transaction received %+v", update)
+	default:
+	}
+
+	unsubscribe()
+	broker.publish(StatusUpdate{TxnRefNo: "TEST_TXN_123", Status: PaymentStatusFailed})
+	select {
+	case update := <-updates:
+		t.Errorf("received %+v after unsubscribing", update)
+	default:
+	}
+	if _, ok := broker.subscribers["TEST_TXN_123"]; ok {
+		t.Error("unsubscribe left an empty subscriber set behind")
+	}
+}
+
+func TestEasypaisaProvider_Initiate(t *testing.T) {
+	tests := []struct {
+		name         string
+		scenario     testutils.Scenario
+		wantStatus   gateway.Status
+		wantCode     string
+		wantRRNEmpty bool
+	}{
+		{"success", testutils.ScenarioSuccess, gateway.StatusSuccess, "0000", false},
+		{"system error", testutils.ScenarioPending, gateway.StatusUnknown, "0001", true},
+		{"declined", testutils.ScenarioFailed, gateway.StatusFailed, "0013", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockEasypaisaServer("0123456789ABCDEF")
+			defer mockServer.Close()
+			mockServer.SetInitiateScenario(tt.scenario)
+
+			provider := NewEasypaisaProvider(mockServer.CreateTestEasypaisaClient())
+
+			result, err := provider.Initiate(context.Background(), InitiateRequest{
+				Transaction: testTransaction("TEST_TXN_123"),
+				Payload: TopUpRequest{
+					Amount:      money.Paisa(50000),
+					Method:      PaymentMethodEasypaisa,
+					PhoneNumber: "+923451234567",
+				},
+			})
+			if err != nil {
+				t.Fatalf("Initiate() error = %v", err)
+			}
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("Initiate() status = %v, want %v", result.Status, tt.wantStatus)
+			}
+			if result.ResponseCode != tt.wantCode {
+				t.Errorf("Initiate() responseCode = %v, want %v", result.ResponseCode, tt.wantCode)
+			}
+			if (result.RRN == "") != tt.wantRRNEmpty {
+				t.Errorf("Initiate() rrn = %q", result.RRN)
+			}
+			if result.Exchange.Request[gateway.EasypaisaFieldAmount] != "500.00" {
+				t.Errorf("Initiate() sent amount %q, want 500.00", result.Exchange.Request[gateway.EasypaisaFieldAmount])
+			}
+		})
+	}
+}
+
+func TestEasypaisaInquiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		scenario   testutils.Scenario
+		wantStatus gateway.Status
+	}{
+		{"paid", testutils.ScenarioSuccess, gateway.StatusSuccess},
+		{"pending", testutils.ScenarioPending, gateway.StatusPending},
+		{"failed", testutils.ScenarioFailed, gateway.StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServer := testutils.NewMockEasypaisaServer("0123456789ABCDEF")
+			defer mockServer.Close()
+			mockServer.SetInquiryScenario(tt.scenario)
+
+			service := newTestService(mockServer.CreateTestEasypaisaClient())
+
+			inquiryResult, err := service.inquire(context.Background(), testTransaction("TEST_TXN_123"))
+			if err != nil {
+				t.Fatalf("Inquiry() error = %v", err)
+			}
+			if inquiryResult.Status != tt.wantStatus {
+				t.Errorf("Inquiry() status = %v, want %v", inquiryResult.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestEasypaisa_WrongHashKeyRejected(t *testing.T) {
+	mockServer := testutils.NewMockEasypaisaServer("0123456789ABCDEF")
+	defer mockServer.Close()
+
+	// A client holding another key is refused like a real store would refuse
+	// it, and cannot verify the store's reply either
+	gatewayClient := gateway.NewEasypaisaClient(
+		"TEST_STORE_ID", "TEST_USERNAME", "TEST_PASSWORD", "FEDCBA9876543210",
+		mockServer.URL()+"/easypay-service/rest/v4/initiate-ma-transaction",
+		mockServer.URL()+"/easypay-service/rest/v4/inquire-transaction",
+		testutils.TestResilienceConfig(),
+	)
+
+	_, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{
+		Amount:       money.Paisa(50000),
+		TxnRefNo:     "TEST_TXN_123",
+		MobileNumber: "03451234567",
+	})
+	if err == nil || !strings.Contains(err.Error(), "hash") {
+		t.Errorf("SubmitMWallet() error = %v, want a hash verification failure", err)
+	}
+
+	_, err = gatewayClient.Inquiry(context.Background(), gateway.InquiryRequest{TxnRefNo: "TEST_TXN_123"})
+	if err == nil || !strings.Contains(err.Error(), "hash") {
+		t.Errorf("Inquiry() error = %v, want a hash verification failure", err)
+	}
+}
+
+func TestMockScript_SettlesAfterInquiries(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	if _, err := mockServer.AddScript(testutils.ScenarioScript{
+		PhoneNumber:          "03123456789",
+		Result:               testutils.ScenarioSuccess,
+		SettleAfterInquiries: 2,
+	}); err != nil {
+		t.Fatalf("AddScript() error = %v", err)
+	}
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+	service := newTestService(gatewayClient)
+
+	submitted, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{
+		Amount:       money.Paisa(50000),
+		TxnRefNo:     "TEST_TXN_123",
+		MobileNumber: "03123456789",
+	})
+	if err != nil {
+		t.Fatalf("SubmitMWallet() error = %v", err)
+	}
+	if submitted.Status != gateway.StatusPending {
+		t.Errorf("SubmitMWallet() status = %v, want %v", submitted.Status, gateway.StatusPending)
+	}
+
+	for i, want := range []gateway.Status{gateway.StatusPending, gateway.StatusSuccess} {
+		inquiryResult, err := service.inquire(context.Background(), testTransaction("TEST_TXN_123"))
+		if err != nil {
+			t.Fatalf("Inquiry() error = %v", err)
+		}
+		if inquiryResult.Status != want {
+			t.Errorf("inquiry %d status = %v, want %v", i+1, inquiryResult.Status, want)
+		}
+	}
+}
+
+func TestMockScript_Faults(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.AddScript(testutils.ScenarioScript{AmountPaisa: "1100", ServerErrors: 1})
+	mockServer.AddScript(testutils.ScenarioScript{AmountPaisa: "2200", CorruptHash: true})
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+
+	// A scripted 500 is a transport failure
+	_, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(1100), TxnRefNo: "TEST_TXN_500"})
+	if err == nil {
+		t.Error("SubmitMWallet() error = nil, want the scripted 500")
+	}
+
+	_, err = gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(2200), TxnRefNo: "TEST_TXN_HASH"})
+	if err == nil || !strings.Contains(err.Error(), "hash") {
+		t.Errorf("SubmitMWallet() error = %v, want a hash verification failure", err)
+	}
+
+	// Unscripted amounts keep the defaults
+	if _, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(3300), TxnRefNo: "TEST_TXN_OK"}); err != nil {
+		t.Errorf("SubmitMWallet() unscripted error = %v", err)
+	}
+}
+
+func TestMockScript_PushIPN(t *testing.T) {
+	mockServer := testutils.NewMockGatewayServer("test_salt_123")
+	defer mockServer.Close()
+	mockServer.AddScript(testutils.ScenarioScript{AmountPaisa: "50000", Result: testutils.ScenarioFailed})
+
+	gatewayClient := mockServer.CreateTestJazzCashClient()
+
+	notifications := make(chan gateway.Notification, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := readNotificationPayload(r)
+		if err != nil {
+			t.Errorf("readNotificationPayload() error = %v", err)
+		}
+		notification, err := gatewayClient.ParseAndVerifyNotification(r.Context(), payload)
+		if err != nil {
+			t.Errorf("ParseAndVerifyNotification() error = %v", err)
+		}
+		notifications <- notification
+	}))
+	defer receiver.Close()
+	mockServer.SetIPNURL(receiver.URL)
+
+	if err := mockServer.PushIPN("TEST_TXN_123", ""); !errors.Is(err, testutils.ErrUnknownTransaction) {
+		t.Errorf("PushIPN() before the transaction error = %v, want %v", err, testutils.ErrUnknownTransaction)
+	}
+
+	if _, err := gatewayClient.SubmitMWallet(context.Background(), gateway.MWalletInitiateRequest{Amount: money.Paisa(50000), TxnRefNo: "TEST_TXN_123"}); err != nil {
+		t.Fatalf("SubmitMWallet() error = %v", err)
+	}
+	if err := mockServer.PushIPN("TEST_TXN_123", ""); err != nil {
+		t.Fatalf("PushIPN() error = %v", err)
+	}
+
+	notification := <-notifications
+	if notification.TxnRefNo != "TEST_TXN_123" || notification.Status != gateway.StatusFailed {
+		t.Errorf("notification = %s %v, want TEST_TXN_123 %v", notification.TxnRefNo, notification.Status, gateway.StatusFailed)
+	}
+}
+
+func TestTopUpLimits_AmountChecks(t *testing.T) {
+	limits := TopUpLimits{
+		MinPerTransaction: money.Paisa(10000),
+		MaxPerTransaction: money.Paisa(5000000),
+	}
+
+	tests := []struct {
+		name     string
+		amount   money.Money
+		expected error
+	}{
+		{"at minimum", money.Paisa(10000), nil},
+		{"below minimum", money.Paisa(9999), ErrAmountBelowMinimum},
+		{"at maximum", money.Paisa(5000000), nil},
+		{"above maximum", money.Paisa(5000001), ErrAmountAboveMaximum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := limits.checkAmountLimits(tt.amount)
+			if len(checks) != 2 {
+				t.Fatalf("checkAmountLimits() ran %d checks, want 2", len(checks))
+			}
+			if err := auditLimitChecks("test", tt.amount, checks); !errors.Is(err, tt.expected) {
+				t.Errorf("checkAmountLimits(%s) error = %v, want %v", tt.amount, err, tt.expected)
+			}
+		})
+	}
+
+	if checks := (TopUpLimits{}).checkAmountLimits(money.Paisa(1)); len(checks) != 0 {
+		t.Errorf("checkAmountLimits() without limits ran %d checks, want 0", len(checks))
+	}
+}
+
+func TestTopUpLimits_UsageChecks(t *testing.T) {
+	limits := TopUpLimits{
+		DailyCap:   money.Paisa(1000000),
+		MonthlyCap: money.Paisa(5000000),
+		UserTypes: map[string]UserTypeLimits{
+			"student": {DailyCap: money.Paisa(200000)},
+		},
+		MaxAttemptsPerHour: 5,
+		MaxPending:         1,
+	}
+
+	tests := []struct {
+		name     string
+		amount   money.Money
+		usage    topUpUsage
+		expected error
+	}{
+		{"fresh user", money.Paisa(50000), topUpUsage{UserType: "employee"}, nil},
+		{"fills daily cap exactly", money.Paisa(50000), topUpUsage{UserType: "employee", DailyTotal: money.Paisa(950000)}, nil},
+		{"over daily cap", money.Paisa(50001), topUpUsage{UserType: "employee", DailyTotal: money.Paisa(950000)}, ErrDailyLimitExceeded},
+		{"student daily cap is lower", money.Paisa(50000), topUpUsage{UserType: "student", DailyTotal: money.Paisa(160000)}, ErrDailyLimitExceeded},
+		{"student keeps default monthly cap", money.Paisa(50000), topUpUsage{UserType: "student", MonthlyTotal: money.Paisa(4980000)}, ErrMonthlyLimitExceeded},
+		{"too many attempts", money.Paisa(50000), topUpUsage{AttemptsLastHour: 5}, ErrTooManyAttempts},
+		{"pending transaction", money.Paisa(50000), topUpUsage{PendingCount: 1}, ErrTooManyPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := auditLimitChecks("test", tt.amount, limits.checkUsageLimits(tt.amount, tt.usage))
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("checkUsageLimits() error = %v, want %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTopUpLimits_Windows(t *testing.T) {
+	// 20:30 UTC on 31 January is already 1 February in Pakistan
+	now := time.Date(2024, time.January, 31, 20, 30, 0, 0, time.UTC)
+
+	day, month, hour := TopUpLimits{}.windows(now)
+
+	if want := time.Date(2024, time.February, 1, 0, 0, 0, 0, common.PKT); !day.Equal(want) {
+		t.Errorf("windows() day = %v, want %v", day, want)
+	}
+	if want := time.Date(2024, time.February, 1, 0, 0, 0, 0, common.PKT); !month.Equal(want) {
+		t.Errorf("windows() month = %v, want %v", month, want)
+	}
+	if want := now.Add(-time.Hour); !hour.Equal(want) {
+		t.Errorf("windows() hour = %v, want %v", hour, want)
+	}
+}
+
+func TestPaymentCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, time.March, 5, 10, 30, 15, 123456000, time.UTC)
+	id := uuid.New()
+
+	gotTime, gotID, err := decodePaymentCursor(encodePaymentCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("decodePaymentCursor() error = %v", err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != id {
+		t.Errorf("decodePaymentCursor() = %v %v, want %v %v", gotTime, gotID, createdAt, id)
+	}
+
+	for _, cursor := range []string{"not base64!", "bm8tc2VwYXJhdG9y", encodePaymentCursor(createdAt, id)[:10]} {
+		if _, _, err := decodePaymentCursor(cursor); !errors.Is(err, ErrInvalidPaymentSearch) {
+			t.Errorf("decodePaymentCursor(%q) error = %v, want %v", cursor, err, ErrInvalidPaymentSearch)
+		}
+	}
+}
+
+func TestSearchParams(t *testing.T) {
+	userID := uuid.New()
+	minAmount := money.Paisa(10000)
+
+	params, err := searchParams(PaymentSearch{
+		UserID:    &userID,
+		Statuses:  []PaymentStatus{PaymentStatusPending, PaymentStatusUnknown},
+		Method:    PaymentMethodCard,
+		MinAmount: &minAmount,
+		RRN:       "RRN1",
+	})
+	if err != nil {
+		t.Fatalf("searchParams() error = %v", err)
+	}
+	if !params.UserID.Valid || params.UserID.Bytes != userID {
+		t.Errorf("searchParams() user = %v, want %v", params.UserID, userID)
+	}
+	if len(params.Statuses) != 2 || params.PaymentMethod.String != "CARD" || params.GatewayRrn.String != "RRN1" {
+		t.Errorf("searchParams() = %+v", params)
+	}
+	if !params.MinAmount.Valid || params.MinAmount.Int64 != 10000 || params.MaxAmount.Valid {
+		t.Errorf("searchParams() amounts = %v - %v, want 10000 - none", params.MinAmount, params.MaxAmount)
+	}
+	if params.TxnRefNo.Valid || params.CreatedFrom.Valid || params.AfterCreatedAt.Valid {
+		t.Errorf("searchParams() set filters that were not given: %+v", params)
+	}
+
+	if _, err := searchParams(PaymentSearch{Statuses: []PaymentStatus{"PAID"}}); !errors.Is(err, ErrInvalidPaymentSearch) {
+		t.Errorf("searchParams() with unknown status error = %v, want %v", err, ErrInvalidPaymentSearch)
+	}
+}
+
+func TestPaymentExportRecord(t *testing.T) {
+	record := paymentExportRecord(AdminPayment{
+		TxnRefNo:      "GIKITU20240101AAA",
+		UserName:      "=HYPERLINK(\"http://evil\")",
+		UserEmail:     "student@giki.edu.pk",
+		PaymentMethod: PaymentMethodMWallet,
+		Status:        PaymentStatusSuccess,
+		Amount:        money.Paisa(150050),
+		CreatedAt:     time.Date(2024, time.January, 1, 5, 0, 0, 0, time.UTC),
+	})
+
+	if len(record) != len(paymentExportHeader) {
+		t.Fatalf("paymentExportRecord() has %d fields, header has %d", len(record), len(paymentExportHeader))
+	}
+	if record[0] != "2024-01-01T10:00:00+05:00" {
+		t.Errorf("created_at = %q, want PKT time", record[0])
+	}
+	if record[4] != "'=HYPERLINK(\"http://evil\")" {
+		t.Errorf("user_name = %q, want formula neutralised", record[4])
+	}
+	if record[5] != "student@giki.edu.pk" {
+		t.Errorf("user_email = %q", record[5])
+	}
+	if record[8] != "150050" || record[9] != "1500.50" {
+		t.Errorf("amount = %q / %q, want 150050 / 1500.50", record[8], record[9])
+	}
+}
+
+func TestReadReceipt(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32)
+
+	tests := []struct {
+		name        string
+		receipt     string
+		contentType string
+		wantErr     bool
+	}{
+		{"png", png, "image/png", false},
+		{"jpeg", "\xff\xd8\xff\xe0" + strings.Repeat("\x00", 32), "image/jpeg", false},
+		{"pdf", "%PDF-1.7\n", "", true},
+		{"html claiming to be an image", "<html><script>alert(1)</script></html>", "", true},
+		{"empty", "", "", true},
+		{"too large", png + strings.Repeat("\x00", maxReceiptSize), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, contentType, err := readReceipt(strings.NewReader(tt.receipt))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidReceipt) {
+					t.Errorf("readReceipt() error = %v, want %v", err, ErrInvalidReceipt)
+				}
+				return
+			}
+			if err != nil || contentType != tt.contentType {
+				t.Errorf("readReceipt() = %q, %v; want %q", contentType, err, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestNormalizeBankReference(t *testing.T) {
+	if got, err := normalizeBankReference("  ft2401abc9 "); err != nil || got != "FT2401ABC9" {
+		t.Errorf("normalizeBankReference() = %q, %v; want FT2401ABC9", got, err)
+	}
+	for _, reference := range []string{"", "   ", strings.Repeat("A", 101)} {
+		if _, err := normalizeBankReference(reference); !errors.Is(err, ErrInvalidBankTransfer) {
+			t.Errorf("normalizeBankReference(%q) error = %v, want %v", reference, err, ErrInvalidBankTransfer)
+		}
+	}
+}
+
+func TestReviewOutcome(t *testing.T) {
+	tests := []struct {
+		decision BankTransferReview
+		reason   string
+		expected PaymentStatus
+		err      error
+	}{
+		{BankTransferApproved, "", PaymentStatusSuccess, nil},
+		{BankTransferRejected, "amount does not match the receipt", PaymentStatusFailed, nil},
+		{BankTransferRejected, "", "", ErrReviewReasonRequired},
+		{BankTransferPending, "", "", ErrInvalidReviewDecision},
+	}
+
+	for _, tt := range tests {
+		got, err := reviewOutcome(tt.decision, tt.reason)
+		if got != tt.expected || !errors.Is(err, tt.err) {
+			t.Errorf("reviewOutcome(%s, %q) = %s, %v; want %s, %v", tt.decision, tt.reason, got, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func TestReceiptObjectKey(t *testing.T) {
+	id := uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7")
+	now := time.Date(2024, time.February, 1, 3, 0, 0, 0, common.PKT) // still January in UTC
+
+	if got := receiptObjectKey(id, "image/webp", now); got != "receipts/2024/01/"+id.String()+".webp" {
+		t.Errorf("receiptObjectKey() = %s, want a UTC-dated .webp key", got)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to IntentState
+		expected bool
+	}{
+		{IntentRequiresPayment, IntentProcessing, true},
+		{IntentFailed, IntentProcessing, true},
+		{IntentProcessing, IntentSucceeded, true},
+		{IntentCancelled, IntentSucceeded, true},
+		{IntentSucceeded, IntentFulfilled, true},
+		{IntentRequiresPayment, IntentCancelled, true},
+		{IntentProcessing, IntentCancelled, false},
+		{IntentSucceeded, IntentProcessing, false},
+		{IntentFulfilled, IntentSucceeded, false},
+		{IntentRequiresPayment, IntentFulfilled, false},
+	}
+
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.expected {
+			t.Errorf("canTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.expected)
+		}
+	}
+}
+
+func TestFulfillmentBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		expected time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{3, 4 * time.Minute},
+		{7, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := fulfillmentBackoff(tt.attempts); got != tt.expected {
+			t.Errorf("fulfillmentBackoff(%d) = %s, want %s", tt.attempts, got, tt.expected)
+		}
+	}
+}
+
+func TestPurposeRegistry(t *testing.T) {
+	registry := NewPurposeRegistry()
+	if _, ok := registry.Handler(PurposeTopUp); ok {
+		t.Fatal("Handler() found a handler in an empty registry")
+	}
+
+	called := false
+	noop := PurposeHandlerFunc(func(ctx context.Context, tx pgx.Tx, intent PaymentIntent) error {
+		called = true
+		return nil
+	})
+	registry.Register("TRANSPORT_TICKET", noop)
+	registry.Register(PurposeTopUp, noop)
+
+	handler, ok := registry.Handler(PurposeTopUp)
+	if !ok {
+		t.Fatal("Handler() did not find the registered handler")
+	}
+	if err := handler.Fulfill(context.Background(), nil, PaymentIntent{}); err != nil || !called {
+		t.Errorf("Fulfill() error = %v, called = %v", err, called)
+	}
+
+	purposes := registry.Purposes()
+	if len(purposes) != 2 || purposes[0] != PurposeTopUp || purposes[1] != "TRANSPORT_TICKET" {
+		t.Errorf("Purposes() = %v, want sorted [TOPUP TRANSPORT_TICKET]", purposes)
+	}
+}
+
+func TestToPaymentIntent(t *testing.T) {
+	fulfilledAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, common.PKT)
+	intent := toPaymentIntent(paymentdb.GikiWalletPaymentIntent{
+		ID:          uuid.New(),
+		Purpose:     "TRANSPORT_TICKET",
+		State:       paymentdb.PaymentIntentStateFULFILLED,
+		Metadata:    []byte(`{"route":"GIKI-ISB"}`),
+		FulfilledAt: pgtype.Timestamptz{Time: fulfilledAt, Valid: true},
+	})
+
+	if intent.State != IntentFulfilled || intent.Metadata["route"] != "GIKI-ISB" {
+		t.Errorf("toPaymentIntent() = %+v, want FULFILLED with route metadata", intent)
+	}
+	if intent.FulfilledAt == nil || !intent.FulfilledAt.Equal(fulfilledAt) {
+		t.Errorf("FulfilledAt = %v, want %v", intent.FulfilledAt, fulfilledAt)
+	}
+}
+
+// newTestService creates a service whose MWallet provider talks to gw.
+// Writes go to a recordingDB so gateway calls can record their events.
+func newTestService(gw gateway.Gateway) *Service {
+	providers := NewRegistry()
+	providers.Register(PaymentMethodMWallet, NewMWalletProvider(gw))
+	return &Service{
+		q:         paymentdb.New(&recordingDB{}),
+		providers: providers,
+	}
+}
+
+// recordingDB keeps the arguments of every Exec and fails reads
+type recordingDB struct {
+	execs [][]any
+}
+
+func (db *recordingDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	db.execs = append(db.execs, args)
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (db *recordingDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return nil, errors.New("recordingDB: reads are not supported")
+}
+
+func (db *recordingDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return errorRow{}
+}
+
+type errorRow struct{}
+
+func (errorRow) Scan(dest ...any) error {
+	return errors.New("recordingDB: reads are not supported")
+}
+
+// mwalletGateway returns the gateway behind the MWallet provider
+func (s *Service) mwalletGateway(t *testing.T) gateway.Gateway {
+	t.Helper()
+	provider, err := s.providers.Provider(PaymentMethodMWallet)
+	if err != nil {
+		t.Fatalf("Provider() error = %v", err)
+	}
+	return provider.Gateway()
+}
+
+// testTransaction builds a pending MWallet transaction row
+func testTransaction(txnRefNo string) paymentdb.GikiWalletGatewayTransaction {
+	return paymentdb.GikiWalletGatewayTransaction{
+		ID:              uuid.New(),
+		TxnRefNo:        txnRefNo,
+		BillRefID:       "TEST_BILL",
+		PaymentMethod:   string(PaymentMethodMWallet),
+		Status:          paymentdb.CurrentStatus("PENDING"),
+		ExpiresAt:       time.Now().Add(walletExpiryWindow),
+		MerchantProfile: DefaultMerchantProfile,
+	}
+}
+
 // Helper function to create test context with user ID
 func createTestContext(userID uuid.UUID) context.Context {
 	ctx := context.Background()
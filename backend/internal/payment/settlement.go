@@ -0,0 +1,614 @@
+package payment
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidSettlementFile Unreadable or incomplete settlement file (400)
+	ErrInvalidSettlementFile = errors.New("invalid settlement file")
+
+	// ErrSettlementNotFound Unknown run or discrepancy (404)
+	ErrSettlementNotFound = errors.New("settlement record not found")
+
+	// ErrResolutionNoteRequired Resolving needs an explanation (400)
+	ErrResolutionNoteRequired = errors.New("resolution note is required")
+
+	// ErrDiscrepancyResolved Discrepancy was already resolved (409)
+	ErrDiscrepancyResolved = errors.New("discrepancy already resolved")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// SettlementImport describes one settlement file handed to ImportSettlement
+type SettlementImport struct {
+	Gateway    string    // gateway that produced the file, e.g. gateway.JazzCashName
+	SourceName string    // file name, for the run record
+	ImportedBy uuid.UUID // uuid.Nil when imported from the CLI
+
+	// Window of local SUCCESS transactions the file should cover.
+	// Derived from the file's transaction dates when left zero.
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// SettlementRow is one transaction line of a settlement file
+type SettlementRow struct {
+	Line     int
+	TxnRefNo string
+	RRN      string
+	Amount   money.Money
+	Paid     bool
+	TxnTime  time.Time // zero when the file has no date column
+}
+
+// settlementColumn is a field we read from the settlement CSV
+type settlementColumn int
+
+const (
+	columnTxnRefNo settlementColumn = iota
+	columnRRN
+	columnAmountRupees
+	columnAmountPaisa
+	columnStatus
+	columnTxnTime
+)
+
+// settlementHeaders maps normalised header names seen in gateway exports to columns
+var settlementHeaders = map[string]settlementColumn{
+	"txnrefno":               columnTxnRefNo,
+	"pptxnrefno":             columnTxnRefNo,
+	"transactionrefno":       columnTxnRefNo,
+	"transactionreferenceno": columnTxnRefNo,
+	"transactionreference":   columnTxnRefNo,
+	"merchanttxnrefno":       columnTxnRefNo,
+	"rrn":                    columnRRN,
+	"retrievalreferenceno":   columnRRN,
+	"retreivalreferenceno":   columnRRN,
+	"ppretreivalreferenceno": columnRRN,
+	"amount":                 columnAmountRupees,
+	"transactionamount":      columnAmountRupees,
+	"txnamount":              columnAmountRupees,
+	"ppamount":               columnAmountPaisa,
+	"status":                 columnStatus,
+	"transactionstatus":      columnStatus,
+	"txnstatus":              columnStatus,
+	"responsecode":           columnStatus,
+	"date":                   columnTxnTime,
+	"transactiondate":        columnTxnTime,
+	"transactiondatetime":    columnTxnTime,
+	"txndatetime":            columnTxnTime,
+	"pptxndatetime":          columnTxnTime,
+}
+
+// settlementPaidStatuses are status values that mean the gateway collected the money
+var settlementPaidStatuses = map[string]bool{
+	"completed":  true,
+	"success":    true,
+	"successful": true,
+	"paid":       true,
+	"settled":    true,
+	"000":        true,
+	"121":        true,
+	"200":        true,
+}
+
+// settlementTimeLayouts are the date formats seen in gateway exports (PKT)
+var settlementTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"20060102150405",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"2006-01-02",
+	"02/01/2006",
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Settlement Reconciliation
+// =============================================================================
+
+// ImportSettlement matches a gateway settlement file against gateway_transactions
+// and stores the run with every discrepancy found
+func (s *Service) ImportSettlement(ctx context.Context, imp SettlementImport, file io.Reader) (*SettlementReport, error) {
+	rows, err := ParseSettlementCSV(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if imp.PeriodStart.IsZero() || imp.PeriodEnd.IsZero() {
+		imp.PeriodStart, imp.PeriodEnd = settlementPeriod(rows)
+		if imp.PeriodStart.IsZero() {
+			return nil, fmt.Errorf("%w: file has no transaction dates, a period is required", ErrInvalidSettlementFile)
+		}
+	}
+	if !imp.PeriodEnd.After(imp.PeriodStart) {
+		return nil, fmt.Errorf("%w: period end must be after period start", ErrInvalidSettlementFile)
+	}
+
+	methods := s.providers.MethodsForGateway(imp.Gateway)
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGateway, imp.Gateway)
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin settlement import: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	paymentQ := s.q.WithTx(tx)
+
+	run, err := paymentQ.CreateSettlementRun(ctx, payment.CreateSettlementRunParams{
+		Gateway:     imp.Gateway,
+		SourceName:  imp.SourceName,
+		ImportedBy:  pgtype.UUID{Bytes: imp.ImportedBy, Valid: imp.ImportedBy != uuid.Nil},
+		PeriodStart: imp.PeriodStart,
+		PeriodEnd:   imp.PeriodEnd,
+	})
+	if err != nil {
+		log.Printf("failed to create settlement run: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+	}
+
+	var discrepancies []payment.CreateSettlementDiscrepancyParams
+	settled := make(map[uuid.UUID]bool, len(rows))
+	var matched int32
+
+	for _, row := range rows {
+		local, err := s.findSettledTransaction(ctx, paymentQ, row)
+		if err != nil {
+			return nil, err
+		}
+		if local != nil && row.Paid {
+			settled[local.ID] = true
+		}
+
+		kinds := compareSettlementRow(row, local)
+		if len(kinds) == 0 {
+			if row.Paid {
+				matched++
+			}
+			continue
+		}
+		for _, kind := range kinds {
+			discrepancies = append(discrepancies, newDiscrepancy(run.ID, kind, &row, local))
+		}
+	}
+
+	// Everything we marked SUCCESS in the window must appear in the file
+	successful, err := paymentQ.ListSuccessfulTransactionsBetween(ctx, payment.ListSuccessfulTransactionsBetweenParams{
+		Methods:     paymentMethodStrings(methods),
+		PeriodStart: imp.PeriodStart,
+		PeriodEnd:   imp.PeriodEnd,
+	})
+	if err != nil {
+		log.Printf("failed to list successful transactions: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	for i := range successful {
+		if !settled[successful[i].ID] {
+			discrepancies = append(discrepancies, newDiscrepancy(run.ID, DiscrepancySuccessNotSettled, nil, &successful[i]))
+		}
+	}
+
+	report := &SettlementReport{Discrepancies: make([]SettlementDiscrepancy, 0, len(discrepancies))}
+	for _, params := range discrepancies {
+		created, err := paymentQ.CreateSettlementDiscrepancy(ctx, params)
+		if err != nil {
+			log.Printf("failed to store settlement discrepancy: %v", err)
+			return nil, fmt.Errorf("%w: %v", ErrTransactionCreation, err)
+		}
+		report.Discrepancies = append(report.Discrepancies, toSettlementDiscrepancy(created))
+	}
+
+	run, err = paymentQ.CompleteSettlementRun(ctx, payment.CompleteSettlementRunParams{
+		ID:               run.ID,
+		RowCount:         int32(len(rows)),
+		MatchedCount:     matched,
+		DiscrepancyCount: int32(len(discrepancies)),
+	})
+	if err != nil {
+		log.Printf("failed to complete settlement run: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit settlement import: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	report.Run = toSettlementRun(run)
+	log.Printf("settlement run %s: %d rows, %d matched, %d discrepancies", run.ID, run.RowCount, run.MatchedCount, run.DiscrepancyCount)
+	return report, nil
+}
+
+// GetSettlementReport returns a stored run with its discrepancies
+func (s *Service) GetSettlementReport(ctx context.Context, runID uuid.UUID) (*SettlementReport, error) {
+	run, err := s.q.GetSettlementRun(ctx, runID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSettlementNotFound
+	} else if err != nil {
+		log.Printf("failed to load settlement run %s: %v", runID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	rows, err := s.q.ListSettlementDiscrepancies(ctx, runID)
+	if err != nil {
+		log.Printf("failed to list discrepancies of %s: %v", runID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	report := &SettlementReport{
+		Run:           toSettlementRun(run),
+		Discrepancies: make([]SettlementDiscrepancy, 0, len(rows)),
+	}
+	for _, row := range rows {
+		report.Discrepancies = append(report.Discrepancies, toSettlementDiscrepancy(row))
+	}
+	return report, nil
+}
+
+// ListSettlementRuns returns stored runs, newest first
+func (s *Service) ListSettlementRuns(ctx context.Context, limit, offset int32) ([]SettlementRun, error) {
+	rows, err := s.q.ListSettlementRuns(ctx, payment.ListSettlementRunsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("failed to list settlement runs: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	runs := make([]SettlementRun, 0, len(rows))
+	for _, row := range rows {
+		runs = append(runs, toSettlementRun(row))
+	}
+	return runs, nil
+}
+
+// ResolveDiscrepancy records how finance settled a discrepancy
+func (s *Service) ResolveDiscrepancy(ctx context.Context, discrepancyID uuid.UUID, note string) (*SettlementDiscrepancy, error) {
+	adminID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, ErrResolutionNoteRequired
+	}
+
+	resolved, err := s.q.ResolveSettlementDiscrepancy(ctx, payment.ResolveSettlementDiscrepancyParams{
+		ID:             discrepancyID,
+		ResolvedBy:     pgtype.UUID{Bytes: adminID, Valid: true},
+		ResolutionNote: common.StringToText(note),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := s.q.GetSettlementDiscrepancy(ctx, discrepancyID); err == nil {
+			return nil, ErrDiscrepancyResolved
+		}
+		return nil, ErrSettlementNotFound
+	} else if err != nil {
+		log.Printf("failed to resolve discrepancy %s: %v", discrepancyID, err)
+		return nil, fmt.Errorf("%w: %v", ErrTransactionUpdate, err)
+	}
+
+	result := toSettlementDiscrepancy(resolved)
+	return &result, nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Settlement Reconciliation
+// =============================================================================
+
+// findSettledTransaction looks a settlement row up by txn_ref_no, then by RRN
+func (s *Service) findSettledTransaction(
+	ctx context.Context,
+	paymentQ *payment.Queries,
+	row SettlementRow,
+) (*payment.GikiWalletGatewayTransaction, error) {
+	if row.TxnRefNo != "" {
+		local, err := paymentQ.GetTransactionByTxnRefNo(ctx, row.TxnRefNo)
+		if err == nil {
+			return &local, nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("failed to look up %s: %v", row.TxnRefNo, err)
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+	}
+
+	if row.RRN != "" {
+		local, err := paymentQ.GetTransactionByGatewayRRN(ctx, common.StringToText(row.RRN))
+		if err == nil {
+			return &local, nil
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("failed to look up RRN %s: %v", row.RRN, err)
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+	}
+
+	return nil, nil
+}
+
+// =============================================================================
+// HELPERS - Settlement Parsing
+// =============================================================================
+
+// ParseSettlementCSV reads a gateway settlement CSV. Columns are found by header
+// name so the column order and extra columns of different exports do not matter.
+func ParseSettlementCSV(r io.Reader) ([]SettlementRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot read header: %v", ErrInvalidSettlementFile, err)
+	}
+
+	columns := make(map[settlementColumn]int)
+	for i, name := range header {
+		if column, ok := settlementHeaders[normalizeHeader(name)]; ok {
+			if _, seen := columns[column]; !seen {
+				columns[column] = i
+			}
+		}
+	}
+
+	_, hasRef := columns[columnTxnRefNo]
+	_, hasRRN := columns[columnRRN]
+	_, hasRupees := columns[columnAmountRupees]
+	_, hasPaisa := columns[columnAmountPaisa]
+	if !hasRef && !hasRRN {
+		return nil, fmt.Errorf("%w: no transaction reference or RRN column", ErrInvalidSettlementFile)
+	}
+	if !hasRupees && !hasPaisa {
+		return nil, fmt.Errorf("%w: no amount column", ErrInvalidSettlementFile)
+	}
+
+	field := func(record []string, column settlementColumn) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []SettlementRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSettlementFile, line, err)
+		}
+
+		row := SettlementRow{
+			Line:     line,
+			TxnRefNo: field(record, columnTxnRefNo),
+			RRN:      field(record, columnRRN),
+			Paid:     true, // settlement files without a status column list collected payments only
+		}
+		if row.TxnRefNo == "" && row.RRN == "" {
+			continue // totals and blank lines
+		}
+
+		if hasPaisa {
+			row.Amount, err = money.ParseMinor(field(record, columnAmountPaisa), money.PKR)
+		} else {
+			row.Amount, err = money.ParseDecimal(field(record, columnAmountRupees), money.PKR)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: bad amount: %v", ErrInvalidSettlementFile, line, err)
+		}
+
+		if _, ok := columns[columnStatus]; ok {
+			row.Paid = settlementPaidStatuses[strings.ToLower(field(record, columnStatus))]
+		}
+
+		if raw := field(record, columnTxnTime); raw != "" {
+			row.TxnTime, err = parseSettlementTime(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidSettlementFile, line, err)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ParseSettlementPeriod parses an inclusive YYYY-MM-DD date range (PKT) into the
+// half-open window ImportSettlement expects. Empty input yields zero times.
+func ParseSettlementPeriod(from, to string) (time.Time, time.Time, error) {
+	if from == "" && to == "" {
+		return time.Time{}, time.Time{}, nil
+	}
+	if from == "" || to == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: both period dates are required", ErrInvalidSettlementFile)
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", from, common.PKT)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period start: %v", ErrInvalidSettlementFile, err)
+	}
+	end, err := time.ParseInLocation("2006-01-02", to, common.PKT)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period end: %v", ErrInvalidSettlementFile, err)
+	}
+
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// normalizeHeader lowercases a header and drops everything but letters and digits
+func normalizeHeader(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseSettlementTime parses a settlement timestamp in any known layout
+func parseSettlementTime(raw string) (time.Time, error) {
+	for _, layout := range settlementTimeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, common.PKT); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", raw)
+}
+
+// settlementPeriod covers the calendar days (PKT) of the dated rows
+func settlementPeriod(rows []SettlementRow) (time.Time, time.Time) {
+	var start, end time.Time
+	for _, row := range rows {
+		if row.TxnTime.IsZero() {
+			continue
+		}
+		if start.IsZero() || row.TxnTime.Before(start) {
+			start = row.TxnTime
+		}
+		if end.IsZero() || row.TxnTime.After(end) {
+			end = row.TxnTime
+		}
+	}
+	if start.IsZero() {
+		return time.Time{}, time.Time{}
+	}
+
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, common.PKT)
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, common.PKT).AddDate(0, 0, 1)
+	return startDay, endDay
+}
+
+// =============================================================================
+// HELPERS - Settlement Matching
+// =============================================================================
+
+// compareSettlementRow returns the discrepancies between a settlement row and
+// the local transaction it matched (nil when nothing matched)
+func compareSettlementRow(row SettlementRow, local *payment.GikiWalletGatewayTransaction) []DiscrepancyKind {
+	if !row.Paid {
+		return nil
+	}
+
+	var kinds []DiscrepancyKind
+	if local == nil || local.Status != payment.CurrentStatus(PaymentStatusSuccess) {
+		kinds = append(kinds, DiscrepancyPaidNotSuccess)
+	}
+	if local != nil && !local.Amount.Equal(row.Amount) {
+		kinds = append(kinds, DiscrepancyAmountMismatch)
+	}
+	return kinds
+}
+
+func newDiscrepancy(
+	runID uuid.UUID,
+	kind DiscrepancyKind,
+	row *SettlementRow,
+	local *payment.GikiWalletGatewayTransaction,
+) payment.CreateSettlementDiscrepancyParams {
+	params := payment.CreateSettlementDiscrepancyParams{
+		RunID: runID,
+		Kind:  payment.SettlementDiscrepancyKind(kind),
+	}
+	if row != nil {
+		params.TxnRefNo = common.StringToText(row.TxnRefNo)
+		params.GatewayRrn = common.StringToText(row.RRN)
+		params.GatewayAmountPaisa = pgtype.Int8{Int64: row.Amount.Minor(), Valid: true}
+	}
+	if local != nil {
+		params.TxnRefNo = common.StringToText(local.TxnRefNo)
+		if local.GatewayRrn.Valid {
+			params.GatewayRrn = local.GatewayRrn
+		}
+		params.LocalStatus = payment.NullCurrentStatus{CurrentStatus: local.Status, Valid: true}
+		params.LocalAmountPaisa = pgtype.Int8{Int64: local.Amount.Minor(), Valid: true}
+	}
+	return params
+}
+
+func paymentMethodStrings(methods []PaymentMethod) []string {
+	out := make([]string, len(methods))
+	for i, method := range methods {
+		out[i] = string(method)
+	}
+	return out
+}
+
+func toSettlementRun(run payment.GikiWalletSettlementRun) SettlementRun {
+	result := SettlementRun{
+		ID:               run.ID,
+		Gateway:          run.Gateway,
+		SourceName:       run.SourceName,
+		PeriodStart:      run.PeriodStart,
+		PeriodEnd:        run.PeriodEnd,
+		RowCount:         run.RowCount,
+		MatchedCount:     run.MatchedCount,
+		DiscrepancyCount: run.DiscrepancyCount,
+		CreatedAt:        run.CreatedAt,
+	}
+	if run.ImportedBy.Valid {
+		importedBy := uuid.UUID(run.ImportedBy.Bytes)
+		result.ImportedBy = &importedBy
+	}
+	return result
+}
+
+func toSettlementDiscrepancy(d payment.GikiWalletSettlementDiscrepancy) SettlementDiscrepancy {
+	result := SettlementDiscrepancy{
+		ID:             d.ID,
+		Kind:           DiscrepancyKind(d.Kind),
+		TxnRefNo:       common.TextToString(d.TxnRefNo),
+		GatewayRRN:     common.TextToString(d.GatewayRrn),
+		ResolutionNote: common.TextToString(d.ResolutionNote),
+	}
+	if d.LocalStatus.Valid {
+		result.LocalStatus = PaymentStatus(d.LocalStatus.CurrentStatus)
+	}
+	if d.LocalAmountPaisa.Valid {
+		amount := money.Paisa(d.LocalAmountPaisa.Int64)
+		result.LocalAmount = &amount
+	}
+	if d.GatewayAmountPaisa.Valid {
+		amount := money.Paisa(d.GatewayAmountPaisa.Int64)
+		result.GatewayAmount = &amount
+	}
+	if d.ResolvedAt.Valid {
+		resolvedAt := d.ResolvedAt.Time
+		result.ResolvedAt = &resolvedAt
+	}
+	if d.ResolvedBy.Valid {
+		resolvedBy := uuid.UUID(d.ResolvedBy.Bytes)
+		result.ResolvedBy = &resolvedBy
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+--- admin payments console
+
+-- name: SearchGatewayTransactions :many
+SELECT t.id, t.user_id, u.name AS user_name, u.email AS user_email,
+    t.txn_ref_no, t.bill_ref_id, t.payment_method, t.status, t.amount,
+    t.gateway_rrn, t.response_code, t.inquiry_attempts, t.merchant_profile, t.created_at, t.updated_at
+FROM giki_wallet.gateway_transactions t
+JOIN giki_wallet.users u ON u.id = t.user_id
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR t.user_id = sqlc.narg(user_id)::uuid)
+    AND (sqlc.narg(statuses)::text[] IS NULL OR t.status::text = ANY(sqlc.narg(statuses)::text[]))
+    AND (sqlc.narg(payment_method)::text IS NULL OR t.payment_method = sqlc.narg(payment_method)::text)
+    AND (sqlc.narg(created_from)::timestamptz IS NULL OR t.created_at >= sqlc.narg(created_from)::timestamptz)
+    AND (sqlc.narg(created_to)::timestamptz IS NULL OR t.created_at < sqlc.narg(created_to)::timestamptz)
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR t.amount >= sqlc.narg(min_amount)::bigint)
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR t.amount <= sqlc.narg(max_amount)::bigint)
+    AND (sqlc.narg(txn_ref_no)::text IS NULL OR t.txn_ref_no = sqlc.narg(txn_ref_no)::text)
+    AND (sqlc.narg(gateway_rrn)::text IS NULL OR t.gateway_rrn = sqlc.narg(gateway_rrn)::text)
+    AND (
+        sqlc.narg(after_created_at)::timestamptz IS NULL
+        OR (t.created_at, t.id) < (sqlc.narg(after_created_at)::timestamptz, sqlc.narg(after_id)::uuid)
+    )
+ORDER BY t.created_at DESC, t.id DESC
+LIMIT sqlc.arg(page_size);
+
+-- name: SummarizeGatewayTransactions :many
+SELECT t.status, COUNT(*)::bigint AS transaction_count, COALESCE(SUM(t.amount), 0)::bigint AS total_amount
+FROM giki_wallet.gateway_transactions t
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR t.user_id = sqlc.narg(user_id)::uuid)
+    AND (sqlc.narg(statuses)::text[] IS NULL OR t.status::text = ANY(sqlc.narg(statuses)::text[]))
+    AND (sqlc.narg(payment_method)::text IS NULL OR t.payment_method = sqlc.narg(payment_method)::text)
+    AND (sqlc.narg(created_from)::timestamptz IS NULL OR t.created_at >= sqlc.narg(created_from)::timestamptz)
+    AND (sqlc.narg(created_to)::timestamptz IS NULL OR t.created_at < sqlc.narg(created_to)::timestamptz)
+    AND (sqlc.narg(min_amount)::bigint IS NULL OR t.amount >= sqlc.narg(min_amount)::bigint)
+    AND (sqlc.narg(max_amount)::bigint IS NULL OR t.amount <= sqlc.narg(max_amount)::bigint)
+    AND (sqlc.narg(txn_ref_no)::text IS NULL OR t.txn_ref_no = sqlc.narg(txn_ref_no)::text)
+    AND (sqlc.narg(gateway_rrn)::text IS NULL OR t.gateway_rrn = sqlc.narg(gateway_rrn)::text)
+GROUP BY t.status
+ORDER BY t.status;
+
+-- name: ResolveUnknownTransaction :one
+UPDATE giki_wallet.gateway_transactions
+SET status = sqlc.arg(status),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE txn_ref_no = sqlc.arg(txn_ref_no) AND status = 'UNKNOWN'
+RETURNING *;
@@ -0,0 +1,35 @@
+--- bank transfer top-ups
+
+-- name: CreateBankTransfer :one
+INSERT INTO giki_wallet.bank_transfers (gateway_transaction_id, bank_reference, receipt_key, receipt_content_type)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetBankTransferByTransaction :one
+SELECT * FROM giki_wallet.bank_transfers
+WHERE gateway_transaction_id = $1;
+
+-- name: GetBankTransfer :one
+SELECT * FROM giki_wallet.bank_transfers
+WHERE id = $1;
+
+-- name: ListBankTransfers :many
+SELECT b.id, b.gateway_transaction_id, t.txn_ref_no, t.user_id, u.name AS user_name, u.email AS user_email,
+    t.amount, b.bank_reference, b.receipt_content_type, b.review_status, b.reviewed_by, b.review_reason,
+    b.reviewed_at, b.created_at
+FROM giki_wallet.bank_transfers b
+JOIN giki_wallet.gateway_transactions t ON t.id = b.gateway_transaction_id
+JOIN giki_wallet.users u ON u.id = t.user_id
+WHERE b.review_status = sqlc.arg(review_status)
+ORDER BY b.created_at
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ReviewBankTransfer :one
+UPDATE giki_wallet.bank_transfers
+SET review_status = sqlc.arg(review_status),
+    reviewed_by = sqlc.arg(reviewed_by),
+    review_reason = sqlc.arg(review_reason),
+    reviewed_at = NOW(),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND review_status = 'PENDING'
+RETURNING *;
@@ -0,0 +1,12 @@
+--- gateway event history
+
+-- name: RecordGatewayEvent :exec
+INSERT INTO giki_wallet.gateway_transaction_events (
+    gateway_transaction_id, gateway, kind, request, response, response_code, gateway_rrn, error
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListGatewayEvents :many
+SELECT * FROM giki_wallet.gateway_transaction_events
+WHERE gateway_transaction_id = $1
+ORDER BY created_at, id;
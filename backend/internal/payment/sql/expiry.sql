@@ -0,0 +1,45 @@
+--- expiry sweeper
+
+-- name: ClaimExpiredTransactions :many
+UPDATE giki_wallet.gateway_transactions
+SET lease_owner = sqlc.arg(lease_owner)::text,
+    lease_expires_at = NOW() + sqlc.arg(lease_seconds)::int * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM giki_wallet.gateway_transactions
+    WHERE status IN ('PENDING', 'UNKNOWN')
+        AND payment_method <> 'BANK_TRANSFER'
+        AND expires_at <= NOW() - sqlc.arg(grace_seconds)::int * INTERVAL '1 second'
+        AND next_inquiry_at <= NOW()
+        AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    ORDER BY expires_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+--- late successes
+
+-- name: MarkLateSuccess :one
+UPDATE giki_wallet.gateway_transactions
+SET status = 'SUCCESS',
+    gateway_rrn = COALESCE(sqlc.narg(gateway_rrn)::varchar, gateway_rrn),
+    response_code = COALESCE(sqlc.narg(response_code)::varchar, response_code),
+    raw_response = COALESCE(sqlc.narg(raw_response)::jsonb, raw_response),
+    updated_at = NOW()
+WHERE txn_ref_no = sqlc.arg(txn_ref_no) AND status = 'FAILED'
+RETURNING *;
+
+-- name: RecordLateSuccess :one
+INSERT INTO giki_wallet.gateway_late_successes (
+    gateway_transaction_id, source, previous_response_code, response_code, gateway_rrn, failed_at
+)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListLateSuccesses :many
+SELECT l.id, l.gateway_transaction_id, t.txn_ref_no, t.payment_method, t.amount,
+    l.source, l.previous_response_code, l.response_code, l.gateway_rrn, l.failed_at, l.created_at
+FROM giki_wallet.gateway_late_successes l
+JOIN giki_wallet.gateway_transactions t ON t.id = l.gateway_transaction_id
+ORDER BY l.created_at DESC
+LIMIT $1 OFFSET $2;
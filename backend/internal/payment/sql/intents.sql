@@ -0,0 +1,60 @@
+--- payment intents
+
+-- name: CreatePaymentIntent :one
+INSERT INTO giki_wallet.payment_intents (purpose, purpose_reference, amount, payer_id, metadata)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPaymentIntent :one
+SELECT * FROM giki_wallet.payment_intents
+WHERE id = $1;
+
+-- name: GetPaymentIntentForUpdate :one
+SELECT * FROM giki_wallet.payment_intents
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetActivePaymentIntent :one
+SELECT * FROM giki_wallet.payment_intents
+WHERE purpose = $1 AND purpose_reference = $2 AND state <> 'CANCELLED';
+
+-- name: TransitionPaymentIntent :one
+UPDATE giki_wallet.payment_intents
+SET state = sqlc.arg(state),
+    next_fulfillment_at = CASE WHEN sqlc.arg(state) = 'SUCCEEDED' THEN NOW() ELSE next_fulfillment_at END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND state::text = ANY(sqlc.arg(from_states)::text[])
+RETURNING *;
+
+-- name: MarkPaymentIntentFulfilled :one
+UPDATE giki_wallet.payment_intents
+SET state = 'FULFILLED',
+    fulfillment_attempts = fulfillment_attempts + 1,
+    next_fulfillment_at = NULL,
+    last_error = NULL,
+    fulfilled_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1 AND state = 'SUCCEEDED'
+RETURNING *;
+
+-- name: RecordFulfillmentFailure :exec
+UPDATE giki_wallet.payment_intents
+SET fulfillment_attempts = fulfillment_attempts + 1,
+    next_fulfillment_at = sqlc.arg(next_fulfillment_at),
+    last_error = sqlc.arg(last_error),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND state = 'SUCCEEDED';
+
+-- name: ClaimUnfulfilledIntent :one
+SELECT * FROM giki_wallet.payment_intents
+WHERE state = 'SUCCEEDED'
+    AND next_fulfillment_at <= NOW()
+    AND purpose = ANY(sqlc.arg(purposes)::text[])
+ORDER BY next_fulfillment_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: ListIntentTransactions :many
+SELECT * FROM giki_wallet.gateway_transactions
+WHERE payment_intent_id = $1
+ORDER BY created_at;
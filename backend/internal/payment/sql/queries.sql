@@ -1,11 +1,8 @@
 -- name: CreateGatewayTransaction :one
-INSERT INTO giki_wallet.gateway_transactions(user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, status, amount)
-VALUES ($1, $2,$3,$4, $5, $6, $7)
+INSERT INTO giki_wallet.gateway_transactions(user_id, idempotency_key, bill_ref_id, txn_ref_no, payment_method, status, amount, expires_at, merchant_profile, payment_intent_id)
+VALUES ($1, $2,$3,$4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
--- name: UpdateGatewayTransactionStatus :exec
-UPDATE giki_wallet.gateway_transactions SET status = $1 WHERE txn_ref_no = $2;
-
 -- name: GetByIdempotencyKey :one
 
 SELECT * FROM giki_wallet.gateway_transactions
@@ -23,15 +20,85 @@ LIMIT 1;
 SELECT * from giki_wallet.gateway_transactions
 WHERE txn_ref_no = $1;
 
---- update polling status
+-- name: GetTransactionByID :one
+SELECT * FROM giki_wallet.gateway_transactions
+WHERE id = $1;
+
+-- name: FinalizeGatewayTransaction :one
+UPDATE giki_wallet.gateway_transactions
+SET status = sqlc.arg(status),
+    gateway_rrn = COALESCE(sqlc.narg(gateway_rrn)::varchar, gateway_rrn),
+    response_code = COALESCE(sqlc.narg(response_code)::varchar, response_code),
+    raw_response = COALESCE(sqlc.narg(raw_response)::jsonb, raw_response),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE txn_ref_no = sqlc.arg(txn_ref_no) AND status IN ('PENDING', 'UNKNOWN')
+RETURNING *;
+
+-- name: MarkTransactionUnknown :one
+UPDATE giki_wallet.gateway_transactions
+SET status = 'UNKNOWN',
+    updated_at = NOW()
+WHERE id = $1 AND status = 'PENDING'
+RETURNING *;
+
+--- reconciliation worker
 
--- name: UpdatePollingStatus :one
+-- name: ClaimDueTransactions :many
 UPDATE giki_wallet.gateway_transactions
-SET is_polling = TRUE
-WHERE txn_ref_no = $1 AND is_polling = FALSE
+SET lease_owner = sqlc.arg(lease_owner)::text,
+    lease_expires_at = NOW() + sqlc.arg(lease_seconds)::int * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM giki_wallet.gateway_transactions
+    WHERE status IN ('PENDING', 'UNKNOWN')
+        AND payment_method <> 'BANK_TRANSFER'
+        AND next_inquiry_at <= NOW()
+        AND expires_at > NOW()
+        AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    ORDER BY next_inquiry_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
 RETURNING *;
 
--- name: ClearPollingStatus :exec
+-- name: RescheduleInquiry :exec
 UPDATE giki_wallet.gateway_transactions
-SET is_polling = FALSE
-WHERE txn_ref_no = $1;
\ No newline at end of file
+SET inquiry_attempts = inquiry_attempts + 1,
+    next_inquiry_at = sqlc.arg(next_inquiry_at),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(lease_owner)::text;
+
+--- IPN notifications
+
+-- name: RecordGatewayNotification :one
+INSERT INTO giki_wallet.gateway_notifications (gateway, txn_ref_no, dedup_key, response_code, payload)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (gateway, dedup_key) DO NOTHING
+RETURNING *;
+
+--- top-up limits
+
+-- name: GetTopUpUsage :one
+SELECT
+    u.user_type,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE t.status IN ('SUCCESS', 'PENDING', 'UNKNOWN') AND t.created_at >= sqlc.arg(day_start)::timestamptz
+    ), 0)::bigint AS daily_total,
+    COALESCE(SUM(t.amount) FILTER (
+        WHERE t.status IN ('SUCCESS', 'PENDING', 'UNKNOWN') AND t.created_at >= sqlc.arg(month_start)::timestamptz
+    ), 0)::bigint AS monthly_total,
+    COUNT(t.id) FILTER (WHERE t.created_at >= sqlc.arg(hour_start)::timestamptz)::bigint AS attempts_last_hour,
+    COUNT(t.id) FILTER (
+        WHERE t.status IN ('PENDING', 'UNKNOWN') AND t.payment_method <> 'BANK_TRANSFER'
+    )::bigint AS pending_count
+FROM giki_wallet.users u
+LEFT JOIN giki_wallet.gateway_transactions t
+    ON t.user_id = u.id
+    AND (t.created_at >= sqlc.arg(window_start)::timestamptz OR t.status IN ('PENDING', 'UNKNOWN'))
+    -- Payments for tickets and other purposes are not top-ups
+    AND t.payment_intent_id IN (SELECT i.id FROM giki_wallet.payment_intents i WHERE i.purpose = 'TOPUP' AND i.payer_id = u.id)
+WHERE u.id = sqlc.arg(user_id)
+GROUP BY u.user_type;
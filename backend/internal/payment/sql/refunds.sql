@@ -0,0 +1,86 @@
+-- name: GetTransactionForUpdate :one
+SELECT * FROM giki_wallet.gateway_transactions
+WHERE txn_ref_no = $1
+FOR UPDATE;
+
+-- name: CreateRefundRequest :one
+INSERT INTO giki_wallet.refund_requests (gateway_transaction_id, idempotency_key, requested_by, reason, amount)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetRefundByIdempotencyKey :one
+SELECT * FROM giki_wallet.refund_requests
+WHERE idempotency_key = $1;
+
+-- name: GetRefundRequest :one
+SELECT * FROM giki_wallet.refund_requests
+WHERE id = $1;
+
+-- name: ListRefundsForTransaction :many
+SELECT * FROM giki_wallet.refund_requests
+WHERE gateway_transaction_id = $1
+ORDER BY created_at;
+
+-- name: SumCommittedRefunds :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS committed
+FROM giki_wallet.refund_requests
+WHERE gateway_transaction_id = $1 AND status <> 'FAILED';
+
+--- refund worker
+
+-- name: ClaimDueRefunds :many
+UPDATE giki_wallet.refund_requests
+SET lease_owner = sqlc.arg(lease_owner)::text,
+    lease_expires_at = NOW() + sqlc.arg(lease_seconds)::int * INTERVAL '1 second'
+WHERE id IN (
+    SELECT id FROM giki_wallet.refund_requests
+    WHERE (status = 'PENDING' OR (status = 'FAILED' AND hold_released_at IS NULL))
+        AND next_attempt_at <= NOW()
+        AND (lease_expires_at IS NULL OR lease_expires_at < NOW())
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordRefundAttempt :one
+UPDATE giki_wallet.refund_requests
+SET status = sqlc.arg(status),
+    attempts = attempts + 1,
+    response_code = sqlc.arg(response_code),
+    response_message = sqlc.arg(response_message),
+    raw_response = sqlc.arg(raw_response),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    processed_at = CASE WHEN sqlc.arg(status) = 'PENDING' THEN NULL ELSE NOW() END,
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND lease_owner = sqlc.arg(lease_owner)::text
+RETURNING *;
+
+-- name: MarkRefundHoldReleased :exec
+UPDATE giki_wallet.refund_requests
+SET hold_released_at = NOW(),
+    lease_owner = NULL,
+    lease_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+
+--- refund review
+
+-- name: ListUnknownRefunds :many
+SELECT * FROM giki_wallet.refund_requests
+WHERE status = 'UNKNOWN'
+ORDER BY updated_at
+LIMIT $1 OFFSET $2;
+
+-- name: ResolveUnknownRefund :one
+UPDATE giki_wallet.refund_requests
+SET status = sqlc.arg(status),
+    response_message = sqlc.arg(response_message),
+    next_attempt_at = NOW(),
+    processed_at = CASE WHEN sqlc.arg(status) = 'PENDING' THEN NULL ELSE NOW() END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id) AND status = 'UNKNOWN'
+RETURNING *;
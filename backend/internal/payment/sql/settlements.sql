@@ -0,0 +1,54 @@
+-- name: GetTransactionByGatewayRRN :one
+SELECT * FROM giki_wallet.gateway_transactions
+WHERE gateway_rrn = $1
+LIMIT 1;
+
+-- name: ListSuccessfulTransactionsBetween :many
+SELECT * FROM giki_wallet.gateway_transactions
+WHERE status = 'SUCCESS'
+    AND payment_method = ANY(sqlc.arg(methods)::text[])
+    AND created_at >= sqlc.arg(period_start)
+    AND created_at < sqlc.arg(period_end)
+ORDER BY created_at;
+
+-- name: CreateSettlementRun :one
+INSERT INTO giki_wallet.settlement_runs (gateway, source_name, imported_by, period_start, period_end)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CompleteSettlementRun :one
+UPDATE giki_wallet.settlement_runs
+SET row_count = $2, matched_count = $3, discrepancy_count = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateSettlementDiscrepancy :one
+INSERT INTO giki_wallet.settlement_discrepancies (
+    run_id, kind, txn_ref_no, gateway_rrn, local_status, local_amount_paisa, gateway_amount_paisa
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetSettlementRun :one
+SELECT * FROM giki_wallet.settlement_runs
+WHERE id = $1;
+
+-- name: ListSettlementRuns :many
+SELECT * FROM giki_wallet.settlement_runs
+ORDER BY created_at DESC
+LIMIT $1 OFFSET $2;
+
+-- name: ListSettlementDiscrepancies :many
+SELECT * FROM giki_wallet.settlement_discrepancies
+WHERE run_id = $1
+ORDER BY kind, txn_ref_no;
+
+-- name: GetSettlementDiscrepancy :one
+SELECT * FROM giki_wallet.settlement_discrepancies
+WHERE id = $1;
+
+-- name: ResolveSettlementDiscrepancy :one
+UPDATE giki_wallet.settlement_discrepancies
+SET resolved_at = NOW(), resolved_by = $2, resolution_note = $3
+WHERE id = $1 AND resolved_at IS NULL
+RETURNING *;
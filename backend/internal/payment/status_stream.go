@@ -0,0 +1,166 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statusChannel is the Postgres NOTIFY channel gateway_transactions status
+// changes are announced on (see the gateway_transactions_status_notify trigger)
+const statusChannel = "gateway_transaction_status"
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// StatusUpdate is one status change of a gateway transaction
+type StatusUpdate struct {
+	TxnRefNo string        `json:"txn_ref_no"`
+	Status   PaymentStatus `json:"status"`
+}
+
+// StatusBroker fans status changes out to the clients watching a transaction.
+//
+// It LISTENs on statusChannel instead of being told about changes directly, so
+// a transaction finalized by another replica's reconciler or IPN reaches the
+// client streaming from this one.
+type StatusBroker struct {
+	dbPool *pgxpool.Pool
+	done   chan struct{}
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan StatusUpdate]struct{}
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// NewStatusBroker creates a broker; call Run to start receiving notifications
+func NewStatusBroker(dbPool *pgxpool.Pool) *StatusBroker {
+	return &StatusBroker{
+		dbPool:      dbPool,
+		done:        make(chan struct{}),
+		subscribers: make(map[string]map[chan StatusUpdate]struct{}),
+	}
+}
+
+// =============================================================================
+// PUBLIC BROKER METHODS
+// =============================================================================
+
+// Run listens for status notifications until ctx is cancelled, reconnecting
+// with backoff if the connection drops
+func (b *StatusBroker) Run(ctx context.Context) {
+	log.Printf("status broker listening on %s", statusChannel)
+	defer log.Printf("status broker stopped")
+	defer close(b.done)
+
+	var failures int32
+	for {
+		started := time.Now()
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that stayed up for a while starts the backoff over
+		if time.Since(started) > time.Minute {
+			failures = 0
+		}
+		delay := inquiryBackoff(failures)
+		failures++
+		log.Printf("status listener disconnected, retrying in %s: %v", delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Done is closed once Run has returned, so open streams can end before the
+// server shuts down instead of holding it up
+func (b *StatusBroker) Done() <-chan struct{} {
+	return b.done
+}
+
+// Subscribe returns a channel receiving status changes of txnRefNo and a
+// function that must be called to stop receiving them. Only the latest
+// undelivered update is kept, which is all a status stream needs.
+func (b *StatusBroker) Subscribe(txnRefNo string) (<-chan StatusUpdate, func()) {
+	updates := make(chan StatusUpdate, 1)
+
+	b.mu.Lock()
+	if b.subscribers[txnRefNo] == nil {
+		b.subscribers[txnRefNo] = make(map[chan StatusUpdate]struct{})
+	}
+	b.subscribers[txnRefNo][updates] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[txnRefNo], updates)
+		if len(b.subscribers[txnRefNo]) == 0 {
+			delete(b.subscribers, txnRefNo)
+		}
+	}
+	return updates, unsubscribe
+}
+
+// =============================================================================
+// PRIVATE BROKER METHODS
+// =============================================================================
+
+// listen holds one pooled connection in LISTEN and publishes what arrives on it
+func (b *StatusBroker) listen(ctx context.Context) error {
+	conn, err := b.dbPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Don't hand a listening connection back to the pool
+		_, _ = conn.Exec(context.Background(), "UNLISTEN *")
+		conn.Release()
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+statusChannel); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var update StatusUpdate
+		if err := json.Unmarshal([]byte(notification.Payload), &update); err != nil {
+			log.Printf("ignoring malformed status notification %q: %v", notification.Payload, err)
+			continue
+		}
+		b.publish(update)
+	}
+}
+
+// publish hands update to every subscriber of its transaction without blocking
+func (b *StatusBroker) publish(update StatusUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for updates := range b.subscribers[update.TxnRefNo] {
+		// Replace an update the subscriber has not read yet
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- update
+	}
+}
@@ -0,0 +1,174 @@
+package payment
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/common"
+	payment "github.com/hash-walker/giki-wallet/internal/payment/payment_db"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// Sweeper closes PENDING/UNKNOWN gateway transactions whose expires_at has
+// passed.
+//
+// A transaction is never failed on age alone: once it is Grace past expiry the
+// sweeper makes one final inquiry and only fails it if the gateway still has
+// not reported it paid. An inquiry that errors is retried with the usual
+// backoff. Rows are claimed with the same lease as the Reconciler, so both can
+// run on every replica.
+type Sweeper struct {
+	service  *Service
+	owner    string
+	interval time.Duration
+	grace    time.Duration
+	lease    time.Duration
+	batch    int32
+}
+
+// SweeperConfig tunes the expiry sweep
+type SweeperConfig struct {
+	Interval  time.Duration // how often expired rows are claimed
+	Grace     time.Duration // how long past expires_at the gateway gets to settle on its own
+	Lease     time.Duration // how long a claim is held before another replica may take it
+	BatchSize int32         // rows claimed per tick
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// DefaultSweeperConfig returns the settings used in production
+func DefaultSweeperConfig() SweeperConfig {
+	return SweeperConfig{
+		Interval:  time.Minute,
+		Grace:     5 * time.Minute,
+		Lease:     30 * time.Second,
+		BatchSize: 20,
+	}
+}
+
+// NewSweeper creates a sweeper that inquires through service's providers
+func NewSweeper(service *Service, config SweeperConfig) *Sweeper {
+	return &Sweeper{
+		service:  service,
+		owner:    reconcilerOwnerID(),
+		interval: config.Interval,
+		grace:    config.Grace,
+		lease:    config.Lease,
+		batch:    config.BatchSize,
+	}
+}
+
+// =============================================================================
+// PUBLIC SWEEPER METHODS
+// =============================================================================
+
+// Run claims and closes expired transactions until ctx is cancelled.
+// In-flight inquiries are allowed to finish before Run returns.
+func (sw *Sweeper) Run(ctx context.Context) {
+	log.Printf("expiry sweeper %s started", sw.owner)
+	defer log.Printf("expiry sweeper %s stopped", sw.owner)
+
+	ticker := time.NewTicker(sw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sw.tick(ctx)
+		}
+	}
+}
+
+// =============================================================================
+// PRIVATE SWEEPER METHODS
+// =============================================================================
+
+// tick claims one batch of expired rows and sweeps them concurrently
+func (sw *Sweeper) tick(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("expiry sweeper panic: %v", rec)
+		}
+	}()
+
+	claimed, err := sw.service.q.ClaimExpiredTransactions(ctx, payment.ClaimExpiredTransactionsParams{
+		LeaseOwner:   sw.owner,
+		LeaseSeconds: int32(sw.lease / time.Second),
+		GraceSeconds: int32(sw.grace / time.Second),
+		BatchSize:    sw.batch,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("failed to claim expired transactions: %v", err)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, gatewayTxn := range claimed {
+		wg.Add(1)
+		go func(gatewayTxn payment.GikiWalletGatewayTransaction) {
+			defer wg.Done()
+			defer func() {
+				if rec := recover(); rec != nil {
+					log.Printf("expiry sweeper panic on %s: %v", gatewayTxn.TxnRefNo, rec)
+				}
+			}()
+			sw.sweep(ctx, gatewayTxn)
+		}(gatewayTxn)
+	}
+	wg.Wait()
+}
+
+// sweep makes the final inquiry for an expired row and closes it, unless the
+// gateway could not be asked
+func (sw *Sweeper) sweep(ctx context.Context, gatewayTxn payment.GikiWalletGatewayTransaction) {
+	inquiryCtx, cancel := context.WithTimeout(ctx, sw.lease)
+	defer cancel()
+
+	if err := sw.service.rateLimiter.Acquire(inquiryCtx); err != nil {
+		sw.service.rescheduleInquiry(gatewayTxn, sw.owner)
+		return
+	}
+	inquiryResult, err := sw.service.inquire(inquiryCtx, gatewayTxn)
+	sw.service.rateLimiter.Release()
+
+	if err != nil {
+		log.Printf("final inquiry failed for expired %s (attempt %d): %v", gatewayTxn.TxnRefNo, gatewayTxn.InquiryAttempts+1, err)
+		sw.service.rescheduleInquiry(gatewayTxn, sw.owner)
+		return
+	}
+
+	status := expiredStatus(gatewayStatusToPaymentStatus(inquiryResult.Status))
+	if status == PaymentStatusFailed {
+		log.Printf("transaction %s expired at %s, closing as %s (gateway said %s)",
+			gatewayTxn.TxnRefNo, gatewayTxn.ExpiresAt.In(common.PKT).Format("2006-01-02 15:04:05"), status, inquiryResult.Status)
+	}
+
+	// Finalizing is not bound to the request context so shutdown cannot lose a settled result
+	if _, err := sw.service.finalizeAndCommit(context.Background(), gatewayTxn, status, inquiryOutcome(inquiryResult)); err != nil {
+		log.Printf("failed to close expired %s as %s: %v", gatewayTxn.TxnRefNo, status, err)
+	}
+}
+
+// =============================================================================
+// HELPERS - Expiry
+// =============================================================================
+
+// expiredStatus is the final status of an expired transaction given what the
+// final inquiry reported: only a reported success survives expiry
+func expiredStatus(inquired PaymentStatus) PaymentStatus {
+	if inquired == PaymentStatusSuccess {
+		return PaymentStatusSuccess
+	}
+	return PaymentStatusFailed
+}
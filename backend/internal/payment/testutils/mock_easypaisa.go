@@ -0,0 +1,246 @@
+package testutils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
+)
+
+const (
+	mockEasypaisaStoreID  = "TEST_STORE_ID"
+	mockEasypaisaUsername = "TEST_USERNAME"
+	mockEasypaisaPassword = "TEST_PASSWORD"
+
+	easypaisaInitiatePath = "/easypay-service/rest/v4/initiate-ma-transaction"
+	easypaisaInquiryPath  = "/easypay-service/rest/v4/inquire-transaction"
+)
+
+// MockEasypaisaServer represents a mock Easypaisa merchant API for testing.
+// It rejects requests whose Credentials header or merchantHashedReq is wrong,
+// and signs its replies with the store's hash key.
+type MockEasypaisaServer struct {
+	server            *httptest.Server
+	hashKey           string
+	verifier          *gateway.EasypaisaClient // computes merchantHashedReq with the same key
+	initiateCode      string                   // responseCode for initiate requests
+	transactionStatus string                   // transactionStatus for inquiry requests
+	failNext          int                      // Requests still to be answered with 503
+	requests          int                      // Requests received
+	mu                sync.RWMutex
+}
+
+// NewMockEasypaisaServer creates a new mock Easypaisa server. hashKey must be
+// a valid AES key (16, 24 or 32 bytes).
+func NewMockEasypaisaServer(hashKey string) *MockEasypaisaServer {
+	mock := &MockEasypaisaServer{
+		hashKey:           hashKey,
+		verifier:          gateway.NewEasypaisaClient("", "", "", hashKey, "", "", gateway.DefaultResilienceConfig()),
+		initiateCode:      "0000", // Default: SUCCESS
+		transactionStatus: "PAID", // Default: SUCCESS
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(easypaisaInitiatePath, mock.handleInitiate)
+	mux.HandleFunc(easypaisaInquiryPath, mock.handleInquiry)
+
+	mock.server = httptest.NewServer(mock.countAndFail(mux))
+	return mock
+}
+
+// SetInitiateScenario sets the response scenario for mobile account initiate requests
+func (m *MockEasypaisaServer) SetInitiateScenario(scenario Scenario) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch scenario {
+	case ScenarioSuccess:
+		m.initiateCode = "0000"
+	case ScenarioPending:
+		m.initiateCode = "0001"
+	case ScenarioFailed:
+		m.initiateCode = "0013"
+	}
+}
+
+// SetInquiryScenario sets the transactionStatus returned by inquiries
+func (m *MockEasypaisaServer) SetInquiryScenario(scenario Scenario) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch scenario {
+	case ScenarioSuccess:
+		m.transactionStatus = "PAID"
+	case ScenarioPending:
+		m.transactionStatus = "PENDING"
+	case ScenarioFailed:
+		m.transactionStatus = "FAILED"
+	}
+}
+
+// FailNextRequests makes the next n requests fail with 503, as during an outage
+func (m *MockEasypaisaServer) FailNextRequests(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failNext = n
+}
+
+// RequestCount returns how many requests reached the server, failed ones included
+func (m *MockEasypaisaServer) RequestCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.requests
+}
+
+// URL returns the base URL of the mock server
+func (m *MockEasypaisaServer) URL() string {
+	return m.server.URL
+}
+
+// Close shuts down the mock server
+func (m *MockEasypaisaServer) Close() {
+	m.server.Close()
+}
+
+// CreateTestEasypaisaClient creates an EasypaisaClient configured to use the mock server
+func (m *MockEasypaisaServer) CreateTestEasypaisaClient() *gateway.EasypaisaClient {
+	return gateway.NewEasypaisaClient(
+		mockEasypaisaStoreID,
+		mockEasypaisaUsername,
+		mockEasypaisaPassword,
+		m.hashKey,
+		m.server.URL+easypaisaInitiatePath,
+		m.server.URL+easypaisaInquiryPath,
+		TestResilienceConfig(),
+	)
+}
+
+// countAndFail counts every request and answers 503 while failures are queued
+func (m *MockEasypaisaServer) countAndFail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.requests++
+		fail := m.failNext > 0
+		if fail {
+			m.failNext--
+		}
+		m.mu.Unlock()
+
+		if fail {
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleInitiate handles mobile account initiate requests
+func (m *MockEasypaisaServer) handleInitiate(w http.ResponseWriter, r *http.Request) {
+	requestData, ok := m.decodeSigned(w, r)
+	if !ok {
+		return
+	}
+
+	m.mu.RLock()
+	responseCode := m.initiateCode
+	m.mu.RUnlock()
+
+	response := map[string]any{
+		gateway.EasypaisaFieldOrderID:      requestData[gateway.EasypaisaFieldOrderID],
+		gateway.EasypaisaFieldStoreID:      requestData[gateway.EasypaisaFieldStoreID],
+		gateway.EasypaisaFieldResponseCode: responseCode,
+		gateway.EasypaisaFieldResponseDesc: easypaisaResponseDesc(responseCode),
+	}
+	if responseCode == "0000" {
+		response[gateway.EasypaisaFieldTransactionID] = "TEST_EP_" + requestData[gateway.EasypaisaFieldOrderID]
+	}
+
+	m.writeSigned(w, response)
+}
+
+// handleInquiry handles transaction inquiry requests
+func (m *MockEasypaisaServer) handleInquiry(w http.ResponseWriter, r *http.Request) {
+	requestData, ok := m.decodeSigned(w, r)
+	if !ok {
+		return
+	}
+
+	m.mu.RLock()
+	transactionStatus := m.transactionStatus
+	m.mu.RUnlock()
+
+	response := map[string]any{
+		gateway.EasypaisaFieldOrderID:           requestData[gateway.EasypaisaFieldOrderID],
+		gateway.EasypaisaFieldStoreID:           requestData[gateway.EasypaisaFieldStoreID],
+		gateway.EasypaisaFieldResponseCode:      "0000",
+		gateway.EasypaisaFieldResponseDesc:      "SUCCESS",
+		gateway.EasypaisaFieldTransactionStatus: transactionStatus,
+		gateway.EasypaisaFieldTransactionID:     "TEST_EP_" + requestData[gateway.EasypaisaFieldOrderID],
+	}
+
+	m.writeSigned(w, response)
+}
+
+// decodeSigned decodes a request after checking its credentials and hash,
+// answering the error itself when either is wrong
+func (m *MockEasypaisaServer) decodeSigned(w http.ResponseWriter, r *http.Request) (gateway.EasypaisaFields, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	expected := base64.StdEncoding.EncodeToString([]byte(mockEasypaisaUsername + ":" + mockEasypaisaPassword))
+	if r.Header.Get(gateway.EasypaisaFieldCredentials) != expected {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	var requestData gateway.EasypaisaFields
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := m.verifier.VerifyEasypaisaHash(requestData); err != nil {
+		m.writeSigned(w, map[string]any{
+			gateway.EasypaisaFieldResponseCode: "0002",
+			gateway.EasypaisaFieldResponseDesc: "Invalid merchantHashedReq: " + err.Error(),
+		})
+		return nil, false
+	}
+
+	return requestData, true
+}
+
+// writeSigned answers a reply carrying merchantHashedReq over its fields
+func (m *MockEasypaisaServer) writeSigned(w http.ResponseWriter, response map[string]any) {
+	fields := make(gateway.EasypaisaFields, len(response))
+	for k, v := range response {
+		fields[k] = fmt.Sprintf("%v", v)
+	}
+	hash, err := m.verifier.EasypaisaHash(fields)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response[gateway.EasypaisaFieldHash] = hash
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// easypaisaResponseDesc returns the description Easypaisa sends with a response code
+func easypaisaResponseDesc(code string) string {
+	switch code {
+	case "0000":
+		return "SUCCESS"
+	case "0001":
+		return "SYSTEM ERROR"
+	case "0013":
+		return "LOW BALANCE"
+	default:
+		return "Transaction status: " + code
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hash-walker/giki-wallet/internal/payment/gateway"
 )
@@ -21,7 +22,18 @@ type MockGatewayServer struct {
 	integritySalt     string
 	mwResponseCode    string // Response code for MWallet requests
 	inquiryResponseCode string // Response code for Inquiry requests
+	refundResponseCode string // Response code for Refund requests
+	refundsAccepted   int          // Refund requests processed, whether or not the answer got back
+	loseRefunds       int          // Refunds still to be processed but answered with 500
+	failNext          int          // Requests still to be answered with 503
+	requests          int          // Requests received
+	paths             []string     // URL path of every request received, in order
 	mu                sync.RWMutex // Protect response codes
+	scripts           []*ScenarioScript          // Per phone number / amount overrides, first match wins
+	nextScriptID      int
+	transactions      map[string]*SimTransaction // Transactions seen, by pp_TxnRefNo
+	ipnURL            string                     // Where IPN pushes are posted; empty disables them
+	handler           http.Handler
 }
 
 // Scenario represents different test scenarios
@@ -35,21 +47,41 @@ const (
 
 // NewMockGatewayServer creates a new mock gateway server
 func NewMockGatewayServer(integritySalt string) *MockGatewayServer {
+	mock := NewMockGateway(integritySalt)
+	mock.server = httptest.NewServer(mock.handler)
+	return mock
+}
+
+// NewMockGateway creates the mock gateway without starting a server, for
+// callers that serve Handler themselves (see cmd/jazzcash-sim)
+func NewMockGateway(integritySalt string) *MockGatewayServer {
 	mock := &MockGatewayServer{
 		integritySalt:      integritySalt,
 		mwResponseCode:      "000", // Default: SUCCESS
 		inquiryResponseCode: "000", // Default: SUCCESS
+		refundResponseCode:  "000", // Default: SUCCESS
+		transactions:        make(map[string]*SimTransaction),
 	}
 
 	mux := http.NewServeMux()
-	// Handle MWallet on both endpoints (since SubmitMWallet currently uses statusInquiryURL)
 	mux.HandleFunc("/ApplicationAPI/API/2.0/Purchase/DoMWalletTransaction", mock.handleMWallet)
-	mux.HandleFunc("/ApplicationAPI/API/PaymentInquiry/Inquire", mock.handleInquiryOrMWallet)
-
-	mock.server = httptest.NewServer(mux)
+	mux.HandleFunc("/ApplicationAPI/API/PaymentInquiry/Inquire", mock.handleInquiry)
+	mux.HandleFunc("/ApplicationAPI/API/Purchase/domwalletrefundtransaction", mock.handleRefund)
+	mux.HandleFunc("/ApplicationAPI/API/authorize/Refund", mock.handleRefund)
+	// Hosted card page, under the test client's path and the real sandbox path
+	mux.HandleFunc(cardPagePath, mock.handleCardPage)
+	mux.HandleFunc("/CustomerPortal/transactionmanagement/merchantform/", mock.handleCardPage)
+	mux.HandleFunc(cardCompletePath, mock.handleCardComplete)
+
+	mock.handler = mock.countAndFail(mux)
 	return mock
 }
 
+// Handler returns the mock's HTTP handler
+func (m *MockGatewayServer) Handler() http.Handler {
+	return m.handler
+}
+
 // SetMWalletScenario sets the response scenario for MWallet requests
 func (m *MockGatewayServer) SetMWalletScenario(scenario Scenario) {
 	m.mu.Lock()
@@ -78,6 +110,56 @@ func (m *MockGatewayServer) SetInquiryScenario(scenario Scenario) {
 	}
 }
 
+// SetRefundScenario sets the response scenario for Refund requests
+func (m *MockGatewayServer) SetRefundScenario(scenario Scenario) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch scenario {
+	case ScenarioSuccess:
+		m.refundResponseCode = "000"
+	case ScenarioPending:
+		m.refundResponseCode = "157"
+	case ScenarioFailed:
+		m.refundResponseCode = "101"
+	}
+}
+
+// LoseNextRefundResponses makes the next n refunds go through but answers
+// them with 500, as when the gateway's response is lost on the way back
+func (m *MockGatewayServer) LoseNextRefundResponses(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loseRefunds = n
+}
+
+// RefundsAccepted is how many refunds the mock has processed
+func (m *MockGatewayServer) RefundsAccepted() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.refundsAccepted
+}
+
+// FailNextRequests makes the next n requests fail with 503, as during an outage
+func (m *MockGatewayServer) FailNextRequests(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failNext = n
+}
+
+// RequestCount returns how many requests reached the server, failed ones included
+func (m *MockGatewayServer) RequestCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.requests
+}
+
+// RequestPaths returns the URL path of every request received, in order
+func (m *MockGatewayServer) RequestPaths() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.paths...)
+}
+
 // URL returns the base URL of the mock server
 func (m *MockGatewayServer) URL() string {
 	return m.server.URL
@@ -85,25 +167,62 @@ func (m *MockGatewayServer) URL() string {
 
 // Close shuts down the mock server
 func (m *MockGatewayServer) Close() {
-	m.server.Close()
+	if m.server != nil {
+		m.server.Close()
+	}
+}
+
+// TestResilienceConfig is DefaultResilienceConfig with retry delays short enough for tests
+func TestResilienceConfig() gateway.ResilienceConfig {
+	config := gateway.DefaultResilienceConfig()
+	config.RetryBaseDelay = time.Millisecond
+	config.RetryMaxDelay = 5 * time.Millisecond
+	return config
 }
 
 // CreateTestJazzCashClient creates a JazzCashClient configured to use the mock server
 func (m *MockGatewayServer) CreateTestJazzCashClient() *gateway.JazzCashClient {
 	baseURL := m.server.URL
 	return gateway.NewJazzCashClient(
-		"TEST_MERCHANT_ID",
-		"TEST_PASSWORD",
-		m.integritySalt,
-		"TEST_MPIN",
+		gateway.NewCredentialStore(gateway.JazzCashCredentials{
+			Version:       "test",
+			MerchantID:    "TEST_MERCHANT_ID",
+			Password:      "TEST_PASSWORD",
+			IntegritySalt: m.integritySalt,
+			MerchantMPIN:  "TEST_MPIN",
+		}, 0),
 		"http://localhost:8080/callback",
 		baseURL,
 		baseURL+"/ApplicationAPI/API/2.0/Purchase/DoMWalletTransaction",
-		baseURL+"/ApplicationAPI/API/CardPayment",
+		baseURL+cardPagePath,
 		baseURL+"/ApplicationAPI/API/PaymentInquiry/Inquire",
+		baseURL+"/ApplicationAPI/API/Purchase/domwalletrefundtransaction",
+		baseURL+"/ApplicationAPI/API/authorize/Refund",
+		TestResilienceConfig(),
+		nil,
 	)
 }
 
+// countAndFail counts every request and answers 503 while failures are queued
+func (m *MockGatewayServer) countAndFail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.requests++
+		m.paths = append(m.paths, r.URL.Path)
+		fail := m.failNext > 0
+		if fail {
+			m.failNext--
+		}
+		m.mu.Unlock()
+
+		if fail {
+			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // handleMWallet handles MWallet payment requests
 func (m *MockGatewayServer) handleMWallet(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -120,39 +239,19 @@ func (m *MockGatewayServer) handleMWallet(w http.ResponseWriter, r *http.Request
 	m.handleMWalletRequest(w, requestData)
 }
 
-// handleInquiryOrMWallet handles both Inquiry and MWallet requests (routes based on request content)
-func (m *MockGatewayServer) handleInquiryOrMWallet(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Peek at request body to determine if it's MWallet or Inquiry
-	var requestData map[string]any
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Check if it's an MWallet request (has pp_TxnType = "MWALLET" or has pp_MobileNumber)
-	if txnType, ok := requestData["pp_TxnType"].(string); ok && txnType == "MWALLET" {
-		m.handleMWalletRequest(w, requestData)
-		return
-	}
-	if _, ok := requestData["pp_MobileNumber"]; ok {
-		m.handleMWalletRequest(w, requestData)
+// handleMWalletRequest handles MWallet request with already-decoded JSON
+func (m *MockGatewayServer) handleMWalletRequest(w http.ResponseWriter, requestData map[string]any) {
+	txn := m.trackTransaction(methodMWallet, requestData)
+	if !m.applyFaults(w, txn) {
 		return
 	}
 
-	// Otherwise, it's an Inquiry request
-	m.handleInquiryRequest(w, requestData)
-}
-
-// handleMWalletRequest handles MWallet request with already-decoded JSON
-func (m *MockGatewayServer) handleMWalletRequest(w http.ResponseWriter, requestData map[string]any) {
 	m.mu.RLock()
 	responseCode := m.mwResponseCode
 	m.mu.RUnlock()
+	if txn.script != nil {
+		responseCode = m.scriptedInitiateCode(txn)
+	}
 
 	response := map[string]any{
 		"pp_TxnType":              "MWALLET",
@@ -171,8 +270,10 @@ func (m *MockGatewayServer) handleMWalletRequest(w http.ResponseWriter, requestD
 		"pp_MerchantID":           getString(requestData, "pp_MerchantID"),
 	}
 
-	secureHash := m.computeSecureHash(response)
-	response["pp_SecureHash"] = secureHash
+	m.mu.RLock()
+	response["pp_SecureHash"] = m.signFor(txn, response)
+	m.mu.RUnlock()
+	m.scheduleIPN(txn)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -180,9 +281,17 @@ func (m *MockGatewayServer) handleMWalletRequest(w http.ResponseWriter, requestD
 
 // handleInquiryRequest handles Inquiry request with already-decoded JSON
 func (m *MockGatewayServer) handleInquiryRequest(w http.ResponseWriter, requestData map[string]any) {
+	txn := m.inquireTransaction(getString(requestData, "pp_TxnRefNo"))
+	if !m.applyFaults(w, txn) {
+		return
+	}
+
 	m.mu.RLock()
 	inquiryCode := m.inquiryResponseCode
 	m.mu.RUnlock()
+	if txn != nil && txn.script != nil {
+		inquiryCode = m.scriptedInquiryCode(txn)
+	}
 
 	response := map[string]any{
 		"pp_ResponseCode":         "000",
@@ -195,14 +304,15 @@ func (m *MockGatewayServer) handleInquiryRequest(w http.ResponseWriter, requestD
 		"pp_MerchantID":           getString(requestData, "pp_MerchantID"),
 	}
 
-	secureHash := m.computeSecureHash(response)
-	response["pp_SecureHash"] = secureHash
+	m.mu.RLock()
+	response["pp_SecureHash"] = m.signFor(txn, response)
+	m.mu.RUnlock()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleInquiry handles Inquiry requests (kept for backward compatibility)
+// handleInquiry handles Inquiry requests
 func (m *MockGatewayServer) handleInquiry(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -218,6 +328,46 @@ func (m *MockGatewayServer) handleInquiry(w http.ResponseWriter, r *http.Request
 	m.handleInquiryRequest(w, requestData)
 }
 
+// handleRefund handles wallet and card Refund requests
+func (m *MockGatewayServer) handleRefund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var requestData map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid JSON: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	responseCode := m.refundResponseCode
+	m.refundsAccepted++
+	lost := m.loseRefunds > 0
+	if lost {
+		m.loseRefunds--
+	}
+	m.mu.Unlock()
+
+	if lost {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]any{
+		"pp_ResponseCode":    responseCode,
+		"pp_ResponseMessage": m.getResponseMessage(responseCode),
+		"pp_TxnRefNo":        getString(requestData, "pp_TxnRefNo"),
+		"pp_Amount":          getString(requestData, "pp_Amount"),
+	}
+
+	response["pp_SecureHash"] = m.computeSecureHash(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // computeSecureHash computes the secure hash using the same algorithm as JazzCash
 func (m *MockGatewayServer) computeSecureHash(data map[string]any) string {
 	ppFields := make(map[string]string)
@@ -259,28 +409,20 @@ func (m *MockGatewayServer) computeSecureHash(data map[string]any) string {
 	return strings.ToUpper(hex.EncodeToString(hash))
 }
 
-// getResponseMessage returns a user-friendly message for the response code
+// getResponseMessage returns the English message the real client shows for
+// the response code, from the same catalog
 func (m *MockGatewayServer) getResponseMessage(code string) string {
-	switch code {
-	case "000", "121":
-		return "Transaction completed successfully"
-	case "157":
-		return "Transaction is pending. Please wait for confirmation"
-	case "101":
-		return "Transaction failed"
-	default:
-		return "Transaction status: " + code
-	}
+	return gateway.JazzCashResponseCodes().Message(code, gateway.LanguageEnglish)
 }
 
-// getStatus returns the status string for the response code
+// getStatus returns the pp_Status word JazzCash reports for the response code
 func (m *MockGatewayServer) getStatus(code string) string {
-	switch code {
-	case "000", "121":
+	switch gateway.JazzCashResponseCodes().Status(code) {
+	case gateway.StatusSuccess:
 		return "Completed"
-	case "157":
+	case gateway.StatusPending:
 		return "Pending"
-	case "101":
+	case gateway.StatusFailed:
 		return "Failed"
 	default:
 		return "Unknown"
@@ -0,0 +1,482 @@
+package testutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	cardPagePath     = "/ApplicationAPI/API/CardPayment"
+	cardCompletePath = "/ApplicationAPI/API/CardPayment/complete"
+
+	methodMWallet = "MWALLET"
+	methodCard    = "CARD"
+)
+
+// ErrUnknownTransaction is returned for a pp_TxnRefNo the mock has not seen
+var ErrUnknownTransaction = errors.New("unknown transaction")
+
+// ScenarioScript overrides the mock's answers for transactions matching its
+// phone number and/or amount. Scripts are checked in the order they were added
+// and the first match wins; unmatched transactions get the Set*Scenario defaults.
+type ScenarioScript struct {
+	ID string `json:"id"`
+
+	// Match on pp_MobileNumber (MWallet only) and pp_Amount in paisa; at least one is required
+	PhoneNumber string `json:"phone_number,omitempty"`
+	AmountPaisa string `json:"amount_paisa,omitempty"`
+
+	// Result is the outcome the transaction settles on
+	Result Scenario `json:"result"`
+	// SettleAfterInquiries keeps the transaction pending until it has been
+	// inquired this many times, then reports Result
+	SettleAfterInquiries int `json:"settle_after_inquiries,omitempty"`
+	// CorruptHash signs every response for the transaction with a wrong pp_SecureHash
+	CorruptHash bool `json:"corrupt_hash,omitempty"`
+	// ServerErrors answers the transaction's first requests with HTTP 500
+	ServerErrors int `json:"server_errors,omitempty"`
+	// DelayMillis holds every response for the transaction this long
+	DelayMillis int `json:"delay_ms,omitempty"`
+	// IPN pushes the settled result to the IPN URL IPNDelayMillis after the transaction starts
+	IPN            bool `json:"ipn,omitempty"`
+	IPNDelayMillis int  `json:"ipn_delay_ms,omitempty"`
+}
+
+// SimTransaction is what the mock remembers about a transaction it has seen
+type SimTransaction struct {
+	TxnRefNo     string    `json:"txn_ref_no"`
+	Method       string    `json:"method"`
+	PhoneNumber  string    `json:"phone_number,omitempty"`
+	AmountPaisa  string    `json:"amount_paisa"`
+	BillRef      string    `json:"bill_reference,omitempty"`
+	ReturnURL    string    `json:"return_url,omitempty"`
+	ScriptID     string    `json:"script_id,omitempty"`
+	Inquiries    int       `json:"inquiries"`
+	ServerErrors int       `json:"server_errors"`
+	ResponseCode string    `json:"response_code"` // last code reported for the payment
+	CreatedAt    time.Time `json:"created_at"`
+
+	script *ScenarioScript
+}
+
+// =============================================================================
+// SCRIPT MANAGEMENT
+// =============================================================================
+
+// AddScript validates script, assigns it an ID and appends it to the scripts
+func (m *MockGatewayServer) AddScript(script ScenarioScript) (ScenarioScript, error) {
+	if script.PhoneNumber == "" && script.AmountPaisa == "" {
+		return ScenarioScript{}, errors.New("script needs a phone_number or amount_paisa to match on")
+	}
+	switch script.Result {
+	case ScenarioSuccess, ScenarioPending, ScenarioFailed:
+	case "":
+		script.Result = ScenarioSuccess
+	default:
+		return ScenarioScript{}, fmt.Errorf("unknown result %q", script.Result)
+	}
+	if script.SettleAfterInquiries < 0 || script.ServerErrors < 0 || script.DelayMillis < 0 || script.IPNDelayMillis < 0 {
+		return ScenarioScript{}, errors.New("counts and delays cannot be negative")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextScriptID++
+	script.ID = strconv.Itoa(m.nextScriptID)
+	m.scripts = append(m.scripts, &script)
+	return script, nil
+}
+
+// Scripts returns the scripts in match order
+func (m *MockGatewayServer) Scripts() []ScenarioScript {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scripts := make([]ScenarioScript, 0, len(m.scripts))
+	for _, script := range m.scripts {
+		scripts = append(scripts, *script)
+	}
+	return scripts
+}
+
+// RemoveScript deletes a script; transactions already matched keep following it
+func (m *MockGatewayServer) RemoveScript(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, script := range m.scripts {
+		if script.ID == id {
+			m.scripts = append(m.scripts[:i], m.scripts[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ClearScripts deletes every script
+func (m *MockGatewayServer) ClearScripts() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scripts = nil
+}
+
+// Transactions returns the transactions the mock has seen
+func (m *MockGatewayServer) Transactions() []SimTransaction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	transactions := make([]SimTransaction, 0, len(m.transactions))
+	for _, txn := range m.transactions {
+		transactions = append(transactions, *txn)
+	}
+	return transactions
+}
+
+// SetIPNURL sets where IPN pushes are posted, e.g. http://localhost:8080/payments/ipn/jazzcash
+func (m *MockGatewayServer) SetIPNURL(ipnURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ipnURL = ipnURL
+}
+
+// =============================================================================
+// IPN
+// =============================================================================
+
+// PushIPN posts a signed notification for a seen transaction. An empty result
+// reports the scripted result, or success for unscripted transactions.
+func (m *MockGatewayServer) PushIPN(txnRefNo string, result Scenario) error {
+	m.mu.RLock()
+	ipnURL := m.ipnURL
+	txn, ok := m.transactions[txnRefNo]
+	var notification map[string]any
+	if ok {
+		if result == "" {
+			result = ScenarioSuccess
+			if txn.script != nil {
+				result = txn.script.Result
+			}
+		}
+		responseCode := resultCode(result)
+		notification = map[string]any{
+			"pp_TxnType":              txn.Method,
+			"pp_TxnRefNo":             txn.TxnRefNo,
+			"pp_Amount":               txn.AmountPaisa,
+			"pp_BillReference":        txn.BillRef,
+			"pp_ResponseCode":         responseCode,
+			"pp_ResponseMessage":      m.getResponseMessage(responseCode),
+			"pp_RetreivalReferenceNo": "SIM_RRN_" + txn.TxnRefNo,
+			"pp_TxnDateTime":          time.Now().Format("20060102150405"),
+		}
+		notification["pp_SecureHash"] = m.signFor(txn, notification)
+	}
+	m.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTransaction, txnRefNo)
+	}
+	if ipnURL == "" {
+		return errors.New("no IPN URL configured")
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(ipnURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("posting IPN: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("IPN answered with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// scheduleIPN pushes the scripted result once the script's IPN delay has passed
+func (m *MockGatewayServer) scheduleIPN(txn *SimTransaction) {
+	if txn.script == nil || !txn.script.IPN {
+		return
+	}
+	delay := time.Duration(txn.script.IPNDelayMillis) * time.Millisecond
+	txnRefNo := txn.TxnRefNo
+
+	time.AfterFunc(delay, func() {
+		if err := m.PushIPN(txnRefNo, ""); err != nil {
+			log.Printf("IPN for %s: %v", txnRefNo, err)
+		}
+	})
+}
+
+// =============================================================================
+// CARD HOSTED PAGE
+// =============================================================================
+
+var cardPageTemplate = template.Must(template.New("card").Parse(`<!DOCTYPE html>
+<html>
+<head><title>JazzCash Simulator - Card Payment</title></head>
+<body style="font-family: sans-serif; max-width: 28em; margin: 4em auto">
+<h2>JazzCash Simulator</h2>
+<p>Order <strong>{{.TxnRefNo}}</strong> for <strong>PKR {{.Rupees}}</strong></p>
+{{if .ScriptID}}<p>Scripted by scenario {{.ScriptID}}: <strong>{{.Result}}</strong></p>{{end}}
+<form method="POST" action="{{.Action}}">
+<input type="hidden" name="txn_ref_no" value="{{.TxnRefNo}}">
+<button name="outcome" value="{{.Result}}">Pay</button>
+<button name="outcome" value="failed">Decline</button>
+</form>
+</body>
+</html>`))
+
+var cardReturnTemplate = template.Must(template.New("return").Parse(`<!DOCTYPE html>
+<html>
+<body onload="document.forms[0].submit()">
+<form method="POST" action="{{.ReturnURL}}">
+{{range $name, $value := .Fields}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<noscript><button>Return to merchant</button></noscript>
+</form>
+</body>
+</html>`))
+
+// handleCardPage serves the hosted card page the browser is posted to
+func (m *MockGatewayServer) handleCardPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid form: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	requestData := make(map[string]any, len(r.PostForm))
+	for key := range r.PostForm {
+		requestData[key] = r.PostForm.Get(key)
+	}
+	if m.computeSecureHash(requestData) != getString(requestData, "pp_SecureHash") {
+		http.Error(w, "pp_SecureHash does not match the posted fields", http.StatusBadRequest)
+		return
+	}
+
+	txn := m.trackTransaction(methodCard, requestData)
+	if !m.applyFaults(w, txn) {
+		return
+	}
+
+	result := ScenarioSuccess
+	if txn.script != nil {
+		result = txn.script.Result
+	}
+
+	paisa, _ := strconv.ParseInt(txn.AmountPaisa, 10, 64)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	cardPageTemplate.Execute(w, map[string]any{
+		"TxnRefNo": txn.TxnRefNo,
+		"Rupees":   fmt.Sprintf("%d.%02d", paisa/100, paisa%100),
+		"ScriptID": txn.ScriptID,
+		"Result":   result,
+		"Action":   cardCompletePath,
+	})
+}
+
+// handleCardComplete sends the browser back to the merchant with the signed callback
+func (m *MockGatewayServer) handleCardComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	txnRefNo := r.FormValue("txn_ref_no")
+	outcome := Scenario(r.FormValue("outcome"))
+
+	m.mu.Lock()
+	txn, ok := m.transactions[txnRefNo]
+	if !ok {
+		m.mu.Unlock()
+		http.Error(w, "Unknown transaction", http.StatusNotFound)
+		return
+	}
+
+	responseCode := resultCode(outcome)
+	if txn.script != nil && txn.script.SettleAfterInquiries > 0 && outcome != ScenarioFailed {
+		responseCode = resultCode(ScenarioPending)
+	}
+	txn.ResponseCode = responseCode
+
+	callback := map[string]any{
+		"pp_TxnType":              "MPAY",
+		"pp_TxnRefNo":             txn.TxnRefNo,
+		"pp_Amount":               txn.AmountPaisa,
+		"pp_BillReference":        txn.BillRef,
+		"pp_ResponseCode":         responseCode,
+		"pp_ResponseMessage":      m.getResponseMessage(responseCode),
+		"pp_RetreivalReferenceNo": "SIM_RRN_" + txn.TxnRefNo,
+		"pp_TxnCurrency":          "PKR",
+	}
+	callback["pp_SecureHash"] = m.signFor(txn, callback)
+	returnURL := txn.ReturnURL
+	m.mu.Unlock()
+
+	fields := make(map[string]string, len(callback))
+	for key, value := range callback {
+		fields[key] = fmt.Sprintf("%v", value)
+	}
+
+	m.scheduleIPN(txn)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	cardReturnTemplate.Execute(w, map[string]any{
+		"ReturnURL": returnURL,
+		"Fields":    fields,
+	})
+}
+
+// =============================================================================
+// HELPERS - Scripted answers
+// =============================================================================
+
+// trackTransaction records a new transaction and matches it against the scripts
+func (m *MockGatewayServer) trackTransaction(method string, requestData map[string]any) *SimTransaction {
+	txnRefNo := getString(requestData, "pp_TxnRefNo")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if txn, ok := m.transactions[txnRefNo]; ok {
+		return txn
+	}
+
+	txn := &SimTransaction{
+		TxnRefNo:    txnRefNo,
+		Method:      method,
+		PhoneNumber: getString(requestData, "pp_MobileNumber"),
+		AmountPaisa: getString(requestData, "pp_Amount"),
+		BillRef:     getString(requestData, "pp_BillReference"),
+		ReturnURL:   getString(requestData, "pp_ReturnURL"),
+		CreatedAt:   time.Now(),
+	}
+	for _, script := range m.scripts {
+		if script.matches(txn) {
+			txn.script = script
+			txn.ScriptID = script.ID
+			break
+		}
+	}
+	m.transactions[txnRefNo] = txn
+	return txn
+}
+
+// inquireTransaction counts an inquiry for a seen transaction, or returns nil
+func (m *MockGatewayServer) inquireTransaction(txnRefNo string) *SimTransaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	txn, ok := m.transactions[txnRefNo]
+	if !ok {
+		return nil
+	}
+	txn.Inquiries++
+	return txn
+}
+
+// applyFaults holds the response for a slow script and answers scripted
+// HTTP 500s. It reports whether the request should still be answered.
+func (m *MockGatewayServer) applyFaults(w http.ResponseWriter, txn *SimTransaction) bool {
+	if txn == nil || txn.script == nil {
+		return true
+	}
+
+	time.Sleep(time.Duration(txn.script.DelayMillis) * time.Millisecond)
+
+	m.mu.Lock()
+	fail := txn.ServerErrors < txn.script.ServerErrors
+	if fail {
+		txn.ServerErrors++
+	}
+	m.mu.Unlock()
+
+	if fail {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// scriptedInitiateCode is the MWallet answer for a scripted transaction
+func (m *MockGatewayServer) scriptedInitiateCode(txn *SimTransaction) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	responseCode := resultCode(txn.script.Result)
+	if txn.script.SettleAfterInquiries > 0 {
+		responseCode = resultCode(ScenarioPending)
+	}
+	txn.ResponseCode = responseCode
+	return responseCode
+}
+
+// scriptedInquiryCode is the pp_PaymentResponseCode for a scripted transaction
+func (m *MockGatewayServer) scriptedInquiryCode(txn *SimTransaction) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	responseCode := resultCode(ScenarioPending)
+	if txn.Inquiries >= txn.script.SettleAfterInquiries {
+		responseCode = resultCode(txn.script.Result)
+		if txn.script.Result == ScenarioSuccess {
+			responseCode = "121" // inquiry success code
+		}
+	}
+	txn.ResponseCode = responseCode
+	return responseCode
+}
+
+// signFor signs data, wrongly when the transaction's script asks for it.
+// Callers must hold m.mu.
+func (m *MockGatewayServer) signFor(txn *SimTransaction, data map[string]any) string {
+	secureHash := m.computeSecureHash(data)
+	if txn != nil && txn.script != nil && txn.script.CorruptHash {
+		return corruptHash(secureHash)
+	}
+	return secureHash
+}
+
+// matches reports whether the script applies to txn
+func (s *ScenarioScript) matches(txn *SimTransaction) bool {
+	if s.PhoneNumber != "" && s.PhoneNumber != txn.PhoneNumber {
+		return false
+	}
+	if s.AmountPaisa != "" && s.AmountPaisa != txn.AmountPaisa {
+		return false
+	}
+	return true
+}
+
+// resultCode is the JazzCash response code reporting result
+func resultCode(result Scenario) string {
+	switch result {
+	case ScenarioPending:
+		return "157"
+	case ScenarioFailed:
+		return "101"
+	default:
+		return "000"
+	}
+}
+
+// corruptHash changes one character of a valid hash
+func corruptHash(secureHash string) string {
+	if secureHash == "" {
+		return "0"
+	}
+	replacement := "0"
+	if secureHash[0] == '0' {
+		replacement = "1"
+	}
+	return replacement + secureHash[1:]
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrNotFound No object is stored under the key
+	ErrNotFound = errors.New("object not found")
+
+	// ErrInvalidKey Key would escape the storage root or is empty
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// Storage keeps uploaded files under keys chosen by the caller. Keys are
+// slash-separated relative paths, e.g. "receipts/2024/01/<uuid>.png".
+type Storage interface {
+	// Put stores everything read from r under key, replacing any previous object
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Open returns the object stored under key; the caller closes it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object under key; a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalDisk stores objects as files below a root directory
+type LocalDisk struct {
+	root string
+}
+
+var _ Storage = (*LocalDisk)(nil)
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// NewLocalDisk creates root if needed and stores objects below it
+func NewLocalDisk(root string) (*LocalDisk, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("create storage root %s: %w", root, err)
+	}
+	return &LocalDisk{root: root}, nil
+}
+
+// =============================================================================
+// PUBLIC STORAGE METHODS
+// =============================================================================
+
+// Put writes to a temporary file first so a failed upload never leaves a
+// partial object behind
+func (d *LocalDisk) Put(ctx context.Context, key string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *LocalDisk) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return file, err
+}
+
+func (d *LocalDisk) Delete(ctx context.Context, key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// path maps key to a file below root, refusing keys that would leave it
+func (d *LocalDisk) path(key string) (string, error) {
+	local := filepath.FromSlash(key)
+	if key == "" || !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(d.root, local), nil
+}
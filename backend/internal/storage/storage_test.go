@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalDisk_PutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	disk, err := NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalDisk() error = %v", err)
+	}
+
+	if err := disk.Put(ctx, "receipts/2024/01/a.png", strings.NewReader("receipt")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	file, err := disk.Open(ctx, "receipts/2024/01/a.png")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	contents, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || string(contents) != "receipt" {
+		t.Errorf("Open() read %q, %v; want receipt", contents, err)
+	}
+
+	if err := disk.Delete(ctx, "receipts/2024/01/a.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := disk.Open(ctx, "receipts/2024/01/a.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := disk.Delete(ctx, "receipts/2024/01/a.png"); err != nil {
+		t.Errorf("Delete() of a missing object error = %v", err)
+	}
+}
+
+func TestLocalDisk_RejectsKeysOutsideRoot(t *testing.T) {
+	disk, err := NewLocalDisk(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocalDisk() error = %v", err)
+	}
+
+	for _, key := range []string{"", "../escape.png", "receipts/../../escape.png", "/etc/passwd"} {
+		if err := disk.Put(context.Background(), key, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
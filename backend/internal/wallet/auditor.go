@@ -0,0 +1,98 @@
+package wallet
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+// LedgerAuditor verifies the ledger's hash chains on a schedule and logs a
+// LEDGER TAMPERING alert for every break it finds. Verification only reads,
+// so each replica may run its own auditor.
+type LedgerAuditor struct {
+	service  *Service
+	interval time.Duration
+}
+
+// LedgerAuditorConfig tunes the scheduled verification
+type LedgerAuditorConfig struct {
+	Interval time.Duration // how often the whole ledger is walked
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+// DefaultLedgerAuditorConfig returns the settings used in production
+func DefaultLedgerAuditorConfig() LedgerAuditorConfig {
+	return LedgerAuditorConfig{
+		Interval: 6 * time.Hour,
+	}
+}
+
+// NewLedgerAuditor creates an auditor that verifies through service
+func NewLedgerAuditor(service *Service, config LedgerAuditorConfig) *LedgerAuditor {
+	return &LedgerAuditor{
+		service:  service,
+		interval: config.Interval,
+	}
+}
+
+// =============================================================================
+// PUBLIC AUDITOR METHODS
+// =============================================================================
+
+// Run verifies the ledger every interval until ctx is cancelled
+func (a *LedgerAuditor) Run(ctx context.Context) {
+	log.Printf("ledger auditor started")
+	defer log.Printf("ledger auditor stopped")
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.tick(ctx)
+		}
+	}
+}
+
+// =============================================================================
+// PRIVATE AUDITOR METHODS
+// =============================================================================
+
+// tick walks the ledger once and reports what it found
+func (a *LedgerAuditor) tick(ctx context.Context) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Printf("ledger auditor panic: %v", rec)
+		}
+	}()
+
+	result, err := a.service.VerifyLedger(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ledger verification failed: %v", err)
+		}
+		return
+	}
+
+	for _, b := range result.Breaks {
+		log.Printf("LEDGER TAMPERING: wallet %s (%s) breaks at seq %d, entry %s: %s",
+			b.WalletID, b.WalletName, b.Seq, b.EntryID, b.Reason)
+	}
+	for _, g := range result.UnbalancedGroups {
+		log.Printf("LEDGER TAMPERING: transaction group %s sums to %s", g.TransactionGroupID, g.Total)
+	}
+
+	log.Printf("ledger verified: %d wallets, %d entries, %d breaks, %d unbalanced groups in %s",
+		result.WalletsChecked, result.EntriesChecked,
+		len(result.Breaks), len(result.UnbalancedGroups), result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond))
+}
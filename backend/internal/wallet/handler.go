@@ -0,0 +1,227 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/common"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// GetWallet returns the user's wallet and balance
+func (h *Handler) GetWallet(w http.ResponseWriter, r *http.Request) {
+	wallet, err := h.service.GetMyWallet(r.Context())
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, wallet)
+}
+
+// ListTransactions returns a page of the user's ledger entries, newest first
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	limit, offset := pageParams(r)
+
+	entries, err := h.service.ListMyEntries(r.Context(), limit, offset)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, entries)
+}
+
+// Transfer sends money from the user's wallet to another user's
+func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
+	var params TransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	transfer, err := h.service.Transfer(r.Context(), params)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusCreated, transfer)
+}
+
+// Statement downloads the user's statement for ?from=&to= (YYYY-MM-DD) as ?format=pdf or csv
+func (h *Handler) Statement(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.service.IssueMyStatement(r.Context(), statementParams(r))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	writeStatement(w, doc)
+}
+
+// UserStatement downloads the statement of any user, as Statement does (admin only)
+func (h *Handler) UserStatement(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		common.ResponseWithError(w, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	doc, err := h.service.IssueStatement(r.Context(), userID, statementParams(r))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	writeStatement(w, doc)
+}
+
+// VerifyStatement checks the verification code printed on a statement (public)
+func (h *Handler) VerifyStatement(w http.ResponseWriter, r *http.Request) {
+	verification, err := h.service.VerifyStatement(r.Context(), chi.URLParam(r, "code"))
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, verification)
+}
+
+// TrialBalance shows that all wallets sum to zero as of ?as_of=, by transaction type (admin only)
+func (h *Handler) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	asOf, err := parseAsOf(r.URL.Query().Get("as_of"), time.Now())
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	balance, err := h.service.TrialBalance(r.Context(), asOf)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	common.ResponseWithJSON(w, http.StatusOK, balance)
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// parseAsOf reads a trial balance time: empty is now, a date is the end of
+// that day in Pakistan time (now for today), otherwise an RFC 3339 time
+func parseAsOf(raw string, now time.Time) (time.Time, error) {
+	if raw == "" {
+		return now, nil
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", raw, common.PKT); err == nil {
+		if day.After(now) {
+			return time.Time{}, ErrInvalidAsOf
+		}
+		if end := day.AddDate(0, 0, 1); end.Before(now) {
+			return end, nil
+		}
+		return now, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, raw)
+	if err != nil || asOf.After(now) {
+		return time.Time{}, ErrInvalidAsOf
+	}
+	return asOf, nil
+}
+
+func statementParams(r *http.Request) StatementRequest {
+	query := r.URL.Query()
+	return StatementRequest{
+		From:   query.Get("from"),
+		To:     query.Get("to"),
+		Format: StatementFormat(query.Get("format")),
+	}
+}
+
+// writeStatement sends doc as a download, with its code in X-Statement-Code
+func writeStatement(w http.ResponseWriter, doc *StatementDocument) {
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", doc.Filename))
+	w.Header().Set("X-Statement-Code", doc.VerificationCode)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(doc.Body); err != nil {
+		log.Printf("failed to send statement %s: %v", doc.VerificationCode, err)
+	}
+}
+
+// pageParams reads limit (default 20, max 100) and offset from the query string
+func pageParams(r *http.Request) (int32, int32) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return int32(limit), int32(offset)
+}
+
+// handleServiceError maps service errors to HTTP responses
+func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
+	switch {
+	// Validation errors (400) - show message to user
+	case errors.Is(err, ErrInvalidPosting), errors.Is(err, ErrInvalidTransfer), errors.Is(err, ErrTransferAboveMaximum),
+		errors.Is(err, ErrInvalidStatement), errors.Is(err, ErrInvalidAsOf):
+		common.ResponseWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrSelfTransfer):
+		common.ResponseWithError(w, http.StatusBadRequest, "You cannot transfer money to your own wallet.")
+
+	// Wallet state and caps (403/409)
+	case errors.Is(err, ErrWalletFrozen):
+		common.ResponseWithError(w, http.StatusForbidden, "Your wallet is frozen. Please contact the accounts office.")
+	case errors.Is(err, ErrTransferDailyLimit):
+		common.ResponseWithError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		common.ResponseWithError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrInsufficientFunds):
+		common.ResponseWithError(w, http.StatusConflict, "Insufficient wallet balance.")
+	case errors.Is(err, ErrReferenceConflict):
+		common.ResponseWithError(w, http.StatusConflict, err.Error())
+
+	// Not found (404)
+	case errors.Is(err, ErrWalletNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "Wallet not found.")
+	case errors.Is(err, ErrRecipientNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "No active GIKI user has that email or registration number.")
+	case errors.Is(err, ErrStatementNotFound):
+		common.ResponseWithError(w, http.StatusNotFound, "No statement was issued with that verification code.")
+
+	// Auth errors (401)
+	case errors.Is(err, ErrUserIDNotFound):
+		common.ResponseWithError(w, http.StatusUnauthorized, "Authentication required.")
+
+	// Internal errors (500) - generic message, log details
+	default:
+		log.Printf("wallet error: %v", err)
+		common.ResponseWithError(w, http.StatusInternalServerError, "An unexpected error occurred. Please try again later.")
+	}
+}
@@ -0,0 +1,258 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/money"
+)
+
+type WalletType string
+
+const (
+	WalletPersonal     WalletType = "PERSONAL"
+	WalletSysRevenue   WalletType = "SYS_REVENUE"
+	WalletSysLiability WalletType = "SYS_LIABILITY"
+)
+
+type WalletStatus string
+
+const (
+	WalletActive WalletStatus = "ACTIVE"
+	WalletFrozen WalletStatus = "FROZEN"
+)
+
+// TransactionType is the business action of a posting; together with the
+// reference id it identifies the posting for idempotency
+type TransactionType string
+
+const (
+	TransactionTopUp          TransactionType = "TOPUP"
+	TransactionTransfer       TransactionType = "TRANSFER"
+	TransactionTicketSale     TransactionType = "TICKET_SALE"
+	TransactionRefund         TransactionType = "REFUND"
+	TransactionRefundReversal TransactionType = "REFUND_REVERSAL"
+)
+
+// System wallet codes. System wallets are created by migration; every
+// posting that moves money in or out of the users' wallets has its
+// counter-leg on one of them.
+const (
+	// GatewayClearingWallet receives the debit of every gateway top-up and
+	// the credit of every gateway refund
+	GatewayClearingWallet = "GATEWAY_CLEARING"
+
+	// TransportRevenueWallet receives the credit of every ticket sale
+	TransportRevenueWallet = "TRANSPORT_REVENUE"
+)
+
+type Wallet struct {
+	ID       uuid.UUID    `json:"id"`
+	Name     string       `json:"name"`
+	Type     WalletType   `json:"type"`
+	Status   WalletStatus `json:"status"`
+	Currency string       `json:"currency"`
+	Balance  money.Money  `json:"balance"`
+}
+
+type LedgerEntry struct {
+	ID                 uuid.UUID       `json:"id"`
+	WalletID           uuid.UUID       `json:"wallet_id"`
+	Amount             money.Money     `json:"amount"`
+	BalanceAfter       money.Money     `json:"balance_after"`
+	TransactionGroupID uuid.UUID       `json:"transaction_group_id"`
+	TransactionType    TransactionType `json:"transaction_type"`
+	ReferenceID        string          `json:"reference_id"`
+	Description        string          `json:"description"`
+	CreatedAt          time.Time       `json:"created_at"`
+}
+
+// Leg moves Amount into (positive, credit) or out of (negative, debit) a wallet
+type Leg struct {
+	WalletID uuid.UUID
+	Amount   money.Money
+}
+
+// PostingRequest is one balanced ledger transaction: its legs sum to zero
+type PostingRequest struct {
+	TransactionType TransactionType
+	ReferenceID     string
+	Description     string
+	Legs            []Leg
+}
+
+// Posting is the result of Post. Replayed is set when the same transaction
+// type and reference were posted before and nothing new was written.
+type Posting struct {
+	TransactionGroupID uuid.UUID
+	Entries            []LedgerEntry
+	Replayed           bool
+}
+
+// ChargeRequest Module → wallet: take Amount from a user's wallet into a
+// system wallet, e.g. a ticket sale into TransportRevenueWallet
+type ChargeRequest struct {
+	UserID          uuid.UUID
+	SystemWallet    string // code of the wallet credited
+	Amount          money.Money
+	TransactionType TransactionType
+	ReferenceID     string // owning module's id, e.g. a ticket id
+	Description     string
+}
+
+// TransferLimits bounds peer-to-peer transfers; a zero limit means no limit
+type TransferLimits struct {
+	MaxPerTransaction money.Money    // largest single transfer
+	DailyCap          money.Money    // outgoing, per sender per calendar day
+	Location          *time.Location // where calendar days start, Pakistan time by default
+}
+
+// TransferRequest sends money to another user, found by email or reg ID
+type TransferRequest struct {
+	IdempotencyKey uuid.UUID   `json:"idempotency_key"`
+	Recipient      string      `json:"recipient"`
+	Amount         money.Money `json:"amount"`
+	Note           string      `json:"note"`
+}
+
+type TransferResult struct {
+	ID        uuid.UUID     `json:"id"`
+	Amount    money.Money   `json:"amount"`
+	Recipient TransferParty `json:"recipient"`
+	Note      string        `json:"note,omitempty"`
+	Balance   money.Money   `json:"balance"` // sender's balance after the transfer
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type TransferParty struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// LedgerVerification is the outcome of one walk over every wallet's chain
+type LedgerVerification struct {
+	WalletsChecked   int
+	EntriesChecked   int64
+	Breaks           []ChainBreak
+	UnbalancedGroups []UnbalancedGroup
+	StartedAt        time.Time
+	FinishedAt       time.Time
+}
+
+// OK reports whether every chain and every transaction group checked out
+func (v *LedgerVerification) OK() bool {
+	return len(v.Breaks) == 0 && len(v.UnbalancedGroups) == 0
+}
+
+// ChainBreak is the first entry of a wallet that fails verification
+type ChainBreak struct {
+	WalletID   uuid.UUID
+	WalletName string
+	Seq        int64
+	EntryID    uuid.UUID
+	Reason     string
+}
+
+// UnbalancedGroup is a transaction group whose legs do not sum to zero
+type UnbalancedGroup struct {
+	TransactionGroupID uuid.UUID
+	Total              money.Money
+}
+
+type StatementFormat string
+
+const (
+	StatementPDF StatementFormat = "pdf"
+	StatementCSV StatementFormat = "csv"
+)
+
+// StatementRequest asks for the entries between two inclusive YYYY-MM-DD
+// dates, Pakistan time. Empty dates default to the current month so far.
+type StatementRequest struct {
+	From   string
+	To     string
+	Format StatementFormat
+}
+
+// Statement is everything a rendered statement shows
+type Statement struct {
+	VerificationCode string
+	Holder           StatementHolder
+	WalletID         uuid.UUID
+	PeriodStart      time.Time // first instant covered
+	PeriodEnd        time.Time // first instant not covered
+	OpeningBalance   money.Money
+	ClosingBalance   money.Money
+	TotalCredits     money.Money
+	TotalDebits      money.Money // positive
+	Entries          []LedgerEntry
+	VerifyURL        string
+	IssuedAt         time.Time
+}
+
+type StatementHolder struct {
+	Name     string
+	Email    string
+	UserType string
+	MemberID string // reg ID of students, employee ID of staff
+}
+
+// StatementDocument is a rendered statement ready to download
+type StatementDocument struct {
+	VerificationCode string
+	Filename         string
+	ContentType      string
+	Body             []byte
+}
+
+// StatementVerification is what the public verify endpoint reveals about an
+// issued statement: enough to compare against a printed copy, no entries
+type StatementVerification struct {
+	VerificationCode string          `json:"verification_code"`
+	HolderName       string          `json:"holder_name"`
+	PeriodFrom       string          `json:"period_from"`
+	PeriodTo         string          `json:"period_to"`
+	OpeningBalance   money.Money     `json:"opening_balance"`
+	ClosingBalance   money.Money     `json:"closing_balance"`
+	TotalCredits     money.Money     `json:"total_credits"`
+	TotalDebits      money.Money     `json:"total_debits"`
+	EntryCount       int32           `json:"entry_count"`
+	Format           StatementFormat `json:"format"`
+	DocumentSHA256   string          `json:"document_sha256"`
+	IssuedAt         time.Time       `json:"issued_at"`
+}
+
+// TrialBalance shows the ledger sums to zero as of a point in time: the
+// balances of every system wallet and of all personal wallets together, and
+// the debits and credits of each transaction type
+type TrialBalance struct {
+	AsOf             time.Time            `json:"as_of"` // entries posted before this
+	SystemWallets    []TrialBalanceWallet `json:"system_wallets"`
+	PersonalWallets  TrialBalancePersonal `json:"personal_wallets"`
+	Total            money.Money          `json:"total"` // zero when balanced
+	TransactionTypes []TrialBalanceLine   `json:"transaction_types"`
+	UnbalancedTypes  []TransactionType    `json:"unbalanced_types,omitempty"`
+	Balanced         bool                 `json:"balanced"`
+}
+
+type TrialBalanceWallet struct {
+	Code    string      `json:"code"`
+	Name    string      `json:"name"`
+	Type    WalletType  `json:"type"`
+	Balance money.Money `json:"balance"`
+}
+
+type TrialBalancePersonal struct {
+	Wallets int64       `json:"wallets"` // with at least one entry
+	Balance money.Money `json:"balance"`
+}
+
+// TrialBalanceLine totals one transaction type; Net is zero because every
+// transaction group balances
+type TrialBalanceLine struct {
+	TransactionType TransactionType `json:"transaction_type"`
+	Entries         int64           `json:"entries"`
+	Debits          money.Money     `json:"debits"`
+	Credits         money.Money     `json:"credits"`
+	Net             money.Money     `json:"net"`
+}
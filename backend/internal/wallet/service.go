@@ -0,0 +1,603 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/notification"
+	"github.com/hash-walker/giki-wallet/internal/payment"
+	walletdb "github.com/hash-walker/giki-wallet/internal/wallet/wallet_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidPosting Posting is malformed or unbalanced (400)
+	ErrInvalidPosting = errors.New("invalid ledger posting")
+
+	// ErrInsufficientFunds Debit would take a personal wallet below zero (409)
+	ErrInsufficientFunds = errors.New("insufficient wallet balance")
+
+	// ErrReferenceConflict Reference already posted with different legs (409)
+	ErrReferenceConflict = errors.New("ledger reference already posted differently")
+
+	// ErrWalletFrozen Frozen wallets cannot be debited (403)
+	ErrWalletFrozen = errors.New("wallet is frozen")
+
+	// ErrWalletNotFound Unknown wallet or user (404)
+	ErrWalletNotFound = errors.New("wallet not found")
+
+	// ErrUserIDNotFound No authenticated user in the request context (401)
+	ErrUserIDNotFound = errors.New("user id not found in context")
+
+	// ErrDatabaseQuery Database errors (500)
+	ErrDatabaseQuery = errors.New("database query failed")
+)
+
+const (
+	// maxReferenceLength is the size of ledger.reference_id
+	maxReferenceLength = 100
+
+	// ledgerReferenceConstraint is the unique index that makes postings idempotent
+	ledgerReferenceConstraint = "idx_ledger_reference"
+)
+
+var _ payment.RefundLedger = (*Service)(nil)
+
+// =============================================================================
+// TYPES
+// =============================================================================
+
+type Service struct {
+	q        *walletdb.Queries
+	dbPool   *pgxpool.Pool
+	notifier *notification.Service
+	limits   TransferLimits
+	hmacKey  []byte // signs each ledger row_hash
+
+	statementVerifyBase string // public page statement codes are checked at
+}
+
+// =============================================================================
+// CONSTRUCTORS
+// =============================================================================
+
+func NewService(dbPool *pgxpool.Pool, notifier *notification.Service, limits TransferLimits, hmacKey []byte, statementVerifyBase string) *Service {
+	return &Service{
+		q:                   walletdb.New(dbPool),
+		dbPool:              dbPool,
+		notifier:            notifier,
+		limits:              limits,
+		hmacKey:             hmacKey,
+		statementVerifyBase: statementVerifyBase,
+	}
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Postings
+// =============================================================================
+
+// Post writes one balanced ledger transaction in tx. The wallets are locked
+// in id order, so concurrent postings on the same wallets serialise without
+// deadlocking and each balance_after and row_hash follows on from the
+// wallet's previous entry. The wallet's head_hash and entry_count move with
+// it. Posting the same transaction type and reference again returns the
+// original entries.
+func (s *Service) Post(ctx context.Context, tx pgx.Tx, req PostingRequest) (*Posting, error) {
+	if err := validatePosting(req); err != nil {
+		return nil, err
+	}
+
+	walletQ := s.q.WithTx(tx)
+
+	ids := make([]uuid.UUID, len(req.Legs))
+	for i, leg := range req.Legs {
+		ids[i] = leg.WalletID
+	}
+	wallets, err := s.lockWallets(ctx, walletQ, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked under the wallet locks, so a concurrent retry of this posting
+	// has either committed or not started yet
+	existing, err := walletQ.GetLedgerEntriesByReference(ctx, walletdb.GetLedgerEntriesByReferenceParams{
+		TransactionType: string(req.TransactionType),
+		ReferenceID:     req.ReferenceID,
+	})
+	if err != nil {
+		log.Printf("failed to look up posting %s %s: %v", req.TransactionType, req.ReferenceID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	if len(existing) > 0 {
+		if !sameLegs(existing, req.Legs) {
+			return nil, fmt.Errorf("%w: %s %s", ErrReferenceConflict, req.TransactionType, req.ReferenceID)
+		}
+		return toPosting(existing, true), nil
+	}
+
+	groupID := uuid.New()
+	// Postgres keeps microseconds; hashing the stored value lets verification recompute it
+	createdAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	entries := make([]walletdb.GikiWalletLedger, 0, len(req.Legs))
+	for _, leg := range req.Legs {
+		w := wallets[leg.WalletID]
+
+		if leg.Amount.IsNegative() && WalletStatus(w.Status) == WalletFrozen {
+			return nil, fmt.Errorf("%w: %s", ErrWalletFrozen, w.Name)
+		}
+
+		balance, err := s.balance(ctx, walletQ, w.ID)
+		if err != nil {
+			return nil, err
+		}
+		balanceAfter, err := balance.Add(leg.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+		}
+		if WalletType(w.Type) == WalletPersonal && balanceAfter.IsNegative() {
+			return nil, fmt.Errorf("%w: balance %s, debit %s", ErrInsufficientFunds, balance, leg.Amount)
+		}
+
+		entry := walletdb.GikiWalletLedger{
+			ID:                 uuid.New(),
+			WalletID:           w.ID,
+			Amount:             leg.Amount,
+			BalanceAfter:       balanceAfter,
+			TransactionGroupID: groupID,
+			TransactionType:    string(req.TransactionType),
+			ReferenceID:        req.ReferenceID,
+			Description:        req.Description,
+			CreatedAt:          createdAt,
+		}
+		entry, err = walletQ.InsertLedgerEntry(ctx, walletdb.InsertLedgerEntryParams{
+			ID:                 entry.ID,
+			WalletID:           entry.WalletID,
+			Amount:             entry.Amount,
+			BalanceAfter:       entry.BalanceAfter,
+			TransactionGroupID: entry.TransactionGroupID,
+			TransactionType:    entry.TransactionType,
+			ReferenceID:        entry.ReferenceID,
+			Description:        entry.Description,
+			RowHash:            rowHash(s.hmacKey, entry, w.HeadHash.String),
+			CreatedAt:          entry.CreatedAt,
+		})
+		if isReferenceViolation(err) {
+			return nil, fmt.Errorf("%w: %s %s", ErrReferenceConflict, req.TransactionType, req.ReferenceID)
+		} else if err != nil {
+			log.Printf("failed to insert ledger entry of %s %s: %v", req.TransactionType, req.ReferenceID, err)
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+
+		err = walletQ.UpdateWalletHead(ctx, walletdb.UpdateWalletHeadParams{
+			ID:         w.ID,
+			HeadHash:   common.StringToText(entry.RowHash),
+			EntryCount: w.EntryCount + 1,
+		})
+		if err != nil {
+			log.Printf("failed to move head of wallet %s: %v", w.ID, err)
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return toPosting(entries, false), nil
+}
+
+// FulfillTopUp is the completion handler of TOPUP payment intents: it credits
+// the payer's wallet and debits gateway clearing. The intent id is the
+// posting reference, so an intent is credited once however often its
+// payment is reported.
+func (s *Service) FulfillTopUp(ctx context.Context, tx pgx.Tx, intent payment.PaymentIntent) error {
+	walletQ := s.q.WithTx(tx)
+
+	personal, err := s.personalWallet(ctx, walletQ, intent.PayerID)
+	if err != nil {
+		return err
+	}
+	clearing, err := s.systemWallet(ctx, walletQ, GatewayClearingWallet)
+	if err != nil {
+		return err
+	}
+
+	debit, err := intent.Amount.Neg()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+	}
+
+	posting, err := s.Post(ctx, tx, PostingRequest{
+		TransactionType: TransactionTopUp,
+		ReferenceID:     intent.ID.String(),
+		Description:     "Wallet top-up",
+		Legs: []Leg{
+			{WalletID: personal.ID, Amount: intent.Amount},
+			{WalletID: clearing.ID, Amount: debit},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if posting.Replayed {
+		log.Printf("top-up intent %s was already credited in group %s", intent.ID, posting.TransactionGroupID)
+	}
+	return nil
+}
+
+// HoldRefund implements payment.RefundLedger for top-ups: it debits the
+// refund from the payer's wallet and credits gateway clearing, before the
+// gateway sends the money back. The refund id is the posting reference.
+func (s *Service) HoldRefund(ctx context.Context, tx pgx.Tx, hold payment.RefundHold) error {
+	if hold.Purpose != payment.PurposeTopUp {
+		return fmt.Errorf("%w: %s payments are not refunded from the wallet", payment.ErrRefundNotAllowed, hold.Purpose)
+	}
+
+	walletQ := s.q.WithTx(tx)
+
+	personal, err := s.personalWallet(ctx, walletQ, hold.PayerID)
+	if err != nil {
+		return err
+	}
+	clearing, err := s.systemWallet(ctx, walletQ, GatewayClearingWallet)
+	if err != nil {
+		return err
+	}
+
+	debit, err := hold.Amount.Neg()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+	}
+
+	_, err = s.Post(ctx, tx, PostingRequest{
+		TransactionType: TransactionRefund,
+		ReferenceID:     hold.RefundID.String(),
+		Description:     "Refund to original payment method",
+		Legs: []Leg{
+			{WalletID: personal.ID, Amount: debit},
+			{WalletID: clearing.ID, Amount: hold.Amount},
+		},
+	})
+	switch {
+	case errors.Is(err, ErrInsufficientFunds):
+		return fmt.Errorf("%w: %v", payment.ErrRefundExceedsBalance, err)
+	case errors.Is(err, ErrWalletFrozen):
+		return fmt.Errorf("%w: %v", payment.ErrRefundNotAllowed, err)
+	}
+	return err
+}
+
+// ReleaseRefund implements payment.RefundLedger: it posts the reverse of a
+// failed refund's hold. Refunds requested before holds were posted have
+// nothing to reverse.
+func (s *Service) ReleaseRefund(ctx context.Context, tx pgx.Tx, hold payment.RefundHold) error {
+	held, err := s.q.WithTx(tx).GetLedgerEntriesByReference(ctx, walletdb.GetLedgerEntriesByReferenceParams{
+		TransactionType: string(TransactionRefund),
+		ReferenceID:     hold.RefundID.String(),
+	})
+	if err != nil {
+		log.Printf("failed to look up hold of refund %s: %v", hold.RefundID, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	if len(held) == 0 {
+		log.Printf("refund %s has no hold to release", hold.RefundID)
+		return nil
+	}
+
+	legs := make([]Leg, len(held))
+	for i, entry := range held {
+		amount, err := entry.Amount.Neg()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+		}
+		legs[i] = Leg{WalletID: entry.WalletID, Amount: amount}
+	}
+
+	_, err = s.Post(ctx, tx, PostingRequest{
+		TransactionType: TransactionRefundReversal,
+		ReferenceID:     hold.RefundID.String(),
+		Description:     "Failed refund returned",
+		Legs:            legs,
+	})
+	return err
+}
+
+// Charge takes req.Amount from the user's wallet into a system wallet in tx,
+// for sales such as tickets paid from the wallet. The transaction type and
+// reference identify the sale, so charging it again returns the original
+// posting instead of taking the money twice.
+func (s *Service) Charge(ctx context.Context, tx pgx.Tx, req ChargeRequest) (*Posting, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: charge amount must be positive", ErrInvalidPosting)
+	}
+
+	walletQ := s.q.WithTx(tx)
+
+	personal, err := s.personalWallet(ctx, walletQ, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	system, err := s.systemWallet(ctx, walletQ, req.SystemWallet)
+	if err != nil {
+		return nil, err
+	}
+
+	debit, err := req.Amount.Neg()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+	}
+
+	return s.Post(ctx, tx, PostingRequest{
+		TransactionType: req.TransactionType,
+		ReferenceID:     req.ReferenceID,
+		Description:     req.Description,
+		Legs: []Leg{
+			{WalletID: personal.ID, Amount: debit},
+			{WalletID: system.ID, Amount: req.Amount},
+		},
+	})
+}
+
+// SellTicket charges the fare of a ticket paid from the wallet into transport
+// revenue. The ticket id is the posting reference, so a ticket is paid once.
+func (s *Service) SellTicket(ctx context.Context, tx pgx.Tx, userID uuid.UUID, ticketID string, fare money.Money) (*Posting, error) {
+	return s.Charge(ctx, tx, ChargeRequest{
+		UserID:          userID,
+		SystemWallet:    TransportRevenueWallet,
+		Amount:          fare,
+		TransactionType: TransactionTicketSale,
+		ReferenceID:     ticketID,
+		Description:     "Transport ticket",
+	})
+}
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Wallets
+// =============================================================================
+
+// GetMyWallet returns the authenticated user's wallet and balance
+func (s *Service) GetMyWallet(ctx context.Context) (*Wallet, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	w, err := s.personalWallet(ctx, s.q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.balance(ctx, s.q, w.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toWallet(w, balance), nil
+}
+
+// ListMyEntries returns the authenticated user's ledger entries, newest first
+func (s *Service) ListMyEntries(ctx context.Context, limit, offset int32) ([]LedgerEntry, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	w, err := s.personalWallet(ctx, s.q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.q.ListWalletEntries(ctx, walletdb.ListWalletEntriesParams{
+		WalletID: w.ID,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		log.Printf("failed to list entries of wallet %s: %v", w.ID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	entries := make([]LedgerEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = toLedgerEntry(row)
+	}
+	return entries, nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Wallets
+// =============================================================================
+
+// lockWallets locks the wallets FOR UPDATE in id order until tx ends, so
+// callers locking overlapping sets never deadlock. Locking a wallet the
+// transaction already holds returns at once.
+func (s *Service) lockWallets(ctx context.Context, walletQ *walletdb.Queries, ids ...uuid.UUID) (map[uuid.UUID]walletdb.GikiWalletWallet, error) {
+	sorted := slices.Clone(ids)
+	slices.SortFunc(sorted, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+
+	locked, err := walletQ.LockWallets(ctx, sorted)
+	if err != nil {
+		log.Printf("failed to lock wallets %v: %v", sorted, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	if len(locked) != len(sorted) {
+		return nil, fmt.Errorf("%w: locking %v", ErrWalletNotFound, sorted)
+	}
+
+	wallets := make(map[uuid.UUID]walletdb.GikiWalletWallet, len(locked))
+	for _, w := range locked {
+		wallets[w.ID] = w
+	}
+	return wallets, nil
+}
+
+// personalWallet returns the wallet of userID, creating it on first use
+func (s *Service) personalWallet(ctx context.Context, walletQ *walletdb.Queries, userID uuid.UUID) (walletdb.GikiWalletWallet, error) {
+	owner := pgtype.UUID{Bytes: userID, Valid: true}
+
+	w, err := walletQ.GetWalletByUserID(ctx, owner)
+	if err == nil {
+		return w, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("failed to get wallet of user %s: %v", userID, err)
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	name, err := walletQ.GetUserName(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: user %s", ErrWalletNotFound, userID)
+	} else if err != nil {
+		log.Printf("failed to get user %s: %v", userID, err)
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	// A concurrent first use may create it too; ON CONFLICT keeps one
+	if err := walletQ.CreatePersonalWallet(ctx, walletdb.CreatePersonalWalletParams{UserID: owner, Name: name}); err != nil {
+		log.Printf("failed to create wallet of user %s: %v", userID, err)
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	w, err = walletQ.GetWalletByUserID(ctx, owner)
+	if err != nil {
+		log.Printf("failed to get created wallet of user %s: %v", userID, err)
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return w, nil
+}
+
+// systemWallet returns the system wallet with code
+func (s *Service) systemWallet(ctx context.Context, walletQ *walletdb.Queries, code string) (walletdb.GikiWalletWallet, error) {
+	w, err := walletQ.GetSystemWallet(ctx, common.StringToText(code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: system wallet %s", ErrWalletNotFound, code)
+	} else if err != nil {
+		log.Printf("failed to get system wallet %s: %v", code, err)
+		return walletdb.GikiWalletWallet{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return w, nil
+}
+
+// balance is the balance_after of the wallet's latest entry, zero without one
+func (s *Service) balance(ctx context.Context, walletQ *walletdb.Queries, walletID uuid.UUID) (money.Money, error) {
+	balance, err := walletQ.GetWalletBalance(ctx, walletID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return money.Paisa(0), nil
+	} else if err != nil {
+		log.Printf("failed to get balance of wallet %s: %v", walletID, err)
+		return money.Money{}, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return balance, nil
+}
+
+// =============================================================================
+// HELPERS - Postings
+// =============================================================================
+
+// validatePosting checks that req names its action and that its legs are
+// non-zero, touch each wallet once and sum to zero
+func validatePosting(req PostingRequest) error {
+	if req.TransactionType == "" || req.ReferenceID == "" || len(req.ReferenceID) > maxReferenceLength {
+		return fmt.Errorf("%w: a transaction type and a reference of at most %d characters are required", ErrInvalidPosting, maxReferenceLength)
+	}
+	if len(req.Legs) < 2 {
+		return fmt.Errorf("%w: at least two legs are required", ErrInvalidPosting)
+	}
+
+	sum := money.Paisa(0)
+	seen := make(map[uuid.UUID]bool, len(req.Legs))
+	for _, leg := range req.Legs {
+		if leg.Amount.IsZero() {
+			return fmt.Errorf("%w: zero amount for wallet %s", ErrInvalidPosting, leg.WalletID)
+		}
+		if seen[leg.WalletID] {
+			return fmt.Errorf("%w: wallet %s appears twice", ErrInvalidPosting, leg.WalletID)
+		}
+		seen[leg.WalletID] = true
+
+		var err error
+		if sum, err = sum.Add(leg.Amount); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPosting, err)
+		}
+	}
+	if !sum.IsZero() {
+		return fmt.Errorf("%w: legs sum to %s", ErrInvalidPosting, sum)
+	}
+	return nil
+}
+
+// sameLegs reports whether entries posted earlier move the same amounts
+// through the same wallets as legs
+func sameLegs(entries []walletdb.GikiWalletLedger, legs []Leg) bool {
+	if len(entries) != len(legs) {
+		return false
+	}
+	posted := make(map[uuid.UUID]money.Money, len(entries))
+	for _, entry := range entries {
+		posted[entry.WalletID] = entry.Amount
+	}
+	for _, leg := range legs {
+		amount, ok := posted[leg.WalletID]
+		if !ok || !amount.Equal(leg.Amount) {
+			return false
+		}
+	}
+	return true
+}
+
+// isReferenceViolation reports whether err is a second posting of the same
+// transaction type and reference to a wallet
+func isReferenceViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.ConstraintName == ledgerReferenceConstraint
+}
+
+func toPosting(rows []walletdb.GikiWalletLedger, replayed bool) *Posting {
+	posting := &Posting{Replayed: replayed, Entries: make([]LedgerEntry, len(rows))}
+	for i, row := range rows {
+		posting.TransactionGroupID = row.TransactionGroupID
+		posting.Entries[i] = toLedgerEntry(row)
+	}
+	return posting
+}
+
+func toLedgerEntry(row walletdb.GikiWalletLedger) LedgerEntry {
+	return LedgerEntry{
+		ID:                 row.ID,
+		WalletID:           row.WalletID,
+		Amount:             row.Amount,
+		BalanceAfter:       row.BalanceAfter,
+		TransactionGroupID: row.TransactionGroupID,
+		TransactionType:    TransactionType(row.TransactionType),
+		ReferenceID:        row.ReferenceID,
+		Description:        row.Description,
+		CreatedAt:          row.CreatedAt,
+	}
+}
+
+func toWallet(w walletdb.GikiWalletWallet, balance money.Money) *Wallet {
+	return &Wallet{
+		ID:       w.ID,
+		Name:     w.Name,
+		Type:     WalletType(w.Type),
+		Status:   WalletStatus(w.Status),
+		Currency: w.Currency,
+		Balance:  balance,
+	}
+}
@@ -0,0 +1,442 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	walletdb "github.com/hash-walker/giki-wallet/internal/wallet/wallet_db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestValidatePosting(t *testing.T) {
+	user, clearing := uuid.New(), uuid.New()
+	balanced := []Leg{
+		{WalletID: user, Amount: money.Paisa(50000)},
+		{WalletID: clearing, Amount: money.Paisa(-50000)},
+	}
+
+	tests := []struct {
+		name string
+		req  PostingRequest
+		err  error
+	}{
+		{"balanced", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: "ref", Legs: balanced}, nil},
+		{"missing reference", PostingRequest{TransactionType: TransactionTopUp, Legs: balanced}, ErrInvalidPosting},
+		{"reference too long", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: strings.Repeat("r", 101), Legs: balanced}, ErrInvalidPosting},
+		{"single leg", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: "ref", Legs: balanced[:1]}, ErrInvalidPosting},
+		{"unbalanced", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: "ref", Legs: []Leg{
+			{WalletID: user, Amount: money.Paisa(50000)},
+			{WalletID: clearing, Amount: money.Paisa(-40000)},
+		}}, ErrInvalidPosting},
+		{"zero leg", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: "ref", Legs: []Leg{
+			{WalletID: user, Amount: money.Paisa(0)},
+			{WalletID: clearing, Amount: money.Paisa(0)},
+		}}, ErrInvalidPosting},
+		{"same wallet twice", PostingRequest{TransactionType: TransactionTopUp, ReferenceID: "ref", Legs: []Leg{
+			{WalletID: user, Amount: money.Paisa(50000)},
+			{WalletID: user, Amount: money.Paisa(-50000)},
+		}}, ErrInvalidPosting},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePosting(tt.req); !errors.Is(err, tt.err) {
+				t.Errorf("validatePosting() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSameLegs(t *testing.T) {
+	user, clearing := uuid.New(), uuid.New()
+	posted := []walletdb.GikiWalletLedger{
+		{WalletID: clearing, Amount: money.Paisa(-50000)},
+		{WalletID: user, Amount: money.Paisa(50000)},
+	}
+
+	same := []Leg{
+		{WalletID: user, Amount: money.Paisa(50000)},
+		{WalletID: clearing, Amount: money.Paisa(-50000)},
+	}
+	if !sameLegs(posted, same) {
+		t.Error("sameLegs() = false for the legs that were posted")
+	}
+
+	different := []Leg{
+		{WalletID: user, Amount: money.Paisa(60000)},
+		{WalletID: clearing, Amount: money.Paisa(-60000)},
+	}
+	if sameLegs(posted, different) {
+		t.Error("sameLegs() = true for a different amount")
+	}
+
+	if sameLegs(posted, same[:1]) {
+		t.Error("sameLegs() = true for fewer legs")
+	}
+}
+
+func TestTransferLimits(t *testing.T) {
+	limits := TransferLimits{MaxPerTransaction: money.Paisa(5_000_00), DailyCap: money.Paisa(20_000_00)}
+
+	amountTests := []struct {
+		amount money.Money
+		err    error
+	}{
+		{money.Paisa(5_000_00), nil},
+		{money.Paisa(5_000_01), ErrTransferAboveMaximum},
+	}
+	for _, tt := range amountTests {
+		if err := limits.checkAmount(tt.amount); !errors.Is(err, tt.err) {
+			t.Errorf("checkAmount(%s) error = %v, want %v", tt.amount, err, tt.err)
+		}
+	}
+
+	capTests := []struct {
+		used, amount money.Money
+		err          error
+	}{
+		{money.Paisa(0), money.Paisa(5_000_00), nil},
+		{money.Paisa(15_000_00), money.Paisa(5_000_00), nil},
+		{money.Paisa(15_000_00), money.Paisa(5_000_01), ErrTransferDailyLimit},
+	}
+	for _, tt := range capTests {
+		if err := limits.checkDailyCap(tt.used, tt.amount); !errors.Is(err, tt.err) {
+			t.Errorf("checkDailyCap(%s, %s) error = %v, want %v", tt.used, tt.amount, err, tt.err)
+		}
+	}
+
+	var unlimited TransferLimits
+	if err := unlimited.checkAmount(money.Paisa(1_000_000_00)); err != nil {
+		t.Errorf("zero MaxPerTransaction should not limit, got %v", err)
+	}
+	if err := unlimited.checkDailyCap(money.Paisa(1_000_000_00), money.Paisa(1)); err != nil {
+		t.Errorf("zero DailyCap should not limit, got %v", err)
+	}
+}
+
+func TestTransferDayStart(t *testing.T) {
+	// 20:30 UTC is already the next day in Pakistan
+	now := time.Date(2024, time.March, 1, 20, 30, 0, 0, time.UTC)
+	want := time.Date(2024, time.March, 2, 0, 0, 0, 0, common.PKT)
+
+	if got := (TransferLimits{}).dayStart(now); !got.Equal(want) {
+		t.Errorf("dayStart() = %s, want %s", got, want)
+	}
+}
+
+func TestTransferDescription(t *testing.T) {
+	if got := transferDescription("Ali", "Sara", ""); got != "Transfer from Ali to Sara" {
+		t.Errorf("transferDescription() = %q", got)
+	}
+	if got := transferDescription("Ali", "Sara", "lunch"); got != "Transfer from Ali to Sara: lunch" {
+		t.Errorf("transferDescription() with note = %q", got)
+	}
+}
+
+// testChain builds a chain of entries for one wallet the way Post does
+func testChain(key []byte, amounts ...int64) []walletdb.GikiWalletLedger {
+	walletID := uuid.New()
+	createdAt := time.Date(2024, time.March, 1, 10, 0, 0, 123456000, time.UTC)
+
+	var (
+		entries  []walletdb.GikiWalletLedger
+		balance  int64
+		prevHash string
+	)
+	for i, amount := range amounts {
+		balance += amount
+		entry := walletdb.GikiWalletLedger{
+			ID:                 uuid.New(),
+			Seq:                int64(i + 1),
+			WalletID:           walletID,
+			Amount:             money.Paisa(amount),
+			BalanceAfter:       money.Paisa(balance),
+			TransactionGroupID: uuid.New(),
+			TransactionType:    string(TransactionTopUp),
+			ReferenceID:        uuid.NewString(),
+			Description:        "Wallet top-up",
+			CreatedAt:          createdAt.Add(time.Duration(i) * time.Minute),
+		}
+		prevHash = rowHash(key, entry, prevHash)
+		entry.RowHash = prevHash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// walkChain returns the index of the first entry that breaks the chain, or -1
+func walkChain(key []byte, entries []walletdb.GikiWalletLedger) (int, *chainWalk) {
+	walk := &chainWalk{key: key, balance: money.Paisa(0)}
+	for i, entry := range entries {
+		if reason := walk.check(entry); reason != "" {
+			return i, walk
+		}
+	}
+	return -1, walk
+}
+
+func TestChainWalk(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	t.Run("intact chain", func(t *testing.T) {
+		if broken, _ := walkChain(key, testChain(key, 500_00, -200_00, 1_000_00)); broken != -1 {
+			t.Errorf("intact chain broke at %d", broken)
+		}
+	})
+
+	t.Run("edited amount and balance", func(t *testing.T) {
+		entries := testChain(key, 500_00, -200_00, 1_000_00)
+		entries[1].Amount = money.Paisa(-100_00)
+		entries[1].BalanceAfter = money.Paisa(400_00)
+		entries[2].BalanceAfter = money.Paisa(1_400_00)
+		if broken, _ := walkChain(key, entries); broken != 1 {
+			t.Errorf("chain broke at %d, want 1", broken)
+		}
+	})
+
+	t.Run("balance does not follow the running sum", func(t *testing.T) {
+		entries := testChain(key, 500_00, -200_00)
+		entries[1].BalanceAfter = money.Paisa(900_00)
+		if broken, _ := walkChain(key, entries); broken != 1 {
+			t.Errorf("chain broke at %d, want 1", broken)
+		}
+	})
+
+	t.Run("entry removed from the middle", func(t *testing.T) {
+		entries := testChain(key, 500_00, 300_00, 1_000_00)
+		entries = append(entries[:1], entries[2:]...)
+		entries[1].BalanceAfter = money.Paisa(1_500_00)
+		if broken, _ := walkChain(key, entries); broken != 1 {
+			t.Errorf("chain broke at %d, want 1", broken)
+		}
+	})
+
+	t.Run("different key", func(t *testing.T) {
+		if broken, _ := walkChain([]byte("another-key-another-key-another!!"), testChain(key, 500_00)); broken != 0 {
+			t.Errorf("chain broke at %d, want 0", broken)
+		}
+	})
+
+	t.Run("hash cleared", func(t *testing.T) {
+		entries := testChain(key, 500_00, 200_00)
+		entries[1].RowHash = ""
+		if broken, _ := walkChain(key, entries); broken != 1 {
+			t.Errorf("chain broke at %d, want 1", broken)
+		}
+	})
+
+	t.Run("chain ends at the wallet head", func(t *testing.T) {
+		entries := testChain(key, 500_00, -200_00, 1_000_00)
+		_, walk := walkChain(key, entries)
+		if reason := walk.checkHead(pgtype.Text{String: entries[2].RowHash, Valid: true}, 3); reason != "" {
+			t.Errorf("checkHead() = %q, want no break", reason)
+		}
+	})
+
+	t.Run("newest entries removed", func(t *testing.T) {
+		entries := testChain(key, 500_00, -200_00, 1_000_00)
+		broken, walk := walkChain(key, entries[:2])
+		if broken != -1 {
+			t.Fatalf("truncated chain broke at %d", broken)
+		}
+		if reason := walk.checkHead(pgtype.Text{String: entries[2].RowHash, Valid: true}, 3); reason == "" {
+			t.Error("checkHead() found no break in a chain missing its newest entry")
+		}
+		if reason := walk.checkHead(pgtype.Text{String: entries[2].RowHash, Valid: true}, 2); reason == "" {
+			t.Error("checkHead() accepted a head hash the chain does not end at")
+		}
+	})
+}
+
+func TestParseStatementPeriod(t *testing.T) {
+	// 20:30 UTC on 14 March is 15 March in Pakistan
+	now := time.Date(2024, time.March, 14, 20, 30, 0, 0, time.UTC)
+
+	start, end, err := parseStatementPeriod("2024-02-01", "2024-02-29", now)
+	if err != nil {
+		t.Fatalf("parseStatementPeriod() error = %v", err)
+	}
+	if want := time.Date(2024, time.February, 1, 0, 0, 0, 0, common.PKT); !start.Equal(want) {
+		t.Errorf("start = %s, want %s", start, want)
+	}
+	if want := time.Date(2024, time.March, 1, 0, 0, 0, 0, common.PKT); !end.Equal(want) {
+		t.Errorf("end = %s, want %s", end, want)
+	}
+
+	start, end, err = parseStatementPeriod("", "", now)
+	if err != nil {
+		t.Fatalf("parseStatementPeriod() defaults error = %v", err)
+	}
+	if want := time.Date(2024, time.March, 1, 0, 0, 0, 0, common.PKT); !start.Equal(want) {
+		t.Errorf("default start = %s, want %s", start, want)
+	}
+	if want := time.Date(2024, time.March, 16, 0, 0, 0, 0, common.PKT); !end.Equal(want) {
+		t.Errorf("default end = %s, want %s", end, want)
+	}
+
+	for _, tc := range []struct{ from, to string }{
+		{"2024-03-01", "2024-03-16"}, // future
+		{"2024-03-02", "2024-03-01"}, // reversed
+		{"2022-01-01", "2024-01-01"}, // too long
+		{"01/02/2024", "2024-02-29"},
+	} {
+		if _, _, err := parseStatementPeriod(tc.from, tc.to, now); !errors.Is(err, ErrInvalidStatement) {
+			t.Errorf("parseStatementPeriod(%q, %q) error = %v, want ErrInvalidStatement", tc.from, tc.to, err)
+		}
+	}
+}
+
+func TestStatementCode(t *testing.T) {
+	code, err := newStatementCode()
+	if err != nil {
+		t.Fatalf("newStatementCode() error = %v", err)
+	}
+	if len(code) != 19 || strings.Count(code, "-") != 3 {
+		t.Errorf("newStatementCode() = %q, want XXXX-XXXX-XXXX-XXXX", code)
+	}
+
+	typed := strings.ToLower(strings.ReplaceAll(code, "-", " "))
+	if got, ok := normalizeStatementCode(typed); !ok || got != code {
+		t.Errorf("normalizeStatementCode(%q) = %q, %v, want %q", typed, got, ok, code)
+	}
+	for _, bad := range []string{"", "ABCD-EFGH", "ABCD-EFGH-IJKL-MN01", code + "-AAAA"} {
+		if _, ok := normalizeStatementCode(bad); ok {
+			t.Errorf("normalizeStatementCode(%q) accepted", bad)
+		}
+	}
+}
+
+func TestStatementTotals(t *testing.T) {
+	entries := []LedgerEntry{
+		{Amount: money.Paisa(500_00), BalanceAfter: money.Paisa(600_00)},
+		{Amount: money.Paisa(-150_00), BalanceAfter: money.Paisa(450_00)},
+		{Amount: money.Paisa(-50_00), BalanceAfter: money.Paisa(400_00)},
+	}
+
+	statement := &Statement{}
+	if err := fillStatementTotals(statement, money.Paisa(100_00), entries); err != nil {
+		t.Fatalf("fillStatementTotals() error = %v", err)
+	}
+	if !statement.TotalCredits.Equal(money.Paisa(500_00)) || !statement.TotalDebits.Equal(money.Paisa(200_00)) {
+		t.Errorf("totals = %s credits, %s debits", statement.TotalCredits, statement.TotalDebits)
+	}
+	if !statement.ClosingBalance.Equal(money.Paisa(400_00)) {
+		t.Errorf("closing = %s, want PKR 400.00", statement.ClosingBalance)
+	}
+
+	entries[1].BalanceAfter = money.Paisa(460_00)
+	if err := fillStatementTotals(&Statement{}, money.Paisa(100_00), entries); err == nil {
+		t.Error("fillStatementTotals() accepted entries that do not add up")
+	}
+}
+
+func TestRenderStatement(t *testing.T) {
+	statement := &Statement{
+		VerificationCode: "ABCD-EFGH-IJKL-MNOP",
+		Holder:           StatementHolder{Name: "=Ali Khan", MemberID: "2021123"},
+		PeriodStart:      time.Date(2024, time.February, 1, 0, 0, 0, 0, common.PKT),
+		PeriodEnd:        time.Date(2024, time.March, 1, 0, 0, 0, 0, common.PKT),
+		VerifyURL:        "https://wallet.giki.edu.pk/statements/verify/ABCD-EFGH-IJKL-MNOP",
+		IssuedAt:         time.Date(2024, time.March, 2, 9, 0, 0, 0, common.PKT),
+	}
+	entries := []LedgerEntry{{
+		Amount:          money.Paisa(-150_00),
+		BalanceAfter:    money.Paisa(350_00),
+		TransactionType: TransactionTransfer,
+		ReferenceID:     "ref-1",
+		Description:     "Transfer from Ali Khan to Sara",
+		CreatedAt:       time.Date(2024, time.February, 10, 7, 0, 0, 0, time.UTC),
+	}}
+	if err := fillStatementTotals(statement, money.Paisa(500_00), entries); err != nil {
+		t.Fatalf("fillStatementTotals() error = %v", err)
+	}
+
+	body, err := renderStatementCSV(statement)
+	if err != nil {
+		t.Fatalf("renderStatementCSV() error = %v", err)
+	}
+	csvText := string(body)
+	for _, want := range []string{
+		"Account holder,'=Ali Khan",
+		"2024-02-01,Opening balance,,,,,500.00",
+		"2024-02-10 12:00:00,Transfer from Ali Khan to Sara,TRANSFER,ref-1,150.00,,350.00",
+		"2024-02-29,Closing balance,,,150.00,0.00,350.00",
+	} {
+		if !strings.Contains(csvText, want) {
+			t.Errorf("CSV statement is missing %q:\n%s", want, csvText)
+		}
+	}
+
+	pdf, err := renderStatementPDF(statement)
+	if err != nil {
+		t.Fatalf("renderStatementPDF() error = %v", err)
+	}
+	if !strings.HasPrefix(string(pdf), "%PDF-") {
+		t.Errorf("renderStatementPDF() did not produce a PDF")
+	}
+}
+
+func TestBuildTrialBalance(t *testing.T) {
+	asOf := time.Date(2024, time.March, 1, 0, 0, 0, 0, common.PKT)
+	systemWallets := []walletdb.ListSystemWalletBalancesRow{
+		{Code: pgtype.Text{String: GatewayClearingWallet, Valid: true}, Name: "Gateway clearing", Type: walletdb.WalletTypeSYSLIABILITY, Balance: -1000_00},
+		{Code: pgtype.Text{String: TransportRevenueWallet, Valid: true}, Name: "Transport revenue", Type: walletdb.WalletTypeSYSREVENUE, Balance: 150_00},
+	}
+	personal := walletdb.GetPersonalWalletsTotalRow{Wallets: 3, Balance: 850_00}
+	types := []walletdb.SumLedgerByTransactionTypeRow{
+		{TransactionType: string(TransactionTicketSale), Entries: 2, Debits: 150_00, Credits: 150_00},
+		{TransactionType: string(TransactionTopUp), Entries: 4, Debits: 1000_00, Credits: 1000_00},
+	}
+
+	balance, err := buildTrialBalance(asOf, systemWallets, personal, types)
+	if err != nil {
+		t.Fatalf("buildTrialBalance() error = %v", err)
+	}
+	if !balance.Balanced || !balance.Total.IsZero() {
+		t.Errorf("balanced ledger: Balanced = %v, Total = %s", balance.Balanced, balance.Total)
+	}
+
+	types[0].Credits, types[0].Net = 160_00, 10_00
+	personal.Balance = 860_00
+	balance, err = buildTrialBalance(asOf, systemWallets, personal, types)
+	if err != nil {
+		t.Fatalf("buildTrialBalance() error = %v", err)
+	}
+	if balance.Balanced || !balance.Total.Equal(money.Paisa(10_00)) {
+		t.Errorf("unbalanced ledger: Balanced = %v, Total = %s", balance.Balanced, balance.Total)
+	}
+	if len(balance.UnbalancedTypes) != 1 || balance.UnbalancedTypes[0] != TransactionTicketSale {
+		t.Errorf("UnbalancedTypes = %v, want [TICKET_SALE]", balance.UnbalancedTypes)
+	}
+}
+
+func TestParseAsOf(t *testing.T) {
+	now := time.Date(2024, time.March, 15, 12, 0, 0, 0, common.PKT)
+
+	tests := []struct {
+		raw  string
+		want time.Time
+	}{
+		{"", now},
+		{"2024-03-01", time.Date(2024, time.March, 2, 0, 0, 0, 0, common.PKT)},
+		{"2024-03-15", now},
+		{"2024-03-01T10:00:00Z", time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range tests {
+		got, err := parseAsOf(tc.raw, now)
+		if err != nil || !got.Equal(tc.want) {
+			t.Errorf("parseAsOf(%q) = %s, %v, want %s", tc.raw, got, err, tc.want)
+		}
+	}
+
+	for _, raw := range []string{"2024-03-16", "2024-03-15T13:00:00+05:00", "yesterday"} {
+		if _, err := parseAsOf(raw, now); !errors.Is(err, ErrInvalidAsOf) {
+			t.Errorf("parseAsOf(%q) error = %v, want ErrInvalidAsOf", raw, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+--- ledger
+
+-- name: GetWalletBalance :one
+SELECT balance_after FROM giki_wallet.ledger
+WHERE wallet_id = $1
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: InsertLedgerEntry :one
+INSERT INTO giki_wallet.ledger (
+    id, wallet_id, amount, balance_after, transaction_group_id, transaction_type, reference_id, description,
+    row_hash, created_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetLedgerEntriesByReference :many
+SELECT * FROM giki_wallet.ledger
+WHERE transaction_type = $1 AND reference_id = $2
+ORDER BY seq;
+
+-- name: ListWalletEntries :many
+SELECT * FROM giki_wallet.ledger
+WHERE wallet_id = $1
+ORDER BY seq DESC
+LIMIT $2 OFFSET $3;
+
+--- ledger verification
+
+-- name: ListWalletsForVerification :many
+SELECT id, name FROM giki_wallet.wallets
+ORDER BY id;
+
+-- name: GetWalletForVerification :one
+SELECT id, head_hash, entry_count FROM giki_wallet.wallets
+WHERE id = $1;
+
+-- name: ListWalletEntriesAfter :many
+SELECT * FROM giki_wallet.ledger
+WHERE wallet_id = sqlc.arg(wallet_id) AND seq > sqlc.arg(after_seq)
+ORDER BY seq
+LIMIT sqlc.arg(batch_size);
+
+-- name: ListUnbalancedGroups :many
+SELECT transaction_group_id, SUM(amount)::bigint AS total
+FROM giki_wallet.ledger
+GROUP BY transaction_group_id
+HAVING SUM(amount) <> 0;
@@ -0,0 +1,34 @@
+--- statements
+
+-- name: GetStatementHolder :one
+SELECT u.id, u.name, u.email, u.user_type,
+       COALESCE(s.reg_id, e.employee_id, '')::text AS member_id
+FROM giki_wallet.users u
+LEFT JOIN giki_wallet.student_profiles s ON s.user_id = u.id
+LEFT JOIN giki_wallet.employee_profiles e ON e.user_id = u.id
+WHERE u.id = $1;
+
+-- name: GetBalanceBefore :one
+SELECT balance_after FROM giki_wallet.ledger
+WHERE wallet_id = sqlc.arg(wallet_id) AND created_at < sqlc.arg(before)::timestamptz
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: ListStatementEntries :many
+SELECT * FROM giki_wallet.ledger
+WHERE wallet_id = sqlc.arg(wallet_id)
+  AND created_at >= sqlc.arg(period_start)::timestamptz
+  AND created_at < sqlc.arg(period_end)::timestamptz
+ORDER BY seq;
+
+-- name: CreateStatement :one
+INSERT INTO giki_wallet.wallet_statements (
+    verification_code, wallet_id, holder_name, period_start, period_end,
+    opening_balance, closing_balance, total_credits, total_debits, entry_count,
+    format, document_sha256, issued_by
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+RETURNING *;
+
+-- name: GetStatementByCode :one
+SELECT * FROM giki_wallet.wallet_statements
+WHERE verification_code = $1;
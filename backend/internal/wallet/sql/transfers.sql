@@ -0,0 +1,25 @@
+--- transfers
+
+-- name: FindRecipientByEmail :one
+SELECT id, name, email FROM giki_wallet.users
+WHERE lower(email) = lower(sqlc.arg(email)::text) AND is_active AND is_verified;
+
+-- name: FindRecipientByRegID :one
+SELECT u.id, u.name, u.email FROM giki_wallet.users u
+JOIN giki_wallet.student_profiles s ON s.user_id = u.id
+WHERE s.reg_id = sqlc.arg(reg_id) AND u.is_active AND u.is_verified;
+
+-- name: CreateTransfer :one
+INSERT INTO giki_wallet.wallet_transfers (
+    id, idempotency_key, sender_id, recipient_id, amount, note, transaction_group_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetTransferByIdempotencyKey :one
+SELECT * FROM giki_wallet.wallet_transfers
+WHERE idempotency_key = $1;
+
+-- name: GetTransferredSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS total
+FROM giki_wallet.wallet_transfers
+WHERE sender_id = sqlc.arg(sender_id) AND created_at >= sqlc.arg(since)::timestamptz;
@@ -0,0 +1,35 @@
+--- trial balance
+
+-- name: ListSystemWalletBalances :many
+SELECT w.id, w.code, w.name, w.type, COALESCE(b.balance_after, 0)::bigint AS balance
+FROM giki_wallet.wallets w
+LEFT JOIN (
+    SELECT DISTINCT ON (l.wallet_id) l.wallet_id, l.balance_after
+    FROM giki_wallet.ledger l
+    JOIN giki_wallet.wallets sw ON sw.id = l.wallet_id
+    WHERE sw.type <> 'PERSONAL' AND l.created_at < sqlc.arg(as_of)::timestamptz
+    ORDER BY l.wallet_id, l.seq DESC
+) b ON b.wallet_id = w.id
+WHERE w.type <> 'PERSONAL'
+ORDER BY w.code;
+
+-- name: GetPersonalWalletsTotal :one
+SELECT COUNT(*)::bigint AS wallets, COALESCE(SUM(b.balance_after), 0)::bigint AS balance
+FROM (
+    SELECT DISTINCT ON (l.wallet_id) l.wallet_id, l.balance_after
+    FROM giki_wallet.ledger l
+    JOIN giki_wallet.wallets w ON w.id = l.wallet_id
+    WHERE w.type = 'PERSONAL' AND l.created_at < sqlc.arg(as_of)::timestamptz
+    ORDER BY l.wallet_id, l.seq DESC
+) b;
+
+-- name: SumLedgerByTransactionType :many
+SELECT transaction_type,
+       COUNT(*)::bigint AS entries,
+       COALESCE(SUM(CASE WHEN amount < 0 THEN -amount ELSE 0 END), 0)::bigint AS debits,
+       COALESCE(SUM(CASE WHEN amount > 0 THEN amount ELSE 0 END), 0)::bigint AS credits,
+       SUM(amount)::bigint AS net
+FROM giki_wallet.ledger
+WHERE created_at < sqlc.arg(as_of)::timestamptz
+GROUP BY transaction_type
+ORDER BY transaction_type;
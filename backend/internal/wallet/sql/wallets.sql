@@ -0,0 +1,30 @@
+--- wallets
+
+-- name: CreatePersonalWallet :exec
+INSERT INTO giki_wallet.wallets (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO NOTHING;
+
+-- name: GetWalletByUserID :one
+SELECT * FROM giki_wallet.wallets
+WHERE user_id = $1;
+
+-- name: GetSystemWallet :one
+SELECT * FROM giki_wallet.wallets
+WHERE code = $1;
+
+-- name: LockWallets :many
+SELECT * FROM giki_wallet.wallets
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+ORDER BY id
+FOR UPDATE;
+
+-- name: UpdateWalletHead :exec
+UPDATE giki_wallet.wallets
+SET head_hash = $2,
+    entry_count = $3
+WHERE id = $1;
+
+-- name: GetUserName :one
+SELECT name FROM giki_wallet.users
+WHERE id = $1;
@@ -0,0 +1,376 @@
+package wallet
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	walletdb "github.com/hash-walker/giki-wallet/internal/wallet/wallet_db"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidStatement Bad period or format (400)
+	ErrInvalidStatement = errors.New("invalid statement request")
+
+	// ErrStatementNotFound No statement was issued with that code (404)
+	ErrStatementNotFound = errors.New("statement not found")
+)
+
+const (
+	// maxStatementDays keeps one statement to a year of entries
+	maxStatementDays = 366
+
+	// statementCodeBytes is 80 bits, 16 base32 characters
+	statementCodeBytes = 10
+)
+
+// statementCodeEncoding is base32 without padding; it has no 0/O or 1/I pairs to misread
+var statementCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Statements
+// =============================================================================
+
+// IssueMyStatement renders a statement of the authenticated user's wallet
+func (s *Service) IssueMyStatement(ctx context.Context, req StatementRequest) (*StatementDocument, error) {
+	userID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	return s.IssueStatement(ctx, userID, req)
+}
+
+// IssueStatement renders a statement of userID's wallet for the requested
+// period and records it under a new verification code. The authenticated
+// user is recorded as the issuer, so admins can issue statements for others.
+func (s *Service) IssueStatement(ctx context.Context, userID uuid.UUID, req StatementRequest) (*StatementDocument, error) {
+	issuerID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	issuedAt := time.Now()
+	start, end, err := parseStatementPeriod(req.From, req.To, issuedAt)
+	if err != nil {
+		return nil, err
+	}
+	format, err := parseStatementFormat(req.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := s.personalWallet(ctx, s.q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	holder, err := s.q.GetStatementHolder(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: user %s", ErrWalletNotFound, userID)
+	} else if err != nil {
+		log.Printf("failed to get statement holder %s: %v", userID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	code, err := newStatementCode()
+	if err != nil {
+		log.Printf("failed to generate statement code: %v", err)
+		return nil, err
+	}
+
+	statement := &Statement{
+		VerificationCode: code,
+		Holder: StatementHolder{
+			Name:     holder.Name,
+			Email:    holder.Email,
+			UserType: holder.UserType,
+			MemberID: holder.MemberID,
+		},
+		WalletID:    w.ID,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		VerifyURL:   s.statementVerifyURL(code),
+		IssuedAt:    issuedAt,
+	}
+	if err := s.loadStatementEntries(ctx, statement); err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	switch format {
+	case StatementCSV:
+		body, err = renderStatementCSV(statement)
+	default:
+		body, err = renderStatementPDF(statement)
+	}
+	if err != nil {
+		log.Printf("failed to render %s statement of wallet %s: %v", format, w.ID, err)
+		return nil, err
+	}
+
+	digest := sha256.Sum256(body)
+	if _, err := s.q.CreateStatement(ctx, walletdb.CreateStatementParams{
+		VerificationCode: code,
+		WalletID:         w.ID,
+		HolderName:       holder.Name,
+		PeriodStart:      start,
+		PeriodEnd:        end,
+		OpeningBalance:   statement.OpeningBalance,
+		ClosingBalance:   statement.ClosingBalance,
+		TotalCredits:     statement.TotalCredits,
+		TotalDebits:      statement.TotalDebits,
+		EntryCount:       int32(len(statement.Entries)),
+		Format:           string(format),
+		DocumentSha256:   hex.EncodeToString(digest[:]),
+		IssuedBy:         issuerID,
+	}); err != nil {
+		log.Printf("failed to record statement of wallet %s: %v", w.ID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	return &StatementDocument{
+		VerificationCode: code,
+		Filename:         statementFilename(statement, format),
+		ContentType:      statementContentType(format),
+		Body:             body,
+	}, nil
+}
+
+// VerifyStatement looks up an issued statement by the code printed on it.
+// Codes are matched ignoring case, spaces and dashes.
+func (s *Service) VerifyStatement(ctx context.Context, code string) (*StatementVerification, error) {
+	code, ok := normalizeStatementCode(code)
+	if !ok {
+		return nil, ErrStatementNotFound
+	}
+
+	row, err := s.q.GetStatementByCode(ctx, code)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrStatementNotFound
+	} else if err != nil {
+		log.Printf("failed to get statement %s: %v", code, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	return toStatementVerification(row), nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Statements
+// =============================================================================
+
+// loadStatementEntries fills in the opening balance, the entries of the
+// period and the totals. Both reads share one snapshot, so a posting
+// committing in between cannot make the opening balance and the entries
+// disagree.
+func (s *Service) loadStatementEntries(ctx context.Context, statement *Statement) error {
+	tx, err := s.dbPool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		log.Printf("failed to begin statement transaction: %v", err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	walletQ := s.q.WithTx(tx)
+
+	opening, err := walletQ.GetBalanceBefore(ctx, walletdb.GetBalanceBeforeParams{
+		WalletID: statement.WalletID,
+		Before:   statement.PeriodStart,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		opening = money.Paisa(0)
+	} else if err != nil {
+		log.Printf("failed to get opening balance of wallet %s: %v", statement.WalletID, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	rows, err := walletQ.ListStatementEntries(ctx, walletdb.ListStatementEntriesParams{
+		WalletID:    statement.WalletID,
+		PeriodStart: statement.PeriodStart,
+		PeriodEnd:   statement.PeriodEnd,
+	})
+	if err != nil {
+		log.Printf("failed to list statement entries of wallet %s: %v", statement.WalletID, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	entries := make([]LedgerEntry, len(rows))
+	for i, row := range rows {
+		entries[i] = toLedgerEntry(row)
+	}
+
+	return fillStatementTotals(statement, opening, entries)
+}
+
+// statementVerifyURL is the public page that checks code
+func (s *Service) statementVerifyURL(code string) string {
+	return strings.TrimRight(s.statementVerifyBase, "/") + "/" + code
+}
+
+// =============================================================================
+// HELPERS - Statements
+// =============================================================================
+
+// parseStatementPeriod turns inclusive YYYY-MM-DD dates (PKT) into a
+// half-open window. A missing to is today and a missing from is the first
+// of to's month.
+func parseStatementPeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	today := now.In(common.PKT)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, common.PKT)
+
+	end := today
+	if to != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", to, common.PKT)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidStatement)
+		}
+		end = parsed
+	}
+	if end.After(today) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to cannot be in the future", ErrInvalidStatement)
+	}
+
+	start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, common.PKT)
+	if from != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", from, common.PKT)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidStatement)
+		}
+		start = parsed
+	}
+
+	end = end.AddDate(0, 0, 1)
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must not be after to", ErrInvalidStatement)
+	}
+	if start.AddDate(0, 0, maxStatementDays).Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: a statement covers at most %d days", ErrInvalidStatement, maxStatementDays)
+	}
+
+	return start, end, nil
+}
+
+// parseStatementFormat defaults to PDF
+func parseStatementFormat(format StatementFormat) (StatementFormat, error) {
+	switch StatementFormat(strings.ToLower(string(format))) {
+	case "", StatementPDF:
+		return StatementPDF, nil
+	case StatementCSV:
+		return StatementCSV, nil
+	default:
+		return "", fmt.Errorf("%w: format must be pdf or csv", ErrInvalidStatement)
+	}
+}
+
+// fillStatementTotals sets the entries, totals and closing balance, and
+// checks the entries carry on from the opening balance
+func fillStatementTotals(statement *Statement, opening money.Money, entries []LedgerEntry) error {
+	credits, debits, running := money.Paisa(0), money.Paisa(0), opening
+
+	for _, entry := range entries {
+		var err error
+		if entry.Amount.IsPositive() {
+			credits, err = credits.Add(entry.Amount)
+		} else {
+			debits, err = debits.Sub(entry.Amount)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+
+		if running, err = running.Add(entry.Amount); err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+		if !running.Equal(entry.BalanceAfter) {
+			log.Printf("LEDGER TAMPERING: wallet %s entry %s has balance_after %s, statement running balance %s",
+				statement.WalletID, entry.ID, entry.BalanceAfter, running)
+			return fmt.Errorf("%w: wallet %s entries do not add up", ErrDatabaseQuery, statement.WalletID)
+		}
+	}
+
+	statement.OpeningBalance = opening
+	statement.ClosingBalance = running
+	statement.TotalCredits = credits
+	statement.TotalDebits = debits
+	statement.Entries = entries
+	return nil
+}
+
+// newStatementCode returns 80 random bits as XXXX-XXXX-XXXX-XXXX
+func newStatementCode() (string, error) {
+	raw := make([]byte, statementCodeBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return formatStatementCode(statementCodeEncoding.EncodeToString(raw)), nil
+}
+
+// normalizeStatementCode accepts a code as someone might type it back in
+func normalizeStatementCode(code string) (string, bool) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	raw, err := statementCodeEncoding.DecodeString(code)
+	if err != nil || len(raw) != statementCodeBytes {
+		return "", false
+	}
+	return formatStatementCode(code), true
+}
+
+// formatStatementCode splits 16 characters into dashed groups of four
+func formatStatementCode(code string) string {
+	groups := make([]string, 0, len(code)/4)
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:min(i+4, len(code))])
+	}
+	return strings.Join(groups, "-")
+}
+
+func statementFilename(statement *Statement, format StatementFormat) string {
+	return fmt.Sprintf("giki-wallet-statement-%s-%s.%s",
+		statement.PeriodStart.In(common.PKT).Format("20060102"),
+		statement.PeriodEnd.AddDate(0, 0, -1).In(common.PKT).Format("20060102"),
+		format)
+}
+
+func statementContentType(format StatementFormat) string {
+	if format == StatementCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/pdf"
+}
+
+func toStatementVerification(row walletdb.GikiWalletWalletStatement) *StatementVerification {
+	return &StatementVerification{
+		VerificationCode: row.VerificationCode,
+		HolderName:       row.HolderName,
+		PeriodFrom:       row.PeriodStart.In(common.PKT).Format("2006-01-02"),
+		PeriodTo:         row.PeriodEnd.AddDate(0, 0, -1).In(common.PKT).Format("2006-01-02"),
+		OpeningBalance:   row.OpeningBalance,
+		ClosingBalance:   row.ClosingBalance,
+		TotalCredits:     row.TotalCredits,
+		TotalDebits:      row.TotalDebits,
+		EntryCount:       row.EntryCount,
+		Format:           StatementFormat(row.Format),
+		DocumentSHA256:   row.DocumentSha256,
+		IssuedAt:         row.CreatedAt,
+	}
+}
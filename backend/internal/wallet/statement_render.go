@@ -0,0 +1,246 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+)
+
+const (
+	statementInstitution = "Ghulam Ishaq Khan Institute of Engineering Sciences and Technology"
+	statementTitle       = "GIKI Wallet — Account Statement"
+)
+
+var statementHeader = []string{"Date", "Description", "Type", "Reference", "Debit", "Credit", "Balance"}
+
+// =============================================================================
+// CSV
+// =============================================================================
+
+// renderStatementCSV writes a few summary lines, then one line per entry
+// between the opening and closing balances, amounts in rupees
+func renderStatementCSV(statement *Statement) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	records := [][]string{
+		{statementTitle},
+		{"Account holder", csvSafe(statement.Holder.Name)},
+		{"Member ID", csvSafe(statement.Holder.MemberID)},
+		{"Email", csvSafe(statement.Holder.Email)},
+		{"Period", statementPeriodFrom(statement), statementPeriodTo(statement)},
+		{"Issued at", statement.IssuedAt.In(common.PKT).Format("2006-01-02 15:04:05")},
+		{"Verification code", statement.VerificationCode},
+		{"Verify at", statement.VerifyURL},
+		{},
+		statementHeader,
+		{statementPeriodFrom(statement), "Opening balance", "", "", "", "", statement.OpeningBalance.Decimal()},
+	}
+	for _, entry := range statement.Entries {
+		debit, credit := statementAmounts(entry.Amount)
+		records = append(records, []string{
+			entry.CreatedAt.In(common.PKT).Format("2006-01-02 15:04:05"),
+			csvSafe(entry.Description),
+			string(entry.TransactionType),
+			csvSafe(entry.ReferenceID),
+			debit,
+			credit,
+			entry.BalanceAfter.Decimal(),
+		})
+	}
+	records = append(records, []string{
+		statementPeriodTo(statement), "Closing balance", "", "",
+		statement.TotalDebits.Decimal(), statement.TotalCredits.Decimal(), statement.ClosingBalance.Decimal(),
+	})
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// =============================================================================
+// PDF
+// =============================================================================
+
+// statementColumns are the widths in mm of the statementHeader columns on
+// landscape A4 with 10mm margins
+var statementColumns = []float64{34, 78, 22, 62, 26, 26, 29}
+
+// renderStatementPDF lays the statement out on landscape A4 pages with the
+// university header, a summary, the entry table and the verification code
+// in every page footer
+func renderStatementPDF(statement *Statement) ([]byte, error) {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // cp1252, covers the core fonts
+
+	pdf.SetTitle(statementTitle, true)
+	pdf.SetCreator("GIKI Wallet", true)
+	pdf.SetCreationDate(statement.IssuedAt)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-14)
+		pdf.SetDrawColor(180, 180, 180)
+		pdf.Line(10, pdf.GetY(), 287, pdf.GetY())
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.CellFormat(230, 6, tr(fmt.Sprintf("Verification code %s — check this statement at %s",
+			statement.VerificationCode, statement.VerifyURL)), "", 0, "L", false, 0, "")
+		pdf.CellFormat(47, 6, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+
+	// Header
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 7, tr(statementInstitution), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 12)
+	pdf.CellFormat(0, 7, tr(statementTitle), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	// Summary
+	summary := [][2]string{
+		{"Account holder", statement.Holder.Name},
+		{"Member ID", statement.Holder.MemberID},
+		{"Email", statement.Holder.Email},
+		{"Period", statementPeriodFrom(statement) + " to " + statementPeriodTo(statement)},
+		{"Issued at", statement.IssuedAt.In(common.PKT).Format("2006-01-02 15:04 PKT")},
+		{"Verification code", statement.VerificationCode},
+	}
+	totals := [][2]string{
+		{"Opening balance", statement.OpeningBalance.String()},
+		{"Total credits", statement.TotalCredits.String()},
+		{"Total debits", statement.TotalDebits.String()},
+		{"Closing balance", statement.ClosingBalance.String()},
+	}
+	top := pdf.GetY()
+	for i, line := range summary {
+		pdf.SetXY(10, top+float64(i)*5.5)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(32, 5.5, line[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(120, 5.5, tr(line[1]), "", 0, "L", false, 0, "")
+	}
+	for i, line := range totals {
+		pdf.SetXY(197, top+float64(i)*5.5)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(40, 5.5, line[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(50, 5.5, line[1], "", 0, "R", false, 0, "")
+	}
+	pdf.SetXY(10, top+float64(len(summary))*5.5+4)
+
+	// Entries
+	tableHeader := func() {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.SetFillColor(230, 230, 230)
+		pdf.SetDrawColor(180, 180, 180)
+		for i, title := range statementHeader {
+			pdf.CellFormat(statementColumns[i], 6, title, "1", 0, statementAlign(i), true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 8)
+	}
+	row := func(cells []string) {
+		// Start a new page ourselves so the table header repeats
+		_, pageHeight := pdf.GetPageSize()
+		_, breakMargin := pdf.GetAutoPageBreak()
+		if pdf.GetY()+5.5 > pageHeight-breakMargin {
+			pdf.AddPage()
+			tableHeader()
+		}
+		for i, cell := range cells {
+			pdf.CellFormat(statementColumns[i], 5.5, fitCell(pdf, tr(cell), statementColumns[i]-2), "1", 0, statementAlign(i), false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	tableHeader()
+	row([]string{statementPeriodFrom(statement), "Opening balance", "", "", "", "", statement.OpeningBalance.Decimal()})
+	for _, entry := range statement.Entries {
+		debit, credit := statementAmounts(entry.Amount)
+		row([]string{
+			entry.CreatedAt.In(common.PKT).Format("2006-01-02 15:04"),
+			entry.Description,
+			string(entry.TransactionType),
+			entry.ReferenceID,
+			debit,
+			credit,
+			entry.BalanceAfter.Decimal(),
+		})
+	}
+	pdf.SetFont("Helvetica", "B", 8)
+	row([]string{
+		statementPeriodTo(statement), "Closing balance", "", "",
+		statement.TotalDebits.Decimal(), statement.TotalCredits.Decimal(), statement.ClosingBalance.Decimal(),
+	})
+
+	if len(statement.Entries) == 0 {
+		pdf.Ln(3)
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.CellFormat(0, 6, "No transactions in this period.", "", 1, "L", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// statementAmounts splits an entry amount into its debit and credit columns
+func statementAmounts(amount money.Money) (string, string) {
+	if amount.IsNegative() {
+		return strings.TrimPrefix(amount.Decimal(), "-"), ""
+	}
+	return "", amount.Decimal()
+}
+
+func statementPeriodFrom(statement *Statement) string {
+	return statement.PeriodStart.In(common.PKT).Format("2006-01-02")
+}
+
+// statementPeriodTo is the last day covered; PeriodEnd is the day after
+func statementPeriodTo(statement *Statement) string {
+	return statement.PeriodEnd.AddDate(0, 0, -1).In(common.PKT).Format("2006-01-02")
+}
+
+// statementAlign right-aligns the amount columns
+func statementAlign(column int) string {
+	if column >= 4 {
+		return "R"
+	}
+	return "L"
+}
+
+// fitCell cuts text to width mm in the current font, marking the cut
+func fitCell(pdf *fpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	for len(text) > 0 && pdf.GetStringWidth(text+"...") > width {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+// csvSafe stops spreadsheet apps from treating user-supplied text as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,344 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hash-walker/giki-wallet/internal/auth"
+	"github.com/hash-walker/giki-wallet/internal/common"
+	"github.com/hash-walker/giki-wallet/internal/money"
+	"github.com/hash-walker/giki-wallet/internal/notification"
+	walletdb "github.com/hash-walker/giki-wallet/internal/wallet/wallet_db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidTransfer Validation errors (400) - show to user
+	ErrInvalidTransfer      = errors.New("invalid transfer request")
+	ErrSelfTransfer         = errors.New("cannot transfer to your own wallet")
+	ErrTransferAboveMaximum = errors.New("transfer amount is above the maximum")
+
+	// ErrTransferDailyLimit Period cap (403)
+	ErrTransferDailyLimit = errors.New("daily transfer limit reached")
+
+	// ErrIdempotencyKeyReused Key already used for a different transfer (409)
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used for another transfer")
+
+	// ErrRecipientNotFound No active user with that email or reg ID (404)
+	ErrRecipientNotFound = errors.New("recipient not found")
+)
+
+const (
+	// maxTransferNote is the size of wallet_transfers.note
+	maxTransferNote = 255
+
+	// transferIdempotencyConstraint catches two first uses of one key racing
+	transferIdempotencyConstraint = "wallet_transfers_idempotency_key_key"
+)
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Transfers
+// =============================================================================
+
+// Transfer moves money from the authenticated user's wallet to another
+// user's in one ledger group and notifies both. Repeating a request with the
+// same idempotency key returns the original transfer.
+func (s *Service) Transfer(ctx context.Context, req TransferRequest) (*TransferResult, error) {
+	senderID, ok := auth.GetUserIDFromContext(ctx)
+	if !ok {
+		log.Printf("user id not found in context")
+		return nil, ErrUserIDNotFound
+	}
+
+	req.Recipient = strings.TrimSpace(req.Recipient)
+	req.Note = strings.TrimSpace(req.Note)
+	if req.IdempotencyKey == uuid.Nil || req.Recipient == "" || !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: idempotency_key, a recipient and a positive amount are required", ErrInvalidTransfer)
+	}
+	if len(req.Note) > maxTransferNote {
+		return nil, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidTransfer, maxTransferNote)
+	}
+	if err := s.limits.checkAmount(req.Amount); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		log.Printf("failed to begin transfer transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	walletQ := s.q.WithTx(tx)
+
+	recipient, err := findRecipient(ctx, walletQ, req.Recipient)
+	if err != nil {
+		return nil, err
+	}
+	if recipient.ID == senderID {
+		return nil, ErrSelfTransfer
+	}
+
+	senderWallet, err := s.personalWallet(ctx, walletQ, senderID)
+	if err != nil {
+		return nil, err
+	}
+	recipientWallet, err := s.personalWallet(ctx, walletQ, recipient.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Holding both wallets serialises the sender's transfers, so the replay
+	// check and the daily cap below see every earlier transfer. Post takes
+	// the same locks again.
+	if _, err := s.lockWallets(ctx, walletQ, senderWallet.ID, recipientWallet.ID); err != nil {
+		return nil, err
+	}
+
+	existing, err := walletQ.GetTransferByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err == nil {
+		if existing.SenderID != senderID || existing.RecipientID != recipient.ID || !existing.Amount.Equal(req.Amount) {
+			return nil, ErrIdempotencyKeyReused
+		}
+		return s.replayTransfer(ctx, walletQ, existing, senderWallet, recipient)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("failed to look up transfer %s: %v", req.IdempotencyKey, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if err := s.enforceDailyCap(ctx, walletQ, senderID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	debit, err := req.Amount.Neg()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTransfer, err)
+	}
+
+	transferID := uuid.New()
+	posting, err := s.Post(ctx, tx, PostingRequest{
+		TransactionType: TransactionTransfer,
+		ReferenceID:     transferID.String(),
+		Description:     transferDescription(senderWallet.Name, recipient.Name, req.Note),
+		Legs: []Leg{
+			{WalletID: senderWallet.ID, Amount: debit},
+			{WalletID: recipientWallet.ID, Amount: req.Amount},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	transfer, err := walletQ.CreateTransfer(ctx, walletdb.CreateTransferParams{
+		ID:                 transferID,
+		IdempotencyKey:     req.IdempotencyKey,
+		SenderID:           senderID,
+		RecipientID:        recipient.ID,
+		Amount:             req.Amount,
+		Note:               req.Note,
+		TransactionGroupID: posting.TransactionGroupID,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.ConstraintName == transferIdempotencyConstraint {
+		return nil, ErrIdempotencyKeyReused
+	} else if err != nil {
+		log.Printf("failed to record transfer %s: %v", transferID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	if err := s.notifyTransfer(ctx, tx, transfer, senderWallet.Name, recipient); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("failed to commit transfer %s: %v", transferID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	log.Printf("transfer %s: %s from user %s to user %s", transferID, req.Amount, senderID, recipient.ID)
+
+	return toTransferResult(transfer, recipient, senderBalance(posting.Entries, senderWallet.ID)), nil
+}
+
+// =============================================================================
+// PRIVATE SERVICE METHODS - Transfers
+// =============================================================================
+
+// enforceDailyCap refuses a transfer that would take the sender's outgoing
+// total for today over the cap. The caller holds the sender's wallet lock.
+func (s *Service) enforceDailyCap(ctx context.Context, walletQ *walletdb.Queries, senderID uuid.UUID, amount money.Money) error {
+	if s.limits.DailyCap.IsZero() {
+		return nil
+	}
+
+	total, err := walletQ.GetTransferredSince(ctx, walletdb.GetTransferredSinceParams{
+		SenderID: senderID,
+		Since:    s.limits.dayStart(time.Now()),
+	})
+	if err != nil {
+		log.Printf("failed to sum transfers of user %s: %v", senderID, err)
+		return fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	return s.limits.checkDailyCap(money.Paisa(total), amount)
+}
+
+// replayTransfer answers a repeated request with the transfer it created
+func (s *Service) replayTransfer(
+	ctx context.Context,
+	walletQ *walletdb.Queries,
+	transfer walletdb.GikiWalletWalletTransfer,
+	senderWallet walletdb.GikiWalletWallet,
+	recipient walletdb.FindRecipientByEmailRow,
+) (*TransferResult, error) {
+	rows, err := walletQ.GetLedgerEntriesByReference(ctx, walletdb.GetLedgerEntriesByReferenceParams{
+		TransactionType: string(TransactionTransfer),
+		ReferenceID:     transfer.ID.String(),
+	})
+	if err != nil {
+		log.Printf("failed to get ledger entries of transfer %s: %v", transfer.ID, err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	return toTransferResult(transfer, recipient, senderBalance(toPosting(rows, true).Entries, senderWallet.ID)), nil
+}
+
+// notifyTransfer tells both parties about a transfer, in its transaction
+func (s *Service) notifyTransfer(
+	ctx context.Context,
+	tx pgx.Tx,
+	transfer walletdb.GikiWalletWalletTransfer,
+	senderName string,
+	recipient walletdb.FindRecipientByEmailRow,
+) error {
+	data := map[string]string{
+		"transfer_id": transfer.ID.String(),
+		"amount":      transfer.Amount.MinorString(),
+	}
+
+	received := fmt.Sprintf("%s sent you %s.", senderName, transfer.Amount)
+	if transfer.Note != "" {
+		received += " Note: " + transfer.Note
+	}
+
+	if err := s.notifier.Notify(ctx, tx, transfer.SenderID, notification.Message{
+		Type:  notification.TypeTransferSent,
+		Title: "Transfer sent",
+		Body:  fmt.Sprintf("You sent %s to %s.", transfer.Amount, recipient.Name),
+		Data:  data,
+	}); err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, tx, transfer.RecipientID, notification.Message{
+		Type:  notification.TypeTransferReceived,
+		Title: "Money received",
+		Body:  received,
+		Data:  data,
+	})
+}
+
+// =============================================================================
+// HELPERS - Transfers
+// =============================================================================
+
+// findRecipient looks an active user up by email, or by student reg ID when
+// the identifier has no @
+func findRecipient(ctx context.Context, walletQ *walletdb.Queries, identifier string) (walletdb.FindRecipientByEmailRow, error) {
+	var (
+		recipient walletdb.FindRecipientByEmailRow
+		err       error
+	)
+	if strings.Contains(identifier, "@") {
+		recipient, err = walletQ.FindRecipientByEmail(ctx, identifier)
+	} else {
+		var row walletdb.FindRecipientByRegIDRow
+		row, err = walletQ.FindRecipientByRegID(ctx, identifier)
+		recipient = walletdb.FindRecipientByEmailRow(row)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return recipient, ErrRecipientNotFound
+	} else if err != nil {
+		log.Printf("failed to look up recipient %q: %v", identifier, err)
+		return recipient, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	return recipient, nil
+}
+
+// checkAmount applies the per-transfer maximum
+func (l TransferLimits) checkAmount(amount money.Money) error {
+	if l.MaxPerTransaction.IsZero() {
+		return nil
+	}
+	if above, err := amount.Cmp(l.MaxPerTransaction); err != nil || above > 0 {
+		return fmt.Errorf("%w: %s requested, maximum is %s", ErrTransferAboveMaximum, amount, l.MaxPerTransaction)
+	}
+	return nil
+}
+
+// checkDailyCap fails when used + amount would go over the daily cap
+func (l TransferLimits) checkDailyCap(used, amount money.Money) error {
+	if l.DailyCap.IsZero() {
+		return nil
+	}
+
+	total, err := used.Add(amount)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTransferDailyLimit, err)
+	}
+	if over, err := total.Cmp(l.DailyCap); err != nil || over > 0 {
+		return fmt.Errorf("%w: %s sent of %s today, %s requested", ErrTransferDailyLimit, used, l.DailyCap, amount)
+	}
+	return nil
+}
+
+// dayStart returns the start of the calendar day at now
+func (l TransferLimits) dayStart(now time.Time) time.Time {
+	location := l.Location
+	if location == nil {
+		location = common.PKT
+	}
+	local := now.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location)
+}
+
+// transferDescription is the ledger description both parties see
+func transferDescription(senderName, recipientName, note string) string {
+	description := fmt.Sprintf("Transfer from %s to %s", senderName, recipientName)
+	if note != "" {
+		description += ": " + note
+	}
+	return description
+}
+
+// senderBalance returns the balance_after of the sender's leg
+func senderBalance(entries []LedgerEntry, senderWalletID uuid.UUID) money.Money {
+	for _, entry := range entries {
+		if entry.WalletID == senderWalletID {
+			return entry.BalanceAfter
+		}
+	}
+	return money.Money{}
+}
+
+func toTransferResult(transfer walletdb.GikiWalletWalletTransfer, recipient walletdb.FindRecipientByEmailRow, balance money.Money) *TransferResult {
+	return &TransferResult{
+		ID:        transfer.ID,
+		Amount:    transfer.Amount,
+		Recipient: TransferParty{Name: recipient.Name, Email: recipient.Email},
+		Note:      transfer.Note,
+		Balance:   balance,
+		CreatedAt: transfer.CreatedAt,
+	}
+}
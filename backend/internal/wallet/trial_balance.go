@@ -0,0 +1,119 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hash-walker/giki-wallet/internal/money"
+	walletdb "github.com/hash-walker/giki-wallet/internal/wallet/wallet_db"
+	"github.com/jackc/pgx/v5"
+)
+
+// =============================================================================
+// SENTINEL ERRORS
+// =============================================================================
+
+var (
+	// ErrInvalidAsOf Unparseable or future trial balance time (400)
+	ErrInvalidAsOf = errors.New("as_of must be an RFC 3339 time or a YYYY-MM-DD date, not in the future")
+)
+
+// =============================================================================
+// PUBLIC SERVICE METHODS - Trial Balance
+// =============================================================================
+
+// TrialBalance sums the ledger as posted before asOf. Every transaction
+// group shares one created_at, so any asOf sees whole groups and a healthy
+// ledger totals zero. All sums are read from one snapshot.
+func (s *Service) TrialBalance(ctx context.Context, asOf time.Time) (*TrialBalance, error) {
+	tx, err := s.dbPool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		log.Printf("failed to begin trial balance transaction: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+	defer tx.Rollback(ctx)
+
+	walletQ := s.q.WithTx(tx)
+
+	systemWallets, err := walletQ.ListSystemWalletBalances(ctx, asOf)
+	if err != nil {
+		log.Printf("failed to get system wallet balances: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	personal, err := walletQ.GetPersonalWalletsTotal(ctx, asOf)
+	if err != nil {
+		log.Printf("failed to get personal wallet total: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	types, err := walletQ.SumLedgerByTransactionType(ctx, asOf)
+	if err != nil {
+		log.Printf("failed to sum ledger by transaction type: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+	}
+
+	balance, err := buildTrialBalance(asOf, systemWallets, personal, types)
+	if err != nil {
+		return nil, err
+	}
+	if !balance.Balanced {
+		log.Printf("LEDGER IMBALANCE: trial balance as of %s totals %s, unbalanced types %v",
+			asOf.Format(time.RFC3339), balance.Total, balance.UnbalancedTypes)
+	}
+	return balance, nil
+}
+
+// =============================================================================
+// HELPERS - Trial Balance
+// =============================================================================
+
+// buildTrialBalance totals the rows of the trial balance queries
+func buildTrialBalance(
+	asOf time.Time,
+	systemWallets []walletdb.ListSystemWalletBalancesRow,
+	personal walletdb.GetPersonalWalletsTotalRow,
+	types []walletdb.SumLedgerByTransactionTypeRow,
+) (*TrialBalance, error) {
+	balance := &TrialBalance{
+		AsOf:             asOf,
+		SystemWallets:    make([]TrialBalanceWallet, len(systemWallets)),
+		PersonalWallets:  TrialBalancePersonal{Wallets: personal.Wallets, Balance: money.Paisa(personal.Balance)},
+		TransactionTypes: make([]TrialBalanceLine, len(types)),
+	}
+
+	total := balance.PersonalWallets.Balance
+	for i, row := range systemWallets {
+		balance.SystemWallets[i] = TrialBalanceWallet{
+			Code:    row.Code.String,
+			Name:    row.Name,
+			Type:    WalletType(row.Type),
+			Balance: money.Paisa(row.Balance),
+		}
+
+		var err error
+		if total, err = total.Add(balance.SystemWallets[i].Balance); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseQuery, err)
+		}
+	}
+
+	for i, row := range types {
+		balance.TransactionTypes[i] = TrialBalanceLine{
+			TransactionType: TransactionType(row.TransactionType),
+			Entries:         row.Entries,
+			Debits:          money.Paisa(row.Debits),
+			Credits:         money.Paisa(row.Credits),
+			Net:             money.Paisa(row.Net),
+		}
+		if row.Net != 0 {
+			balance.UnbalancedTypes = append(balance.UnbalancedTypes, TransactionType(row.TransactionType))
+		}
+	}
+
+	balance.Total = total
+	balance.Balanced = total.IsZero() && len(balance.UnbalancedTypes) == 0
+	return balance, nil
+}
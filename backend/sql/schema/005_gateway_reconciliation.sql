@@ -0,0 +1,25 @@
+-- +goose up
+
+-- Reconciliation worker claims rows with an expiring lease instead of is_polling,
+-- so a crashed replica never strands a transaction.
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN lease_owner VARCHAR(100),
+    ADD COLUMN lease_expires_at TIMESTAMPTZ,
+    ADD COLUMN next_inquiry_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN inquiry_attempts INT NOT NULL DEFAULT 0,
+    DROP COLUMN is_polling;
+
+CREATE INDEX idx_gateway_transactions_due
+    ON giki_wallet.gateway_transactions (next_inquiry_at)
+    WHERE status IN ('PENDING', 'UNKNOWN');
+
+-- +goose down
+
+DROP INDEX giki_wallet.idx_gateway_transactions_due;
+
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN is_polling BOOLEAN DEFAULT FALSE,
+    DROP COLUMN inquiry_attempts,
+    DROP COLUMN next_inquiry_at,
+    DROP COLUMN lease_expires_at,
+    DROP COLUMN lease_owner;
@@ -0,0 +1,24 @@
+-- +goose up
+
+-- Server-to-server payment notifications (IPN). Gateways retry until they are
+-- acknowledged, so each one is recorded once and repeats are ignored.
+CREATE TABLE giki_wallet.gateway_notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway VARCHAR(50) NOT NULL,
+    txn_ref_no VARCHAR(50) NOT NULL,
+
+    -- Identifies a notification across retries (the gateway's secure hash)
+    dedup_key VARCHAR(255) NOT NULL,
+
+    response_code VARCHAR(20) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (gateway, dedup_key)
+);
+
+CREATE INDEX idx_gateway_notifications_txn_ref_no ON giki_wallet.gateway_notifications (txn_ref_no);
+
+-- +goose down
+
+DROP TABLE giki_wallet.gateway_notifications;
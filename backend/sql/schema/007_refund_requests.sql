@@ -0,0 +1,51 @@
+-- +goose up
+
+-- UNKNOWN: the request went out but no definite answer came back. It is not
+-- submitted again until an admin has checked with the gateway, since a
+-- second submission can pay out twice.
+CREATE TYPE refund_status AS ENUM ('PENDING', 'SUCCESS', 'FAILED', 'UNKNOWN');
+
+-- Full or partial refunds against a SUCCESS gateway transaction.
+-- PENDING rows are picked up by the reconciliation worker with the same lease
+-- scheme as gateway_transactions and retried until they reach a final status.
+-- So are FAILED rows whose wallet hold has not been given back yet.
+CREATE TABLE giki_wallet.refund_requests (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway_transaction_id uuid NOT NULL REFERENCES giki_wallet.gateway_transactions(id) ON DELETE RESTRICT,
+    idempotency_key uuid NOT NULL UNIQUE,
+    requested_by uuid NOT NULL REFERENCES giki_wallet.users(id),
+    reason TEXT NOT NULL,
+
+    -- Same unit as gateway_transactions.amount
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    status refund_status NOT NULL DEFAULT 'PENDING',
+
+    -- Gateway outcome of the latest attempt
+    attempts INT NOT NULL DEFAULT 0,
+    response_code VARCHAR(20),
+    response_message TEXT,
+    raw_response JSONB,
+
+    -- Worker scheduling
+    lease_owner VARCHAR(100),
+    lease_expires_at TIMESTAMPTZ,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- Set once the wallet hold of a FAILED refund has been given back
+    hold_released_at TIMESTAMPTZ,
+
+    processed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refund_requests_gateway_transaction_id ON giki_wallet.refund_requests (gateway_transaction_id);
+
+CREATE INDEX idx_refund_requests_due
+    ON giki_wallet.refund_requests (next_attempt_at)
+    WHERE status = 'PENDING' OR (status = 'FAILED' AND hold_released_at IS NULL);
+
+-- +goose down
+
+DROP TABLE giki_wallet.refund_requests;
+DROP TYPE refund_status;
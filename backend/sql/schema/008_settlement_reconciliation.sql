@@ -0,0 +1,56 @@
+-- +goose up
+
+CREATE TYPE settlement_discrepancy_kind AS ENUM (
+    'PAID_NOT_SUCCESS',     -- settled by the gateway, FAILED/PENDING or unknown locally
+    'SUCCESS_NOT_SETTLED',  -- SUCCESS locally, absent from the settlement file
+    'AMOUNT_MISMATCH'       -- present on both sides with different amounts
+);
+
+-- One import of a gateway settlement file
+CREATE TABLE giki_wallet.settlement_runs (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway VARCHAR(50) NOT NULL,
+    source_name VARCHAR(255) NOT NULL,
+    imported_by uuid REFERENCES giki_wallet.users(id),
+
+    -- Local SUCCESS transactions in this window are expected in the file
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+
+    row_count INT NOT NULL DEFAULT 0,
+    matched_count INT NOT NULL DEFAULT 0,
+    discrepancy_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE giki_wallet.settlement_discrepancies (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    run_id uuid NOT NULL REFERENCES giki_wallet.settlement_runs(id) ON DELETE CASCADE,
+    kind settlement_discrepancy_kind NOT NULL,
+
+    txn_ref_no VARCHAR(50),
+    gateway_rrn VARCHAR(50),
+    local_status current_status,
+    local_amount_paisa BIGINT,
+    gateway_amount_paisa BIGINT,
+
+    -- Resolution tracking
+    resolved_at TIMESTAMPTZ,
+    resolved_by uuid REFERENCES giki_wallet.users(id),
+    resolution_note TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_settlement_discrepancies_run_id ON giki_wallet.settlement_discrepancies (run_id);
+CREATE INDEX idx_settlement_discrepancies_open
+    ON giki_wallet.settlement_discrepancies (created_at)
+    WHERE resolved_at IS NULL;
+CREATE INDEX idx_gateway_transactions_gateway_rrn ON giki_wallet.gateway_transactions (gateway_rrn);
+
+-- +goose down
+
+DROP INDEX giki_wallet.idx_gateway_transactions_gateway_rrn;
+DROP TABLE giki_wallet.settlement_discrepancies;
+DROP TABLE giki_wallet.settlement_runs;
+DROP TYPE settlement_discrepancy_kind;
@@ -0,0 +1,53 @@
+-- +goose up
+
+CREATE TYPE gateway_event_kind AS ENUM ('INITIATE', 'INQUIRY', 'CALLBACK', 'IPN', 'REFUND');
+
+-- Every exchange with a gateway about one transaction, oldest first.
+-- Payloads are stored with secrets (pp_Password, MPIN, ...) redacted.
+-- Rows are never updated or deleted so the history can settle disputes.
+CREATE TABLE giki_wallet.gateway_transaction_events (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway_transaction_id uuid NOT NULL REFERENCES giki_wallet.gateway_transactions(id) ON DELETE RESTRICT,
+    gateway VARCHAR(50) NOT NULL,
+    kind gateway_event_kind NOT NULL,
+
+    request JSONB,
+    response JSONB,
+
+    -- What the gateway reported, when it answered
+    response_code VARCHAR(20),
+    gateway_rrn VARCHAR(50),
+
+    -- Transport or verification failure, when it did not
+    error TEXT,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_gateway_transaction_events_transaction
+    ON giki_wallet.gateway_transaction_events (gateway_transaction_id, created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION giki_wallet.reject_gateway_event_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'gateway_transaction_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER gateway_transaction_events_append_only
+    BEFORE UPDATE OR DELETE ON giki_wallet.gateway_transaction_events
+    FOR EACH ROW EXECUTE FUNCTION giki_wallet.reject_gateway_event_change();
+
+-- Final gateway answer, next to gateway_rrn and raw_response
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN response_code VARCHAR(20);
+
+-- +goose down
+
+ALTER TABLE giki_wallet.gateway_transactions
+    DROP COLUMN response_code;
+
+DROP TABLE giki_wallet.gateway_transaction_events;
+DROP FUNCTION giki_wallet.reject_gateway_event_change();
+DROP TYPE gateway_event_kind;
@@ -0,0 +1,27 @@
+-- +goose up
+
+-- Announce every status change on the gateway_transaction_status channel so
+-- each API replica can push it to clients streaming that transaction.
+-- NOTIFY is delivered on commit, so listeners never see a rolled back status.
+-- +goose StatementBegin
+CREATE FUNCTION giki_wallet.notify_gateway_transaction_status() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify(
+        'gateway_transaction_status',
+        json_build_object('txn_ref_no', NEW.txn_ref_no, 'status', NEW.status)::text
+    );
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER gateway_transactions_status_notify
+    AFTER UPDATE OF status ON giki_wallet.gateway_transactions
+    FOR EACH ROW
+    WHEN (OLD.status IS DISTINCT FROM NEW.status)
+    EXECUTE FUNCTION giki_wallet.notify_gateway_transaction_status();
+
+-- +goose down
+
+DROP TRIGGER gateway_transactions_status_notify ON giki_wallet.gateway_transactions;
+DROP FUNCTION giki_wallet.notify_gateway_transaction_status();
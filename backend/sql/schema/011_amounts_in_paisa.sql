@@ -0,0 +1,18 @@
+-- +goose up
+
+-- Amounts were stored in whole rupees and multiplied by 100 on the way to the
+-- gateway. Store paisa everywhere instead, the unit gateways and settlement
+-- files already use, so the database never needs a conversion factor.
+UPDATE giki_wallet.gateway_transactions SET amount = amount * 100;
+UPDATE giki_wallet.refund_requests SET amount = amount * 100;
+
+COMMENT ON COLUMN giki_wallet.gateway_transactions.amount IS 'PKR paisa';
+COMMENT ON COLUMN giki_wallet.refund_requests.amount IS 'PKR paisa';
+
+-- +goose down
+
+COMMENT ON COLUMN giki_wallet.refund_requests.amount IS NULL;
+COMMENT ON COLUMN giki_wallet.gateway_transactions.amount IS NULL;
+
+UPDATE giki_wallet.refund_requests SET amount = amount / 100;
+UPDATE giki_wallet.gateway_transactions SET amount = amount / 100;
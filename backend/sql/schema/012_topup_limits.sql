@@ -0,0 +1,9 @@
+-- +goose up
+
+-- Top-up limits sum and count a user's recent transactions on every initiate
+CREATE INDEX idx_gateway_transactions_user_created
+    ON giki_wallet.gateway_transactions (user_id, created_at);
+
+-- +goose down
+
+DROP INDEX giki_wallet.idx_gateway_transactions_user_created;
@@ -0,0 +1,15 @@
+-- +goose up
+
+-- Admin actions on a transaction (manual resolution) go into the same
+-- append-only history as gateway exchanges, with the admin and reason.
+ALTER TYPE gateway_event_kind ADD VALUE 'MANUAL';
+
+-- Admin console lists newest first and filters by status
+CREATE INDEX idx_gateway_transactions_created
+    ON giki_wallet.gateway_transactions (created_at DESC, id DESC);
+
+-- +goose down
+
+DROP INDEX giki_wallet.idx_gateway_transactions_created;
+
+-- Enum values cannot be dropped; MANUAL stays but is no longer written.
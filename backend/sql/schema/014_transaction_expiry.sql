@@ -0,0 +1,64 @@
+-- +goose up
+
+-- When the gateway stops accepting the transaction, the pp_TxnExpiryDateTime we
+-- sent it. Until then a PENDING transaction may still be approved, so nothing
+-- is closed as FAILED before expires_at has passed and a final inquiry ran.
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN expires_at TIMESTAMPTZ;
+
+UPDATE giki_wallet.gateway_transactions
+SET expires_at = created_at + CASE payment_method
+    WHEN 'CARD' THEN INTERVAL '1 hour'
+    ELSE INTERVAL '24 hours'
+END;
+
+ALTER TABLE giki_wallet.gateway_transactions
+    ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX idx_gateway_transactions_expiry
+    ON giki_wallet.gateway_transactions (expires_at)
+    WHERE status IN ('PENDING', 'UNKNOWN');
+
+-- Transactions the gateway reported as paid after we had closed them as FAILED.
+-- The status flip happens through a separate path and every one is kept here
+-- for finance to review. Rows are never updated or deleted.
+CREATE TABLE giki_wallet.gateway_late_successes (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway_transaction_id uuid NOT NULL REFERENCES giki_wallet.gateway_transactions(id) ON DELETE RESTRICT,
+
+    -- What reported the success: INQUIRY, IPN or CALLBACK
+    source gateway_event_kind NOT NULL,
+
+    -- Answer that closed the transaction as FAILED, and the one that reopened it
+    previous_response_code VARCHAR(20),
+    response_code VARCHAR(20),
+    gateway_rrn VARCHAR(50),
+
+    failed_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_gateway_late_successes_created
+    ON giki_wallet.gateway_late_successes (created_at DESC);
+
+-- +goose StatementBegin
+CREATE FUNCTION giki_wallet.reject_late_success_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'gateway_late_successes is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER gateway_late_successes_append_only
+    BEFORE UPDATE OR DELETE ON giki_wallet.gateway_late_successes
+    FOR EACH ROW EXECUTE FUNCTION giki_wallet.reject_late_success_change();
+
+-- +goose down
+
+DROP TABLE giki_wallet.gateway_late_successes;
+DROP FUNCTION giki_wallet.reject_late_success_change();
+
+DROP INDEX giki_wallet.idx_gateway_transactions_expiry;
+
+ALTER TABLE giki_wallet.gateway_transactions
+    DROP COLUMN expires_at;
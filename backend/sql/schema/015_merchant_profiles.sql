@@ -0,0 +1,11 @@
+-- +goose up
+
+-- Merchant account the transaction was created under, e.g. student_transport.
+-- Inquiries, refunds and callbacks are signed with this profile's credentials,
+-- so it never changes after creation. Rows from before profiles used 'default'.
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN merchant_profile VARCHAR(50) NOT NULL DEFAULT 'default';
+
+-- +goose down
+ALTER TABLE giki_wallet.gateway_transactions
+    DROP COLUMN merchant_profile;
@@ -0,0 +1,38 @@
+-- +goose up
+
+CREATE TYPE bank_transfer_review AS ENUM ('PENDING', 'APPROVED', 'REJECTED');
+
+-- Top-ups paid by bank transfer to the university account. Each has a
+-- BANK_TRANSFER gateway_transactions row that stays PENDING until an admin
+-- checks the receipt, and then goes to SUCCESS or FAILED like any top-up.
+-- Nothing polls these rows: the reconciler and expiry sweeper skip them.
+CREATE TABLE giki_wallet.bank_transfers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    gateway_transaction_id uuid NOT NULL UNIQUE REFERENCES giki_wallet.gateway_transactions(id) ON DELETE RESTRICT,
+
+    -- What the user gave us: the bank's reference and a receipt image in storage
+    bank_reference VARCHAR(100) NOT NULL,
+    receipt_key VARCHAR(255) NOT NULL,
+    receipt_content_type VARCHAR(100) NOT NULL,
+
+    review_status bank_transfer_review NOT NULL DEFAULT 'PENDING',
+    reviewed_by uuid REFERENCES giki_wallet.users(id),
+    review_reason TEXT,
+    reviewed_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A transfer can only be claimed once; a rejected claim frees its reference
+CREATE UNIQUE INDEX idx_bank_transfers_reference
+    ON giki_wallet.bank_transfers (bank_reference)
+    WHERE review_status <> 'REJECTED';
+
+CREATE INDEX idx_bank_transfers_review_queue
+    ON giki_wallet.bank_transfers (review_status, created_at);
+
+-- +goose down
+
+DROP TABLE giki_wallet.bank_transfers;
+DROP TYPE bank_transfer_review;
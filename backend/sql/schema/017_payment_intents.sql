@@ -0,0 +1,76 @@
+-- +goose up
+
+CREATE TYPE payment_intent_state AS ENUM (
+    'REQUIRES_PAYMENT', 'PROCESSING', 'FAILED', 'SUCCEEDED', 'FULFILLED', 'CANCELLED'
+);
+
+-- What a payment is for. A top-up, a ticket or anything else a module sells
+-- creates an intent; each attempt to pay it is a gateway_transactions row.
+-- Once an attempt succeeds the intent is SUCCEEDED, and it becomes FULFILLED
+-- when the handler registered for its purpose has delivered what was paid for.
+CREATE TABLE giki_wallet.payment_intents (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Purpose (e.g. TOPUP) and the owning module's id for the thing being paid for
+    purpose VARCHAR(50) NOT NULL,
+    purpose_reference VARCHAR(100) NOT NULL,
+
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    payer_id uuid NOT NULL REFERENCES giki_wallet.users(id) ON DELETE RESTRICT,
+    state payment_intent_state NOT NULL DEFAULT 'REQUIRES_PAYMENT',
+
+    -- Purpose-specific details, e.g. a guest's name on a ticket
+    metadata JSONB,
+
+    -- Completion handler retries while SUCCEEDED
+    fulfillment_attempts INT NOT NULL DEFAULT 0,
+    next_fulfillment_at TIMESTAMPTZ,
+    last_error TEXT,
+    fulfilled_at TIMESTAMPTZ,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A module asks for payment of one thing once; a cancelled intent frees its reference
+CREATE UNIQUE INDEX idx_payment_intents_reference
+    ON giki_wallet.payment_intents (purpose, purpose_reference)
+    WHERE state <> 'CANCELLED';
+
+CREATE INDEX idx_payment_intents_unfulfilled
+    ON giki_wallet.payment_intents (next_fulfillment_at)
+    WHERE state = 'SUCCEEDED';
+
+ALTER TABLE giki_wallet.gateway_transactions
+    ADD COLUMN payment_intent_id uuid REFERENCES giki_wallet.payment_intents(id) ON DELETE RESTRICT;
+
+-- Every existing transaction was a top-up. Paid ones stay SUCCEEDED, not
+-- FULFILLED, so a TOPUP handler registered later still completes them.
+INSERT INTO giki_wallet.payment_intents (purpose, purpose_reference, amount, payer_id, state, next_fulfillment_at, created_at, updated_at)
+SELECT 'TOPUP', t.idempotency_key::text, t.amount, t.user_id,
+    CASE t.status
+        WHEN 'SUCCESS' THEN 'SUCCEEDED'
+        WHEN 'FAILED' THEN 'FAILED'
+        ELSE 'PROCESSING'
+    END::payment_intent_state,
+    CASE WHEN t.status = 'SUCCESS' THEN NOW() END,
+    t.created_at, t.updated_at
+FROM giki_wallet.gateway_transactions t;
+
+UPDATE giki_wallet.gateway_transactions t
+SET payment_intent_id = i.id
+FROM giki_wallet.payment_intents i
+WHERE i.purpose = 'TOPUP' AND i.purpose_reference = t.idempotency_key::text;
+
+ALTER TABLE giki_wallet.gateway_transactions
+    ALTER COLUMN payment_intent_id SET NOT NULL;
+
+CREATE INDEX idx_gateway_transactions_intent
+    ON giki_wallet.gateway_transactions (payment_intent_id);
+
+-- +goose down
+
+ALTER TABLE giki_wallet.gateway_transactions
+    DROP COLUMN payment_intent_id;
+DROP TABLE giki_wallet.payment_intents;
+DROP TYPE payment_intent_state;
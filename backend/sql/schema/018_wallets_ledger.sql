@@ -0,0 +1,82 @@
+-- +goose up
+
+CREATE TYPE wallet_type AS ENUM ('PERSONAL', 'SYS_REVENUE', 'SYS_LIABILITY');
+CREATE TYPE wallet_status AS ENUM ('ACTIVE', 'FROZEN');
+
+-- One PERSONAL wallet per user, created on first use, plus system wallets
+-- (identified by code) that carry the other side of every posting.
+-- A wallet has no balance column: its balance is the balance_after of its
+-- latest ledger row. Every posting records the wallet's newest row_hash and
+-- its number of entries under the wallet lock, so verification notices a
+-- chain whose newest rows were removed.
+CREATE TABLE giki_wallet.wallets (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid UNIQUE REFERENCES giki_wallet.users(id) ON DELETE RESTRICT,
+    code VARCHAR(50) UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    type wallet_type NOT NULL DEFAULT 'PERSONAL',
+    status wallet_status NOT NULL DEFAULT 'ACTIVE',
+    currency VARCHAR(3) NOT NULL DEFAULT 'PKR',
+    head_hash VARCHAR(255),
+    entry_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK (
+        (type = 'PERSONAL' AND user_id IS NOT NULL AND code IS NULL) OR
+        (type <> 'PERSONAL' AND user_id IS NULL AND code IS NOT NULL)
+    )
+);
+
+-- Money received through the gateways; it is debited by every top-up, so its
+-- balance is minus what users have topped up
+INSERT INTO giki_wallet.wallets (code, name, type)
+VALUES ('GATEWAY_CLEARING', 'Gateway clearing', 'SYS_LIABILITY');
+
+-- Append-only double-entry ledger. The rows of one transaction_group_id sum
+-- to zero; amount is +credit / -debit in paisa. seq orders the rows of a
+-- wallet, since rows posted in one database transaction share created_at.
+CREATE TABLE giki_wallet.ledger (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL UNIQUE,
+    wallet_id uuid NOT NULL REFERENCES giki_wallet.wallets(id) ON DELETE RESTRICT,
+    amount BIGINT NOT NULL CHECK (amount <> 0),
+    balance_after BIGINT NOT NULL,
+    transaction_group_id uuid NOT NULL,
+    transaction_type VARCHAR(50) NOT NULL,
+    reference_id VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    row_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A business action (e.g. TOPUP of one payment intent) is posted once: a
+-- retried posting hits this index instead of moving money twice
+CREATE UNIQUE INDEX idx_ledger_reference
+    ON giki_wallet.ledger (transaction_type, reference_id, wallet_id);
+
+CREATE INDEX idx_ledger_wallet ON giki_wallet.ledger (wallet_id, seq);
+CREATE INDEX idx_ledger_group ON giki_wallet.ledger (transaction_group_id);
+
+-- Corrections are new postings, never edits
+-- +goose StatementBegin
+CREATE FUNCTION giki_wallet.reject_ledger_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows are immutable';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER ledger_immutable
+    BEFORE UPDATE OR DELETE ON giki_wallet.ledger
+    FOR EACH ROW
+    EXECUTE FUNCTION giki_wallet.reject_ledger_change();
+
+-- +goose down
+
+DROP TRIGGER ledger_immutable ON giki_wallet.ledger;
+DROP FUNCTION giki_wallet.reject_ledger_change();
+DROP TABLE giki_wallet.ledger;
+DROP TABLE giki_wallet.wallets;
+DROP TYPE wallet_status;
+DROP TYPE wallet_type;
@@ -0,0 +1,44 @@
+-- +goose up
+
+-- Peer-to-peer transfers. The money moves in the TRANSFER ledger group whose
+-- reference_id is this row's id; the row keeps the client's idempotency key
+-- and what the daily cap sums.
+CREATE TABLE giki_wallet.wallet_transfers (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    idempotency_key uuid NOT NULL UNIQUE,
+    sender_id uuid NOT NULL REFERENCES giki_wallet.users(id) ON DELETE RESTRICT,
+    recipient_id uuid NOT NULL REFERENCES giki_wallet.users(id) ON DELETE RESTRICT,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    note VARCHAR(255) NOT NULL DEFAULT '',
+    transaction_group_id uuid NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK (sender_id <> recipient_id)
+);
+
+CREATE INDEX idx_wallet_transfers_sender ON giki_wallet.wallet_transfers (sender_id, created_at);
+
+-- Notification queue. IN_APP notifications are delivered by being stored;
+-- other channels stay PENDING until a sender picks them up.
+CREATE TABLE giki_wallet.notifications (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id uuid NOT NULL REFERENCES giki_wallet.users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    channel VARCHAR(20) NOT NULL DEFAULT 'IN_APP',
+    destination VARCHAR(255),
+    title VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    data JSONB,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    error_log TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_notifications_user ON giki_wallet.notifications (user_id, created_at DESC);
+
+-- +goose down
+
+DROP TABLE giki_wallet.notifications;
+DROP TABLE giki_wallet.wallet_transfers;
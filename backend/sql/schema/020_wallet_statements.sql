@@ -0,0 +1,34 @@
+-- +goose up
+
+-- Every statement handed out, so the verification code printed on it can be
+-- checked. The figures are what the statement showed; document_sha256 lets a
+-- reader confirm a file was not altered after download.
+CREATE TABLE giki_wallet.wallet_statements (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    verification_code VARCHAR(20) NOT NULL UNIQUE,
+    wallet_id uuid NOT NULL REFERENCES giki_wallet.wallets(id) ON DELETE RESTRICT,
+    holder_name VARCHAR(150) NOT NULL,
+
+    -- [period_start, period_end): whole days in Pakistan time
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+
+    opening_balance BIGINT NOT NULL,
+    closing_balance BIGINT NOT NULL,
+    total_credits BIGINT NOT NULL,
+    total_debits BIGINT NOT NULL,
+    entry_count INT NOT NULL,
+
+    format VARCHAR(10) NOT NULL,
+    document_sha256 VARCHAR(64) NOT NULL,
+    issued_by uuid NOT NULL REFERENCES giki_wallet.users(id) ON DELETE RESTRICT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CHECK (period_end > period_start)
+);
+
+CREATE INDEX idx_wallet_statements_wallet ON giki_wallet.wallet_statements (wallet_id, created_at);
+
+-- +goose down
+
+DROP TABLE giki_wallet.wallet_statements;
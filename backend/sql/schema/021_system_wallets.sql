@@ -0,0 +1,19 @@
+-- +goose up
+
+-- Revenue of the transport office: credited by every ticket paid from a
+-- wallet, so its balance is what users have spent on tickets
+INSERT INTO giki_wallet.wallets (code, name, type)
+VALUES ('TRANSPORT_REVENUE', 'Transport revenue', 'SYS_REVENUE')
+ON CONFLICT (code) DO NOTHING;
+
+-- The trial balance reads balances as of a point in time
+CREATE INDEX idx_ledger_created_at ON giki_wallet.ledger (created_at);
+
+-- +goose down
+
+DROP INDEX giki_wallet.idx_ledger_created_at;
+
+-- Only while nothing has been posted to it; the ledger keeps the wallet otherwise
+DELETE FROM giki_wallet.wallets w
+WHERE w.code = 'TRANSPORT_REVENUE'
+  AND NOT EXISTS (SELECT 1 FROM giki_wallet.ledger l WHERE l.wallet_id = w.id);